@@ -264,6 +264,12 @@ func createTransaction(t *testing.T, endpoint, from, to, amount string) uuid.UUI
 }
 
 func tryCreateTransaction(endpoint, from, to, amount string) (uuid.UUID, error) {
+	for _, account := range []string{from, to} {
+		if err := ensureAccount(endpoint, account); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
 	reqBody := TransactionRequest{
 		FromAccount: from,
 		ToAccount:   to,
@@ -298,6 +304,31 @@ func tryCreateTransaction(endpoint, from, to, amount string) (uuid.UUID, error)
 	return txResp.Transaction.ID, nil
 }
 
+// ensureAccount registers an account, treating an existing one as success
+func ensureAccount(endpoint, id string) error {
+	jsonData, err := json.Marshal(map[string]string{
+		"id":       id,
+		"owner":    "integration-test",
+		"currency": "USD",
+	})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: TestTimeout}
+	resp, err := client.Post(endpoint+"/accounts", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		return fmt.Errorf("unexpected status code creating account %s: %d", id, resp.StatusCode)
+	}
+
+	return nil
+}
+
 func getTransaction(t *testing.T, endpoint string, txID uuid.UUID) *models.Transaction {
 	client := &http.Client{Timeout: TestTimeout}
 	resp, err := client.Get(endpoint + "/transactions/" + txID.String())
@@ -323,10 +354,27 @@ func getTransaction(t *testing.T, endpoint string, txID uuid.UUID) *models.Trans
 }
 
 func updateTransactionStatus(t *testing.T, endpoint string, txID uuid.UUID, status string) {
-	// This assumes you have a PATCH or PUT endpoint for updating status
-	// If not, you'll need to add it to the API
-	// For now, this is a placeholder
-	t.Logf("Updating transaction %s status to %s (endpoint not yet implemented)", txID, status)
+	jsonData, err := json.Marshal(map[string]string{"status": status})
+	if err != nil {
+		t.Fatalf("Failed to marshal status update: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, endpoint+"/transactions/"+txID.String()+"/status", bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Fatalf("Failed to build status update request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: TestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to update transaction status: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status code updating transaction status: %d", resp.StatusCode)
+	}
 }
 
 func listTransactions(t *testing.T, endpoint string) []*models.Transaction {
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// CreateAccount handles POST /accounts
+func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var req models.AccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate request
+	if req.Owner == "" || req.Currency == "" {
+		h.respondError(w, http.StatusBadRequest, "Missing required fields", nil)
+		return
+	}
+
+	if !models.IsValidCurrencyCode(req.Currency) {
+		h.respondError(w, http.StatusBadRequest, "Invalid currency code", nil)
+		return
+	}
+
+	if models.IsSystemAccount(req.ID) {
+		h.respondError(w, http.StatusBadRequest, "Account ID is reserved", nil)
+		return
+	}
+
+	overdraftLimit := decimal.Zero
+	if req.OverdraftLimit != "" {
+		parsed, err := models.ParseAmount(req.OverdraftLimit, req.Currency)
+		if err != nil || parsed.Value.IsNegative() {
+			h.respondError(w, http.StatusBadRequest, "Invalid overdraft limit", err)
+			return
+		}
+		overdraftLimit = parsed.Value
+	}
+
+	id := req.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	now := time.Now().UTC()
+	account := &models.Account{
+		ID:             id,
+		Owner:          req.Owner,
+		Currency:       req.Currency,
+		Status:         models.AccountStatusActive,
+		Balance:        decimal.Zero,
+		OverdraftLimit: overdraftLimit,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := h.db.CreateAccount(r.Context(), account); err != nil {
+		if errors.Is(err, models.ErrAccountExists) {
+			h.respondError(w, http.StatusConflict, "Account already exists", err)
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, "Failed to create account", err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, models.AccountResponse{
+		Account: account,
+		Message: "Account created successfully",
+	})
+}
+
+// GetAccount handles GET /accounts/{id}
+func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.lookupAccount(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, models.AccountResponse{
+		Account: account,
+	})
+}
+
+// GetAccountBalance handles GET /accounts/{id}/balance
+func (h *Handler) GetAccountBalance(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.lookupAccount(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, models.AccountBalance{
+		AccountID: account.ID,
+		Currency:  account.Currency,
+		Balance:   account.Balance,
+		Held:      account.Held,
+		Available: account.Available(),
+		AsOf:      time.Now().UTC(),
+	})
+}
+
+// lookupAccount loads an account and writes the error response if it can't be found
+func (h *Handler) lookupAccount(ctx context.Context, w http.ResponseWriter, id string) (*models.Account, bool) {
+	account, err := h.db.GetAccount(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrAccountNotFound) {
+			h.respondError(w, http.StatusNotFound, "Account not found", err)
+			return nil, false
+		}
+		h.respondError(w, http.StatusInternalServerError, "Failed to get account", err)
+		return nil, false
+	}
+
+	return account, true
+}
+
+// checkTransferAccounts verifies that both sides of a transfer exist, are open
+// and are not system accounts, and writes the error response if they are not
+func (h *Handler) checkTransferAccounts(ctx context.Context, w http.ResponseWriter, fromID, toID string) (*models.Account, *models.Account, bool) {
+	from, to, reqErr := h.transferAccounts(ctx, fromID, toID)
+	if reqErr != nil {
+		h.writeRequestError(w, reqErr)
+		return nil, nil, false
+	}
+	return from, to, true
+}
+
+// transferAccounts loads both sides of a transfer and checks that they are open
+// client accounts
+func (h *Handler) transferAccounts(ctx context.Context, fromID, toID string) (*models.Account, *models.Account, *requestError) {
+	accounts := make([]*models.Account, 0, 2)
+	for _, id := range []string{fromID, toID} {
+		if models.IsSystemAccount(id) {
+			return nil, nil, &requestError{status: http.StatusUnprocessableEntity, message: "Account is reserved: " + id, err: models.ErrSystemAccount}
+		}
+
+		account, err := h.db.GetAccount(ctx, id)
+		if err != nil {
+			if errors.Is(err, models.ErrAccountNotFound) {
+				return nil, nil, &requestError{status: http.StatusUnprocessableEntity, message: "Unknown account: " + id, err: err}
+			}
+			return nil, nil, &requestError{status: http.StatusInternalServerError, message: "Failed to get account", err: err}
+		}
+
+		if !account.IsActive() {
+			return nil, nil, &requestError{status: http.StatusUnprocessableEntity, message: "Account is closed: " + id, err: models.ErrAccountClosed}
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts[0], accounts[1], nil
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Test CreateAccount
+
+func TestCreateAccount_Success(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	var created *models.Account
+	mockDB.createAccountFunc = func(account *models.Account) error {
+		created = account
+		return nil
+	}
+
+	body, _ := json.Marshal(models.AccountRequest{Owner: "alice", Currency: "USD"})
+	req := httptest.NewRequest("POST", "/accounts", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	if created == nil {
+		t.Fatal("Expected account to be persisted")
+	}
+	if created.ID == "" {
+		t.Error("Expected generated account ID")
+	}
+	if created.Status != models.AccountStatusActive {
+		t.Errorf("Expected status active, got %s", created.Status)
+	}
+	if !created.Balance.IsZero() {
+		t.Errorf("Expected zero opening balance, got %s", created.Balance)
+	}
+}
+
+func TestCreateAccount_Validation(t *testing.T) {
+	handler, _, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{"invalid json", "not json"},
+		{"missing owner", `{"currency":"USD"}`},
+		{"missing currency", `{"owner":"alice"}`},
+		{"invalid currency", `{"owner":"alice","currency":"dollars"}`},
+		{"invalid overdraft", `{"owner":"alice","currency":"USD","overdraft_limit":"lots"}`},
+		{"negative overdraft", `{"owner":"alice","currency":"USD","overdraft_limit":"-5"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/accounts", bytes.NewReader([]byte(tc.body)))
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateAccount_Duplicate(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.createAccountFunc = func(account *models.Account) error {
+		return fmt.Errorf("%w: %s", models.ErrAccountExists, account.ID)
+	}
+
+	body, _ := json.Marshal(models.AccountRequest{ID: "acc1", Owner: "alice", Currency: "USD"})
+	req := httptest.NewRequest("POST", "/accounts", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+// Test GetAccount
+
+func TestGetAccount_Success(t *testing.T) {
+	handler, _, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	req := httptest.NewRequest("GET", "/accounts/acc1", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response models.AccountResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Account == nil || response.Account.ID != "acc1" {
+		t.Errorf("Expected account acc1, got %+v", response.Account)
+	}
+}
+
+func TestGetAccount_NotFound(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getAccountFunc = func(id string) (*models.Account, error) {
+		return nil, fmt.Errorf("%w: %s", models.ErrAccountNotFound, id)
+	}
+
+	req := httptest.NewRequest("GET", "/accounts/missing", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestGetAccount_DatabaseError(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getAccountFunc = func(id string) (*models.Account, error) {
+		return nil, errors.New("database error")
+	}
+
+	req := httptest.NewRequest("GET", "/accounts/acc1", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+// Test GetAccountBalance
+
+func TestGetAccountBalance_Success(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getAccountFunc = func(id string) (*models.Account, error) {
+		return &models.Account{
+			ID:       id,
+			Currency: "EUR",
+			Status:   models.AccountStatusActive,
+			Balance:  decimal.RequireFromString("42.10"),
+			Held:     decimal.RequireFromString("12.00"),
+		}, nil
+	}
+
+	req := httptest.NewRequest("GET", "/accounts/acc1/balance", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response models.AccountBalance
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Currency != "EUR" {
+		t.Errorf("Expected currency EUR, got %s", response.Currency)
+	}
+	if !response.Balance.Equal(decimal.RequireFromString("42.10")) {
+		t.Errorf("Expected balance 42.10, got %s", response.Balance)
+	}
+	if !response.Held.Equal(decimal.RequireFromString("12.00")) || !response.Available.Equal(decimal.RequireFromString("30.10")) {
+		t.Errorf("Expected 12.00 held and 30.10 available, got %s and %s", response.Held, response.Available)
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// SetMaxBatchSize sets the largest number of transfers accepted in one batch
+func (h *Handler) SetMaxBatchSize(size int) {
+	if size > 0 {
+		h.maxBatchSize = size
+	}
+}
+
+// CreateTransactionBatch handles POST /transactions/batch.
+// Every transfer in the batch is validated and then written in a single
+// database transaction, so either all of them are made or none are. When the
+// batch is rejected, results carry the error for each failing index.
+func (h *Handler) CreateTransactionBatch(w http.ResponseWriter, r *http.Request) {
+	var req models.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if len(req.Transactions) == 0 {
+		h.respondError(w, http.StatusBadRequest, "Batch contains no transactions", nil)
+		return
+	}
+	if len(req.Transactions) > h.maxBatchSize {
+		h.respondError(w, http.StatusBadRequest,
+			fmt.Sprintf("Batch contains %d transactions; the limit is %d", len(req.Transactions), h.maxBatchSize), nil)
+		return
+	}
+
+	batchID := uuid.New()
+	txs := make([]*models.Transaction, len(req.Transactions))
+	results := make([]models.BatchItemResult, len(req.Transactions))
+	failed := false
+	for i, item := range req.Transactions {
+		results[i].Index = i
+		tx, reqErr := h.newTransfer(r.Context(), item)
+		if reqErr != nil {
+			if reqErr.status == http.StatusInternalServerError {
+				h.respondError(w, reqErr.status, reqErr.message, reqErr.err)
+				return
+			}
+			results[i].Error = reqErr.message
+			results[i].Code = reqErr.code
+			failed = true
+			continue
+		}
+		txs[i] = tx
+	}
+
+	if failed {
+		h.respondBatchRejected(w, batchID, results)
+		return
+	}
+
+	// One audit log and message describe the whole batch. They are saved
+	// with it and delivered by the outbox relay once it commits.
+	ids := make([]uuid.UUID, len(txs))
+	for i, tx := range txs {
+		ids[i] = tx.ID
+	}
+	auditLog := &models.BatchAuditLog{
+		BatchID:        batchID,
+		Region:         h.region,
+		Action:         "transaction_batch_created",
+		Timestamp:      time.Now().UTC(),
+		TransactionIDs: ids,
+		Details:        fmt.Sprintf("Batch of %d transactions created via API", len(txs)),
+	}
+	key := fmt.Sprintf("batches/%s/%s.json", h.region, batchID.String())
+	event := &events.TransactionBatchCreated{BatchID: batchID, TransactionIDs: ids}
+	outbox, err := h.newOutbox(r, event, key, auditLog)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to create transactions", err)
+		return
+	}
+
+	response := func() interface{} {
+		for i, tx := range txs {
+			results[i].Transaction = tx
+		}
+		return models.BatchResponse{
+			BatchID: batchID,
+			Results: results,
+			Message: "Transaction batch created successfully",
+		}
+	}
+
+	// Save to database
+	completion := h.idempotentResponse(r, http.StatusCreated, response)
+	if err := h.db.CreateTransactions(r.Context(), txs, completion, outbox...); err != nil {
+		if h.respondReservationLost(w, err) {
+			return
+		}
+		var itemErr *models.BatchItemError
+		if !errors.As(err, &itemErr) || itemErr.Index < 0 || itemErr.Index >= len(results) {
+			h.respondError(w, http.StatusInternalServerError, "Failed to create transactions", err)
+			return
+		}
+
+		reqErr := transferError(itemErr.Err)
+		if reqErr.status == http.StatusInternalServerError {
+			h.respondError(w, reqErr.status, reqErr.message, err)
+			return
+		}
+		results[itemErr.Index].Error = reqErr.message
+		results[itemErr.Index].Code = reqErr.code
+		h.respondBatchRejected(w, batchID, results)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, response())
+}
+
+// respondBatchRejected writes the response for a batch that was not written
+func (h *Handler) respondBatchRejected(w http.ResponseWriter, batchID uuid.UUID, results []models.BatchItemResult) {
+	var rejected []models.BatchItemResult
+	for _, result := range results {
+		if result.Error != "" {
+			rejected = append(rejected, result)
+		}
+	}
+
+	h.logger.Warn("Transaction batch rejected",
+		zap.String("batch_id", batchID.String()),
+		zap.Int("rejected", len(rejected)),
+	)
+
+	h.respondJSON(w, http.StatusUnprocessableEntity, models.BatchResponse{
+		BatchID: batchID,
+		Results: rejected,
+		Error:   fmt.Sprintf("%d of %d transactions were rejected; no transactions were made", len(rejected), len(results)),
+		Code:    models.ErrorCodeBatchRejected,
+	})
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+)
+
+func postBatch(handler *Handler, items []models.TransactionRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(models.BatchRequest{Transactions: items})
+	w := httptest.NewRecorder()
+	createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("POST", "/transactions/batch", bytes.NewReader(body)))
+	return w
+}
+
+func decodeBatchResponse(t *testing.T, w *httptest.ResponseRecorder) models.BatchResponse {
+	t.Helper()
+	var response models.BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return response
+}
+
+func TestCreateTransactionBatch_Success(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	var written []*models.Transaction
+	mockDB.createTransactionsFunc = func(txs []*models.Transaction) error {
+		written = txs
+		return nil
+	}
+	mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+		t.Error("CreateTransaction should not be called")
+		return nil
+	}
+
+	w := postBatch(handler, []models.TransactionRequest{
+		{FromAccount: "acc1", ToAccount: "acc2", Amount: "10.00"},
+		{FromAccount: "acc2", ToAccount: "acc3", Amount: "2.50"},
+		{FromAccount: "acc3", ToAccount: "acc1", Amount: "1"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if len(written) != 3 {
+		t.Fatalf("Expected 3 transactions written together, got %d", len(written))
+	}
+
+	response := decodeBatchResponse(t, w)
+	if len(response.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(response.Results))
+	}
+	for i, result := range response.Results {
+		if result.Index != i || result.Transaction == nil || result.Transaction.ID != written[i].ID {
+			t.Errorf("Expected result %d to carry transaction %s, got %+v", i, written[i].ID, result)
+		}
+	}
+
+	// One audit object and one message for the whole batch, saved with it
+	var keys []string
+	var messages []*events.Envelope
+	for _, msg := range mockDB.outbox {
+		switch msg.Kind {
+		case models.OutboxKindAuditLog:
+			keys = append(keys, msg.Key)
+		case models.OutboxKindMessage:
+			var sent events.Envelope
+			if err := json.Unmarshal(msg.Payload, &sent); err != nil {
+				t.Fatalf("Failed to decode outbox message: %v", err)
+			}
+			messages = append(messages, &sent)
+		}
+	}
+	expectedKey := fmt.Sprintf("batches/us-east-1/%s.json", response.BatchID)
+	if len(keys) != 1 || keys[0] != expectedKey {
+		t.Errorf("Expected a single audit log at %s, got %v", expectedKey, keys)
+	}
+	if len(messages) != 1 || messages[0].Type != events.TypeTransactionBatchCreated || messages[0].Subject != response.BatchID.String() {
+		t.Fatalf("Expected a single TransactionBatchCreated event, got %+v", messages)
+	}
+	var created events.TransactionBatchCreated
+	if err := messages[0].Decode(&created); err != nil || len(created.TransactionIDs) != 3 {
+		t.Errorf("Expected the batch's 3 transactions in the payload, got %+v (%v)", created, err)
+	}
+}
+
+func TestCreateTransactionBatch_ValidationErrorsPerIndex(t *testing.T) {
+	handler, mockDB, _, mockPublisher := createTestHandler()
+
+	mockDB.createTransactionsFunc = func(txs []*models.Transaction) error {
+		t.Error("CreateTransactions should not be called")
+		return nil
+	}
+	mockPublisher.publishFunc = func(event *events.Envelope) error {
+		t.Error("SendMessage should not be called")
+		return nil
+	}
+
+	w := postBatch(handler, []models.TransactionRequest{
+		{FromAccount: "acc1", ToAccount: "acc2", Amount: "10.00"},
+		{FromAccount: "acc1", ToAccount: "acc1", Amount: "10.00"},
+		{FromAccount: "acc1", ToAccount: "acc2", Amount: "10.00", Currency: "EUR"},
+		{FromAccount: "acc1", ToAccount: "acc2", Amount: "1.001"},
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	response := decodeBatchResponse(t, w)
+	if response.Code != models.ErrorCodeBatchRejected {
+		t.Errorf("Expected code %q, got %q", models.ErrorCodeBatchRejected, response.Code)
+	}
+
+	if len(response.Results) != 3 {
+		t.Fatalf("Expected 3 rejected items, got %+v", response.Results)
+	}
+	for i, expected := range []int{1, 2, 3} {
+		if response.Results[i].Index != expected || response.Results[i].Error == "" {
+			t.Errorf("Expected an error for item %d, got %+v", expected, response.Results[i])
+		}
+	}
+	if response.Results[1].Code != models.ErrorCodeCurrencyMismatch {
+		t.Errorf("Expected code %q for item 2, got %q", models.ErrorCodeCurrencyMismatch, response.Results[1].Code)
+	}
+}
+
+func TestCreateTransactionBatch_DatabaseRejectsItem(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	mockDB.createTransactionsFunc = func(txs []*models.Transaction) error {
+		return &models.BatchItemError{
+			Index: 1,
+			Err:   fmt.Errorf("failed to create transaction: %w", models.ErrInsufficientFunds),
+		}
+	}
+
+	w := postBatch(handler, []models.TransactionRequest{
+		{FromAccount: "acc1", ToAccount: "acc2", Amount: "10.00"},
+		{FromAccount: "acc2", ToAccount: "acc3", Amount: "5000.00"},
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	response := decodeBatchResponse(t, w)
+	if len(response.Results) != 1 || response.Results[0].Index != 1 ||
+		response.Results[0].Code != models.ErrorCodeInsufficientFunds {
+		t.Errorf("Expected insufficient funds for item 1, got %+v", response.Results)
+	}
+}
+
+func TestCreateTransactionBatch_DatabaseError(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	mockDB.createTransactionsFunc = func(txs []*models.Transaction) error {
+		return fmt.Errorf("failed to commit transaction batch: connection reset")
+	}
+
+	w := postBatch(handler, []models.TransactionRequest{
+		{FromAccount: "acc1", ToAccount: "acc2", Amount: "10.00"},
+	})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestCreateTransactionBatch_Size(t *testing.T) {
+	tests := []struct {
+		name  string
+		items int
+	}{
+		{"empty", 0},
+		{"over limit", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			handler.SetMaxBatchSize(2)
+			mockDB.createTransactionsFunc = func(txs []*models.Transaction) error {
+				t.Error("CreateTransactions should not be called")
+				return nil
+			}
+
+			items := make([]models.TransactionRequest, tt.items)
+			for i := range items {
+				items[i] = models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "1"}
+			}
+
+			w := postBatch(handler, items)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+			if tt.items > 0 && !strings.Contains(w.Body.String(), "limit is 2") {
+				t.Errorf("Expected the limit in the error, got %s", w.Body.String())
+			}
+		})
+	}
+}
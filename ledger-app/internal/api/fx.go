@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/fx"
+	"github.com/project-atlas/ledger-app/internal/models"
+)
+
+// defaultQuoteTTL is how long an FX quote can be used when no TTL is configured
+const defaultQuoteTTL = 30 * time.Second
+
+// SetRateProvider enables FX quotes, priced by provider and valid for ttl
+func (h *Handler) SetRateProvider(provider fx.RateProvider, ttl time.Duration) {
+	h.rates = provider
+	if ttl > 0 {
+		h.quoteTTL = ttl
+	}
+}
+
+// CreateFXQuote handles POST /fx/quotes
+func (h *Handler) CreateFXQuote(w http.ResponseWriter, r *http.Request) {
+	if h.rates == nil {
+		h.respondError(w, http.StatusServiceUnavailable, "FX quotes are not enabled", nil)
+		return
+	}
+
+	var req models.FXQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate request
+	if req.FromCurrency == "" || req.ToCurrency == "" || req.Amount == "" {
+		h.respondError(w, http.StatusBadRequest, "Missing required fields", nil)
+		return
+	}
+
+	if !models.IsValidCurrencyCode(req.FromCurrency) || !models.IsValidCurrencyCode(req.ToCurrency) {
+		h.respondError(w, http.StatusBadRequest, "Unsupported currency", nil)
+		return
+	}
+
+	if req.FromCurrency == req.ToCurrency {
+		h.respondError(w, http.StatusBadRequest, "Source and target currencies must differ", nil)
+		return
+	}
+
+	amount, ok := h.parseAmount(w, req.Amount, req.FromCurrency)
+	if !ok {
+		return
+	}
+
+	rate, err := h.rates.Rate(req.FromCurrency, req.ToCurrency)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateUnavailable) {
+			h.respondError(w, http.StatusUnprocessableEntity,
+				fmt.Sprintf("No rate available for %s/%s", req.FromCurrency, req.ToCurrency), err)
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, "Failed to get FX rate", err)
+		return
+	}
+
+	quote, err := models.NewFXQuote(amount, req.ToCurrency, rate, h.quoteTTL)
+	if err != nil {
+		h.respondError(w, http.StatusUnprocessableEntity, "Amount is too small to convert", err)
+		return
+	}
+
+	if err := h.db.CreateFXQuote(r.Context(), quote); err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to create FX quote", err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, models.FXQuoteResponse{
+		Quote:   quote,
+		Message: "FX quote created successfully",
+	})
+}
+
+// resolveQuote loads the quote a transfer refers to and checks that it is
+// still usable and matches the transfer. The quote is only claimed when the
+// transfer is written, so two requests racing for the same quote are settled
+// by the database.
+func (h *Handler) resolveQuote(ctx context.Context, req models.TransactionRequest, fromAccount, toAccount *models.Account) (*models.FXQuote, *requestError) {
+	id, err := uuid.Parse(req.QuoteID)
+	if err != nil {
+		return nil, &requestError{status: http.StatusBadRequest, message: "Invalid quote ID", err: err}
+	}
+
+	quote, err := h.db.GetFXQuote(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrQuoteNotFound) {
+			return nil, &requestError{status: http.StatusUnprocessableEntity, message: "Unknown FX quote", err: err}
+		}
+		return nil, &requestError{status: http.StatusInternalServerError, message: "Failed to get FX quote", err: err}
+	}
+
+	if quote.TransactionID != nil {
+		return nil, &requestError{http.StatusConflict, models.ErrorCodeQuoteUsed, "FX quote has already been used", models.ErrQuoteUsed}
+	}
+	if quote.IsExpired(time.Now().UTC()) {
+		return nil, &requestError{http.StatusUnprocessableEntity, models.ErrorCodeQuoteExpired, "FX quote has expired", models.ErrQuoteExpired}
+	}
+
+	if fromAccount.Currency != quote.SourceAmount.Currency || toAccount.Currency != quote.TargetAmount.Currency {
+		return nil, &requestError{
+			status: http.StatusUnprocessableEntity,
+			code:   models.ErrorCodeCurrencyMismatch,
+			message: fmt.Sprintf("Quote converts %s to %s but accounts are held in %s and %s",
+				quote.SourceAmount.Currency, quote.TargetAmount.Currency, fromAccount.Currency, toAccount.Currency),
+			err: models.ErrQuoteMismatch,
+		}
+	}
+
+	// An amount sent alongside a quote must be the quoted one
+	if req.Currency != "" && req.Currency != quote.SourceAmount.Currency {
+		return nil, &requestError{status: http.StatusUnprocessableEntity, message: "Currency does not match FX quote", err: models.ErrQuoteMismatch}
+	}
+	if req.Amount != "" {
+		amount, reqErr := validateAmount(req.Amount, quote.SourceAmount.Currency)
+		if reqErr != nil {
+			return nil, reqErr
+		}
+		if !amount.Equal(quote.SourceAmount) {
+			return nil, &requestError{
+				status:  http.StatusUnprocessableEntity,
+				message: fmt.Sprintf("Amount does not match FX quote for %s", quote.SourceAmount),
+				err:     models.ErrQuoteMismatch,
+			}
+		}
+	}
+
+	return quote, nil
+}
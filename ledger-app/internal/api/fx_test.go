@@ -0,0 +1,260 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/fx"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func createFXTestHandler(t *testing.T) (*Handler, *mockDB) {
+	handler, mockDB, _, _ := createTestHandler()
+	rates, err := fx.NewStaticProvider(map[string]decimal.Decimal{
+		"USD/EUR": decimal.RequireFromString("0.9"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rate provider: %v", err)
+	}
+	handler.SetRateProvider(rates, time.Minute)
+	return handler, mockDB
+}
+
+func postQuote(handler *Handler, req models.FXQuoteRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("POST", "/fx/quotes", bytes.NewReader(body)))
+	return w
+}
+
+func TestCreateFXQuote_Success(t *testing.T) {
+	handler, mockDB := createFXTestHandler(t)
+
+	var stored *models.FXQuote
+	mockDB.createFXQuoteFunc = func(quote *models.FXQuote) error {
+		stored = quote
+		return nil
+	}
+
+	w := postQuote(handler, models.FXQuoteRequest{FromCurrency: "USD", ToCurrency: "EUR", Amount: "100"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response models.FXQuoteResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Quote == nil || stored == nil || response.Quote.ID != stored.ID {
+		t.Fatalf("Expected stored quote in response, got %+v", response.Quote)
+	}
+	if response.Quote.TargetAmount.String() != "90.00 EUR" {
+		t.Errorf("Expected target 90.00 EUR, got %s", response.Quote.TargetAmount)
+	}
+	if got := response.Quote.ExpiresAt.Sub(response.Quote.CreatedAt); got != time.Minute {
+		t.Errorf("Expected quote to be valid for 1m, got %s", got)
+	}
+}
+
+func TestCreateFXQuote_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      models.FXQuoteRequest
+		expected int
+	}{
+		{"missing amount", models.FXQuoteRequest{FromCurrency: "USD", ToCurrency: "EUR"}, http.StatusBadRequest},
+		{"unsupported currency", models.FXQuoteRequest{FromCurrency: "USD", ToCurrency: "XYZ", Amount: "10"}, http.StatusBadRequest},
+		{"same currency", models.FXQuoteRequest{FromCurrency: "USD", ToCurrency: "USD", Amount: "10"}, http.StatusBadRequest},
+		{"negative amount", models.FXQuoteRequest{FromCurrency: "USD", ToCurrency: "EUR", Amount: "-10"}, http.StatusBadRequest},
+		{"no rate", models.FXQuoteRequest{FromCurrency: "USD", ToCurrency: "GBP", Amount: "10"}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB := createFXTestHandler(t)
+			mockDB.createFXQuoteFunc = func(quote *models.FXQuote) error {
+				t.Error("CreateFXQuote should not be called")
+				return nil
+			}
+
+			if w := postQuote(handler, tt.req); w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateFXQuote_NotEnabled(t *testing.T) {
+	handler, _, _, _ := createTestHandler()
+
+	w := postQuote(handler, models.FXQuoteRequest{FromCurrency: "USD", ToCurrency: "EUR", Amount: "100"})
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+// fxAccounts serves acc1 in USD and acc2 in EUR
+func fxAccounts(id string) (*models.Account, error) {
+	currency := "USD"
+	if id == "acc2" {
+		currency = "EUR"
+	}
+	return &models.Account{ID: id, Currency: currency, Status: models.AccountStatusActive}, nil
+}
+
+func newTestQuote() *models.FXQuote {
+	now := time.Now().UTC()
+	return &models.FXQuote{
+		ID:           uuid.New(),
+		Rate:         decimal.RequireFromString("0.9"),
+		SourceAmount: models.Money{Value: decimal.NewFromInt(100), Currency: "USD"},
+		TargetAmount: models.Money{Value: decimal.NewFromInt(90), Currency: "EUR"},
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(time.Minute),
+	}
+}
+
+func TestCreateTransaction_WithQuote(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	quote := newTestQuote()
+	mockDB.getAccountFunc = fxAccounts
+	mockDB.getFXQuoteFunc = func(id uuid.UUID) (*models.FXQuote, error) {
+		return quote, nil
+	}
+	var created *models.Transaction
+	mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+		created = tx
+		return nil
+	}
+
+	body, _ := json.Marshal(models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", QuoteID: quote.ID.String()})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/transactions", bytes.NewReader(body)))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if !created.Amount.Equal(quote.SourceAmount) || !created.ConvertedAmount.Equal(quote.TargetAmount) {
+		t.Errorf("Expected both legs from the quote, got %s -> %v", created.Amount, created.ConvertedAmount)
+	}
+	if created.FXRate == nil || !created.FXRate.Equal(quote.Rate) || created.QuoteID == nil || *created.QuoteID != quote.ID {
+		t.Errorf("Expected rate and quote ID to be recorded, got %v %v", created.FXRate, created.QuoteID)
+	}
+}
+
+func TestCreateTransaction_QuoteRejected(t *testing.T) {
+	usedBy := uuid.New()
+
+	tests := []struct {
+		name         string
+		quote        func(q *models.FXQuote)
+		req          models.TransactionRequest
+		dbErr        error
+		expected     int
+		expectedCode string
+	}{
+		{
+			name:         "expired",
+			quote:        func(q *models.FXQuote) { q.ExpiresAt = time.Now().Add(-time.Second) },
+			expected:     http.StatusUnprocessableEntity,
+			expectedCode: models.ErrorCodeQuoteExpired,
+		},
+		{
+			name:         "already used",
+			quote:        func(q *models.FXQuote) { q.TransactionID = &usedBy },
+			expected:     http.StatusConflict,
+			expectedCode: models.ErrorCodeQuoteUsed,
+		},
+		{
+			name:         "used concurrently",
+			dbErr:        fmt.Errorf("failed to create transaction: %w", models.ErrQuoteUsed),
+			expected:     http.StatusConflict,
+			expectedCode: models.ErrorCodeQuoteUsed,
+		},
+		{
+			name:         "accounts in other currencies",
+			quote:        func(q *models.FXQuote) { q.TargetAmount.Currency = "GBP" },
+			expected:     http.StatusUnprocessableEntity,
+			expectedCode: models.ErrorCodeCurrencyMismatch,
+		},
+		{
+			name:     "different amount",
+			req:      models.TransactionRequest{Amount: "150"},
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "malformed quote ID",
+			req:      models.TransactionRequest{QuoteID: "not-a-uuid"},
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			router := createTestRouter(handler)
+
+			quote := newTestQuote()
+			if tt.quote != nil {
+				tt.quote(quote)
+			}
+			mockDB.getAccountFunc = fxAccounts
+			mockDB.getFXQuoteFunc = func(id uuid.UUID) (*models.FXQuote, error) {
+				return quote, nil
+			}
+			mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+				if tt.dbErr == nil {
+					t.Error("CreateTransaction should not be called")
+				}
+				return tt.dbErr
+			}
+
+			req := tt.req
+			req.FromAccount, req.ToAccount = "acc1", "acc2"
+			if req.QuoteID == "" {
+				req.QuoteID = quote.ID.String()
+			}
+			body, _ := json.Marshal(req)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/transactions", bytes.NewReader(body)))
+
+			if w.Code != tt.expected {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+			var response models.TransactionResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Code != tt.expectedCode {
+				t.Errorf("Expected code %q, got %q", tt.expectedCode, response.Code)
+			}
+		})
+	}
+}
+
+func TestCreateAccount_RejectsSystemAccountID(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.createAccountFunc = func(account *models.Account) error {
+		t.Error("CreateAccount should not be called")
+		return nil
+	}
+
+	body, _ := json.Marshal(models.AccountRequest{ID: models.FXPositionAccount("USD"), Owner: "mallory", Currency: "USD"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/accounts", bytes.NewReader(body)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -1,37 +1,52 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/fx"
 	"github.com/project-atlas/ledger-app/internal/models"
-	"github.com/project-atlas/ledger-app/internal/sqs"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
 // Handler holds all HTTP handlers
 type Handler struct {
-	db      DBInterface
-	s3      S3Interface
-	sqs     SQSInterface
-	region  string
-	logger  *zap.Logger
+	db               DBInterface
+	s3               S3Interface
+	publisher        events.Publisher
+	region           string
+	idempotencyTTL   time.Duration
+	idempotencyLease time.Duration
+	rates            fx.RateProvider
+	quoteTTL         time.Duration
+	holdTTL          time.Duration
+	maxBatchSize     int
+	logger           *zap.Logger
 }
 
 // NewHandler creates a new handler instance
-func NewHandler(db DBInterface, s3Client S3Interface, sqsClient SQSInterface, region string, logger *zap.Logger) *Handler {
+func NewHandler(db DBInterface, s3Client S3Interface, publisher events.Publisher, region string, logger *zap.Logger) *Handler {
 	return &Handler{
-		db:     db,
-		s3:     s3Client,
-		sqs:    sqsClient,
-		region: region,
-		logger: logger,
+		db:               db,
+		s3:               s3Client,
+		publisher:        publisher,
+		region:           region,
+		idempotencyTTL:   defaultIdempotencyTTL,
+		idempotencyLease: defaultIdempotencyLease,
+		quoteTTL:         defaultQuoteTTL,
+		holdTTL:          defaultHoldTTL,
+		maxBatchSize:     models.DefaultMaxBatchSize,
+		logger:           logger,
 	}
 }
 
@@ -43,76 +58,77 @@ func (h *Handler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate request
-	if req.FromAccount == "" || req.ToAccount == "" || req.Amount == "" {
-		h.respondError(w, http.StatusBadRequest, "Missing required fields", nil)
+	tx, reqErr := h.newTransfer(r.Context(), req)
+	if reqErr != nil {
+		h.writeRequestError(w, reqErr)
 		return
 	}
 
-	// Parse and validate amount
-	amount, err := models.ParseAmount(req.Amount)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "Invalid amount format", err)
-		return
+	// The audit log and message are saved with the transaction and
+	// delivered by the outbox relay once it commits
+	auditLog := &models.AuditLog{
+		TransactionID: tx.ID,
+		Region:        h.region,
+		Action:        "transaction_created",
+		Timestamp:     time.Now().UTC(),
+		Details:       "Transaction created via API",
 	}
-
-	// Validate amount is positive
-	if amount.LessThanOrEqual(decimal.Zero) {
-		h.respondError(w, http.StatusBadRequest, "Amount must be greater than zero", nil)
+	key := fmt.Sprintf("transactions/%s/%s.json", h.region, tx.ID.String())
+	outbox, err := h.newOutbox(r, events.NewTransactionCreated(tx), key, auditLog)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to create transaction", err)
 		return
 	}
 
-	// Create transaction
-	tx := &models.Transaction{
-		ID:          uuid.New(),
-		Region:      h.region,
-		Amount:      amount,
-		FromAccount: req.FromAccount,
-		ToAccount:   req.ToAccount,
-		Status:      "pending",
-		Timestamp:   time.Now().UTC(),
+	response := func() interface{} {
+		return models.TransactionResponse{
+			Transaction: tx,
+			Message:     "Transaction created successfully",
+		}
 	}
 
 	// Save to database
-	if err := h.db.CreateTransaction(tx); err != nil {
-		h.respondError(w, http.StatusInternalServerError, "Failed to create transaction", err)
+	completion := h.idempotentResponse(r, http.StatusCreated, response)
+	if err := h.db.CreateTransaction(r.Context(), tx, completion, outbox...); err != nil {
+		if h.respondReservationLost(w, err) {
+			return
+		}
+		h.writeRequestError(w, transferError(err))
 		return
 	}
 
-	// Write audit log to S3
-	auditLog := &models.AuditLog{
-		TransactionID: tx.ID,
-		Region:        h.region,
-		Action:        "transaction_created",
-		Timestamp:     time.Now().UTC(),
-		Details:       "Transaction created via API",
-	}
-	auditJSON, err := auditLog.ToJSON()
-	if err == nil {
-		key := fmt.Sprintf("transactions/%s/%s.json", h.region, tx.ID.String())
-		h.s3.WriteAuditLog(key, []byte(auditJSON))
+	h.respondJSON(w, http.StatusCreated, response())
+}
+
+// GetTransaction handles GET /transactions/{id}
+func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid transaction ID", err)
+		return
 	}
 
-	// Send message to SQS
-	sqsMsg := &sqs.Message{
-		TransactionID: tx.ID.String(),
-		Region:        h.region,
-		Action:        "transaction_created",
-		Timestamp:     time.Now().UTC(),
-		Data:          auditJSON,
+	tx, err := h.db.GetTransaction(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "Transaction not found", err)
+		return
 	}
-	if err := h.sqs.SendMessage(sqsMsg); err != nil {
-		h.logger.Warn("Failed to send SQS message", zap.Error(err))
+
+	entries, err := h.db.GetTransactionEntries(r.Context(), id)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to get transaction entries", err)
+		return
 	}
+	tx.Entries = entries
 
-	h.respondJSON(w, http.StatusCreated, models.TransactionResponse{
+	h.respondJSON(w, http.StatusOK, models.TransactionResponse{
 		Transaction: tx,
-		Message:     "Transaction created successfully",
 	})
 }
 
-// GetTransaction handles GET /transactions/{id}
-func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+// UpdateTransactionStatus handles PATCH /transactions/{id}/status
+func (h *Handler) UpdateTransactionStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := uuid.Parse(vars["id"])
 	if err != nil {
@@ -120,50 +136,182 @@ func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := h.db.GetTransaction(id)
+	var req models.StatusUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if !models.IsValidStatus(req.Status) {
+		h.respondError(w, http.StatusBadRequest, "Invalid status", nil)
+		return
+	}
+
+	// Reversing moves money, so it needs compensating entries
+	if req.Status == models.StatusReversed {
+		h.respondError(w, http.StatusUnprocessableEntity,
+			"Use POST /transactions/{id}/reverse to reverse a transaction", nil)
+		return
+	}
+
+	tx, err := h.db.GetTransaction(r.Context(), id)
 	if err != nil {
 		h.respondError(w, http.StatusNotFound, "Transaction not found", err)
 		return
 	}
 
+	if !models.CanTransition(tx.Status, req.Status) {
+		h.respondError(w, http.StatusUnprocessableEntity,
+			fmt.Sprintf("Cannot change status from %s to %s", tx.Status, req.Status), nil)
+		return
+	}
+
+	previous := tx.Status
+	tx.Status = req.Status
+
+	key := fmt.Sprintf("transactions/%s/%s-%s.json", h.region, tx.ID.String(), tx.Status)
+	outbox, err := h.auditOutbox(r, events.NewTransactionStatusChanged(tx, previous), "transaction_status_changed",
+		tx.ID, key, fmt.Sprintf("Status changed from %s to %s", previous, tx.Status))
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to update transaction status", err)
+		return
+	}
+
+	// Compare-and-set against the status we just read
+	if err := h.db.UpdateTransactionStatus(r.Context(), id, previous, tx.Status, outbox...); err != nil {
+		switch {
+		case errors.Is(err, models.ErrStatusConflict):
+			h.respondError(w, http.StatusConflict, "Transaction status was changed by another request", err)
+		case errors.Is(err, models.ErrTransactionNotFound):
+			h.respondError(w, http.StatusNotFound, "Transaction not found", err)
+		case errors.Is(err, models.ErrInvalidTransition):
+			h.respondError(w, http.StatusUnprocessableEntity, "Invalid status transition", err)
+		case errors.Is(err, models.ErrInsufficientFunds):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeInsufficientFunds,
+				"Insufficient funds", err)
+		case errors.Is(err, models.ErrAccountClosed):
+			h.respondError(w, http.StatusUnprocessableEntity, "Account is closed", err)
+		case errors.Is(err, models.ErrCurrencyMismatch):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeCurrencyMismatch, "Currency mismatch", err)
+		default:
+			h.respondError(w, http.StatusInternalServerError, "Failed to update transaction status", err)
+		}
+		return
+	}
+
 	h.respondJSON(w, http.StatusOK, models.TransactionResponse{
 		Transaction: tx,
+		Message:     "Transaction status updated successfully",
 	})
 }
 
-// ListTransactions handles GET /transactions
+// ListTransactions handles GET /transactions.
+// Pages are selected with either a cursor from a previous response's
+// next_cursor or, for older clients, an offset. Results may be filtered by
+// account, status, region, currency, amount and time range, and sorted by
+// timestamp or amount.
 func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
-	limit := 50
-	offset := 0
+	params := r.URL.Query()
+	query := models.TransactionQuery{Limit: 50}
 
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+	if limitStr := params.Get("limit"); limitStr != "" {
 		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
+			query.Limit = parsed
 		}
 	}
 
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+	if token := params.Get("cursor"); token != "" {
+		decoded, err := models.DecodeCursor(token)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+		query.Cursor = &decoded
+	} else if offsetStr := params.Get("offset"); offsetStr != "" {
 		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed >= 0 {
-			offset = parsed
+			query.Offset = parsed
 		}
 	}
 
-	transactions, err := h.db.ListTransactions(limit, offset)
+	if err := parseTransactionQuery(params, &query); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if err := query.Validate(); err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	var transactions []*models.Transaction
+	var err error
+	if query.IsDefault() {
+		transactions, err = h.db.ListTransactions(r.Context(), query.Limit, query.Offset)
+	} else {
+		transactions, err = h.db.SearchTransactions(r.Context(), query)
+	}
 	if err != nil {
 		h.respondError(w, http.StatusInternalServerError, "Failed to list transactions", err)
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"transactions": transactions,
-		"limit":        limit,
-		"offset":       offset,
-	})
+		"limit":        query.Limit,
+	}
+	if query.Cursor == nil {
+		response["offset"] = query.Offset
+	}
+
+	// A full page may be followed by more transactions
+	if len(transactions) == query.Limit {
+		response["next_cursor"] = models.CursorAfter(transactions[len(transactions)-1]).Encode()
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// parseTransactionQuery reads the filter and sort parameters of
+// GET /transactions into query
+func parseTransactionQuery(params url.Values, query *models.TransactionQuery) error {
+	f := &query.Filter
+	f.Account = params.Get("account")
+	f.Status = params.Get("status")
+	f.Region = params.Get("region")
+	f.Currency = params.Get("currency")
+
+	for name, dst := range map[string]**decimal.Decimal{"min_amount": &f.MinAmount, "max_amount": &f.MaxAmount} {
+		if value := params.Get(name); value != "" {
+			amount, err := decimal.NewFromString(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %q", name, value)
+			}
+			*dst = &amount
+		}
+	}
+	for name, dst := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		if value := params.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: expected an RFC 3339 timestamp", name)
+			}
+			*dst = &t
+		}
+	}
+
+	query.SortBy = params.Get("sort")
+	switch order := params.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		query.Ascending = true
+	default:
+		return fmt.Errorf("invalid order: %q", order)
+	}
+	return nil
 }
 
 // GetStats handles GET /stats
 func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.db.GetTransactionStats()
+	stats, err := h.db.GetTransactionStats(r.Context())
 	if err != nil {
 		h.respondError(w, http.StatusInternalServerError, "Failed to get statistics", err)
 		return
@@ -180,7 +328,7 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check database
-	if err := h.db.Health(); err != nil {
+	if err := h.db.Health(r.Context()); err != nil {
 		health["status"] = "unhealthy"
 		health["database"] = "unhealthy"
 		h.respondJSON(w, http.StatusServiceUnavailable, health)
@@ -189,7 +337,7 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	health["database"] = "healthy"
 
 	// Check S3
-	if err := h.s3.Health(); err != nil {
+	if err := h.s3.Health(r.Context()); err != nil {
 		health["status"] = "unhealthy"
 		health["s3"] = "unhealthy"
 		h.respondJSON(w, http.StatusServiceUnavailable, health)
@@ -197,14 +345,14 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	}
 	health["s3"] = "healthy"
 
-	// Check SQS
-	if err := h.sqs.Health(); err != nil {
+	// Check message broker
+	if err := h.publisher.Health(r.Context()); err != nil {
 		health["status"] = "unhealthy"
-		health["sqs"] = "unhealthy"
+		health["broker"] = "unhealthy"
 		h.respondJSON(w, http.StatusServiceUnavailable, health)
 		return
 	}
-	health["sqs"] = "healthy"
+	health["broker"] = "healthy"
 
 	h.respondJSON(w, http.StatusOK, health)
 }
@@ -212,7 +360,7 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 // Readiness handles GET /ready
 func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
 	// Check if database is ready
-	if err := h.db.Health(); err != nil {
+	if err := h.db.Health(r.Context()); err != nil {
 		h.respondJSON(w, http.StatusServiceUnavailable, map[string]string{
 			"status": "not ready",
 			"reason": "database unavailable",
@@ -234,6 +382,196 @@ func (h *Handler) Liveness(w http.ResponseWriter, r *http.Request) {
 
 // Helper methods
 
+// requestError describes why a request was rejected, so validation can be
+// shared by handlers that report errors differently
+type requestError struct {
+	status  int
+	code    string
+	message string
+	err     error
+}
+
+func (h *Handler) writeRequestError(w http.ResponseWriter, e *requestError) {
+	h.respondErrorCode(w, e.status, e.code, e.message, e.err)
+}
+
+// newTransfer validates a transfer request against the current state of its
+// accounts and builds the pending transaction
+func (h *Handler) newTransfer(ctx context.Context, req models.TransactionRequest) (*models.Transaction, *requestError) {
+	// A quote fixes the amount, so it may be left out
+	if req.FromAccount == "" || req.ToAccount == "" || (req.Amount == "" && req.QuoteID == "") {
+		return nil, &requestError{status: http.StatusBadRequest, message: "Missing required fields"}
+	}
+
+	if req.FromAccount == req.ToAccount {
+		return nil, &requestError{status: http.StatusBadRequest, message: "Source and destination accounts must differ"}
+	}
+
+	if req.Currency != "" && !models.IsValidCurrencyCode(req.Currency) {
+		return nil, &requestError{status: http.StatusBadRequest, message: "Unsupported currency"}
+	}
+
+	// Reject transfers involving unknown or closed accounts
+	fromAccount, toAccount, reqErr := h.transferAccounts(ctx, req.FromAccount, req.ToAccount)
+	if reqErr != nil {
+		return nil, reqErr
+	}
+
+	tx := &models.Transaction{
+		ID:          uuid.New(),
+		Region:      h.region,
+		FromAccount: req.FromAccount,
+		ToAccount:   req.ToAccount,
+		Status:      models.StatusPending,
+		Timestamp:   time.Now().UTC(),
+	}
+
+	if req.QuoteID != "" {
+		// Cross-currency transfers take their amounts and rate from the quote
+		quote, reqErr := h.resolveQuote(ctx, req, fromAccount, toAccount)
+		if reqErr != nil {
+			return nil, reqErr
+		}
+		tx.Amount = quote.SourceAmount
+		tx.ConvertedAmount = &quote.TargetAmount
+		tx.FXRate = &quote.Rate
+		tx.QuoteID = &quote.ID
+		return tx, nil
+	}
+
+	// Amounts are in the source account's currency unless the request says otherwise
+	currency := req.Currency
+	if currency == "" {
+		currency = fromAccount.Currency
+	}
+
+	amount, reqErr := validateAmount(req.Amount, currency)
+	if reqErr != nil {
+		return nil, reqErr
+	}
+
+	if fromAccount.Currency != currency || toAccount.Currency != currency {
+		return nil, &requestError{
+			status:  http.StatusUnprocessableEntity,
+			code:    models.ErrorCodeCurrencyMismatch,
+			message: fmt.Sprintf("Both accounts must be held in %s; use an FX quote to convert", currency),
+			err:     models.ErrCurrencyMismatch,
+		}
+	}
+	tx.Amount = amount
+
+	return tx, nil
+}
+
+// transferError maps an error from writing a transfer to the response for it
+func transferError(err error) *requestError {
+	switch {
+	case errors.Is(err, models.ErrInsufficientFunds):
+		return &requestError{http.StatusUnprocessableEntity, models.ErrorCodeInsufficientFunds, "Insufficient funds", err}
+	case errors.Is(err, models.ErrAccountNotFound):
+		return &requestError{http.StatusUnprocessableEntity, "", "Unknown account", err}
+	case errors.Is(err, models.ErrAccountClosed):
+		return &requestError{http.StatusUnprocessableEntity, "", "Account is closed", err}
+	case errors.Is(err, models.ErrSystemAccount):
+		return &requestError{http.StatusUnprocessableEntity, "", "Account is reserved", err}
+	case errors.Is(err, models.ErrCurrencyMismatch):
+		return &requestError{http.StatusUnprocessableEntity, models.ErrorCodeCurrencyMismatch, "Currency mismatch", err}
+	case errors.Is(err, models.ErrQuoteExpired):
+		return &requestError{http.StatusUnprocessableEntity, models.ErrorCodeQuoteExpired, "FX quote has expired", err}
+	case errors.Is(err, models.ErrQuoteUsed):
+		return &requestError{http.StatusConflict, models.ErrorCodeQuoteUsed, "FX quote has already been used", err}
+	case errors.Is(err, models.ErrQuoteNotFound):
+		return &requestError{http.StatusUnprocessableEntity, "", "Unknown FX quote", err}
+	}
+	return &requestError{http.StatusInternalServerError, "", "Failed to create transaction", err}
+}
+
+// afterCommit returns the context for the side effects of a committed write,
+// such as its audit log and message. They aren't cancelled when the client
+// goes away, but each call still has its own timeout.
+func afterCommit(r *http.Request) context.Context {
+	return context.WithoutCancel(r.Context())
+}
+
+// auditRecord is an audit log that can be encoded for S3
+type auditRecord interface {
+	ToJSON() (string, error)
+}
+
+// correlationIDHeader carries the correlation ID of a request. Events
+// published by the request share it; without it each event is correlated to
+// itself.
+const correlationIDHeader = "X-Correlation-ID"
+
+// newOutbox builds the outbox messages that write auditLog to S3 under key and
+// publish event, to be saved in the same database transaction as the write
+// they describe
+func (h *Handler) newOutbox(r *http.Request, event events.Event, key string, auditLog auditRecord) ([]*models.OutboxMessage, error) {
+	auditJSON, err := auditLog.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit log: %w", err)
+	}
+
+	env, err := events.NewEnvelope(h.region, r.Header.Get(correlationIDHeader), event)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	return []*models.OutboxMessage{
+		models.NewOutboxAuditLog(h.region, key, []byte(auditJSON)),
+		models.NewOutboxMessage(h.region, env.OrderingKey(), payload),
+	}, nil
+}
+
+// auditOutbox builds the outbox messages that write an audit log of action on
+// id to S3 under key and publish event
+func (h *Handler) auditOutbox(r *http.Request, event events.Event, action string, id uuid.UUID, key, details string) ([]*models.OutboxMessage, error) {
+	auditLog := &models.AuditLog{
+		TransactionID: id,
+		Region:        h.region,
+		Action:        action,
+		Timestamp:     time.Now().UTC(),
+		Details:       details,
+	}
+	return h.newOutbox(r, event, key, auditLog)
+}
+
+// parseAmount parses a positive amount in currency and writes the error
+// response if it is malformed or doesn't fit the currency's scale
+func (h *Handler) parseAmount(w http.ResponseWriter, value, currency string) (models.Money, bool) {
+	amount, reqErr := validateAmount(value, currency)
+	if reqErr != nil {
+		h.writeRequestError(w, reqErr)
+		return models.Money{}, false
+	}
+	return amount, true
+}
+
+// validateAmount parses a positive amount in currency
+func validateAmount(value, currency string) (models.Money, *requestError) {
+	amount, err := models.ParseAmount(value, currency)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidScale):
+			return models.Money{}, &requestError{status: http.StatusBadRequest, message: "Too many decimal places for " + currency, err: err}
+		case errors.Is(err, models.ErrUnsupportedCurrency):
+			return models.Money{}, &requestError{status: http.StatusBadRequest, message: "Unsupported currency", err: err}
+		default:
+			return models.Money{}, &requestError{status: http.StatusBadRequest, message: "Invalid amount format", err: err}
+		}
+	}
+
+	if !amount.IsPositive() {
+		return models.Money{}, &requestError{status: http.StatusBadRequest, message: "Amount must be greater than zero"}
+	}
+
+	return amount, nil
+}
+
 func (h *Handler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -243,8 +581,13 @@ func (h *Handler) respondJSON(w http.ResponseWriter, status int, data interface{
 }
 
 func (h *Handler) respondError(w http.ResponseWriter, status int, message string, err error) {
+	h.respondErrorCode(w, status, "", message, err)
+}
+
+func (h *Handler) respondErrorCode(w http.ResponseWriter, status int, code, message string, err error) {
 	response := models.TransactionResponse{
 		Error: message,
+		Code:  code,
 	}
 	if err != nil {
 		h.logger.Error(message, zap.Error(err))
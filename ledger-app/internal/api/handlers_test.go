@@ -2,8 +2,10 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,8 +13,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/project-atlas/ledger-app/internal/events"
 	"github.com/project-atlas/ledger-app/internal/models"
-	"github.com/project-atlas/ledger-app/internal/sqs"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
@@ -20,50 +22,290 @@ import (
 // Mock implementations for testing
 
 type mockDB struct {
-	createTransactionFunc    func(tx *models.Transaction) error
-	getTransactionFunc        func(id uuid.UUID) (*models.Transaction, error)
-	listTransactionsFunc      func(limit, offset int) ([]*models.Transaction, error)
-	updateTransactionStatusFunc func(id uuid.UUID, status string) error
-	getTransactionStatsFunc   func() (map[string]interface{}, error)
-	healthFunc                func() error
+	createTransactionFunc       func(tx *models.Transaction) error
+	createTransactionsFunc      func(txs []*models.Transaction) error
+	getTransactionFunc          func(id uuid.UUID) (*models.Transaction, error)
+	getTransactionEntriesFunc   func(transactionID uuid.UUID) ([]*models.Entry, error)
+	listTransactionsFunc        func(limit, offset int) ([]*models.Transaction, error)
+	searchTransactionsFunc      func(query models.TransactionQuery) ([]*models.Transaction, error)
+	updateTransactionStatusFunc func(id uuid.UUID, from, to string) error
+	reverseTransactionFunc      func(reversal *models.Transaction) error
+	getTransactionStatsFunc     func() (map[string]interface{}, error)
+	createFXQuoteFunc           func(quote *models.FXQuote) error
+	createHoldFunc              func(hold *models.Hold) error
+	getHoldFunc                 func(id uuid.UUID) (*models.Hold, error)
+	captureHoldFunc             func(hold *models.Hold, capture *models.Transaction) error
+	voidHoldFunc                func(hold *models.Hold) error
+	createScheduleFunc          func(schedule *models.Schedule) error
+	getScheduleFunc             func(id uuid.UUID) (*models.Schedule, error)
+	cancelScheduleFunc          func(schedule *models.Schedule) error
+	listScheduleRunsFunc        func(scheduleID uuid.UUID, limit int) ([]models.ScheduleRun, error)
+	getFXQuoteFunc              func(id uuid.UUID) (*models.FXQuote, error)
+	createAccountFunc           func(account *models.Account) error
+	getAccountFunc              func(id string) (*models.Account, error)
+	listAccountPostingsFunc     func(account string, limit, offset int) ([]*models.Posting, error)
+	getStatementFunc            func(account *models.Account, from, to time.Time) (*models.Statement, error)
+	getIdempotencyRecordFunc    func(key string) (*models.IdempotencyRecord, error)
+	reserveIdempotencyKeyFunc   func(record *models.IdempotencyRecord) error
+	completeIdempotencyKeyFunc  func(key string, reservationID uuid.UUID, statusCode int, responseBody []byte, expiresAt time.Time) error
+	releaseIdempotencyKeyFunc   func(key string, reservationID uuid.UUID) error
+	healthFunc                  func() error
+
+	// outbox holds the outbox messages passed with the last write
+	outbox []*models.OutboxMessage
+	// completion holds the idempotency completion passed with the last write
+	completion *models.IdempotencyCompletion
 }
 
-func (m *mockDB) CreateTransaction(tx *models.Transaction) error {
+func (m *mockDB) CreateTransaction(ctx context.Context, tx *models.Transaction, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	m.outbox = outbox
 	if m.createTransactionFunc != nil {
-		return m.createTransactionFunc(tx)
+		if err := m.createTransactionFunc(tx); err != nil {
+			return err
+		}
 	}
-	return nil
+	return m.completeIdempotency(completion)
 }
 
-func (m *mockDB) GetTransaction(id uuid.UUID) (*models.Transaction, error) {
+func (m *mockDB) CreateTransactions(ctx context.Context, txs []*models.Transaction, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	m.outbox = outbox
+	if m.createTransactionsFunc != nil {
+		if err := m.createTransactionsFunc(txs); err != nil {
+			return err
+		}
+	}
+	return m.completeIdempotency(completion)
+}
+
+func (m *mockDB) GetTransaction(ctx context.Context, id uuid.UUID) (*models.Transaction, error) {
 	if m.getTransactionFunc != nil {
 		return m.getTransactionFunc(id)
 	}
 	return nil, errors.New("transaction not found")
 }
 
-func (m *mockDB) ListTransactions(limit, offset int) ([]*models.Transaction, error) {
+func (m *mockDB) GetTransactionEntries(ctx context.Context, transactionID uuid.UUID) ([]*models.Entry, error) {
+	if m.getTransactionEntriesFunc != nil {
+		return m.getTransactionEntriesFunc(transactionID)
+	}
+	return nil, nil
+}
+
+func (m *mockDB) ListTransactions(ctx context.Context, limit, offset int) ([]*models.Transaction, error) {
 	if m.listTransactionsFunc != nil {
 		return m.listTransactionsFunc(limit, offset)
 	}
 	return []*models.Transaction{}, nil
 }
 
-func (m *mockDB) UpdateTransactionStatus(id uuid.UUID, status string) error {
+func (m *mockDB) SearchTransactions(ctx context.Context, query models.TransactionQuery) ([]*models.Transaction, error) {
+	if m.searchTransactionsFunc != nil {
+		return m.searchTransactionsFunc(query)
+	}
+	return []*models.Transaction{}, nil
+}
+
+func (m *mockDB) UpdateTransactionStatus(ctx context.Context, id uuid.UUID, from, to string, outbox ...*models.OutboxMessage) error {
+	m.outbox = outbox
 	if m.updateTransactionStatusFunc != nil {
-		return m.updateTransactionStatusFunc(id, status)
+		return m.updateTransactionStatusFunc(id, from, to)
 	}
 	return nil
 }
 
-func (m *mockDB) GetTransactionStats() (map[string]interface{}, error) {
+func (m *mockDB) ReverseTransaction(ctx context.Context, reversal *models.Transaction, completion *models.IdempotencyCompletion, outbox models.OutboxBuilder) error {
+	if m.reverseTransactionFunc != nil {
+		if err := m.reverseTransactionFunc(reversal); err != nil {
+			return err
+		}
+	}
+	if err := m.buildOutbox(outbox); err != nil {
+		return err
+	}
+	return m.completeIdempotency(completion)
+}
+
+func (m *mockDB) GetTransactionStats(ctx context.Context) (map[string]interface{}, error) {
 	if m.getTransactionStatsFunc != nil {
 		return m.getTransactionStatsFunc()
 	}
 	return map[string]interface{}{}, nil
 }
 
-func (m *mockDB) Health() error {
+func (m *mockDB) CreateFXQuote(ctx context.Context, quote *models.FXQuote) error {
+	if m.createFXQuoteFunc != nil {
+		return m.createFXQuoteFunc(quote)
+	}
+	return nil
+}
+
+func (m *mockDB) GetFXQuote(ctx context.Context, id uuid.UUID) (*models.FXQuote, error) {
+	if m.getFXQuoteFunc != nil {
+		return m.getFXQuoteFunc(id)
+	}
+	return nil, fmt.Errorf("%w: %s", models.ErrQuoteNotFound, id)
+}
+
+func (m *mockDB) CreateHold(ctx context.Context, hold *models.Hold, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	m.outbox = outbox
+	if m.createHoldFunc != nil {
+		if err := m.createHoldFunc(hold); err != nil {
+			return err
+		}
+	}
+	return m.completeIdempotency(completion)
+}
+
+func (m *mockDB) GetHold(ctx context.Context, id uuid.UUID) (*models.Hold, error) {
+	if m.getHoldFunc != nil {
+		return m.getHoldFunc(id)
+	}
+	return nil, fmt.Errorf("%w: %s", models.ErrHoldNotFound, id)
+}
+
+func (m *mockDB) CaptureHold(ctx context.Context, hold *models.Hold, capture *models.Transaction, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	m.outbox = outbox
+	if m.captureHoldFunc != nil {
+		if err := m.captureHoldFunc(hold, capture); err != nil {
+			return err
+		}
+	}
+	return m.completeIdempotency(completion)
+}
+
+func (m *mockDB) VoidHold(ctx context.Context, hold *models.Hold, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	m.outbox = outbox
+	if m.voidHoldFunc != nil {
+		if err := m.voidHoldFunc(hold); err != nil {
+			return err
+		}
+	}
+	return m.completeIdempotency(completion)
+}
+
+func (m *mockDB) CreateSchedule(ctx context.Context, schedule *models.Schedule, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	m.outbox = outbox
+	if m.createScheduleFunc != nil {
+		if err := m.createScheduleFunc(schedule); err != nil {
+			return err
+		}
+	}
+	return m.completeIdempotency(completion)
+}
+
+func (m *mockDB) GetSchedule(ctx context.Context, id uuid.UUID) (*models.Schedule, error) {
+	if m.getScheduleFunc != nil {
+		return m.getScheduleFunc(id)
+	}
+	return nil, fmt.Errorf("%w: %s", models.ErrScheduleNotFound, id.String())
+}
+
+func (m *mockDB) CancelSchedule(ctx context.Context, schedule *models.Schedule, outbox models.OutboxBuilder) error {
+	if m.cancelScheduleFunc != nil {
+		if err := m.cancelScheduleFunc(schedule); err != nil {
+			return err
+		}
+	}
+	return m.buildOutbox(outbox)
+}
+
+// buildOutbox builds the messages of a write that succeeded, like the
+// database does before committing it
+func (m *mockDB) buildOutbox(outbox models.OutboxBuilder) error {
+	messages, err := outbox()
+	if err != nil {
+		return err
+	}
+	m.outbox = messages
+	return nil
+}
+
+// completeIdempotency stores the response of a write that succeeded with its
+// idempotency key, like the database does before committing it
+func (m *mockDB) completeIdempotency(completion *models.IdempotencyCompletion) error {
+	m.completion = completion
+	if completion == nil || m.completeIdempotencyKeyFunc == nil {
+		return nil
+	}
+	statusCode, body, err := completion.Response()
+	if err != nil {
+		return err
+	}
+	return m.completeIdempotencyKeyFunc(completion.Key, completion.ReservationID, statusCode, body, completion.ExpiresAt)
+}
+
+func (m *mockDB) ListScheduleRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]models.ScheduleRun, error) {
+	if m.listScheduleRunsFunc != nil {
+		return m.listScheduleRunsFunc(scheduleID, limit)
+	}
+	return nil, nil
+}
+
+func (m *mockDB) CreateAccount(ctx context.Context, account *models.Account) error {
+	if m.createAccountFunc != nil {
+		return m.createAccountFunc(account)
+	}
+	return nil
+}
+
+// GetAccount defaults to an active account so transfer tests only need to
+// override it when exercising account validation
+func (m *mockDB) GetAccount(ctx context.Context, id string) (*models.Account, error) {
+	if m.getAccountFunc != nil {
+		return m.getAccountFunc(id)
+	}
+	return &models.Account{ID: id, Currency: "USD", Status: models.AccountStatusActive}, nil
+}
+
+func (m *mockDB) ListAccountPostings(ctx context.Context, account string, limit, offset int) ([]*models.Posting, error) {
+	if m.listAccountPostingsFunc != nil {
+		return m.listAccountPostingsFunc(account, limit, offset)
+	}
+	return []*models.Posting{}, nil
+}
+
+func (m *mockDB) GetStatement(ctx context.Context, account *models.Account, from, to time.Time) (*models.Statement, error) {
+	if m.getStatementFunc != nil {
+		return m.getStatementFunc(account, from, to)
+	}
+	return models.NewStatement(account, from, to, decimal.Zero, nil), nil
+}
+
+func (m *mockDB) GetIdempotencyRecord(ctx context.Context, key string) (*models.IdempotencyRecord, error) {
+	if m.getIdempotencyRecordFunc != nil {
+		return m.getIdempotencyRecordFunc(key)
+	}
+	return nil, models.ErrIdempotencyRecordNotFound
+}
+
+func (m *mockDB) ReserveIdempotencyKey(ctx context.Context, record *models.IdempotencyRecord) error {
+	if m.reserveIdempotencyKeyFunc != nil {
+		return m.reserveIdempotencyKeyFunc(record)
+	}
+	return nil
+}
+
+func (m *mockDB) CompleteIdempotencyKey(ctx context.Context, key string, reservationID uuid.UUID, statusCode int, responseBody []byte, expiresAt time.Time) error {
+	if m.completeIdempotencyKeyFunc != nil {
+		// Like the database, a reservation that was completed or replaced
+		// is left alone without an error
+		err := m.completeIdempotencyKeyFunc(key, reservationID, statusCode, responseBody, expiresAt)
+		if errors.Is(err, models.ErrIdempotencyKeyInUse) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *mockDB) ReleaseIdempotencyKey(ctx context.Context, key string, reservationID uuid.UUID) error {
+	if m.releaseIdempotencyKeyFunc != nil {
+		return m.releaseIdempotencyKeyFunc(key, reservationID)
+	}
+	return nil
+}
+
+func (m *mockDB) Health(ctx context.Context) error {
 	if m.healthFunc != nil {
 		return m.healthFunc()
 	}
@@ -75,33 +317,33 @@ type mockS3 struct {
 	healthFunc        func() error
 }
 
-func (m *mockS3) WriteAuditLog(key string, content []byte) error {
+func (m *mockS3) WriteAuditLog(ctx context.Context, key string, content []byte) error {
 	if m.writeAuditLogFunc != nil {
 		return m.writeAuditLogFunc(key, content)
 	}
 	return nil
 }
 
-func (m *mockS3) Health() error {
+func (m *mockS3) Health(ctx context.Context) error {
 	if m.healthFunc != nil {
 		return m.healthFunc()
 	}
 	return nil
 }
 
-type mockSQS struct {
-	sendMessageFunc func(msg *sqs.Message) error
-	healthFunc      func() error
+type mockPublisher struct {
+	publishFunc func(event *events.Envelope) error
+	healthFunc  func() error
 }
 
-func (m *mockSQS) SendMessage(msg *sqs.Message) error {
-	if m.sendMessageFunc != nil {
-		return m.sendMessageFunc(msg)
+func (m *mockPublisher) Publish(ctx context.Context, event *events.Envelope) error {
+	if m.publishFunc != nil {
+		return m.publishFunc(event)
 	}
 	return nil
 }
 
-func (m *mockSQS) Health() error {
+func (m *mockPublisher) Health(ctx context.Context) error {
 	if m.healthFunc != nil {
 		return m.healthFunc()
 	}
@@ -110,20 +352,55 @@ func (m *mockSQS) Health() error {
 
 // Helper functions
 
-func createTestHandler() (*Handler, *mockDB, *mockS3, *mockSQS) {
+func createTestHandler() (*Handler, *mockDB, *mockS3, *mockPublisher) {
 	mockDB := &mockDB{}
 	mockS3 := &mockS3{}
-	mockSQS := &mockSQS{}
+	mockPublisher := &mockPublisher{}
 	logger := zap.NewNop()
-	handler := NewHandler(mockDB, mockS3, mockSQS, "us-east-1", logger)
-	return handler, mockDB, mockS3, mockSQS
+	handler := NewHandler(mockDB, mockS3, mockPublisher, "us-east-1", logger)
+	return handler, mockDB, mockS3, mockPublisher
+}
+
+// decodeOutbox returns the audit log and the decoded event of the outbox
+// messages saved with a write
+func decodeOutbox(t *testing.T, outbox []*models.OutboxMessage) (*models.OutboxMessage, *events.Envelope) {
+	t.Helper()
+	if len(outbox) != 2 {
+		t.Fatalf("Expected an audit log and a message in the outbox, got %d", len(outbox))
+	}
+	audit, message := outbox[0], outbox[1]
+	if audit.Kind != models.OutboxKindAuditLog {
+		t.Fatalf("Expected an audit log first, got %s", audit.Kind)
+	}
+	var sent events.Envelope
+	if message.Kind != models.OutboxKindMessage || json.Unmarshal(message.Payload, &sent) != nil {
+		t.Fatalf("Expected an SQS message, got %s: %s", message.Kind, message.Payload)
+	}
+	return audit, &sent
 }
 
 func createTestRouter(handler *Handler) *mux.Router {
 	router := mux.NewRouter()
-	router.HandleFunc("/transactions", handler.CreateTransaction).Methods("POST")
+	router.HandleFunc("/transactions", handler.Idempotent(handler.CreateTransaction)).Methods("POST")
 	router.HandleFunc("/transactions", handler.ListTransactions).Methods("GET")
+	router.HandleFunc("/transactions/batch", handler.Idempotent(handler.CreateTransactionBatch)).Methods("POST")
 	router.HandleFunc("/transactions/{id}", handler.GetTransaction).Methods("GET")
+	router.HandleFunc("/transactions/{id}/status", handler.UpdateTransactionStatus).Methods("PATCH")
+	router.HandleFunc("/transactions/{id}/reverse", handler.Idempotent(handler.ReverseTransaction)).Methods("POST")
+	router.HandleFunc("/fx/quotes", handler.CreateFXQuote).Methods("POST")
+	router.HandleFunc("/holds", handler.Idempotent(handler.CreateHold)).Methods("POST")
+	router.HandleFunc("/holds/{id}", handler.GetHold).Methods("GET")
+	router.HandleFunc("/holds/{id}/capture", handler.Idempotent(handler.CaptureHold)).Methods("POST")
+	router.HandleFunc("/holds/{id}/void", handler.Idempotent(handler.VoidHold)).Methods("POST")
+	router.HandleFunc("/schedules", handler.Idempotent(handler.CreateSchedule)).Methods("POST")
+	router.HandleFunc("/schedules/{id}", handler.GetSchedule).Methods("GET")
+	router.HandleFunc("/schedules/{id}/runs", handler.ListScheduleRuns).Methods("GET")
+	router.HandleFunc("/schedules/{id}/cancel", handler.CancelSchedule).Methods("POST")
+	router.HandleFunc("/accounts", handler.CreateAccount).Methods("POST")
+	router.HandleFunc("/accounts/{id}", handler.GetAccount).Methods("GET")
+	router.HandleFunc("/accounts/{id}/balance", handler.GetAccountBalance).Methods("GET")
+	router.HandleFunc("/accounts/{id}/transactions", handler.ListAccountTransactions).Methods("GET")
+	router.HandleFunc("/accounts/{id}/statement", handler.GetAccountStatement).Methods("GET")
 	router.HandleFunc("/stats", handler.GetStats).Methods("GET")
 	router.HandleFunc("/health", handler.Health).Methods("GET")
 	router.HandleFunc("/ready", handler.Readiness).Methods("GET")
@@ -137,9 +414,9 @@ func TestCreateTransaction_Success(t *testing.T) {
 	handler, mockDB, _, _ := createTestHandler()
 	router := createTestRouter(handler)
 
-	txID := uuid.New()
+	var written *models.Transaction
 	mockDB.createTransactionFunc = func(tx *models.Transaction) error {
-		tx.ID = txID
+		written = tx
 		return nil
 	}
 
@@ -151,6 +428,7 @@ func TestCreateTransaction_Success(t *testing.T) {
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Correlation-ID", "checkout-42")
 	w := httptest.NewRecorder()
 
 	router.ServeHTTP(w, req)
@@ -167,12 +445,37 @@ func TestCreateTransaction_Success(t *testing.T) {
 	if response.Transaction == nil {
 		t.Fatal("Expected transaction in response")
 	}
+	if written == nil || response.Transaction.ID != written.ID {
+		t.Errorf("Expected the written transaction in the response, got %s", response.Transaction.ID)
+	}
 	if response.Transaction.FromAccount != "acc1" {
 		t.Errorf("Expected FromAccount 'acc1', got '%s'", response.Transaction.FromAccount)
 	}
 	if response.Message != "Transaction created successfully" {
 		t.Errorf("Expected success message, got '%s'", response.Message)
 	}
+
+	// The audit log and message are saved with the transaction for the relay
+	if len(mockDB.outbox) != 2 {
+		t.Fatalf("Expected an audit log and a message in the outbox, got %d", len(mockDB.outbox))
+	}
+	audit, message := mockDB.outbox[0], mockDB.outbox[1]
+	expectedKey := fmt.Sprintf("transactions/us-east-1/%s.json", response.Transaction.ID)
+	if audit.Kind != models.OutboxKindAuditLog || audit.Key != expectedKey {
+		t.Errorf("Expected an audit log at %s, got %s at %s", expectedKey, audit.Kind, audit.Key)
+	}
+	var sent events.Envelope
+	if message.Kind != models.OutboxKindMessage || json.Unmarshal(message.Payload, &sent) != nil {
+		t.Fatalf("Expected an SQS message, got %s: %s", message.Kind, message.Payload)
+	}
+	if sent.Type != events.TypeTransactionCreated || sent.Subject != response.Transaction.ID.String() ||
+		sent.CorrelationID != "checkout-42" || sent.ProducerRegion != "us-east-1" {
+		t.Errorf("Unexpected message %+v", sent)
+	}
+	var created events.TransactionCreated
+	if err := sent.Decode(&created); err != nil || created.Amount != "100.50" || created.Currency != "USD" {
+		t.Errorf("Unexpected TransactionCreated payload %+v (%v)", created, err)
+	}
 }
 
 func TestCreateTransaction_InvalidJSON(t *testing.T) {
@@ -224,7 +527,7 @@ func TestCreateTransaction_InvalidAmount(t *testing.T) {
 	router := createTestRouter(handler)
 
 	testCases := []struct {
-		name  string
+		name   string
 		amount string
 	}{
 		{"invalid format", "not-a-number"},
@@ -253,6 +556,216 @@ func TestCreateTransaction_InvalidAmount(t *testing.T) {
 	}
 }
 
+func TestCreateTransaction_SameAccount(t *testing.T) {
+	handler, _, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	body, _ := json.Marshal(models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc1", Amount: "10"})
+	req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestCreateTransaction_RejectsUnknownOrClosedAccounts(t *testing.T) {
+	testCases := []struct {
+		name       string
+		getAccount func(id string) (*models.Account, error)
+	}{
+		{
+			name: "unknown destination",
+			getAccount: func(id string) (*models.Account, error) {
+				if id == "acc2" {
+					return nil, fmt.Errorf("%w: %s", models.ErrAccountNotFound, id)
+				}
+				return &models.Account{ID: id, Status: models.AccountStatusActive}, nil
+			},
+		},
+		{
+			name: "closed source",
+			getAccount: func(id string) (*models.Account, error) {
+				if id == "acc1" {
+					return &models.Account{ID: id, Status: models.AccountStatusClosed}, nil
+				}
+				return &models.Account{ID: id, Status: models.AccountStatusActive}, nil
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			router := createTestRouter(handler)
+
+			mockDB.getAccountFunc = tc.getAccount
+			mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+				t.Error("CreateTransaction should not be called")
+				return nil
+			}
+
+			body, _ := json.Marshal(models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10"})
+			req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateTransaction_RejectsSystemAccounts(t *testing.T) {
+	for _, req := range []models.TransactionRequest{
+		{FromAccount: models.FXPositionAccount("USD"), ToAccount: "acc2", Amount: "10"},
+		{FromAccount: "acc1", ToAccount: models.FXPositionAccount("USD"), Amount: "10"},
+	} {
+		handler, mockDB, _, _ := createTestHandler()
+		router := createTestRouter(handler)
+
+		mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+			t.Error("CreateTransaction should not be called")
+			return nil
+		}
+
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/transactions", bytes.NewReader(body)))
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s to %s: expected status %d, got %d", req.FromAccount, req.ToAccount, http.StatusUnprocessableEntity, w.Code)
+		}
+	}
+}
+
+func TestCreateTransaction_InsufficientFunds(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+		return fmt.Errorf("failed to create transaction: %w", models.ErrInsufficientFunds)
+	}
+
+	body, _ := json.Marshal(models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "1000000"})
+	req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	var response models.TransactionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Code != models.ErrorCodeInsufficientFunds {
+		t.Errorf("Expected code %q, got %q", models.ErrorCodeInsufficientFunds, response.Code)
+	}
+}
+
+func TestCreateTransaction_Currency(t *testing.T) {
+	tests := []struct {
+		name         string
+		currencies   map[string]string
+		request      models.TransactionRequest
+		expected     int
+		expectedCode string
+		expectedAmt  string
+		expectedCur  string
+	}{
+		{
+			name:        "defaults to source account currency",
+			currencies:  map[string]string{"acc1": "JPY", "acc2": "JPY"},
+			request:     models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "1500"},
+			expected:    http.StatusCreated,
+			expectedAmt: `"1500"`,
+			expectedCur: "JPY",
+		},
+		{
+			name:        "eight decimal instrument",
+			currencies:  map[string]string{"acc1": "BTC", "acc2": "BTC"},
+			request:     models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "0.00000001", Currency: "BTC"},
+			expected:    http.StatusCreated,
+			expectedAmt: `"0.00000001"`,
+			expectedCur: "BTC",
+		},
+		{
+			name:       "fraction of zero-decimal currency",
+			currencies: map[string]string{"acc1": "JPY", "acc2": "JPY"},
+			request:    models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "1500.5"},
+			expected:   http.StatusBadRequest,
+		},
+		{
+			name:       "unsupported currency",
+			currencies: map[string]string{"acc1": "USD", "acc2": "USD"},
+			request:    models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10", Currency: "XYZ"},
+			expected:   http.StatusBadRequest,
+		},
+		{
+			name:         "destination in another currency",
+			currencies:   map[string]string{"acc1": "USD", "acc2": "EUR"},
+			request:      models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10"},
+			expected:     http.StatusUnprocessableEntity,
+			expectedCode: models.ErrorCodeCurrencyMismatch,
+		},
+		{
+			name:         "request currency differs from accounts",
+			currencies:   map[string]string{"acc1": "USD", "acc2": "USD"},
+			request:      models.TransactionRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10", Currency: "EUR"},
+			expected:     http.StatusUnprocessableEntity,
+			expectedCode: models.ErrorCodeCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			router := createTestRouter(handler)
+
+			mockDB.getAccountFunc = func(id string) (*models.Account, error) {
+				return &models.Account{ID: id, Currency: tt.currencies[id], Status: models.AccountStatusActive}, nil
+			}
+
+			body, _ := json.Marshal(tt.request)
+			req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+
+			var response struct {
+				Transaction struct {
+					Amount   json.RawMessage `json:"amount"`
+					Currency string          `json:"currency"`
+				} `json:"transaction"`
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Code != tt.expectedCode {
+				t.Errorf("Expected code %q, got %q", tt.expectedCode, response.Code)
+			}
+			if tt.expectedAmt != "" && string(response.Transaction.Amount) != tt.expectedAmt {
+				t.Errorf("Expected amount %s, got %s", tt.expectedAmt, response.Transaction.Amount)
+			}
+			if response.Transaction.Currency != tt.expectedCur {
+				t.Errorf("Expected currency %q, got %q", tt.expectedCur, response.Transaction.Currency)
+			}
+		})
+	}
+}
+
 func TestCreateTransaction_DatabaseError(t *testing.T) {
 	handler, mockDB, _, _ := createTestHandler()
 	router := createTestRouter(handler)
@@ -288,7 +801,7 @@ func TestGetTransaction_Success(t *testing.T) {
 	expectedTx := &models.Transaction{
 		ID:          txID,
 		Region:      "us-east-1",
-		Amount:      decimal.NewFromInt(10050).Div(decimal.NewFromInt(100)),
+		Amount:      models.Money{Value: decimal.NewFromInt(10050).Div(decimal.NewFromInt(100)), Currency: "USD"},
 		FromAccount: "acc1",
 		ToAccount:   "acc2",
 		Status:      "pending",
@@ -357,6 +870,276 @@ func TestGetTransaction_NotFound(t *testing.T) {
 	}
 }
 
+func TestGetTransaction_IncludesEntries(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	txID := uuid.New()
+	amount := decimal.NewFromInt(25)
+	mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return &models.Transaction{ID: id, Amount: models.Money{Value: amount, Currency: "USD"}, FromAccount: "acc1", ToAccount: "acc2"}, nil
+	}
+	mockDB.getTransactionEntriesFunc = func(transactionID uuid.UUID) ([]*models.Entry, error) {
+		return []*models.Entry{
+			{ID: uuid.New(), TransactionID: transactionID, Account: "acc1", Amount: amount.Neg(), Currency: "USD"},
+			{ID: uuid.New(), TransactionID: transactionID, Account: "acc2", Amount: amount, Currency: "USD"},
+		}, nil
+	}
+
+	req := httptest.NewRequest("GET", "/transactions/"+txID.String(), nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response models.TransactionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Transaction.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(response.Transaction.Entries))
+	}
+	if err := models.ValidateEntries(response.Transaction.Entries); err != nil {
+		t.Errorf("Expected balanced entries, got: %v", err)
+	}
+}
+
+func TestGetTransaction_EntriesError(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return &models.Transaction{ID: id}, nil
+	}
+	mockDB.getTransactionEntriesFunc = func(transactionID uuid.UUID) ([]*models.Entry, error) {
+		return nil, errors.New("database error")
+	}
+
+	req := httptest.NewRequest("GET", "/transactions/"+uuid.New().String(), nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+// Test UpdateTransactionStatus
+
+func patchStatus(router http.Handler, id, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("PATCH", "/transactions/"+id+"/status", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateTransactionStatus_Success(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	txID := uuid.New()
+	mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return &models.Transaction{ID: id, Status: models.StatusPending}, nil
+	}
+
+	var gotFrom, gotTo string
+	mockDB.updateTransactionStatusFunc = func(id uuid.UUID, from, to string) error {
+		gotFrom, gotTo = from, to
+		return nil
+	}
+
+	w := patchStatus(router, txID.String(), `{"status":"completed"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if gotFrom != models.StatusPending || gotTo != models.StatusCompleted {
+		t.Errorf("Expected compare-and-set pending -> completed, got %s -> %s", gotFrom, gotTo)
+	}
+
+	var response models.TransactionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Transaction.Status != models.StatusCompleted {
+		t.Errorf("Expected status completed in response, got %s", response.Transaction.Status)
+	}
+
+	// The audit log and message are saved with the status change
+	audit, sent := decodeOutbox(t, mockDB.outbox)
+	expectedKey := fmt.Sprintf("transactions/us-east-1/%s-completed.json", txID)
+	if audit.Key != expectedKey {
+		t.Errorf("Expected an audit log at %s, got %s", expectedKey, audit.Key)
+	}
+	if sent.Type != events.TypeTransactionStatusChanged {
+		t.Fatalf("Expected TransactionStatusChanged event, got %+v", sent)
+	}
+	var changed events.TransactionStatusChanged
+	if err := sent.Decode(&changed); err != nil || changed.PreviousStatus != models.StatusPending || changed.Status != models.StatusCompleted {
+		t.Errorf("Unexpected TransactionStatusChanged payload %+v (%v)", changed, err)
+	}
+}
+
+// cancellingDB cancels the request once the status is written, as if the
+// client went away while the response was being prepared
+type cancellingDB struct {
+	*mockDB
+	cancel context.CancelFunc
+	ctx    context.Context
+}
+
+func (m *cancellingDB) UpdateTransactionStatus(ctx context.Context, id uuid.UUID, from, to string, outbox ...*models.OutboxMessage) error {
+	m.ctx = ctx
+	m.outbox = outbox
+	m.cancel()
+	return nil
+}
+
+// contextS3 and contextPublisher record the context they are called with
+type contextS3 struct {
+	mockS3
+	ctx context.Context
+}
+
+func (m *contextS3) WriteAuditLog(ctx context.Context, key string, content []byte) error {
+	m.ctx = ctx
+	return nil
+}
+
+type contextPublisher struct {
+	mockPublisher
+	ctx context.Context
+}
+
+func (m *contextPublisher) Publish(ctx context.Context, event *events.Envelope) error {
+	m.ctx = ctx
+	return nil
+}
+
+func TestUpdateTransactionStatus_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "request"))
+	defer cancel()
+
+	db := &cancellingDB{mockDB: &mockDB{}, cancel: cancel}
+	db.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return &models.Transaction{ID: id, Status: models.StatusPending}, nil
+	}
+	s3Client, publisher := &contextS3{}, &contextPublisher{}
+	handler := NewHandler(db, s3Client, publisher, "us-east-1", zap.NewNop())
+
+	req := httptest.NewRequest("PATCH", "/transactions/"+uuid.New().String()+"/status",
+		bytes.NewReader([]byte(`{"status":"completed"}`))).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	createTestRouter(handler).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if db.ctx == nil || db.ctx.Value(ctxKey{}) != "request" {
+		t.Error("Expected the database write to use the request context")
+	}
+	// The audit log and message are committed with the write, so the relay
+	// delivers them whatever happens to the request
+	if len(db.outbox) != 2 {
+		t.Errorf("Expected an audit log and a message saved with the write, got %d", len(db.outbox))
+	}
+	if s3Client.ctx != nil || publisher.ctx != nil {
+		t.Error("Expected no audit log or message to be sent from the request")
+	}
+}
+
+type ctxKey struct{}
+
+func TestUpdateTransactionStatus_InvalidRequests(t *testing.T) {
+	testCases := []struct {
+		name     string
+		id       string
+		body     string
+		expected int
+	}{
+		{"invalid id", "not-a-uuid", `{"status":"completed"}`, http.StatusBadRequest},
+		{"invalid json", uuid.New().String(), `status`, http.StatusBadRequest},
+		{"unknown status", uuid.New().String(), `{"status":"done"}`, http.StatusBadRequest},
+		{"illegal transition", uuid.New().String(), `{"status":"reversed"}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			router := createTestRouter(handler)
+
+			mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+				return &models.Transaction{ID: id, Status: models.StatusPending}, nil
+			}
+			mockDB.updateTransactionStatusFunc = func(id uuid.UUID, from, to string) error {
+				t.Error("UpdateTransactionStatus should not be called")
+				return nil
+			}
+
+			if w := patchStatus(router, tc.id, tc.body); w.Code != tc.expected {
+				t.Errorf("Expected status %d, got %d", tc.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestUpdateTransactionStatus_NotFound(t *testing.T) {
+	handler, _, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	if w := patchStatus(router, uuid.New().String(), `{"status":"completed"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestUpdateTransactionStatus_ConcurrentUpdate(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return &models.Transaction{ID: id, Status: models.StatusPending}, nil
+	}
+	mockDB.updateTransactionStatusFunc = func(id uuid.UUID, from, to string) error {
+		return fmt.Errorf("%w: expected pending, found cancelled", models.ErrStatusConflict)
+	}
+
+	if w := patchStatus(router, uuid.New().String(), `{"status":"completed"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestUpdateTransactionStatus_InsufficientFundsToRelease(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return &models.Transaction{ID: id, Status: models.StatusPending}, nil
+	}
+	mockDB.updateTransactionStatusFunc = func(id uuid.UUID, from, to string) error {
+		return fmt.Errorf("failed to release transaction funds: %w", models.ErrInsufficientFunds)
+	}
+
+	w := patchStatus(router, uuid.New().String(), `{"status":"failed"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	var response models.TransactionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Code != models.ErrorCodeInsufficientFunds {
+		t.Errorf("Expected code %q, got %q", models.ErrorCodeInsufficientFunds, response.Code)
+	}
+}
+
 // Test ListTransactions
 
 func TestListTransactions_Success(t *testing.T) {
@@ -367,7 +1150,7 @@ func TestListTransactions_Success(t *testing.T) {
 		{
 			ID:          uuid.New(),
 			Region:      "us-east-1",
-			Amount:      decimal.NewFromInt(10000).Div(decimal.NewFromInt(100)),
+			Amount:      models.Money{Value: decimal.NewFromInt(10000).Div(decimal.NewFromInt(100)), Currency: "USD"},
 			FromAccount: "acc1",
 			ToAccount:   "acc2",
 			Status:      "pending",
@@ -456,6 +1239,135 @@ func TestListTransactions_InvalidPagination(t *testing.T) {
 	}
 }
 
+func TestListTransactions_CursorPagination(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	now := time.Now().UTC()
+	page := []*models.Transaction{
+		{ID: uuid.New(), Timestamp: now},
+		{ID: uuid.New(), Timestamp: now.Add(-time.Minute)},
+	}
+	mockDB.listTransactionsFunc = func(limit, offset int) ([]*models.Transaction, error) {
+		return page, nil
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/transactions?limit=2", nil))
+
+	var first map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &first); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	token, ok := first["next_cursor"].(string)
+	if !ok || token == "" {
+		t.Fatalf("Expected next_cursor after a full page, got %v", first["next_cursor"])
+	}
+
+	// The next page continues after the last transaction of the first
+	var got models.TransactionCursor
+	mockDB.searchTransactionsFunc = func(query models.TransactionQuery) ([]*models.Transaction, error) {
+		got = *query.Cursor
+		return page[:1], nil
+	}
+	mockDB.listTransactionsFunc = func(limit, offset int) ([]*models.Transaction, error) {
+		t.Error("ListTransactions should not be called with a cursor")
+		return nil, nil
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/transactions?limit=2&offset=5&cursor="+token, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got.ID != page[1].ID || !got.Timestamp.Equal(page[1].Timestamp) {
+		t.Errorf("Expected cursor at %s, got %+v", page[1].ID, got)
+	}
+
+	var second map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &second); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if _, ok := second["next_cursor"]; ok {
+		t.Error("Expected no next_cursor after the last page")
+	}
+}
+
+func TestListTransactions_InvalidCursor(t *testing.T) {
+	handler, _, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/transactions?cursor=garbage", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestListTransactions_Filtered(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	var got models.TransactionQuery
+	mockDB.searchTransactionsFunc = func(query models.TransactionQuery) ([]*models.Transaction, error) {
+		got = query
+		return []*models.Transaction{}, nil
+	}
+	mockDB.listTransactionsFunc = func(limit, offset int) ([]*models.Transaction, error) {
+		t.Error("ListTransactions should not be called with filters")
+		return nil, nil
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/transactions?account=acc1&status=completed&region=eu-central-1"+
+		"&min_amount=10&max_amount=99.50&from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z&sort=amount&order=asc&limit=20", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	f := got.Filter
+	if f.Account != "acc1" || f.Status != "completed" || f.Region != "eu-central-1" {
+		t.Errorf("Unexpected filter %+v", f)
+	}
+	if f.MinAmount == nil || !f.MinAmount.Equal(decimal.NewFromInt(10)) || f.MaxAmount == nil || f.MaxAmount.String() != "99.5" {
+		t.Errorf("Unexpected amount range %v - %v", f.MinAmount, f.MaxAmount)
+	}
+	if f.From == nil || f.To == nil || f.To.Sub(*f.From) != 31*24*time.Hour {
+		t.Errorf("Unexpected time range %v - %v", f.From, f.To)
+	}
+	if got.SortBy != models.SortByAmount || !got.Ascending || got.Limit != 20 {
+		t.Errorf("Unexpected sort or limit %+v", got)
+	}
+}
+
+func TestListTransactions_InvalidFilters(t *testing.T) {
+	queries := []string{
+		"status=unknown",
+		"min_amount=ten",
+		"min_amount=100&max_amount=10",
+		"from=yesterday",
+		"from=2026-02-01T00:00:00Z&to=2026-01-01T00:00:00Z",
+		"sort=from_account",
+		"order=sideways",
+	}
+
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			mockDB.searchTransactionsFunc = func(models.TransactionQuery) ([]*models.Transaction, error) {
+				t.Error("SearchTransactions should not be called")
+				return nil, nil
+			}
+
+			w := httptest.NewRecorder()
+			createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("GET", "/transactions?"+query, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
 func TestListTransactions_DatabaseError(t *testing.T) {
 	handler, mockDB, _, _ := createTestHandler()
 	router := createTestRouter(handler)
@@ -483,11 +1395,11 @@ func TestGetStats_Success(t *testing.T) {
 	expectedStats := map[string]interface{}{
 		"total_transactions": 10,
 		"by_status": map[string]int{
-			"pending": 5,
+			"pending":   5,
 			"completed": 5,
 		},
 		"by_region": map[string]int{
-			"us-east-1": 6,
+			"us-east-1":    6,
 			"eu-central-1": 4,
 		},
 	}
@@ -536,12 +1448,12 @@ func TestGetStats_DatabaseError(t *testing.T) {
 // Test Health
 
 func TestHealth_AllHealthy(t *testing.T) {
-	handler, mockDB, mockS3, mockSQS := createTestHandler()
+	handler, mockDB, mockS3, mockPublisher := createTestHandler()
 	router := createTestRouter(handler)
 
 	mockDB.healthFunc = func() error { return nil }
 	mockS3.healthFunc = func() error { return nil }
-	mockSQS.healthFunc = func() error { return nil }
+	mockPublisher.healthFunc = func() error { return nil }
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
@@ -563,12 +1475,12 @@ func TestHealth_AllHealthy(t *testing.T) {
 }
 
 func TestHealth_DatabaseUnhealthy(t *testing.T) {
-	handler, mockDB, mockS3, mockSQS := createTestHandler()
+	handler, mockDB, mockS3, mockPublisher := createTestHandler()
 	router := createTestRouter(handler)
 
 	mockDB.healthFunc = func() error { return errors.New("database down") }
 	mockS3.healthFunc = func() error { return nil }
-	mockSQS.healthFunc = func() error { return nil }
+	mockPublisher.healthFunc = func() error { return nil }
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
@@ -593,12 +1505,12 @@ func TestHealth_DatabaseUnhealthy(t *testing.T) {
 }
 
 func TestHealth_S3Unhealthy(t *testing.T) {
-	handler, mockDB, mockS3, mockSQS := createTestHandler()
+	handler, mockDB, mockS3, mockPublisher := createTestHandler()
 	router := createTestRouter(handler)
 
 	mockDB.healthFunc = func() error { return nil }
 	mockS3.healthFunc = func() error { return errors.New("S3 down") }
-	mockSQS.healthFunc = func() error { return nil }
+	mockPublisher.healthFunc = func() error { return nil }
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
@@ -610,13 +1522,13 @@ func TestHealth_S3Unhealthy(t *testing.T) {
 	}
 }
 
-func TestHealth_SQSUnhealthy(t *testing.T) {
-	handler, mockDB, mockS3, mockSQS := createTestHandler()
+func TestHealth_BrokerUnhealthy(t *testing.T) {
+	handler, mockDB, mockS3, mockPublisher := createTestHandler()
 	router := createTestRouter(handler)
 
 	mockDB.healthFunc = func() error { return nil }
 	mockS3.healthFunc = func() error { return nil }
-	mockSQS.healthFunc = func() error { return errors.New("SQS down") }
+	mockPublisher.healthFunc = func() error { return errors.New("broker down") }
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
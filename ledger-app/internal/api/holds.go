@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// defaultHoldTTL is how long a hold reserves funds when the request and
+	// configuration don't say otherwise
+	defaultHoldTTL = 7 * 24 * time.Hour
+	// maxHoldTTL caps how long a single hold may reserve funds
+	maxHoldTTL = 30 * 24 * time.Hour
+)
+
+// SetHoldTTL sets how long holds reserve funds when a request doesn't say
+func (h *Handler) SetHoldTTL(ttl time.Duration) {
+	if ttl > 0 && ttl <= maxHoldTTL {
+		h.holdTTL = ttl
+	}
+}
+
+// CreateHold handles POST /holds
+func (h *Handler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	var req models.HoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate request
+	if req.FromAccount == "" || req.ToAccount == "" || req.Amount == "" {
+		h.respondError(w, http.StatusBadRequest, "Missing required fields", nil)
+		return
+	}
+
+	if req.FromAccount == req.ToAccount {
+		h.respondError(w, http.StatusBadRequest, "Source and destination accounts must differ", nil)
+		return
+	}
+
+	if req.Currency != "" && !models.IsValidCurrencyCode(req.Currency) {
+		h.respondError(w, http.StatusBadRequest, "Unsupported currency", nil)
+		return
+	}
+
+	ttl := h.holdTTL
+	if req.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || parsed <= 0 || parsed > maxHoldTTL {
+			h.respondError(w, http.StatusBadRequest,
+				fmt.Sprintf("expires_in must be a duration between 0 and %s", maxHoldTTL), err)
+			return
+		}
+		ttl = parsed
+	}
+
+	// Reject holds involving unknown or closed accounts
+	fromAccount, toAccount, ok := h.checkTransferAccounts(r.Context(), w, req.FromAccount, req.ToAccount)
+	if !ok {
+		return
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = fromAccount.Currency
+	}
+
+	amount, ok := h.parseAmount(w, req.Amount, currency)
+	if !ok {
+		return
+	}
+
+	if fromAccount.Currency != currency || toAccount.Currency != currency {
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeCurrencyMismatch,
+			fmt.Sprintf("Both accounts must be held in %s", currency), models.ErrCurrencyMismatch)
+		return
+	}
+
+	hold := models.NewHold(req.FromAccount, req.ToAccount, amount, h.region, ttl)
+	outbox, err := h.holdOutbox(r, events.NewHoldCreated(hold), "hold_created", hold.ID, hold, hold.Status,
+		fmt.Sprintf("Held %s on %s until %s", hold.Amount, hold.FromAccount, hold.ExpiresAt.Format(time.RFC3339)))
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to create hold", err)
+		return
+	}
+
+	response := func() interface{} {
+		return models.HoldResponse{
+			Hold:    hold,
+			Message: "Hold created successfully",
+		}
+	}
+
+	completion := h.idempotentResponse(r, http.StatusCreated, response)
+	if err := h.db.CreateHold(r.Context(), hold, completion, outbox...); err != nil {
+		if h.respondReservationLost(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, models.ErrInsufficientFunds):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeInsufficientFunds, "Insufficient funds", err)
+		case errors.Is(err, models.ErrAccountNotFound):
+			h.respondError(w, http.StatusUnprocessableEntity, "Unknown account", err)
+		case errors.Is(err, models.ErrAccountClosed):
+			h.respondError(w, http.StatusUnprocessableEntity, "Account is closed", err)
+		case errors.Is(err, models.ErrCurrencyMismatch):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeCurrencyMismatch, "Currency mismatch", err)
+		default:
+			h.respondError(w, http.StatusInternalServerError, "Failed to create hold", err)
+		}
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, response())
+}
+
+// GetHold handles GET /holds/{id}
+func (h *Handler) GetHold(w http.ResponseWriter, r *http.Request) {
+	hold, ok := h.lookupHold(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, models.HoldResponse{
+		Hold: hold,
+	})
+}
+
+// CaptureHold handles POST /holds/{id}/capture.
+// An empty body captures the full hold.
+func (h *Handler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	var req models.CaptureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	hold, ok := h.lookupHold(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if !hold.IsActive() {
+		h.respondError(w, http.StatusUnprocessableEntity,
+			fmt.Sprintf("Cannot capture a %s hold", hold.Status), models.ErrHoldNotActive)
+		return
+	}
+
+	// Captures are always in the hold's currency
+	amount := decimal.Zero
+	if req.Amount != "" {
+		parsed, ok := h.parseAmount(w, req.Amount, hold.Amount.Currency)
+		if !ok {
+			return
+		}
+		amount = parsed.Value
+	}
+
+	capture, err := hold.NewCapture(amount, h.region)
+	if err != nil {
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeOverCapture,
+			"Capture exceeds the held amount", err)
+		return
+	}
+
+	outbox, err := h.holdOutbox(r, events.NewHoldCaptured(hold, capture), "hold_captured", capture.ID, hold,
+		models.HoldStatusCaptured, fmt.Sprintf("Captured %s of hold %s", capture.Amount, hold.ID.String()))
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to capture hold", err)
+		return
+	}
+
+	response := func() interface{} {
+		return models.HoldResponse{
+			Hold:        hold,
+			Transaction: capture,
+			Message:     "Hold captured successfully",
+		}
+	}
+
+	completion := h.idempotentResponse(r, http.StatusCreated, response)
+	if err := h.db.CaptureHold(r.Context(), hold, capture, completion, outbox...); err != nil {
+		if h.respondReservationLost(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, models.ErrHoldExpired):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeHoldExpired, "Hold has expired", err)
+		case errors.Is(err, models.ErrHoldNotActive):
+			h.respondError(w, http.StatusUnprocessableEntity, "Hold is no longer active", err)
+		case errors.Is(err, models.ErrOverCapture):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeOverCapture,
+				"Capture exceeds the held amount", err)
+		case errors.Is(err, models.ErrHoldNotFound):
+			h.respondError(w, http.StatusNotFound, "Hold not found", err)
+		case errors.Is(err, models.ErrInsufficientFunds):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeInsufficientFunds, "Insufficient funds", err)
+		case errors.Is(err, models.ErrAccountNotFound):
+			h.respondError(w, http.StatusUnprocessableEntity, "Unknown account", err)
+		case errors.Is(err, models.ErrAccountClosed):
+			h.respondError(w, http.StatusUnprocessableEntity, "Account is closed", err)
+		case errors.Is(err, models.ErrSystemAccount):
+			h.respondError(w, http.StatusUnprocessableEntity, "Account is reserved", err)
+		default:
+			h.respondError(w, http.StatusInternalServerError, "Failed to capture hold", err)
+		}
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, response())
+}
+
+// VoidHold handles POST /holds/{id}/void
+func (h *Handler) VoidHold(w http.ResponseWriter, r *http.Request) {
+	hold, ok := h.lookupHold(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	outbox, err := h.holdOutbox(r, events.NewHoldVoided(hold), "hold_voided", hold.ID, hold,
+		models.HoldStatusVoided, fmt.Sprintf("Released %s on %s", hold.Amount, hold.FromAccount))
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to void hold", err)
+		return
+	}
+
+	response := func() interface{} {
+		return models.HoldResponse{
+			Hold:    hold,
+			Message: "Hold voided successfully",
+		}
+	}
+
+	completion := h.idempotentResponse(r, http.StatusOK, response)
+	if err := h.db.VoidHold(r.Context(), hold, completion, outbox...); err != nil {
+		if h.respondReservationLost(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, models.ErrHoldNotActive):
+			h.respondError(w, http.StatusUnprocessableEntity, "Hold is no longer active", err)
+		case errors.Is(err, models.ErrHoldNotFound):
+			h.respondError(w, http.StatusNotFound, "Hold not found", err)
+		default:
+			h.respondError(w, http.StatusInternalServerError, "Failed to void hold", err)
+		}
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, response())
+}
+
+// lookupHold loads a hold and writes the error response if it can't be found
+func (h *Handler) lookupHold(ctx context.Context, w http.ResponseWriter, rawID string) (*models.Hold, bool) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid hold ID", err)
+		return nil, false
+	}
+
+	hold, err := h.db.GetHold(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrHoldNotFound) {
+			h.respondError(w, http.StatusNotFound, "Hold not found", err)
+			return nil, false
+		}
+		h.respondError(w, http.StatusInternalServerError, "Failed to get hold", err)
+		return nil, false
+	}
+
+	return hold, true
+}
+
+// holdOutbox builds the outbox messages for a hold operation that leaves hold
+// in status. id is the transaction the operation creates, or the hold itself
+// when no money moves.
+func (h *Handler) holdOutbox(r *http.Request, event events.Event, action string, id uuid.UUID, hold *models.Hold, status, details string) ([]*models.OutboxMessage, error) {
+	key := fmt.Sprintf("holds/%s/%s-%s.json", h.region, hold.ID.String(), status)
+	return h.auditOutbox(r, event, action, id, key, details)
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func postHold(handler *Handler, path string, body interface{}) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	w := httptest.NewRecorder()
+	createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("POST", path, bytes.NewReader(data)))
+	return w
+}
+
+func activeHold() *models.Hold {
+	return models.NewHold("acc1", "merchant", models.Money{Value: decimal.NewFromInt(100), Currency: "USD"}, "us-east-1", time.Hour)
+}
+
+func TestCreateHold_Success(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	var created *models.Hold
+	mockDB.createHoldFunc = func(hold *models.Hold) error {
+		created = hold
+		return nil
+	}
+
+	w := postHold(handler, "/holds", models.HoldRequest{FromAccount: "acc1", ToAccount: "merchant", Amount: "25.00", ExpiresIn: "2h"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if created == nil || created.Amount.String() != "25.00 USD" || !created.IsActive() {
+		t.Fatalf("Expected an active 25.00 USD hold, got %+v", created)
+	}
+	if got := created.ExpiresAt.Sub(created.CreatedAt); got != 2*time.Hour {
+		t.Errorf("Expected hold to expire after 2h, got %s", got)
+	}
+	audit, sent := decodeOutbox(t, mockDB.outbox)
+	expectedKey := fmt.Sprintf("holds/us-east-1/%s-active.json", created.ID)
+	if audit.Key != expectedKey || sent.Type != events.TypeHoldCreated {
+		t.Errorf("Expected a HoldCreated event and an audit log at %s, got %s at %s", expectedKey, sent.Type, audit.Key)
+	}
+}
+
+func TestCreateHold_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      models.HoldRequest
+		expected int
+	}{
+		{"missing amount", models.HoldRequest{FromAccount: "acc1", ToAccount: "merchant"}, http.StatusBadRequest},
+		{"same account", models.HoldRequest{FromAccount: "acc1", ToAccount: "acc1", Amount: "10"}, http.StatusBadRequest},
+		{"bad expiry", models.HoldRequest{FromAccount: "acc1", ToAccount: "merchant", Amount: "10", ExpiresIn: "soon"}, http.StatusBadRequest},
+		{"expiry too long", models.HoldRequest{FromAccount: "acc1", ToAccount: "merchant", Amount: "10", ExpiresIn: "2000h"}, http.StatusBadRequest},
+		{"zero amount", models.HoldRequest{FromAccount: "acc1", ToAccount: "merchant", Amount: "0"}, http.StatusBadRequest},
+		{"other currency", models.HoldRequest{FromAccount: "acc1", ToAccount: "merchant", Amount: "10", Currency: "EUR"}, http.StatusUnprocessableEntity},
+		{"system account", models.HoldRequest{FromAccount: models.FXPositionAccount("USD"), ToAccount: "merchant", Amount: "10"}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			mockDB.createHoldFunc = func(hold *models.Hold) error {
+				t.Error("CreateHold should not be called")
+				return nil
+			}
+
+			if w := postHold(handler, "/holds", tt.req); w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateHold_InsufficientFunds(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	mockDB.createHoldFunc = func(hold *models.Hold) error {
+		return fmt.Errorf("failed to create hold: %w", models.ErrInsufficientFunds)
+	}
+
+	w := postHold(handler, "/holds", models.HoldRequest{FromAccount: "acc1", ToAccount: "merchant", Amount: "1000"})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	var response models.HoldResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Error == "" {
+		t.Error("Expected error message in response")
+	}
+}
+
+func TestCaptureHold_Partial(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	hold := activeHold()
+	mockDB.getHoldFunc = func(id uuid.UUID) (*models.Hold, error) {
+		return hold, nil
+	}
+	var captured *models.Transaction
+	mockDB.captureHoldFunc = func(h *models.Hold, capture *models.Transaction) error {
+		captured = capture
+		h.Status = models.HoldStatusCaptured
+		h.TransactionID = &capture.ID
+		return nil
+	}
+
+	w := postHold(handler, "/holds/"+hold.ID.String()+"/capture", models.CaptureRequest{Amount: "60"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if captured == nil || captured.Amount.String() != "60.00 USD" || captured.ToAccount != "merchant" {
+		t.Fatalf("Expected 60.00 USD captured to merchant, got %+v", captured)
+	}
+
+	var response models.HoldResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Hold == nil || response.Hold.Status != models.HoldStatusCaptured || response.Transaction == nil {
+		t.Errorf("Expected captured hold and transaction in response, got %+v", response)
+	}
+}
+
+func TestCaptureHold_EmptyBodyCapturesFullHold(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	hold := activeHold()
+	mockDB.getHoldFunc = func(id uuid.UUID) (*models.Hold, error) {
+		return hold, nil
+	}
+	var captured *models.Transaction
+	mockDB.captureHoldFunc = func(h *models.Hold, capture *models.Transaction) error {
+		captured = capture
+		return nil
+	}
+
+	w := postHold(handler, "/holds/"+hold.ID.String()+"/capture", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if captured == nil || !captured.Amount.Equal(hold.Amount) {
+		t.Errorf("Expected full hold to be captured, got %+v", captured)
+	}
+}
+
+func TestCaptureHold_Rejected(t *testing.T) {
+	tests := []struct {
+		name         string
+		hold         func(h *models.Hold)
+		amount       string
+		dbErr        error
+		expected     int
+		expectedCode string
+	}{
+		{
+			name:         "more than held",
+			amount:       "100.01",
+			expected:     http.StatusUnprocessableEntity,
+			expectedCode: models.ErrorCodeOverCapture,
+		},
+		{
+			name:     "already voided",
+			hold:     func(h *models.Hold) { h.Status = models.HoldStatusVoided },
+			expected: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "expired",
+			dbErr:        fmt.Errorf("failed to capture hold: %w", models.ErrHoldExpired),
+			expected:     http.StatusUnprocessableEntity,
+			expectedCode: models.ErrorCodeHoldExpired,
+		},
+		{
+			name:     "too many decimals",
+			amount:   "1.001",
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+
+			hold := activeHold()
+			if tt.hold != nil {
+				tt.hold(hold)
+			}
+			mockDB.getHoldFunc = func(id uuid.UUID) (*models.Hold, error) {
+				return hold, nil
+			}
+			mockDB.captureHoldFunc = func(h *models.Hold, capture *models.Transaction) error {
+				if tt.dbErr == nil {
+					t.Error("CaptureHold should not be called")
+				}
+				return tt.dbErr
+			}
+
+			w := postHold(handler, "/holds/"+hold.ID.String()+"/capture", models.CaptureRequest{Amount: tt.amount})
+			if w.Code != tt.expected {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+
+			var response models.TransactionResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Code != tt.expectedCode {
+				t.Errorf("Expected code %q, got %q", tt.expectedCode, response.Code)
+			}
+		})
+	}
+}
+
+func TestCaptureHold_NotFound(t *testing.T) {
+	handler, _, _, _ := createTestHandler()
+
+	if w := postHold(handler, "/holds/"+uuid.New().String()+"/capture", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := postHold(handler, "/holds/not-a-uuid/capture", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestVoidHold(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	hold := activeHold()
+	mockDB.getHoldFunc = func(id uuid.UUID) (*models.Hold, error) {
+		return hold, nil
+	}
+	mockDB.voidHoldFunc = func(h *models.Hold) error {
+		h.Status = models.HoldStatusVoided
+		return nil
+	}
+
+	w := postHold(handler, "/holds/"+hold.ID.String()+"/void", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response models.HoldResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Hold == nil || response.Hold.Status != models.HoldStatusVoided {
+		t.Errorf("Expected voided hold in response, got %+v", response.Hold)
+	}
+
+	audit, sent := decodeOutbox(t, mockDB.outbox)
+	expectedKey := fmt.Sprintf("holds/us-east-1/%s-voided.json", hold.ID)
+	if audit.Key != expectedKey || sent.Type != events.TypeHoldVoided {
+		t.Errorf("Expected a HoldVoided event and an audit log at %s, got %s at %s", expectedKey, sent.Type, audit.Key)
+	}
+}
+
+func TestVoidHold_NotActive(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	hold := activeHold()
+	mockDB.getHoldFunc = func(id uuid.UUID) (*models.Hold, error) {
+		return hold, nil
+	}
+	mockDB.voidHoldFunc = func(h *models.Hold) error {
+		return fmt.Errorf("failed to void hold: %w", models.ErrHoldNotActive)
+	}
+
+	if w := postHold(handler, "/holds/"+hold.ID.String()+"/void", nil); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+}
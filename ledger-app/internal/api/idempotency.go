@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// defaultIdempotencyTTL is how long idempotency keys are remembered unless configured
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// defaultIdempotencyLease is how long a key stays reserved for a request that
+// has not finished, unless configured. It outlasts the server's request
+// timeouts, so only a reservation whose request died with its process expires.
+const defaultIdempotencyLease = time.Minute
+
+// SetIdempotencyTTL sets how long responses are kept for replay
+func (h *Handler) SetIdempotencyTTL(ttl time.Duration) {
+	if ttl > 0 {
+		h.idempotencyTTL = ttl
+	}
+}
+
+// SetIdempotencyLease sets how long a key stays reserved before a request that
+// never finished can be retried
+func (h *Handler) SetIdempotencyLease(lease time.Duration) {
+	if lease > 0 {
+		h.idempotencyLease = lease
+	}
+}
+
+// Idempotent wraps a handler so that requests carrying an Idempotency-Key header
+// are executed at most once. A retry with the same key and body replays the
+// stored response; reusing a key for a different request returns 409. A key is
+// only leased while its request runs, so a reservation left behind by a crash
+// can be reclaimed once the lease runs out. Handlers that write pass
+// idempotentResponse to the database so the response is recorded in the same
+// transaction as the write; a reservation that is still in progress when its
+// lease runs out therefore never made one.
+func (h *Handler) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(models.IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		if len(key) > models.MaxIdempotencyKeyLength {
+			h.respondError(w, http.StatusBadRequest, "Idempotency-Key is too long", nil)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := models.RequestFingerprint(r.Method, r.URL.Path, body)
+
+		record, err := h.db.GetIdempotencyRecord(r.Context(), key)
+		switch {
+		case err == nil:
+			h.replayIdempotent(w, record, fingerprint)
+			return
+		case !errors.Is(err, models.ErrIdempotencyRecordNotFound):
+			h.respondError(w, http.StatusInternalServerError, "Failed to check idempotency key", err)
+			return
+		}
+
+		now := time.Now().UTC()
+		reservation := &models.IdempotencyRecord{
+			Key:           key,
+			Fingerprint:   fingerprint,
+			ReservationID: uuid.New(),
+			CreatedAt:     now,
+			ExpiresAt:     now.Add(h.idempotencyLease),
+		}
+		err = h.db.ReserveIdempotencyKey(r.Context(), reservation)
+		if err != nil {
+			if errors.Is(err, models.ErrIdempotencyKeyInUse) {
+				h.respondError(w, http.StatusConflict, "A request with this Idempotency-Key is already in progress", err)
+				return
+			}
+			h.respondError(w, http.StatusInternalServerError, "Failed to reserve idempotency key", err)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r.WithContext(context.WithValue(r.Context(), reservationKey{}, reservation)))
+
+		// Requests that didn't write are completed here. The outcome is
+		// recorded even if the client has gone away meanwhile, otherwise its
+		// retry would find the key stuck in progress. Both calls leave a key
+		// that its write already completed, or that another request has
+		// reserved since, alone.
+		ctx := afterCommit(r)
+
+		// Server errors are not remembered so that the client can retry them
+		if rec.status >= http.StatusInternalServerError {
+			if err := h.db.ReleaseIdempotencyKey(ctx, key, reservation.ReservationID); err != nil {
+				h.logger.Error("Failed to release idempotency key", zap.Error(err), zap.String("idempotency_key", key))
+			}
+			return
+		}
+
+		expiresAt := time.Now().UTC().Add(h.idempotencyTTL)
+		if err := h.db.CompleteIdempotencyKey(ctx, key, reservation.ReservationID, rec.status, rec.body.Bytes(), expiresAt); err != nil {
+			h.logger.Error("Failed to store idempotent response", zap.Error(err), zap.String("idempotency_key", key))
+		}
+	}
+}
+
+// reservationKey is the context key of the idempotency reservation held by a
+// request
+type reservationKey struct{}
+
+// idempotentResponse returns the completion of the request's idempotency
+// reservation with the response built by response, for the database to store
+// with the write it describes. It is nil for requests without an
+// Idempotency-Key. response is called once the write has run, and its result
+// must be what the handler then writes with status.
+func (h *Handler) idempotentResponse(r *http.Request, status int, response func() interface{}) *models.IdempotencyCompletion {
+	reservation, ok := r.Context().Value(reservationKey{}).(*models.IdempotencyRecord)
+	if !ok {
+		return nil
+	}
+
+	return &models.IdempotencyCompletion{
+		Key:           reservation.Key,
+		ReservationID: reservation.ReservationID,
+		ExpiresAt:     time.Now().UTC().Add(h.idempotencyTTL),
+		Response: func() (int, []byte, error) {
+			// Encoded like respondJSON so a replay is byte for byte the same
+			var body bytes.Buffer
+			if err := json.NewEncoder(&body).Encode(response()); err != nil {
+				return 0, nil, err
+			}
+			return status, body.Bytes(), nil
+		},
+	}
+}
+
+// respondReservationLost answers a write that was rolled back because the
+// request's idempotency reservation ran out and another request took the key.
+// It reports whether err was that.
+func (h *Handler) respondReservationLost(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, models.ErrIdempotencyKeyInUse) {
+		return false
+	}
+	h.respondError(w, http.StatusConflict, "A request with this Idempotency-Key is already in progress", err)
+	return true
+}
+
+// replayIdempotent answers a retried request from its stored record
+func (h *Handler) replayIdempotent(w http.ResponseWriter, record *models.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		h.respondError(w, http.StatusConflict, "Idempotency-Key was already used with a different request", nil)
+		return
+	}
+
+	if !record.IsComplete() {
+		h.respondError(w, http.StatusConflict, "A request with this Idempotency-Key is already in progress", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	if _, err := w.Write(record.ResponseBody); err != nil {
+		h.logger.Error("Failed to write replayed response", zap.Error(err))
+	}
+}
+
+// responseRecorder captures the status and body written by a handler while
+// still passing them through to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+)
+
+// memoryIdempotencyStore wires the idempotency methods of mockDB to a map.
+// Like the database, it ignores and replaces expired records, and only
+// completes or releases the reservation a request holds.
+func memoryIdempotencyStore(m *mockDB) map[string]*models.IdempotencyRecord {
+	records := make(map[string]*models.IdempotencyRecord)
+	m.getIdempotencyRecordFunc = func(key string) (*models.IdempotencyRecord, error) {
+		if record, ok := records[key]; ok && record.ExpiresAt.After(time.Now()) {
+			return record, nil
+		}
+		return nil, models.ErrIdempotencyRecordNotFound
+	}
+	m.reserveIdempotencyKeyFunc = func(record *models.IdempotencyRecord) error {
+		if existing, ok := records[record.Key]; ok && existing.ExpiresAt.After(record.CreatedAt) {
+			return models.ErrIdempotencyKeyInUse
+		}
+		records[record.Key] = record
+		return nil
+	}
+	m.completeIdempotencyKeyFunc = func(key string, reservationID uuid.UUID, statusCode int, responseBody []byte, expiresAt time.Time) error {
+		record, ok := records[key]
+		if !ok || record.ReservationID != reservationID || record.IsComplete() {
+			return models.ErrIdempotencyKeyInUse
+		}
+		records[key].StatusCode = statusCode
+		records[key].ResponseBody = append([]byte(nil), responseBody...)
+		records[key].ExpiresAt = expiresAt
+		return nil
+	}
+	m.releaseIdempotencyKeyFunc = func(key string, reservationID uuid.UUID) error {
+		if record, ok := records[key]; ok && record.ReservationID == reservationID && !record.IsComplete() {
+			delete(records, key)
+		}
+		return nil
+	}
+	return records
+}
+
+func postTransaction(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/transactions", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(models.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+const transferBody = `{"from_account":"acc1","to_account":"acc2","amount":"10.00"}`
+
+func TestIdempotent_ReplaysOriginalResponse(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+	records := memoryIdempotencyStore(mockDB)
+
+	calls := 0
+	mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+		calls++
+		return nil
+	}
+
+	first := postTransaction(router, "key-1", transferBody)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, first.Code)
+	}
+	if !records["key-1"].IsComplete() {
+		t.Fatal("Expected response to be stored for the key")
+	}
+
+	second := postTransaction(router, "key-1", transferBody)
+	if second.Code != http.StatusCreated {
+		t.Errorf("Expected replayed status %d, got %d", http.StatusCreated, second.Code)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected Idempotent-Replayed header on replay")
+	}
+	if !bytes.Equal(first.Body.Bytes(), second.Body.Bytes()) {
+		t.Errorf("Expected identical body on replay\nfirst:  %s\nsecond: %s", first.Body, second.Body)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected CreateTransaction to run once, ran %d times", calls)
+	}
+}
+
+func TestIdempotent_DifferentBodyConflicts(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+	memoryIdempotencyStore(mockDB)
+
+	if w := postTransaction(router, "key-1", transferBody); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	other := `{"from_account":"acc1","to_account":"acc2","amount":"99.00"}`
+	if w := postTransaction(router, "key-1", other); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestIdempotent_InFlightRequestConflicts(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getIdempotencyRecordFunc = func(key string) (*models.IdempotencyRecord, error) {
+		return &models.IdempotencyRecord{
+			Key:         key,
+			Fingerprint: models.RequestFingerprint("POST", "/transactions", []byte(transferBody)),
+			ExpiresAt:   time.Now().Add(time.Hour),
+		}, nil
+	}
+	mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+		t.Error("CreateTransaction should not run while the key is held")
+		return nil
+	}
+
+	if w := postTransaction(router, "key-1", transferBody); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestIdempotent_LostReservationRace(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.reserveIdempotencyKeyFunc = func(record *models.IdempotencyRecord) error {
+		return models.ErrIdempotencyKeyInUse
+	}
+
+	if w := postTransaction(router, "key-1", transferBody); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestIdempotent_ServerErrorReleasesKey(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+	records := memoryIdempotencyStore(mockDB)
+
+	mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+		return errors.New("database error")
+	}
+
+	if w := postTransaction(router, "key-1", transferBody); w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	if _, ok := records["key-1"]; ok {
+		t.Error("Expected key to be released after a server error")
+	}
+}
+
+func TestIdempotent_ReservationUsesLease(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+	handler.SetIdempotencyTTL(time.Hour)
+	handler.SetIdempotencyLease(time.Minute)
+
+	var reserved *models.IdempotencyRecord
+	mockDB.reserveIdempotencyKeyFunc = func(record *models.IdempotencyRecord) error {
+		reserved = record
+		return nil
+	}
+	var completedUntil time.Time
+	mockDB.completeIdempotencyKeyFunc = func(key string, reservationID uuid.UUID, statusCode int, responseBody []byte, expiresAt time.Time) error {
+		completedUntil = expiresAt
+		return nil
+	}
+
+	postTransaction(router, "key-1", transferBody)
+
+	if reserved == nil {
+		t.Fatal("Expected key to be reserved")
+	}
+	if lease := reserved.ExpiresAt.Sub(reserved.CreatedAt); lease != time.Minute {
+		t.Errorf("Expected lease of 1m, got %v", lease)
+	}
+	if ttl := completedUntil.Sub(reserved.CreatedAt); ttl < time.Hour || ttl > time.Hour+time.Minute {
+		t.Errorf("Expected completed key to be kept for 1h, got %v", ttl)
+	}
+}
+
+func TestIdempotent_StaleReservationIsReclaimed(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+	records := memoryIdempotencyStore(mockDB)
+
+	// A reservation left behind by a request that never finished
+	records["key-1"] = &models.IdempotencyRecord{
+		Key:         "key-1",
+		Fingerprint: models.RequestFingerprint("POST", "/transactions", []byte(transferBody)),
+		CreatedAt:   time.Now().Add(-2 * defaultIdempotencyLease),
+		ExpiresAt:   time.Now().Add(-defaultIdempotencyLease),
+	}
+
+	if w := postTransaction(router, "key-1", transferBody); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if !records["key-1"].IsComplete() {
+		t.Error("Expected response to be stored for the reclaimed key")
+	}
+}
+
+func TestIdempotent_ResponseStoredWithWrite(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+	records := memoryIdempotencyStore(mockDB)
+
+	var reservationID uuid.UUID
+	mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+		reservationID = records["key-1"].ReservationID
+		return nil
+	}
+
+	w := postTransaction(router, "key-1", transferBody)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	completion := mockDB.completion
+	if completion == nil {
+		t.Fatal("Expected the response to be passed with the write")
+	}
+	if completion.Key != "key-1" || completion.ReservationID != reservationID {
+		t.Errorf("Expected completion of reservation %s of key-1, got %s of %s", reservationID, completion.ReservationID, completion.Key)
+	}
+	statusCode, body, err := completion.Response()
+	if err != nil {
+		t.Fatalf("Failed to build response: %v", err)
+	}
+	if statusCode != http.StatusCreated || !bytes.Equal(body, w.Body.Bytes()) {
+		t.Errorf("Expected stored response to match the one sent\nstored: %d %s\nsent:   %d %s", statusCode, body, w.Code, w.Body)
+	}
+}
+
+func TestIdempotent_ReplacedReservationRollsBackWrite(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+	records := memoryIdempotencyStore(mockDB)
+
+	// The lease runs out mid-request and a retry reserves the key again
+	retry := &models.IdempotencyRecord{
+		Key:           "key-1",
+		Fingerprint:   models.RequestFingerprint("POST", "/transactions", []byte(transferBody)),
+		ReservationID: uuid.New(),
+		CreatedAt:     time.Now(),
+		ExpiresAt:     time.Now().Add(defaultIdempotencyLease),
+	}
+	mockDB.createTransactionFunc = func(tx *models.Transaction) error {
+		records["key-1"] = retry
+		return nil
+	}
+
+	if w := postTransaction(router, "key-1", transferBody); w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	if records["key-1"] != retry || retry.IsComplete() {
+		t.Error("Expected the retry's reservation to be left alone")
+	}
+}
+
+func TestIdempotent_WithoutKeyPassesThrough(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getIdempotencyRecordFunc = func(key string) (*models.IdempotencyRecord, error) {
+		t.Error("Idempotency store should not be consulted without a key")
+		return nil, models.ErrIdempotencyRecordNotFound
+	}
+
+	if w := postTransaction(router, "", transferBody); w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+}
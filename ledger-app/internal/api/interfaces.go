@@ -1,29 +1,48 @@
 package api
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/project-atlas/ledger-app/internal/models"
-	"github.com/project-atlas/ledger-app/internal/sqs"
 )
 
-// DBInterface defines the database operations needed by handlers
+// DBInterface defines the database operations needed by handlers. Handlers
+// pass the request's context, so a client that goes away cancels its queries.
 type DBInterface interface {
-	CreateTransaction(tx *models.Transaction) error
-	GetTransaction(id uuid.UUID) (*models.Transaction, error)
-	ListTransactions(limit, offset int) ([]*models.Transaction, error)
-	UpdateTransactionStatus(id uuid.UUID, status string) error
-	GetTransactionStats() (map[string]interface{}, error)
-	Health() error
+	CreateTransaction(ctx context.Context, tx *models.Transaction, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error
+	CreateTransactions(ctx context.Context, txs []*models.Transaction, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error
+	GetTransaction(ctx context.Context, id uuid.UUID) (*models.Transaction, error)
+	GetTransactionEntries(ctx context.Context, transactionID uuid.UUID) ([]*models.Entry, error)
+	ListTransactions(ctx context.Context, limit, offset int) ([]*models.Transaction, error)
+	SearchTransactions(ctx context.Context, query models.TransactionQuery) ([]*models.Transaction, error)
+	UpdateTransactionStatus(ctx context.Context, id uuid.UUID, from, to string, outbox ...*models.OutboxMessage) error
+	ReverseTransaction(ctx context.Context, reversal *models.Transaction, completion *models.IdempotencyCompletion, outbox models.OutboxBuilder) error
+	GetTransactionStats(ctx context.Context) (map[string]interface{}, error)
+	CreateFXQuote(ctx context.Context, quote *models.FXQuote) error
+	GetFXQuote(ctx context.Context, id uuid.UUID) (*models.FXQuote, error)
+	CreateHold(ctx context.Context, hold *models.Hold, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error
+	GetHold(ctx context.Context, id uuid.UUID) (*models.Hold, error)
+	CaptureHold(ctx context.Context, hold *models.Hold, capture *models.Transaction, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error
+	VoidHold(ctx context.Context, hold *models.Hold, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error
+	CreateSchedule(ctx context.Context, schedule *models.Schedule, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error
+	GetSchedule(ctx context.Context, id uuid.UUID) (*models.Schedule, error)
+	CancelSchedule(ctx context.Context, schedule *models.Schedule, outbox models.OutboxBuilder) error
+	ListScheduleRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]models.ScheduleRun, error)
+	CreateAccount(ctx context.Context, account *models.Account) error
+	GetAccount(ctx context.Context, id string) (*models.Account, error)
+	ListAccountPostings(ctx context.Context, account string, limit, offset int) ([]*models.Posting, error)
+	GetStatement(ctx context.Context, account *models.Account, from, to time.Time) (*models.Statement, error)
+	GetIdempotencyRecord(ctx context.Context, key string) (*models.IdempotencyRecord, error)
+	ReserveIdempotencyKey(ctx context.Context, record *models.IdempotencyRecord) error
+	CompleteIdempotencyKey(ctx context.Context, key string, reservationID uuid.UUID, statusCode int, responseBody []byte, expiresAt time.Time) error
+	ReleaseIdempotencyKey(ctx context.Context, key string, reservationID uuid.UUID) error
+	Health(ctx context.Context) error
 }
 
 // S3Interface defines the S3 operations needed by handlers
 type S3Interface interface {
-	WriteAuditLog(key string, content []byte) error
-	Health() error
-}
-
-// SQSInterface defines the SQS operations needed by handlers
-type SQSInterface interface {
-	SendMessage(msg *sqs.Message) error
-	Health() error
+	WriteAuditLog(ctx context.Context, key string, content []byte) error
+	Health(ctx context.Context) error
 }
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// ReverseTransaction handles POST /transactions/{id}/reverse.
+// An empty body reverses the full remaining amount.
+func (h *Handler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid transaction ID", err)
+		return
+	}
+
+	var req models.ReversalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	original, err := h.db.GetTransaction(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrTransactionNotFound) {
+			h.respondError(w, http.StatusNotFound, "Transaction not found", err)
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, "Failed to get transaction", err)
+		return
+	}
+
+	if !original.CanBeReversed() {
+		h.respondError(w, http.StatusUnprocessableEntity,
+			fmt.Sprintf("Cannot reverse a %s transaction", original.Status), models.ErrNotReversible)
+		return
+	}
+
+	// Reversals are always in the original's currency
+	amount := decimal.Zero
+	if req.Amount != "" {
+		parsed, ok := h.parseAmount(w, req.Amount, original.Amount.Currency)
+		if !ok {
+			return
+		}
+		amount = parsed.Value
+	}
+
+	reversal := models.NewReversal(original, amount, h.region)
+	// The amount and accounts of the reversal are only known once the
+	// original is locked
+	outbox := func() ([]*models.OutboxMessage, error) {
+		details := fmt.Sprintf("Reversed %s of transaction %s", reversal.Amount, original.ID.String())
+		if req.Reason != "" {
+			details += ": " + req.Reason
+		}
+		key := fmt.Sprintf("transactions/%s/%s.json", h.region, reversal.ID.String())
+		return h.auditOutbox(r, events.NewTransactionReversed(reversal, req.Reason), "transaction_reversed",
+			reversal.ID, key, details)
+	}
+
+	response := func() interface{} {
+		return models.TransactionResponse{
+			Transaction: reversal,
+			Message:     "Transaction reversed successfully",
+		}
+	}
+
+	completion := h.idempotentResponse(r, http.StatusCreated, response)
+	if err := h.db.ReverseTransaction(r.Context(), reversal, completion, outbox); err != nil {
+		if h.respondReservationLost(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, models.ErrOverRefund):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeOverRefund,
+				"Reversal exceeds the amount left to refund", err)
+		case errors.Is(err, models.ErrNotReversible):
+			h.respondError(w, http.StatusUnprocessableEntity, "Transaction cannot be reversed", err)
+		case errors.Is(err, models.ErrTransactionNotFound):
+			h.respondError(w, http.StatusNotFound, "Transaction not found", err)
+		case errors.Is(err, models.ErrInsufficientFunds):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeInsufficientFunds,
+				"Insufficient funds", err)
+		case errors.Is(err, models.ErrAccountClosed):
+			h.respondError(w, http.StatusUnprocessableEntity, "Account is closed", err)
+		case errors.Is(err, models.ErrCurrencyMismatch):
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeCurrencyMismatch, "Currency mismatch", err)
+		default:
+			h.respondError(w, http.StatusInternalServerError, "Failed to reverse transaction", err)
+		}
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, response())
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func postReverse(router http.Handler, id, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/transactions/"+id+"/reverse", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func completedTransaction(id uuid.UUID) *models.Transaction {
+	return &models.Transaction{
+		ID:          id,
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: decimal.NewFromInt(100), Currency: "USD"},
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Status:      models.StatusCompleted,
+	}
+}
+
+func TestReverseTransaction_Success(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	txID := uuid.New()
+	mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return completedTransaction(id), nil
+	}
+
+	var got *models.Transaction
+	mockDB.reverseTransactionFunc = func(reversal *models.Transaction) error {
+		got = reversal
+		if reversal.Amount.IsZero() {
+			reversal.Amount.Value = decimal.NewFromInt(100)
+		}
+		return nil
+	}
+
+	w := postReverse(router, txID.String(), `{"reason":"customer refund"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if got == nil {
+		t.Fatal("Expected reversal to be recorded")
+	}
+	if got.ReversalOf == nil || *got.ReversalOf != txID {
+		t.Errorf("Expected reversal to reference %s, got %v", txID, got.ReversalOf)
+	}
+	if got.FromAccount != "acc2" || got.ToAccount != "acc1" {
+		t.Errorf("Expected reversal from acc2 to acc1, got %s to %s", got.FromAccount, got.ToAccount)
+	}
+	if !got.Amount.Equal(models.Money{Value: decimal.NewFromInt(100), Currency: "USD"}) {
+		t.Errorf("Expected full amount to be reversed, got %s", got.Amount)
+	}
+
+	// The audit log and message are built from the recorded reversal and
+	// saved with it
+	audit, sent := decodeOutbox(t, mockDB.outbox)
+	expectedKey := fmt.Sprintf("transactions/us-east-1/%s.json", got.ID)
+	if audit.Key != expectedKey {
+		t.Errorf("Expected audit key %s, got %s", expectedKey, audit.Key)
+	}
+	var auditLog models.AuditLog
+	if err := json.Unmarshal(audit.Payload, &auditLog); err != nil {
+		t.Fatalf("Failed to decode audit log: %v", err)
+	}
+	if !strings.Contains(auditLog.Details, "100.00 USD") {
+		t.Errorf("Expected the recorded amount in the audit log, got %q", auditLog.Details)
+	}
+	if auditLog.Action != "transaction_reversed" || !strings.Contains(auditLog.Details, "customer refund") {
+		t.Errorf("Unexpected audit log: %+v", auditLog)
+	}
+
+	if sent.Type != events.TypeTransactionReversed {
+		t.Fatalf("Expected TransactionReversed event, got %+v", sent)
+	}
+	var reversed events.TransactionReversed
+	if err := sent.Decode(&reversed); err != nil || reversed.ReversalOf != txID || reversed.Reason != "customer refund" {
+		t.Errorf("Unexpected TransactionReversed payload %+v (%v)", reversed, err)
+	}
+
+	var response models.TransactionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Transaction.ReversalOf == nil || *response.Transaction.ReversalOf != txID {
+		t.Errorf("Expected reversal_of %s in response", txID)
+	}
+}
+
+func TestReverseTransaction_EmptyBodyAndPartialAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected decimal.Decimal
+	}{
+		{"empty body", "", decimal.Zero},
+		{"empty object", `{}`, decimal.Zero},
+		{"partial", `{"amount":"25.50"}`, decimal.RequireFromString("25.50")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			router := createTestRouter(handler)
+
+			mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+				return completedTransaction(id), nil
+			}
+
+			var requested decimal.Decimal
+			mockDB.reverseTransactionFunc = func(reversal *models.Transaction) error {
+				requested = reversal.Amount.Value
+				return nil
+			}
+
+			w := postReverse(router, uuid.New().String(), tt.body)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+			}
+			if !requested.Equal(tt.expected) {
+				t.Errorf("Expected requested amount %s, got %s", tt.expected, requested)
+			}
+		})
+	}
+}
+
+func TestReverseTransaction_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		body     string
+		expected int
+	}{
+		{"invalid id", "not-a-uuid", `{}`, http.StatusBadRequest},
+		{"invalid json", uuid.New().String(), `{`, http.StatusBadRequest},
+		{"invalid amount", uuid.New().String(), `{"amount":"abc"}`, http.StatusBadRequest},
+		{"negative amount", uuid.New().String(), `{"amount":"-5"}`, http.StatusBadRequest},
+		{"zero amount", uuid.New().String(), `{"amount":"0"}`, http.StatusBadRequest},
+		{"too many decimals", uuid.New().String(), `{"amount":"1.001"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			router := createTestRouter(handler)
+
+			mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+				return completedTransaction(id), nil
+			}
+
+			mockDB.reverseTransactionFunc = func(reversal *models.Transaction) error {
+				t.Error("Expected reversal not to be recorded")
+				return nil
+			}
+
+			w := postReverse(router, tt.id, tt.body)
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestReverseTransaction_NotFound(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return nil, fmt.Errorf("%w: %s", models.ErrTransactionNotFound, id)
+	}
+
+	w := postReverse(router, uuid.New().String(), `{}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestReverseTransaction_NotReversible(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   func(id uuid.UUID) *models.Transaction
+	}{
+		{"pending", func(id uuid.UUID) *models.Transaction {
+			tx := completedTransaction(id)
+			tx.Status = models.StatusPending
+			return tx
+		}},
+		{"cancelled", func(id uuid.UUID) *models.Transaction {
+			tx := completedTransaction(id)
+			tx.Status = models.StatusCancelled
+			return tx
+		}},
+		{"reversal", func(id uuid.UUID) *models.Transaction {
+			tx := completedTransaction(id)
+			original := uuid.New()
+			tx.ReversalOf = &original
+			return tx
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			router := createTestRouter(handler)
+
+			mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+				return tt.tx(id), nil
+			}
+
+			w := postReverse(router, uuid.New().String(), `{}`)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+			}
+		})
+	}
+}
+
+func TestReverseTransaction_OverRefund(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return completedTransaction(id), nil
+	}
+	mockDB.reverseTransactionFunc = func(reversal *models.Transaction) error {
+		return fmt.Errorf("%w: requested 150, remaining 100", models.ErrOverRefund)
+	}
+	w := postReverse(router, uuid.New().String(), `{"amount":"150"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	if len(mockDB.outbox) != 0 {
+		t.Errorf("Expected no outbox messages for a rejected reversal, got %d", len(mockDB.outbox))
+	}
+
+	var response models.TransactionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Code != models.ErrorCodeOverRefund {
+		t.Errorf("Expected code %s, got %s", models.ErrorCodeOverRefund, response.Code)
+	}
+}
+
+func TestUpdateTransactionStatus_RejectsReversed(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	router := createTestRouter(handler)
+
+	mockDB.getTransactionFunc = func(id uuid.UUID) (*models.Transaction, error) {
+		return completedTransaction(id), nil
+	}
+	mockDB.updateTransactionStatusFunc = func(id uuid.UUID, from, to string) error {
+		t.Error("Expected status not to be updated")
+		return nil
+	}
+
+	w := patchStatus(router, uuid.New().String(), `{"status":"reversed"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+}
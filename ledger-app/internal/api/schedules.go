@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/project-atlas/ledger-app/internal/schedule"
+)
+
+// CreateSchedule handles POST /schedules
+func (h *Handler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req models.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate request
+	if req.FromAccount == "" || req.ToAccount == "" || req.Amount == "" {
+		h.respondError(w, http.StatusBadRequest, "Missing required fields", nil)
+		return
+	}
+
+	if req.FromAccount == req.ToAccount {
+		h.respondError(w, http.StatusBadRequest, "Source and destination accounts must differ", nil)
+		return
+	}
+
+	if req.Currency != "" && !models.IsValidCurrencyCode(req.Currency) {
+		h.respondError(w, http.StatusBadRequest, "Unsupported currency", nil)
+		return
+	}
+
+	if req.Schedule == "" && req.StartAt == nil {
+		h.respondError(w, http.StatusBadRequest, "Either schedule or start_at is required", nil)
+		return
+	}
+
+	if req.MaxRuns != nil && *req.MaxRuns <= 0 {
+		h.respondError(w, http.StatusBadRequest, "max_runs must be greater than zero", nil)
+		return
+	}
+
+	now := time.Now().UTC()
+	startAt := now
+	if req.StartAt != nil {
+		startAt = req.StartAt.UTC()
+	}
+
+	spec, err := schedule.Parse(req.Schedule, startAt)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid schedule", err)
+		return
+	}
+
+	// Reject schedules involving unknown or closed accounts
+	fromAccount, toAccount, ok := h.checkTransferAccounts(r.Context(), w, req.FromAccount, req.ToAccount)
+	if !ok {
+		return
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = fromAccount.Currency
+	}
+
+	amount, ok := h.parseAmount(w, req.Amount, currency)
+	if !ok {
+		return
+	}
+
+	if fromAccount.Currency != currency || toAccount.Currency != currency {
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, models.ErrorCodeCurrencyMismatch,
+			fmt.Sprintf("Both accounts must be held in %s", currency), models.ErrCurrencyMismatch)
+		return
+	}
+
+	// Limits in an RRULE apply unless the request sets its own
+	maxRuns, endAt := req.MaxRuns, req.EndAt
+	if maxRuns == nil && spec.Count > 0 {
+		maxRuns = &spec.Count
+	}
+	if endAt == nil && !spec.Until.IsZero() {
+		endAt = &spec.Until
+	}
+
+	first := spec.Next(now)
+	sched := models.NewSchedule(req.FromAccount, req.ToAccount, amount, req.Schedule, h.region, startAt, first)
+	sched.MaxRuns = maxRuns
+	sched.EndAt = endAt
+	if !sched.Allows(first, 0) {
+		h.respondError(w, http.StatusBadRequest, "Schedule has no executions in the future", nil)
+		return
+	}
+
+	outbox, err := h.scheduleOutbox(r, events.NewScheduleCreated(sched), "schedule_created", sched,
+		fmt.Sprintf("Scheduled %s from %s to %s, first run at %s",
+			sched.Amount, sched.FromAccount, sched.ToAccount, first.Format(time.RFC3339)))
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to create schedule", err)
+		return
+	}
+
+	response := func() interface{} {
+		return models.ScheduleResponse{
+			Schedule: sched,
+			Message:  "Schedule created successfully",
+		}
+	}
+
+	completion := h.idempotentResponse(r, http.StatusCreated, response)
+	if err := h.db.CreateSchedule(r.Context(), sched, completion, outbox...); err != nil {
+		if h.respondReservationLost(w, err) {
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, "Failed to create schedule", err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, response())
+}
+
+// GetSchedule handles GET /schedules/{id}
+func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	sched, ok := h.lookupSchedule(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, models.ScheduleResponse{
+		Schedule: sched,
+	})
+}
+
+// ListScheduleRuns handles GET /schedules/{id}/runs
+func (h *Handler) ListScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	sched, ok := h.lookupSchedule(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	runs, err := h.db.ListScheduleRuns(r.Context(), sched.ID, limit)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to list schedule runs", err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, models.ScheduleResponse{
+		Schedule: sched,
+		Runs:     runs,
+	})
+}
+
+// CancelSchedule handles POST /schedules/{id}/cancel
+func (h *Handler) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	sched, ok := h.lookupSchedule(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	// The run count is only final once the schedule is locked
+	outbox := func() ([]*models.OutboxMessage, error) {
+		return h.scheduleOutbox(r, events.NewScheduleCancelled(sched), "schedule_cancelled", sched,
+			fmt.Sprintf("Cancelled after %d runs", sched.RunCount))
+	}
+
+	if err := h.db.CancelSchedule(r.Context(), sched, outbox); err != nil {
+		if errors.Is(err, models.ErrScheduleNotActive) {
+			h.respondError(w, http.StatusUnprocessableEntity, "Schedule is no longer active", err)
+			return
+		}
+		h.respondError(w, http.StatusInternalServerError, "Failed to cancel schedule", err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, models.ScheduleResponse{
+		Schedule: sched,
+		Message:  "Schedule cancelled successfully",
+	})
+}
+
+// lookupSchedule loads a schedule and writes the error response if it can't be found
+func (h *Handler) lookupSchedule(ctx context.Context, w http.ResponseWriter, rawID string) (*models.Schedule, bool) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid schedule ID", err)
+		return nil, false
+	}
+
+	sched, err := h.db.GetSchedule(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrScheduleNotFound) {
+			h.respondError(w, http.StatusNotFound, "Schedule not found", err)
+			return nil, false
+		}
+		h.respondError(w, http.StatusInternalServerError, "Failed to get schedule", err)
+		return nil, false
+	}
+
+	return sched, true
+}
+
+// scheduleOutbox builds the outbox messages for a schedule operation that
+// leaves sched in its current status
+func (h *Handler) scheduleOutbox(r *http.Request, event events.Event, action string, sched *models.Schedule, details string) ([]*models.OutboxMessage, error) {
+	key := fmt.Sprintf("schedules/%s/%s-%s.json", h.region, sched.ID.String(), sched.Status)
+	return h.auditOutbox(r, event, action, sched.ID, key, details)
+}
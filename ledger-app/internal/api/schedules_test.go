@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestCreateSchedule_Recurring(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	var created *models.Schedule
+	mockDB.createScheduleFunc = func(schedule *models.Schedule) error {
+		created = schedule
+		return nil
+	}
+
+	w := postHold(handler, "/schedules", models.ScheduleRequest{
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Amount:      "1200.00",
+		Schedule:    "FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=9;BYMINUTE=0;COUNT=12",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if created == nil || created.NextRunAt == nil {
+		t.Fatalf("Expected a schedule with a next run, got %+v", created)
+	}
+	if next := *created.NextRunAt; next.Day() != 1 || next.Hour() != 9 || !next.After(time.Now()) {
+		t.Errorf("Expected the next run on the 1st at 09:00, got %s", next)
+	}
+	if created.MaxRuns == nil || *created.MaxRuns != 12 {
+		t.Errorf("Expected COUNT to limit the schedule to 12 runs, got %v", created.MaxRuns)
+	}
+}
+
+func TestCreateSchedule_OneOff(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	var created *models.Schedule
+	mockDB.createScheduleFunc = func(schedule *models.Schedule) error {
+		created = schedule
+		return nil
+	}
+
+	startAt := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Minute)
+	w := postHold(handler, "/schedules", models.ScheduleRequest{
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Amount:      "50",
+		StartAt:     &startAt,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if created == nil || created.NextRunAt == nil || !created.NextRunAt.Equal(startAt) {
+		t.Errorf("Expected a single run at %s, got %+v", startAt, created)
+	}
+}
+
+func TestCreateSchedule_InvalidRequests(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	zero := 0
+
+	tests := []struct {
+		name     string
+		req      models.ScheduleRequest
+		expected int
+	}{
+		{"missing amount", models.ScheduleRequest{FromAccount: "acc1", ToAccount: "acc2", Schedule: "@daily"}, http.StatusBadRequest},
+		{"no schedule or start", models.ScheduleRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10"}, http.StatusBadRequest},
+		{"bad cron", models.ScheduleRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10", Schedule: "61 * * * *"}, http.StatusBadRequest},
+		{"one-off in the past", models.ScheduleRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10", StartAt: &past}, http.StatusBadRequest},
+		{"zero max runs", models.ScheduleRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10", Schedule: "@daily", MaxRuns: &zero}, http.StatusBadRequest},
+		{"ended", models.ScheduleRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10", Schedule: "@daily", EndAt: &past}, http.StatusBadRequest},
+		{"other currency", models.ScheduleRequest{FromAccount: "acc1", ToAccount: "acc2", Amount: "10", Currency: "EUR", Schedule: "@daily"}, http.StatusUnprocessableEntity},
+		{"system account", models.ScheduleRequest{FromAccount: "acc1", ToAccount: models.FXPositionAccount("USD"), Amount: "10", Schedule: "@daily"}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			mockDB.createScheduleFunc = func(schedule *models.Schedule) error {
+				t.Error("CreateSchedule should not be called")
+				return nil
+			}
+
+			if w := postHold(handler, "/schedules", tt.req); w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestListScheduleRuns(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	next := time.Now().UTC().Add(time.Hour)
+	schedule := models.NewSchedule("acc1", "acc2", models.Money{Value: decimal.NewFromInt(10), Currency: "USD"},
+		"@hourly", "us-east-1", next, next)
+	mockDB.getScheduleFunc = func(id uuid.UUID) (*models.Schedule, error) {
+		return schedule, nil
+	}
+	txID := uuid.New()
+	mockDB.listScheduleRunsFunc = func(scheduleID uuid.UUID, limit int) ([]models.ScheduleRun, error) {
+		if scheduleID != schedule.ID || limit != 10 {
+			t.Errorf("Unexpected arguments %s, %d", scheduleID, limit)
+		}
+		return []models.ScheduleRun{
+			{ID: uuid.New(), ScheduleID: schedule.ID, Status: models.ScheduleRunSucceeded, TransactionID: &txID},
+			{ID: uuid.New(), ScheduleID: schedule.ID, Status: models.ScheduleRunFailed, Error: "insufficient funds"},
+		}, nil
+	}
+
+	w := httptest.NewRecorder()
+	createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("GET", "/schedules/"+schedule.ID.String()+"/runs?limit=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response models.ScheduleResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Runs) != 2 || response.Runs[1].Error == "" {
+		t.Errorf("Expected 2 runs, got %+v", response.Runs)
+	}
+}
+
+func TestCancelSchedule(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	id := uuid.New()
+	mockDB.getScheduleFunc = func(uuid.UUID) (*models.Schedule, error) {
+		return &models.Schedule{ID: id, FromAccount: "acc1", Status: models.ScheduleStatusActive, RunCount: 2}, nil
+	}
+	// A run completed between the read and the cancellation
+	mockDB.cancelScheduleFunc = func(schedule *models.Schedule) error {
+		schedule.Status = models.ScheduleStatusCancelled
+		schedule.RunCount = 3
+		return nil
+	}
+
+	if w := postHold(handler, "/schedules/"+id.String()+"/cancel", nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	audit, sent := decodeOutbox(t, mockDB.outbox)
+	expectedKey := fmt.Sprintf("schedules/us-east-1/%s-cancelled.json", id)
+	if audit.Key != expectedKey {
+		t.Errorf("Expected an audit log at %s, got %s", expectedKey, audit.Key)
+	}
+	var cancelled events.ScheduleCancelled
+	if sent.Type != events.TypeScheduleCancelled || sent.Decode(&cancelled) != nil || cancelled.RunCount != 3 {
+		t.Errorf("Expected a ScheduleCancelled event after 3 runs, got %s %+v", sent.Type, cancelled)
+	}
+}
+
+func TestCancelSchedule_NotActive(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	id := uuid.New()
+	mockDB.getScheduleFunc = func(uuid.UUID) (*models.Schedule, error) {
+		return &models.Schedule{ID: id, Status: models.ScheduleStatusCompleted}, nil
+	}
+	mockDB.cancelScheduleFunc = func(schedule *models.Schedule) error {
+		return fmt.Errorf("%w: %s", models.ErrScheduleNotActive, id)
+	}
+
+	if w := postHold(handler, "/schedules/"+id.String()+"/cancel", nil); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
+func TestGetSchedule_NotFound(t *testing.T) {
+	handler, _, _, _ := createTestHandler()
+
+	w := httptest.NewRecorder()
+	createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("GET", "/schedules/"+uuid.New().String(), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// statementDateLayout is accepted for from and to alongside RFC 3339 timestamps
+const statementDateLayout = "2006-01-02"
+
+// statementExtensions are the file extensions archived statements are stored with
+var statementExtensions = map[string]string{
+	models.StatementFormatJSON: "json",
+	models.StatementFormatCSV:  "csv",
+	models.StatementFormatText: "txt",
+}
+
+// ListAccountTransactions handles GET /accounts/{id}/transactions.
+// Postings are returned newest first, each with the account's running balance.
+func (h *Handler) ListAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.lookupAccount(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	postings, err := h.db.ListAccountPostings(r.Context(), account.ID, limit, offset)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to list account transactions", err)
+		return
+	}
+	if postings == nil {
+		postings = []*models.Posting{}
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"account_id": account.ID,
+		"currency":   account.Currency,
+		"postings":   postings,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+// GetAccountStatement handles GET /accounts/{id}/statement.
+// The period runs from `from` up to `to`, which defaults to now; a date
+// without a time covers that whole day. `format` is json, csv or text, and
+// `archive=true` also stores the rendered statement in the audit bucket.
+func (h *Handler) GetAccountStatement(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	format := params.Get("format")
+	if format == "" {
+		format = models.StatementFormatJSON
+	}
+	if !models.IsValidStatementFormat(format) {
+		h.respondError(w, http.StatusBadRequest, "Invalid format: expected json, csv or text", nil)
+		return
+	}
+
+	if params.Get("from") == "" {
+		h.respondError(w, http.StatusBadRequest, "Missing required parameter: from", nil)
+		return
+	}
+	from, err := parseStatementTime(params.Get("from"), false)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid from: expected an RFC 3339 timestamp or a date", err)
+		return
+	}
+	to := time.Now().UTC()
+	if value := params.Get("to"); value != "" {
+		if to, err = parseStatementTime(value, true); err != nil {
+			h.respondError(w, http.StatusBadRequest, "Invalid to: expected an RFC 3339 timestamp or a date", err)
+			return
+		}
+	}
+	if !from.Before(to) {
+		h.respondError(w, http.StatusBadRequest, "from must be before to", nil)
+		return
+	}
+
+	archive := false
+	if value := params.Get("archive"); value != "" {
+		if archive, err = strconv.ParseBool(value); err != nil {
+			h.respondError(w, http.StatusBadRequest, "Invalid archive: expected true or false", err)
+			return
+		}
+	}
+
+	account, ok := h.lookupAccount(r.Context(), w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	statement, err := h.db.GetStatement(r.Context(), account, from, to)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to build statement", err)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := statement.Render(&body, format); err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to render statement", err)
+		return
+	}
+
+	if archive {
+		key := fmt.Sprintf("statements/%s/%s/%s_%s.%s", h.region, account.ID,
+			from.Format(time.RFC3339), to.Format(time.RFC3339), statementExtensions[format])
+		if err := h.s3.WriteAuditLog(r.Context(), key, body.Bytes()); err != nil {
+			h.respondError(w, http.StatusInternalServerError, "Failed to archive statement", err)
+			return
+		}
+		w.Header().Set("X-Statement-Archive-Key", key)
+	}
+
+	w.Header().Set("Content-Type", models.StatementContentType(format))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		h.logger.Error("Failed to write statement", zap.Error(err))
+	}
+}
+
+// parseStatementTime parses an RFC 3339 timestamp or a date. A date at the
+// end of a period covers that whole day, so it is moved to the next midnight.
+func parseStatementTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(statementDateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
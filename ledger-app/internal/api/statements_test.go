@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestListAccountTransactions(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+
+	mockDB.listAccountPostingsFunc = func(account string, limit, offset int) ([]*models.Posting, error) {
+		if account != "acc1" || limit != 10 || offset != 20 {
+			t.Errorf("Unexpected arguments %s, %d, %d", account, limit, offset)
+		}
+		return []*models.Posting{
+			{Entry: models.Entry{ID: uuid.New(), Account: "acc1", Amount: decimal.NewFromInt(-25)}, Counterparty: "acc2", Balance: decimal.NewFromInt(75)},
+		}, nil
+	}
+
+	w := httptest.NewRecorder()
+	createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("GET", "/accounts/acc1/transactions?limit=10&offset=20", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Postings []models.Posting `json:"postings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Postings) != 1 || !response.Postings[0].Balance.Equal(decimal.NewFromInt(75)) || response.Postings[0].Counterparty != "acc2" {
+		t.Errorf("Unexpected postings %+v", response.Postings)
+	}
+}
+
+func TestListAccountTransactions_NotFound(t *testing.T) {
+	handler, mockDB, _, _ := createTestHandler()
+	mockDB.getAccountFunc = func(id string) (*models.Account, error) {
+		return nil, fmt.Errorf("%w: %s", models.ErrAccountNotFound, id)
+	}
+
+	w := httptest.NewRecorder()
+	createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("GET", "/accounts/missing/transactions", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestGetAccountStatement_CSVArchived(t *testing.T) {
+	handler, mockDB, mockS3, _ := createTestHandler()
+
+	var gotFrom, gotTo time.Time
+	mockDB.getStatementFunc = func(account *models.Account, from, to time.Time) (*models.Statement, error) {
+		gotFrom, gotTo = from, to
+		return models.NewStatement(account, from, to, decimal.NewFromInt(100), []*models.Posting{
+			{Entry: models.Entry{TransactionID: uuid.New(), Amount: decimal.NewFromInt(-40), Timestamp: from.Add(time.Hour)}, Counterparty: "acc2"},
+		}), nil
+	}
+	var archived string
+	mockS3.writeAuditLogFunc = func(key string, content []byte) error {
+		archived = key
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("GET", "/accounts/acc1/statement?from=2026-01-01&to=2026-01-31&format=csv&archive=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	if !gotFrom.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !gotTo.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the whole of January, got %s to %s", gotFrom, gotTo)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Expected CSV, got %s", ct)
+	}
+	if !strings.Contains(w.Body.String(), "closing balance,,USD,60.00") {
+		t.Errorf("Expected a closing balance of 60.00, got:\n%s", w.Body.String())
+	}
+	if archived == "" || !strings.HasPrefix(archived, "statements/us-east-1/acc1/") || !strings.HasSuffix(archived, ".csv") {
+		t.Errorf("Unexpected archive key %q", archived)
+	}
+	if w.Header().Get("X-Statement-Archive-Key") != archived {
+		t.Errorf("Expected the archive key in the response headers")
+	}
+}
+
+func TestGetAccountStatement_InvalidParameters(t *testing.T) {
+	queries := []string{
+		"",
+		"from=last-week",
+		"from=2026-02-01&to=2026-01-01",
+		"from=2026-01-01&format=pdf",
+		"from=2026-01-01&archive=maybe",
+	}
+
+	for _, query := range queries {
+		t.Run(query, func(t *testing.T) {
+			handler, mockDB, _, _ := createTestHandler()
+			mockDB.getStatementFunc = func(*models.Account, time.Time, time.Time) (*models.Statement, error) {
+				t.Error("GetStatement should not be called")
+				return nil, nil
+			}
+
+			w := httptest.NewRecorder()
+			createTestRouter(handler).ServeHTTP(w, httptest.NewRequest("GET", "/accounts/acc1/statement?"+query, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
@@ -3,26 +3,40 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all non-sensitive configuration
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	AWS      AWSConfig
+	App       AppConfig
+	Database  DatabaseConfig
+	AWS       AWSConfig
+	FX        FXConfig
+	Holds     HoldsConfig
+	Schedules SchedulesConfig
+	Outbox    OutboxConfig
+	Events    EventsConfig
+	Consumer  ConsumerConfig
 }
 
 // AppConfig holds application-level configuration
 type AppConfig struct {
-	Port   int
-	Region string
+	Port             int
+	Region           string
+	IdempotencyTTL   time.Duration
+	IdempotencyLease time.Duration
+	MaxBatchSize     int
 }
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
-	Host     string
-	Port     int
-	Database string
+	Host        string
+	Port        int
+	Database    string
+	AutoMigrate bool
+	// Timeout bounds connecting and every query
+	Timeout time.Duration
 }
 
 // AWSConfig holds AWS/LocalStack configuration
@@ -31,25 +45,104 @@ type AWSConfig struct {
 	Endpoint string
 	S3Bucket  string
 	SQSQueue  string
+	// Timeout bounds every S3 and SQS call
+	Timeout time.Duration
+	// MaxReceiveCount is how often an SQS message is received before it is
+	// moved to the dead-letter queue; zero disables the dead-letter queue
+	MaxReceiveCount int
+	// SQSWaitTime is how long an SQS receive long-polls for messages
+	SQSWaitTime time.Duration
+	// SQSFIFO uses FIFO queues, which keep each account's events in order
+	SQSFIFO bool
+}
+
+// FXConfig holds FX quote configuration
+type FXConfig struct {
+	RatesFile string
+	QuoteTTL  time.Duration
+}
+
+// HoldsConfig holds authorization hold configuration
+type HoldsConfig struct {
+	DefaultTTL    time.Duration
+	SweepInterval time.Duration
+}
+
+// SchedulesConfig holds scheduled transfer configuration
+type SchedulesConfig struct {
+	PollInterval time.Duration
+}
+
+// OutboxConfig holds outbox relay configuration
+type OutboxConfig struct {
+	PollInterval time.Duration
+}
+
+// EventsConfig selects and configures the message broker
+type EventsConfig struct {
+	// Backend is "sqs", "kafka" or "memory"
+	Backend      string
+	KafkaBrokers []string
+	KafkaTopic   string
+	KafkaGroupID string
+}
+
+// ConsumerConfig holds message consumer configuration
+type ConsumerConfig struct {
+	Workers           int
+	VisibilityTimeout time.Duration
 }
 
 // LoadConfig loads configuration from environment variables
 func LoadConfig() Config {
 	return Config{
 		App: AppConfig{
-			Port:   getEnvInt("APP_PORT", 8080),
-			Region: getEnv("REGION", "us-east-1"),
+			Port:             getEnvInt("APP_PORT", 8080),
+			Region:           getEnv("REGION", "us-east-1"),
+			IdempotencyTTL:   getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+			IdempotencyLease: getEnvDuration("IDEMPOTENCY_LEASE", time.Minute),
+			MaxBatchSize:     getEnvInt("BATCH_MAX_SIZE", 500),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("COCKROACHDB_HOST", "cockroachdb-public"),
-			Port:     getEnvInt("COCKROACHDB_PORT", 26257),
-			Database: getEnv("COCKROACHDB_DATABASE", "ledger"),
+			Host:        getEnv("COCKROACHDB_HOST", "cockroachdb-public"),
+			Port:        getEnvInt("COCKROACHDB_PORT", 26257),
+			Database:    getEnv("COCKROACHDB_DATABASE", "ledger"),
+			AutoMigrate: getEnvBool("DB_AUTO_MIGRATE", false),
+			Timeout:     getEnvDuration("DB_TIMEOUT", 10*time.Second),
 		},
 		AWS: AWSConfig{
-			Region:   getEnv("AWS_REGION", "us-east-1"),
-			Endpoint: getEnv("AWS_ENDPOINT", "http://localhost:4566"),
-			S3Bucket: getEnv("S3_BUCKET", "us-east-1-audit-logs"),
-			SQSQueue: getEnv("SQS_QUEUE", "us-east-1-transaction-queue"),
+			Region:          getEnv("AWS_REGION", "us-east-1"),
+			Endpoint:        getEnv("AWS_ENDPOINT", "http://localhost:4566"),
+			S3Bucket:        getEnv("S3_BUCKET", "us-east-1-audit-logs"),
+			SQSQueue:        getEnv("SQS_QUEUE", "us-east-1-transaction-queue"),
+			Timeout:         getEnvDuration("AWS_TIMEOUT", 10*time.Second),
+			MaxReceiveCount: getEnvInt("SQS_MAX_RECEIVE_COUNT", 5),
+			SQSWaitTime:     getEnvDuration("SQS_WAIT_TIME", 20*time.Second),
+			SQSFIFO:         getEnvBool("SQS_FIFO", false),
+		},
+		FX: FXConfig{
+			RatesFile: getEnv("FX_RATES_FILE", ""),
+			QuoteTTL:  getEnvDuration("FX_QUOTE_TTL", 30*time.Second),
+		},
+		Holds: HoldsConfig{
+			DefaultTTL:    getEnvDuration("HOLD_TTL", 7*24*time.Hour),
+			SweepInterval: getEnvDuration("HOLD_SWEEP_INTERVAL", time.Minute),
+		},
+		Schedules: SchedulesConfig{
+			PollInterval: getEnvDuration("SCHEDULE_POLL_INTERVAL", 30*time.Second),
+		},
+		Outbox: OutboxConfig{
+			PollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		},
+		Events: EventsConfig{
+			Backend:      getEnv("EVENTS_BACKEND", "sqs"),
+			KafkaBrokers: getEnvList("KAFKA_BROKERS", []string{"localhost:9092"}),
+			KafkaTopic:   getEnv("KAFKA_TOPIC", "us-east-1-transaction-events"),
+			KafkaGroupID: getEnv("KAFKA_GROUP_ID", "ledger-app"),
+		},
+		Consumer: ConsumerConfig{
+			Workers:           getEnvInt("SQS_CONSUMER_WORKERS", 4),
+			VisibilityTimeout: getEnvDuration("SQS_VISIBILITY_TIMEOUT", 30*time.Second),
 		},
 	}
 }
@@ -63,6 +156,21 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvList(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		if len(list) > 0 {
+			return list
+		}
+	}
+	return defaultValue
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -72,3 +180,20 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
+		}
+	}
+	return defaultValue
+}
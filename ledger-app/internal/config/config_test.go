@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -181,3 +182,96 @@ func TestGetEnvInt(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEnvBool(t *testing.T) {
+	original := os.Getenv("TEST_BOOL_VAR")
+
+	defer func() {
+		if original != "" {
+			os.Setenv("TEST_BOOL_VAR", original)
+		} else {
+			os.Unsetenv("TEST_BOOL_VAR")
+		}
+	}()
+
+	tests := []struct {
+		name     string
+		setup    func()
+		expected bool
+	}{
+		{
+			name:     "true",
+			setup:    func() { os.Setenv("TEST_BOOL_VAR", "true") },
+			expected: true,
+		},
+		{
+			name:     "invalid boolean returns default",
+			setup:    func() { os.Setenv("TEST_BOOL_VAR", "yes please") },
+			expected: false,
+		},
+		{
+			name:     "not set returns default",
+			setup:    func() { os.Unsetenv("TEST_BOOL_VAR") },
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			result := getEnvBool("TEST_BOOL_VAR", false)
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetEnvDuration(t *testing.T) {
+	original := os.Getenv("TEST_DURATION_VAR")
+
+	defer func() {
+		if original != "" {
+			os.Setenv("TEST_DURATION_VAR", original)
+		} else {
+			os.Unsetenv("TEST_DURATION_VAR")
+		}
+	}()
+
+	tests := []struct {
+		name     string
+		setup    func()
+		expected time.Duration
+	}{
+		{
+			name:     "valid duration",
+			setup:    func() { os.Setenv("TEST_DURATION_VAR", "90m") },
+			expected: 90 * time.Minute,
+		},
+		{
+			name:     "invalid duration returns default",
+			setup:    func() { os.Setenv("TEST_DURATION_VAR", "forever") },
+			expected: time.Hour,
+		},
+		{
+			name:     "non-positive duration returns default",
+			setup:    func() { os.Setenv("TEST_DURATION_VAR", "-5s") },
+			expected: time.Hour,
+		},
+		{
+			name:     "not set returns default",
+			setup:    func() { os.Unsetenv("TEST_DURATION_VAR") },
+			expected: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			result := getEnvDuration("TEST_DURATION_VAR", time.Hour)
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
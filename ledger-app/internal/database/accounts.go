@@ -0,0 +1,238 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lib/pq"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// uniqueViolation is the SQLSTATE code for unique constraint violations
+const uniqueViolation = "23505"
+
+// CreateAccount creates a new account in the database
+func (db *DB) CreateAccount(ctx context.Context, account *models.Account) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO accounts (id, owner, currency, status, balance, overdraft_limit, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, owner, currency, status, balance, held, overdraft_limit, created_at, updated_at
+	`
+
+	err := db.conn.QueryRowContext(ctx,
+		query,
+		account.ID,
+		account.Owner,
+		account.Currency,
+		account.Status,
+		account.Balance,
+		account.OverdraftLimit,
+		account.CreatedAt,
+		account.UpdatedAt,
+	).Scan(
+		&account.ID,
+		&account.Owner,
+		&account.Currency,
+		&account.Status,
+		&account.Balance,
+		&account.Held,
+		&account.OverdraftLimit,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %s", models.ErrAccountExists, account.ID)
+	}
+	if err != nil {
+		db.logger.Error("Failed to create account",
+			zap.Error(err),
+			zap.String("account_id", account.ID),
+		)
+		return fmt.Errorf("failed to create account: %w", err)
+	}
+
+	db.logger.Info("Account created",
+		zap.String("account_id", account.ID),
+		zap.String("currency", account.Currency),
+	)
+
+	return nil
+}
+
+// GetAccount retrieves an account by ID
+func (db *DB) GetAccount(ctx context.Context, id string) (*models.Account, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var account models.Account
+	query := `
+		SELECT id, owner, currency, status, balance, held, overdraft_limit, created_at, updated_at
+		FROM accounts
+		WHERE id = $1
+	`
+
+	err := db.conn.QueryRowContext(ctx, query, id).Scan(
+		&account.ID,
+		&account.Owner,
+		&account.Currency,
+		&account.Status,
+		&account.Balance,
+		&account.Held,
+		&account.OverdraftLimit,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrAccountNotFound, id)
+	}
+	if err != nil {
+		db.logger.Error("Failed to get account",
+			zap.Error(err),
+			zap.String("account_id", id),
+		)
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	return &account, nil
+}
+
+// checkFunds locks every account debited by the entries of tx and verifies
+// that the debit is in the account's currency and keeps it within its
+// overdraft limit. Only the FX legs of tx are exempt. It must run inside a
+// SERIALIZABLE transaction so concurrent transfers from the same account, in
+// any region, can't both pass the check against the same balance.
+func checkFunds(ctx context.Context, sqlTx *sql.Tx, tx *models.Transaction, entries []*models.Entry) error {
+	debits := make(map[string]decimal.Decimal)
+	currencies := make(map[string]string)
+	for _, e := range entries {
+		// FX positions may go negative
+		if e.IsDebit() && !tx.IsFXLeg(e) {
+			debits[e.Account] = debits[e.Account].Add(e.Amount.Neg())
+			currencies[e.Account] = e.Currency
+		}
+	}
+
+	// Lock accounts in a stable order to avoid deadlocks between transfers
+	accountIDs := make([]string, 0, len(debits))
+	for id := range debits {
+		accountIDs = append(accountIDs, id)
+	}
+	sort.Strings(accountIDs)
+
+	for _, id := range accountIDs {
+		if err := checkAccount(ctx, sqlTx, id, models.Money{Value: debits[id], Currency: currencies[id]}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkPostings locks every account the entries of tx post to, debited or
+// credited, in one stable order. Debited accounts are checked as by
+// checkFunds; credited accounts must be open and held in the currency
+// credited to them, so a transfer can't land in an account that was closed
+// or changed after the request was validated. Only the FX legs of tx are
+// exempt.
+func checkPostings(ctx context.Context, sqlTx *sql.Tx, tx *models.Transaction, entries []*models.Entry) error {
+	debits := make(map[string]decimal.Decimal)
+	currencies := make(map[string]string)
+	for _, e := range entries {
+		if tx.IsFXLeg(e) {
+			continue
+		}
+		if e.IsDebit() {
+			debits[e.Account] = debits[e.Account].Add(e.Amount.Neg())
+		}
+		currencies[e.Account] = e.Currency
+	}
+
+	// Lock accounts in a stable order to avoid deadlocks between transfers
+	accountIDs := make([]string, 0, len(currencies))
+	for id := range currencies {
+		accountIDs = append(accountIDs, id)
+	}
+	sort.Strings(accountIDs)
+
+	for _, id := range accountIDs {
+		if err := checkAccount(ctx, sqlTx, id, models.Money{Value: debits[id], Currency: currencies[id]}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAccount locks a single account and verifies that it is open, held in
+// the currency of amount, and that amount can be taken from its available
+// balance, so funds reserved by holds can't be spent twice. A zero amount
+// checks an account that is only credited.
+func checkAccount(ctx context.Context, sqlTx *sql.Tx, id string, amount models.Money) error {
+	query := `
+		SELECT status, currency, balance, held, overdraft_limit
+		FROM accounts
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	account := models.Account{ID: id}
+	err := sqlTx.QueryRowContext(ctx, query, id).Scan(&account.Status, &account.Currency, &account.Balance, &account.Held, &account.OverdraftLimit)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", models.ErrAccountNotFound, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock account %s: %w", id, err)
+	}
+
+	if !account.IsActive() {
+		return fmt.Errorf("%w: %s", models.ErrAccountClosed, id)
+	}
+	if account.Currency != amount.Currency {
+		return fmt.Errorf("%w: account %s is held in %s, posting is in %s",
+			models.ErrCurrencyMismatch, id, account.Currency, amount.Currency)
+	}
+	if amount.IsPositive() && !account.CanDebit(amount.Value) {
+		return fmt.Errorf("%w: account %s has available balance %s, overdraft limit %s, requested %s",
+			models.ErrInsufficientFunds, id, account.Available(), account.OverdraftLimit, amount.Value)
+	}
+
+	return nil
+}
+
+// applyEntries adds each posting to its account's running balance within an
+// open database transaction. Postings against unknown accounts abort the write.
+func applyEntries(ctx context.Context, sqlTx *sql.Tx, entries []*models.Entry) error {
+	query := `
+		UPDATE accounts
+		SET balance = balance + $1, updated_at = now()
+		WHERE id = $2
+	`
+
+	for _, e := range entries {
+		result, err := sqlTx.ExecContext(ctx, query, e.Amount, e.Account)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: %s", models.ErrAccountNotFound, e.Account)
+		}
+	}
+
+	return nil
+}
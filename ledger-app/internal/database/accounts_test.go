@@ -0,0 +1,357 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+var accountColumns = []string{"id", "owner", "currency", "status", "balance", "held", "overdraft_limit", "created_at", "updated_at"}
+
+var fundsColumns = []string{"status", "currency", "balance", "held", "overdraft_limit"}
+
+// expectCreditedLock expects an open account held in currency to be locked
+// as the credited side of a transfer
+func expectCreditedLock(mock sqlmock.Sqlmock, id, currency string) {
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", currency, "0", "0", "0"))
+}
+
+func TestCreateAccount_Success(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	account := &models.Account{
+		ID:        "acc1",
+		Owner:     "alice",
+		Currency:  "USD",
+		Status:    models.AccountStatusActive,
+		Balance:   decimal.Zero,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	rows := sqlmock.NewRows(accountColumns).
+		AddRow("acc1", "alice", "USD", "active", decimal.Zero, decimal.Zero, decimal.Zero, now, now)
+
+	mock.ExpectQuery(`INSERT INTO accounts`).
+		WithArgs("acc1", "alice", "USD", "active", decimal.Zero, decimal.Zero, now, now).
+		WillReturnRows(rows)
+
+	if err := db.CreateAccount(context.Background(), account); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateAccount_Duplicate(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`INSERT INTO accounts`).
+		WillReturnError(&pq.Error{Code: uniqueViolation})
+
+	err := db.CreateAccount(context.Background(), &models.Account{ID: "acc1"})
+	if !errors.Is(err, models.ErrAccountExists) {
+		t.Errorf("Expected ErrAccountExists, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateAccount_DatabaseError(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`INSERT INTO accounts`).
+		WillReturnError(errors.New("database error"))
+
+	err := db.CreateAccount(context.Background(), &models.Account{ID: "acc1"})
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if errors.Is(err, models.ErrAccountExists) {
+		t.Errorf("Expected generic error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetAccount_Success(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	balance := decimal.RequireFromString("250.00")
+	rows := sqlmock.NewRows(accountColumns).
+		AddRow("acc1", "alice", "USD", "active", balance, decimal.Zero, decimal.NewFromInt(100), now, now)
+
+	mock.ExpectQuery(`SELECT id, owner, currency, status, balance`).
+		WithArgs("acc1").
+		WillReturnRows(rows)
+
+	account, err := db.GetAccount(context.Background(), "acc1")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if account.Owner != "alice" {
+		t.Errorf("Expected owner alice, got %s", account.Owner)
+	}
+	if !account.Balance.Equal(balance) {
+		t.Errorf("Expected balance %s, got %s", balance, account.Balance)
+	}
+	if !account.OverdraftLimit.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Expected overdraft limit 100, got %s", account.OverdraftLimit)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetAccount_NotFound(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT id, owner, currency, status, balance`).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	account, err := db.GetAccount(context.Background(), "missing")
+	if !errors.Is(err, models.ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound, got: %v", err)
+	}
+	if account != nil {
+		t.Errorf("Expected nil account, got %v", account)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// newFundsTx opens a mock database transaction for exercising checkFunds directly
+func newFundsTx(t *testing.T) (*sql.Tx, sqlmock.Sqlmock, func()) {
+	db, mock, cleanup := setupTestDB(t)
+	mock.ExpectBegin()
+	sqlTx, err := db.conn.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	return sqlTx, mock, cleanup
+}
+
+func TestCheckFunds(t *testing.T) {
+	debit := func(account, amount string) []*models.Entry {
+		return []*models.Entry{
+			{Account: account, Amount: decimal.RequireFromString(amount).Neg(), Currency: "USD"},
+			{Account: "dest", Amount: decimal.RequireFromString(amount), Currency: "USD"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		entries []*models.Entry
+		row     []driver.Value
+		wantErr error
+	}{
+		{
+			name:    "sufficient balance",
+			entries: debit("acc1", "50.00"),
+			row:     []driver.Value{"active", "USD", "100.00", "0", "0"},
+		},
+		{
+			name:    "exact balance",
+			entries: debit("acc1", "100.00"),
+			row:     []driver.Value{"active", "USD", "100.00", "0", "0"},
+		},
+		{
+			name:    "within overdraft",
+			entries: debit("acc1", "150.00"),
+			row:     []driver.Value{"active", "USD", "100.00", "0", "50.00"},
+		},
+		{
+			name:    "beyond overdraft",
+			entries: debit("acc1", "150.01"),
+			row:     []driver.Value{"active", "USD", "100.00", "0", "50.00"},
+			wantErr: models.ErrInsufficientFunds,
+		},
+		{
+			name:    "balance reserved by holds",
+			entries: debit("acc1", "50.01"),
+			row:     []driver.Value{"active", "USD", "100.00", "50.00", "0"},
+			wantErr: models.ErrInsufficientFunds,
+		},
+		{
+			name:    "closed account",
+			entries: debit("acc1", "1.00"),
+			row:     []driver.Value{"closed", "USD", "100.00", "0", "0"},
+			wantErr: models.ErrAccountClosed,
+		},
+		{
+			name:    "account in another currency",
+			entries: debit("acc1", "1.00"),
+			row:     []driver.Value{"active", "EUR", "100.00", "0", "0"},
+			wantErr: models.ErrCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlTx, mock, cleanup := newFundsTx(t)
+			defer cleanup()
+
+			mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit\s+FROM accounts\s+WHERE id = \$1\s+FOR UPDATE`).
+				WithArgs("acc1").
+				WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow(tt.row...))
+
+			err := checkFunds(context.Background(), sqlTx, &models.Transaction{}, tt.entries)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckFunds_UnknownAccount(t *testing.T) {
+	sqlTx, mock, cleanup := newFundsTx(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	err := checkFunds(context.Background(), sqlTx, &models.Transaction{}, []*models.Entry{
+		{Account: "missing", Amount: decimal.NewFromInt(-1)},
+		{Account: "dest", Amount: decimal.NewFromInt(1)},
+	})
+	if !errors.Is(err, models.ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCheckFunds_SystemAccounts(t *testing.T) {
+	rate := decimal.RequireFromString("0.9")
+	fxTx := &models.Transaction{
+		Amount:          models.Money{Value: decimal.NewFromInt(100), Currency: "USD"},
+		ConvertedAmount: &models.Money{Value: decimal.NewFromInt(90), Currency: "EUR"},
+		FXRate:          &rate,
+		FromAccount:     "acc1",
+		ToAccount:       "acc2",
+	}
+
+	t.Run("fx legs are exempt", func(t *testing.T) {
+		sqlTx, mock, cleanup := newFundsTx(t)
+		defer cleanup()
+
+		// Only the source account is checked, not the EUR position it is converted through
+		mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+			WithArgs("acc1").
+			WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "100.00", "0", "0"))
+
+		if err := checkFunds(context.Background(), sqlTx, fxTx, models.NewFXTransferEntries(fxTx)); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("other debits of a system account are checked", func(t *testing.T) {
+		sqlTx, mock, cleanup := newFundsTx(t)
+		defer cleanup()
+
+		position := models.FXPositionAccount("USD")
+		mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+			WithArgs(position).
+			WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "-500.00", "0", "0"))
+
+		tx := &models.Transaction{Amount: models.Money{Value: decimal.NewFromInt(100), Currency: "USD"}, FromAccount: position, ToAccount: "acc2"}
+		err := checkFunds(context.Background(), sqlTx, tx, models.NewTransferEntries(tx))
+		if !errors.Is(err, models.ErrInsufficientFunds) {
+			t.Errorf("Expected ErrInsufficientFunds, got: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestCheckPostings_CreditedAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     []driver.Value
+		wantErr error
+	}{
+		{
+			name: "open account, even when overdrawn",
+			row:  []driver.Value{"active", "USD", "-20.00", "0", "0"},
+		},
+		{
+			name:    "closed account",
+			row:     []driver.Value{"closed", "USD", "0", "0", "0"},
+			wantErr: models.ErrAccountClosed,
+		},
+		{
+			name:    "account in another currency",
+			row:     []driver.Value{"active", "EUR", "0", "0", "0"},
+			wantErr: models.ErrCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlTx, mock, cleanup := newFundsTx(t)
+			defer cleanup()
+
+			tx := &models.Transaction{Amount: models.Money{Value: decimal.NewFromInt(10), Currency: "USD"}, FromAccount: "acc1", ToAccount: "acc2"}
+			mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit\s+FROM accounts\s+WHERE id = \$1\s+FOR UPDATE`).
+				WithArgs("acc1").
+				WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "100.00", "0", "0"))
+			mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit\s+FROM accounts\s+WHERE id = \$1\s+FOR UPDATE`).
+				WithArgs("acc2").
+				WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow(tt.row...))
+
+			err := checkPostings(context.Background(), sqlTx, tx, models.NewTransferEntries(tx))
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
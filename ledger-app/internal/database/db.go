@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -11,8 +12,9 @@ import (
 
 // DB wraps the database connection
 type DB struct {
-	conn   *sql.DB
-	logger *zap.Logger
+	conn    *sql.DB
+	logger  *zap.Logger
+	timeout time.Duration
 }
 
 // Config holds database configuration
@@ -22,11 +24,38 @@ type Config struct {
 	Database string
 	User     string
 	Password string
-	Timeout  time.Duration
+	// Timeout bounds connecting and every database operation; the context
+	// passed to an operation can end it sooner
+	Timeout time.Duration
+	// AutoMigrate applies pending migrations before the schema version is checked
+	AutoMigrate bool
 }
 
-// New creates a new database connection
+// New creates a new database connection and checks that the schema has every
+// migration this build needs, applying them first if config.AutoMigrate is set
 func New(config Config, logger *zap.Logger) (*DB, error) {
+	db, err := Open(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.AutoMigrate {
+		if _, err := db.MigrateUp(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	if err := db.checkSchemaVersion(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Open creates a new database connection without checking the schema
+func Open(config Config, logger *zap.Logger) (*DB, error) {
 	dsn := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable connect_timeout=%d",
 		config.Host,
@@ -60,8 +89,9 @@ func New(config Config, logger *zap.Logger) (*DB, error) {
 	)
 
 	return &DB{
-		conn:   conn,
-		logger: logger,
+		conn:    conn,
+		logger:  logger,
+		timeout: config.Timeout,
 	}, nil
 }
 
@@ -75,8 +105,19 @@ func (db *DB) GetConnection() *sql.DB {
 	return db.conn
 }
 
+// withTimeout derives the context of a single operation from ctx, bounded
+// by the configured timeout
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.timeout)
+}
+
 // Health checks if the database is healthy
-func (db *DB) Health() error {
-	return db.conn.Ping()
+func (db *DB) Health(ctx context.Context) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	return db.conn.PingContext(ctx)
 }
 
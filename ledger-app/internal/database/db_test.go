@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -15,7 +16,7 @@ func TestDB_Health_Success(t *testing.T) {
 	var _ sqlmock.Sqlmock = mock // Use sqlmock type explicitly
 	mock.ExpectPing()
 
-	err := db.Health()
+	err := db.Health(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -31,7 +32,7 @@ func TestDB_Health_Failure(t *testing.T) {
 
 	mock.ExpectPing().WillReturnError(errors.New("connection failed"))
 
-	err := db.Health()
+	err := db.Health(context.Background())
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// CreateFXQuote stores a newly issued FX quote
+func (db *DB) CreateFXQuote(ctx context.Context, quote *models.FXQuote) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO fx_quotes (id, from_currency, to_currency, rate, source_amount, target_amount, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := db.conn.ExecContext(ctx,
+		query,
+		quote.ID,
+		quote.SourceAmount.Currency,
+		quote.TargetAmount.Currency,
+		quote.Rate,
+		quote.SourceAmount.Value,
+		quote.TargetAmount.Value,
+		quote.CreatedAt,
+		quote.ExpiresAt,
+	)
+	if err != nil {
+		db.logger.Error("Failed to create fx quote",
+			zap.Error(err),
+			zap.String("quote_id", quote.ID.String()),
+		)
+		return fmt.Errorf("failed to create fx quote: %w", err)
+	}
+
+	return nil
+}
+
+// GetFXQuote retrieves an FX quote by ID, whether or not it is still usable
+func (db *DB) GetFXQuote(ctx context.Context, id uuid.UUID) (*models.FXQuote, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var quote models.FXQuote
+	var transactionID uuid.NullUUID
+	query := `
+		SELECT id, from_currency, to_currency, rate, source_amount, target_amount, created_at, expires_at, transaction_id
+		FROM fx_quotes
+		WHERE id = $1
+	`
+
+	err := db.conn.QueryRowContext(ctx, query, id).Scan(
+		&quote.ID,
+		&quote.SourceAmount.Currency,
+		&quote.TargetAmount.Currency,
+		&quote.Rate,
+		&quote.SourceAmount.Value,
+		&quote.TargetAmount.Value,
+		&quote.CreatedAt,
+		&quote.ExpiresAt,
+		&transactionID,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrQuoteNotFound, id.String())
+	}
+	if err != nil {
+		db.logger.Error("Failed to get fx quote",
+			zap.Error(err),
+			zap.String("quote_id", id.String()),
+		)
+		return nil, fmt.Errorf("failed to get fx quote: %w", err)
+	}
+
+	if transactionID.Valid {
+		quote.TransactionID = &transactionID.UUID
+	}
+	return &quote, nil
+}
+
+// claimFXQuote marks a quote as used by a transaction within an open database
+// transaction, so a quote can fund at most one transfer even when both
+// regions race to use it
+func claimFXQuote(ctx context.Context, sqlTx *sql.Tx, quoteID, transactionID uuid.UUID) error {
+	now := time.Now().UTC()
+	query := `
+		UPDATE fx_quotes
+		SET transaction_id = $1
+		WHERE id = $2 AND transaction_id IS NULL AND expires_at > $3
+	`
+
+	result, err := sqlTx.ExecContext(ctx, query, transactionID, quoteID, now)
+	if err != nil {
+		return fmt.Errorf("failed to claim fx quote: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 1 {
+		return nil
+	}
+
+	// Work out why the quote couldn't be claimed
+	var usedBy uuid.NullUUID
+	var expiresAt time.Time
+	err = sqlTx.QueryRowContext(ctx, "SELECT transaction_id, expires_at FROM fx_quotes WHERE id = $1", quoteID).Scan(&usedBy, &expiresAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", models.ErrQuoteNotFound, quoteID.String())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get fx quote: %w", err)
+	}
+	if usedBy.Valid {
+		return fmt.Errorf("%w: %s by transaction %s", models.ErrQuoteUsed, quoteID.String(), usedBy.UUID.String())
+	}
+	return fmt.Errorf("%w: %s at %s", models.ErrQuoteExpired, quoteID.String(), expiresAt.Format(time.RFC3339))
+}
+
+// ensureSystemAccounts creates the system accounts that entries post to, such
+// as FX position accounts, if they don't exist yet
+func ensureSystemAccounts(ctx context.Context, sqlTx *sql.Tx, entries []*models.Entry) error {
+	query := `
+		INSERT INTO accounts (id, owner, currency, status, balance, overdraft_limit, created_at, updated_at)
+		VALUES ($1, 'system', $2, $3, 0, 0, now(), now())
+		ON CONFLICT (id) DO NOTHING
+	`
+
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if !models.IsSystemAccount(e.Account) || seen[e.Account] {
+			continue
+		}
+		seen[e.Account] = true
+
+		if _, err := sqlTx.ExecContext(ctx, query, e.Account, e.Currency, models.AccountStatusActive); err != nil {
+			return fmt.Errorf("failed to create system account %s: %w", e.Account, err)
+		}
+	}
+
+	return nil
+}
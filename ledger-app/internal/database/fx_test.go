@@ -0,0 +1,276 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+var fxQuoteColumns = []string{"id", "from_currency", "to_currency", "rate", "source_amount", "target_amount", "created_at", "expires_at", "transaction_id"}
+
+func TestCreateFXQuote_Success(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	quote, err := models.NewFXQuote(models.Money{Value: decimal.NewFromInt(100), Currency: "USD"}, "EUR", decimal.RequireFromString("0.9"), time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to build quote: %v", err)
+	}
+
+	mock.ExpectExec(`INSERT INTO fx_quotes`).
+		WithArgs(quote.ID, "USD", "EUR", quote.Rate, quote.SourceAmount.Value, quote.TargetAmount.Value, quote.CreatedAt, quote.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := db.CreateFXQuote(context.Background(), quote); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetFXQuote(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	quoteID := uuid.New()
+	txID := uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(`SELECT id, from_currency, to_currency, rate, source_amount, target_amount, created_at, expires_at, transaction_id\s+FROM fx_quotes`).
+		WithArgs(quoteID).
+		WillReturnRows(sqlmock.NewRows(fxQuoteColumns).
+			AddRow(quoteID, "USD", "EUR", "0.9", "100", "90", now, now.Add(time.Minute), txID))
+
+	quote, err := db.GetFXQuote(context.Background(), quoteID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if quote.SourceAmount.String() != "100.00 USD" || quote.TargetAmount.String() != "90.00 EUR" {
+		t.Errorf("Unexpected amounts: %s -> %s", quote.SourceAmount, quote.TargetAmount)
+	}
+	if quote.TransactionID == nil || *quote.TransactionID != txID {
+		t.Errorf("Expected quote to be used by %s, got %v", txID, quote.TransactionID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetFXQuote_NotFound(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	quoteID := uuid.New()
+	mock.ExpectQuery(`SELECT id, from_currency`).
+		WithArgs(quoteID).
+		WillReturnRows(sqlmock.NewRows(fxQuoteColumns))
+
+	_, err := db.GetFXQuote(context.Background(), quoteID)
+	if !errors.Is(err, models.ErrQuoteNotFound) {
+		t.Errorf("Expected ErrQuoteNotFound, got: %v", err)
+	}
+}
+
+func TestClaimFXQuote(t *testing.T) {
+	quoteID := uuid.New()
+	txID := uuid.New()
+	expiresAt := time.Now().UTC()
+
+	tests := []struct {
+		name    string
+		setup   func(mock sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{
+			name: "claimed",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE fx_quotes`).
+					WithArgs(txID, quoteID, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "already used",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE fx_quotes`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT transaction_id, expires_at FROM fx_quotes`).
+					WithArgs(quoteID).
+					WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "expires_at"}).AddRow(uuid.New(), expiresAt))
+			},
+			wantErr: models.ErrQuoteUsed,
+		},
+		{
+			name: "expired",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE fx_quotes`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT transaction_id, expires_at FROM fx_quotes`).
+					WithArgs(quoteID).
+					WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "expires_at"}).AddRow(nil, expiresAt))
+			},
+			wantErr: models.ErrQuoteExpired,
+		},
+		{
+			name: "unknown",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE fx_quotes`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT transaction_id, expires_at FROM fx_quotes`).
+					WithArgs(quoteID).
+					WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "expires_at"}))
+			},
+			wantErr: models.ErrQuoteNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			mock.ExpectBegin()
+			tt.setup(mock)
+			mock.ExpectRollback()
+
+			sqlTx, err := db.conn.Begin()
+			if err != nil {
+				t.Fatalf("Failed to begin: %v", err)
+			}
+			defer sqlTx.Rollback()
+
+			err = claimFXQuote(context.Background(), sqlTx, quoteID, txID)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCreateTransaction_FX(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+	quoteID := uuid.New()
+	now := time.Now()
+	source := decimal.NewFromInt(100)
+	target := decimal.NewFromInt(90)
+	rate := decimal.RequireFromString("0.9")
+
+	tx := &models.Transaction{
+		ID:              txID,
+		Region:          "us-east-1",
+		Amount:          models.Money{Value: source, Currency: "USD"},
+		ConvertedAmount: &models.Money{Value: target, Currency: "EUR"},
+		FXRate:          &rate,
+		QuoteID:         &quoteID,
+		FromAccount:     "acc1",
+		ToAccount:       "acc2",
+		Status:          "completed",
+		Timestamp:       now,
+	}
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "1000.00", "0", "0"))
+	expectCreditedLock(mock, "acc2", "EUR")
+	mock.ExpectExec(`UPDATE fx_quotes`).
+		WithArgs(txID, quoteID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO accounts .* ON CONFLICT \(id\) DO NOTHING`).
+		WithArgs("fx-position-USD", "USD", "active").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO accounts .* ON CONFLICT \(id\) DO NOTHING`).
+		WithArgs("fx-position-EUR", "EUR", "active").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO transactions`).
+		WithArgs(txID, "us-east-1", source, "USD", "acc1", "acc2", "completed", now, nil, target, "EUR", &rate, &quoteID).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).
+			AddRow(txID, "us-east-1", source, "USD", "acc1", "acc2", "completed", now, nil, target, "EUR", rate, quoteID))
+	for _, posting := range []struct {
+		account  string
+		amount   decimal.Decimal
+		currency string
+	}{
+		{"acc1", source.Neg(), "USD"},
+		{"fx-position-USD", source, "USD"},
+		{"fx-position-EUR", target.Neg(), "EUR"},
+		{"acc2", target, "EUR"},
+	} {
+		mock.ExpectExec(`INSERT INTO entries`).
+			WithArgs(sqlmock.AnyArg(), txID, posting.account, posting.amount, posting.currency, "us-east-1", now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(source.Neg(), "acc1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(source, "fx-position-USD").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(target.Neg(), "fx-position-EUR").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(target, "acc2").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	if err := db.CreateTransaction(context.Background(), tx, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !tx.IsFX() || tx.ConvertedAmount.String() != "90.00 EUR" {
+		t.Errorf("Expected converted amount 90.00 EUR, got %v", tx.ConvertedAmount)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTransaction_FXQuoteUsed(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	quoteID := uuid.New()
+	rate := decimal.RequireFromString("0.9")
+	tx := &models.Transaction{
+		ID:              uuid.New(),
+		Region:          "us-east-1",
+		Amount:          models.Money{Value: decimal.NewFromInt(100), Currency: "USD"},
+		ConvertedAmount: &models.Money{Value: decimal.NewFromInt(90), Currency: "EUR"},
+		FXRate:          &rate,
+		QuoteID:         &quoteID,
+		FromAccount:     "acc1",
+		ToAccount:       "acc2",
+		Status:          "completed",
+		Timestamp:       time.Now(),
+	}
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "1000.00", "0", "0"))
+	expectCreditedLock(mock, "acc2", "EUR")
+	mock.ExpectExec(`UPDATE fx_quotes`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT transaction_id, expires_at FROM fx_quotes`).
+		WithArgs(quoteID).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "expires_at"}).AddRow(uuid.New(), time.Now().Add(time.Minute)))
+	mock.ExpectRollback()
+
+	err := db.CreateTransaction(context.Background(), tx, nil)
+	if !errors.Is(err, models.ErrQuoteUsed) {
+		t.Errorf("Expected ErrQuoteUsed, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// holdColumns lists the holds columns in the order scanHold reads them
+const holdColumns = "id, region, amount, currency, from_account, to_account, status, captured_amount, transaction_id, " +
+	"created_at, expires_at, updated_at"
+
+// scanHold reads a row selected with holdColumns into hold
+func scanHold(row rowScanner, hold *models.Hold) error {
+	var captured decimal.NullDecimal
+	var transactionID uuid.NullUUID
+	err := row.Scan(
+		&hold.ID,
+		&hold.Region,
+		&hold.Amount.Value,
+		&hold.Amount.Currency,
+		&hold.FromAccount,
+		&hold.ToAccount,
+		&hold.Status,
+		&captured,
+		&transactionID,
+		&hold.CreatedAt,
+		&hold.ExpiresAt,
+		&hold.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	hold.CapturedAmount = nil
+	if captured.Valid {
+		hold.CapturedAmount = &captured.Decimal
+	}
+	hold.TransactionID = nil
+	if transactionID.Valid {
+		hold.TransactionID = &transactionID.UUID
+	}
+	return nil
+}
+
+// CreateHold reserves the hold's amount on its source account. The funds check
+// and the reservation happen in the same SERIALIZABLE database transaction, so
+// a hold can't reserve funds that a concurrent transfer or hold is spending.
+// The outbox messages for the hold are stored in the same transaction, which
+// also completes the request's idempotency key.
+func (db *DB) CreateHold(ctx context.Context, hold *models.Hold, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		if err := checkAccount(ctx, sqlTx, hold.FromAccount, hold.Amount); err != nil {
+			db.logger.Warn("Hold rejected by funds check",
+				zap.Error(err),
+				zap.String("hold_id", hold.ID.String()),
+			)
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+
+		if err := adjustHeld(ctx, sqlTx, hold.FromAccount, hold.Amount.Value); err != nil {
+			return fmt.Errorf("failed to reserve hold funds: %w", err)
+		}
+
+		query := `
+			INSERT INTO holds (id, region, amount, currency, from_account, to_account, status, created_at, expires_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING ` + holdColumns
+
+		err := scanHold(sqlTx.QueryRowContext(ctx,
+			query,
+			hold.ID,
+			hold.Region,
+			hold.Amount.Value,
+			hold.Amount.Currency,
+			hold.FromAccount,
+			hold.ToAccount,
+			hold.Status,
+			hold.CreatedAt,
+			hold.ExpiresAt,
+			hold.UpdatedAt,
+		), hold)
+		if err != nil {
+			db.logger.Error("Failed to create hold",
+				zap.Error(err),
+				zap.String("hold_id", hold.ID.String()),
+			)
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+
+		if err := insertOutbox(ctx, sqlTx, outbox); err != nil {
+			return err
+		}
+		return completeIdempotency(ctx, sqlTx, completion)
+	}); err != nil {
+		return err
+	}
+
+	db.logger.Info("Hold created",
+		zap.String("hold_id", hold.ID.String()),
+		zap.String("account_id", hold.FromAccount),
+		zap.String("amount", hold.Amount.String()),
+	)
+
+	return nil
+}
+
+// GetHold retrieves a hold by ID
+func (db *DB) GetHold(ctx context.Context, id uuid.UUID) (*models.Hold, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var hold models.Hold
+	query := `SELECT ` + holdColumns + ` FROM holds WHERE id = $1`
+
+	err := scanHold(db.conn.QueryRowContext(ctx, query, id), &hold)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrHoldNotFound, id.String())
+	}
+	if err != nil {
+		db.logger.Error("Failed to get hold",
+			zap.Error(err),
+			zap.String("hold_id", id.String()),
+		)
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	return &hold, nil
+}
+
+// CaptureHold settles hold with capture, a transfer built with
+// Hold.NewCapture. The hold is locked and checked again, its full amount is
+// released, and the capture is posted like any other transfer, all in the
+// same SERIALIZABLE database transaction, together with the outbox messages
+// for the capture and the completion of the request's idempotency key. Any
+// part of the hold that isn't captured goes back to the available balance.
+// hold is refreshed with the captured state.
+func (db *DB) CaptureHold(ctx context.Context, hold *models.Hold, capture *models.Transaction, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		if err := lockHold(ctx, sqlTx, hold); err != nil {
+			return fmt.Errorf("failed to capture hold: %w", err)
+		}
+		if !hold.IsActive() {
+			return fmt.Errorf("%w: %s is %s", models.ErrHoldNotActive, hold.ID.String(), hold.Status)
+		}
+		if hold.IsExpired(time.Now().UTC()) {
+			return fmt.Errorf("%w: %s at %s", models.ErrHoldExpired, hold.ID.String(), hold.ExpiresAt.Format(time.RFC3339))
+		}
+		if capture.Amount.Currency != hold.Amount.Currency || capture.Amount.Value.GreaterThan(hold.Amount.Value) {
+			return fmt.Errorf("%w: capturing %s of %s", models.ErrOverCapture, capture.Amount, hold.Amount)
+		}
+
+		// Release the reservation first so the capture is checked against the
+		// balance the hold was protecting
+		if err := adjustHeld(ctx, sqlTx, hold.FromAccount, hold.Amount.Value.Neg()); err != nil {
+			return fmt.Errorf("failed to release hold funds: %w", err)
+		}
+
+		if err := prepareEntries(capture); err != nil {
+			return fmt.Errorf("failed to capture hold: %w", err)
+		}
+		if err := db.postTransaction(ctx, sqlTx, capture); err != nil {
+			return fmt.Errorf("failed to capture hold: %w", err)
+		}
+
+		captured := capture.Amount.Value
+		hold.Status = models.HoldStatusCaptured
+		hold.CapturedAmount = &captured
+		hold.TransactionID = &capture.ID
+		if err := updateHold(ctx, sqlTx, hold); err != nil {
+			return fmt.Errorf("failed to capture hold: %w", err)
+		}
+
+		if err := insertOutbox(ctx, sqlTx, outbox); err != nil {
+			return err
+		}
+		return completeIdempotency(ctx, sqlTx, completion)
+	}); err != nil {
+		return err
+	}
+
+	db.logger.Info("Hold captured",
+		zap.String("hold_id", hold.ID.String()),
+		zap.String("transaction_id", capture.ID.String()),
+		zap.String("captured", capture.Amount.String()),
+	)
+
+	return nil
+}
+
+// VoidHold releases an active hold without moving any money and stores the
+// outbox messages for it and the completion of the request's idempotency key
+// in the same database transaction. hold is refreshed with the voided state.
+func (db *DB) VoidHold(ctx context.Context, hold *models.Hold, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		if err := lockHold(ctx, sqlTx, hold); err != nil {
+			return fmt.Errorf("failed to void hold: %w", err)
+		}
+		if err := releaseHold(ctx, sqlTx, hold, models.HoldStatusVoided); err != nil {
+			return fmt.Errorf("failed to void hold: %w", err)
+		}
+
+		if err := insertOutbox(ctx, sqlTx, outbox); err != nil {
+			return err
+		}
+		return completeIdempotency(ctx, sqlTx, completion)
+	}); err != nil {
+		return err
+	}
+
+	db.logger.Info("Hold voided",
+		zap.String("hold_id", hold.ID.String()),
+		zap.String("account_id", hold.FromAccount),
+	)
+
+	return nil
+}
+
+// ExpireHolds releases up to limit active holds whose expiry time is before
+// now and returns how many were expired. Sweepers in both regions may run at
+// once; the row locks and SERIALIZABLE isolation make sure each hold is
+// released only once.
+func (db *DB) ExpireHolds(ctx context.Context, now time.Time, limit int) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var holds []*models.Hold
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		query := `
+			SELECT ` + holdColumns + `
+			FROM holds
+			WHERE status = $1 AND expires_at <= $2
+			ORDER BY expires_at
+			LIMIT $3
+			FOR UPDATE
+		`
+
+		rows, err := sqlTx.QueryContext(ctx, query, models.HoldStatusActive, now, limit)
+		if err != nil {
+			return fmt.Errorf("failed to find expired holds: %w", err)
+		}
+
+		holds = nil
+		for rows.Next() {
+			var hold models.Hold
+			if err := scanHold(rows, &hold); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan hold: %w", err)
+			}
+			holds = append(holds, &hold)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating holds: %w", err)
+		}
+
+		for _, hold := range holds {
+			if err := releaseHold(ctx, sqlTx, hold, models.HoldStatusExpired); err != nil {
+				return fmt.Errorf("failed to expire hold %s: %w", hold.ID.String(), err)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	if len(holds) > 0 {
+		db.logger.Info("Holds expired", zap.Int("count", len(holds)))
+	}
+
+	return len(holds), nil
+}
+
+// lockHold reloads hold by ID with a row lock within an open database transaction
+func lockHold(ctx context.Context, sqlTx *sql.Tx, hold *models.Hold) error {
+	query := `SELECT ` + holdColumns + ` FROM holds WHERE id = $1 FOR UPDATE`
+
+	id := hold.ID
+	err := scanHold(sqlTx.QueryRowContext(ctx, query, id), hold)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", models.ErrHoldNotFound, id.String())
+	}
+	return err
+}
+
+// releaseHold returns a locked, active hold's amount to the available balance
+// and moves the hold to status
+func releaseHold(ctx context.Context, sqlTx *sql.Tx, hold *models.Hold, status string) error {
+	if !hold.IsActive() {
+		return fmt.Errorf("%w: %s is %s", models.ErrHoldNotActive, hold.ID.String(), hold.Status)
+	}
+
+	if err := adjustHeld(ctx, sqlTx, hold.FromAccount, hold.Amount.Value.Neg()); err != nil {
+		return err
+	}
+
+	hold.Status = status
+	return updateHold(ctx, sqlTx, hold)
+}
+
+// updateHold writes a hold's settlement state within an open database transaction
+func updateHold(ctx context.Context, sqlTx *sql.Tx, hold *models.Hold) error {
+	query := `
+		UPDATE holds
+		SET status = $1, captured_amount = $2, transaction_id = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	hold.UpdatedAt = time.Now().UTC()
+	_, err := sqlTx.ExecContext(ctx, query, hold.Status, hold.CapturedAmount, hold.TransactionID, hold.UpdatedAt, hold.ID)
+	return err
+}
+
+// adjustHeld changes the amount reserved on an account within an open
+// database transaction
+func adjustHeld(ctx context.Context, sqlTx *sql.Tx, accountID string, delta decimal.Decimal) error {
+	query := `
+		UPDATE accounts
+		SET held = held + $1, updated_at = now()
+		WHERE id = $2
+	`
+
+	result, err := sqlTx.ExecContext(ctx, query, delta, accountID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", models.ErrAccountNotFound, accountID)
+	}
+
+	return nil
+}
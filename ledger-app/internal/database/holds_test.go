@@ -0,0 +1,279 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+var holdColumnNames = []string{"id", "region", "amount", "currency", "from_account", "to_account", "status",
+	"captured_amount", "transaction_id", "created_at", "expires_at", "updated_at"}
+
+func newActiveHold() *models.Hold {
+	return models.NewHold("acc1", "merchant", models.Money{Value: decimal.NewFromInt(100), Currency: "USD"}, "us-east-1", time.Hour)
+}
+
+// holdRow returns hold as a row of holdColumnNames
+func holdRow(hold *models.Hold) *sqlmock.Rows {
+	return sqlmock.NewRows(holdColumnNames).AddRow(
+		hold.ID, hold.Region, hold.Amount.Value, hold.Amount.Currency, hold.FromAccount, hold.ToAccount,
+		hold.Status, nil, nil, hold.CreatedAt, hold.ExpiresAt, hold.UpdatedAt,
+	)
+}
+
+func TestCreateHold_Success(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	hold := newActiveHold()
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "150.00", "50.00", "0"))
+	mock.ExpectExec(`UPDATE accounts\s+SET held = held \+ \$1`).
+		WithArgs(hold.Amount.Value, "acc1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO holds`).
+		WithArgs(hold.ID, "us-east-1", hold.Amount.Value, "USD", "acc1", "merchant", "active", hold.CreatedAt, hold.ExpiresAt, hold.UpdatedAt).
+		WillReturnRows(holdRow(hold))
+	expectTxCommit(mock)
+
+	if err := db.CreateHold(context.Background(), hold, nil); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateHold_InsufficientAvailableBalance(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	hold := newActiveHold()
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "150.00", "50.01", "0"))
+	mock.ExpectRollback()
+
+	err := db.CreateHold(context.Background(), hold, nil)
+	if !errors.Is(err, models.ErrInsufficientFunds) {
+		t.Errorf("Expected ErrInsufficientFunds, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetHold_NotFound(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	id := uuid.New()
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, captured_amount`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(holdColumnNames))
+
+	_, err := db.GetHold(context.Background(), id)
+	if !errors.Is(err, models.ErrHoldNotFound) {
+		t.Errorf("Expected ErrHoldNotFound, got: %v", err)
+	}
+}
+
+func TestCaptureHold_Partial(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	hold := newActiveHold()
+	capture, err := hold.NewCapture(decimal.NewFromInt(60), "us-east-1")
+	if err != nil {
+		t.Fatalf("Failed to build capture: %v", err)
+	}
+	amount := capture.Amount.Value
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT .* FROM holds WHERE id = \$1 FOR UPDATE`).
+		WithArgs(hold.ID).
+		WillReturnRows(holdRow(hold))
+	// The whole hold is released, then only the captured part is debited
+	mock.ExpectExec(`UPDATE accounts\s+SET held = held \+ \$1`).
+		WithArgs(hold.Amount.Value.Neg(), "acc1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "100.00", "0", "0"))
+	expectCreditedLock(mock, "merchant", "USD")
+	mock.ExpectQuery(`INSERT INTO transactions`).
+		WithArgs(capture.ID, "us-east-1", amount, "USD", "acc1", "merchant", "completed", capture.Timestamp, nil, nil, nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).
+			AddRow(capture.ID, "us-east-1", amount, "USD", "acc1", "merchant", "completed", capture.Timestamp, nil, nil, nil, nil, nil))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts\s+SET balance`).WithArgs(amount.Neg(), "acc1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts\s+SET balance`).WithArgs(amount, "merchant").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE holds`).
+		WithArgs("captured", &amount, &capture.ID, sqlmock.AnyArg(), hold.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	if err := db.CaptureHold(context.Background(), hold, capture, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if hold.Status != models.HoldStatusCaptured || hold.TransactionID == nil || *hold.TransactionID != capture.ID {
+		t.Errorf("Expected hold to be captured by %s, got %+v", capture.ID, hold)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCaptureHold_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		locked  func(h *models.Hold)
+		wantErr error
+	}{
+		{"already voided", func(h *models.Hold) { h.Status = models.HoldStatusVoided }, models.ErrHoldNotActive},
+		{"past expiry", func(h *models.Hold) { h.ExpiresAt = time.Now().Add(-time.Second) }, models.ErrHoldExpired},
+		{"more than held", func(h *models.Hold) { h.Amount.Value = decimal.NewFromInt(50) }, models.ErrOverCapture},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			hold := newActiveHold()
+			capture, _ := hold.NewCapture(decimal.Zero, "us-east-1")
+
+			// The locked row reflects what happened since the handler read it
+			locked := *hold
+			tt.locked(&locked)
+
+			expectTxBegin(mock)
+			mock.ExpectQuery(`SELECT .* FROM holds WHERE id = \$1 FOR UPDATE`).
+				WithArgs(hold.ID).
+				WillReturnRows(holdRow(&locked))
+			mock.ExpectRollback()
+
+			err := db.CaptureHold(context.Background(), hold, capture, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestVoidHold(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	hold := newActiveHold()
+	message := models.NewOutboxMessage("us-east-1", "acc1", []byte(`{"type":"hold.voided"}`))
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT .* FROM holds WHERE id = \$1 FOR UPDATE`).
+		WithArgs(hold.ID).
+		WillReturnRows(holdRow(hold))
+	mock.ExpectExec(`UPDATE accounts\s+SET held = held \+ \$1`).
+		WithArgs(hold.Amount.Value.Neg(), "acc1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE holds`).
+		WithArgs("voided", nil, nil, sqlmock.AnyArg(), hold.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(message.ID, "us-east-1", models.OutboxKindMessage, nil, "acc1", message.Payload, message.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	if err := db.VoidHold(context.Background(), hold, nil, message); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if hold.Status != models.HoldStatusVoided {
+		t.Errorf("Expected status voided, got %s", hold.Status)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestVoidHold_NotActive(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	hold := newActiveHold()
+	captured := *hold
+	captured.Status = models.HoldStatusCaptured
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT .* FROM holds WHERE id = \$1 FOR UPDATE`).
+		WithArgs(hold.ID).
+		WillReturnRows(holdRow(&captured))
+	mock.ExpectRollback()
+
+	err := db.VoidHold(context.Background(), hold, nil)
+	if !errors.Is(err, models.ErrHoldNotActive) {
+		t.Errorf("Expected ErrHoldNotActive, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExpireHolds(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().UTC()
+	first, second := newActiveHold(), newActiveHold()
+	second.FromAccount = "acc2"
+
+	rows := holdRow(first)
+	rows.AddRow(second.ID, second.Region, second.Amount.Value, "USD", "acc2", "merchant",
+		"active", nil, nil, second.CreatedAt, second.ExpiresAt, second.UpdatedAt)
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT .*\s+FROM holds\s+WHERE status = \$1 AND expires_at <= \$2`).
+		WithArgs("active", now, 100).
+		WillReturnRows(rows)
+	for _, hold := range []*models.Hold{first, second} {
+		mock.ExpectExec(`UPDATE accounts\s+SET held = held \+ \$1`).
+			WithArgs(hold.Amount.Value.Neg(), hold.FromAccount).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE holds`).
+			WithArgs("expired", nil, nil, sqlmock.AnyArg(), hold.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	expectTxCommit(mock)
+
+	expired, err := db.ExpireHolds(context.Background(), now, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if expired != 2 {
+		t.Errorf("Expected 2 holds expired, got %d", expired)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// GetIdempotencyRecord retrieves the unexpired record for an idempotency key
+func (db *DB) GetIdempotencyRecord(ctx context.Context, key string) (*models.IdempotencyRecord, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var record models.IdempotencyRecord
+	query := `
+		SELECT key, fingerprint, status_code, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE key = $1 AND expires_at > $2
+	`
+
+	err := db.conn.QueryRowContext(ctx, query, key, time.Now().UTC()).Scan(
+		&record.Key,
+		&record.Fingerprint,
+		&record.StatusCode,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrIdempotencyRecordNotFound, key)
+	}
+	if err != nil {
+		db.logger.Error("Failed to get idempotency record",
+			zap.Error(err),
+			zap.String("idempotency_key", key),
+		)
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	return &record, nil
+}
+
+// ReserveIdempotencyKey claims a key for an in-flight request under
+// record.ReservationID. A live record for the same key makes the reservation
+// fail with models.ErrIdempotencyKeyInUse; an expired one is replaced.
+//
+// Replacing a reservation whose lease has run out is safe because every write
+// made under a key completes its reservation in the write's own transaction
+// (see completeIdempotency). A reservation still in progress therefore never
+// has a committed write, and once it is replaced the original request can no
+// longer commit one.
+func (db *DB) ReserveIdempotencyKey(ctx context.Context, record *models.IdempotencyRecord) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO idempotency_keys (key, fingerprint, reservation_id, status_code, response_body, created_at, expires_at)
+		VALUES ($1, $2, $3, 0, NULL, $4, $5)
+		ON CONFLICT (key) DO UPDATE
+		SET fingerprint = excluded.fingerprint,
+			reservation_id = excluded.reservation_id,
+			status_code = 0,
+			response_body = NULL,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at <= excluded.created_at
+	`
+
+	result, err := db.conn.ExecContext(ctx, query, record.Key, record.Fingerprint, record.ReservationID,
+		record.CreatedAt, record.ExpiresAt)
+	if err != nil {
+		db.logger.Error("Failed to reserve idempotency key",
+			zap.Error(err),
+			zap.String("idempotency_key", record.Key),
+		)
+		return fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", models.ErrIdempotencyKeyInUse, record.Key)
+	}
+
+	return nil
+}
+
+// completeIdempotencyQuery stores the response of the request holding a
+// reservation, as long as the reservation hasn't been replaced
+const completeIdempotencyQuery = `
+	UPDATE idempotency_keys
+	SET status_code = $1, response_body = $2, expires_at = $3
+	WHERE key = $4 AND reservation_id = $5 AND status_code = 0
+`
+
+// CompleteIdempotencyKey stores the response of a request that made no write
+// under the reservation it holds, so later retries can replay it until
+// expiresAt. A reservation that was replaced in the meantime is left alone.
+func (db *DB) CompleteIdempotencyKey(ctx context.Context, key string, reservationID uuid.UUID, statusCode int, responseBody []byte, expiresAt time.Time) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := db.conn.ExecContext(ctx, completeIdempotencyQuery, statusCode, responseBody, expiresAt, key, reservationID); err != nil {
+		db.logger.Error("Failed to complete idempotency key",
+			zap.Error(err),
+			zap.String("idempotency_key", key),
+		)
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey drops a reservation so the request can be retried,
+// used when the original request failed without a replayable outcome
+func (db *DB) ReleaseIdempotencyKey(ctx context.Context, key string, reservationID uuid.UUID) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE key = $1 AND reservation_id = $2 AND status_code = 0
+	`
+
+	if _, err := db.conn.ExecContext(ctx, query, key, reservationID); err != nil {
+		db.logger.Error("Failed to release idempotency key",
+			zap.Error(err),
+			zap.String("idempotency_key", key),
+		)
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// completeIdempotency stores the response to a write as the outcome of its
+// request's reservation, within the write's open database transaction. It
+// fails with models.ErrIdempotencyKeyInUse, rolling the write back, if the
+// reservation was replaced after its lease ran out.
+func completeIdempotency(ctx context.Context, sqlTx *sql.Tx, completion *models.IdempotencyCompletion) error {
+	if completion == nil {
+		return nil
+	}
+
+	statusCode, body, err := completion.Response()
+	if err != nil {
+		return fmt.Errorf("failed to build idempotent response: %w", err)
+	}
+
+	result, err := sqlTx.ExecContext(ctx, completeIdempotencyQuery, statusCode, body, completion.ExpiresAt,
+		completion.Key, completion.ReservationID)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s was reserved again", models.ErrIdempotencyKeyInUse, completion.Key)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+)
+
+func TestGetIdempotencyRecord_Success(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"key", "fingerprint", "status_code", "response_body", "created_at", "expires_at"}).
+		AddRow("key-1", "abc", 201, []byte(`{"message":"ok"}`), now, now.Add(time.Hour))
+
+	mock.ExpectQuery(`SELECT key, fingerprint, status_code, response_body, created_at, expires_at`).
+		WithArgs("key-1", sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	record, err := db.GetIdempotencyRecord(context.Background(), "key-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if record.StatusCode != 201 || !record.IsComplete() {
+		t.Errorf("Expected completed record with status 201, got %+v", record)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetIdempotencyRecord_NotFound(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT key, fingerprint`).
+		WithArgs("key-1", sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := db.GetIdempotencyRecord(context.Background(), "key-1")
+	if !errors.Is(err, models.ErrIdempotencyRecordNotFound) {
+		t.Errorf("Expected ErrIdempotencyRecordNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	now := time.Now()
+	record := &models.IdempotencyRecord{Key: "key-1", Fingerprint: "abc", ReservationID: uuid.New(), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		wantErr      error
+	}{
+		{name: "new key", rowsAffected: 1},
+		{name: "live key held elsewhere", rowsAffected: 0, wantErr: models.ErrIdempotencyKeyInUse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			mock.ExpectExec(`INSERT INTO idempotency_keys .* ON CONFLICT \(key\) DO UPDATE`).
+				WithArgs("key-1", "abc", record.ReservationID, now, now.Add(time.Hour)).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+
+			err := db.ReserveIdempotencyKey(context.Background(), record)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompleteIdempotencyKey(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	reservationID := uuid.New()
+	body := []byte(`{"message":"ok"}`)
+	expiresAt := time.Now().UTC().Add(24 * time.Hour)
+	mock.ExpectExec(`UPDATE idempotency_keys .* WHERE key = \$4 AND reservation_id = \$5 AND status_code = 0`).
+		WithArgs(201, body, expiresAt, "key-1", reservationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := db.CompleteIdempotencyKey(context.Background(), "key-1", reservationID, 201, body, expiresAt); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReleaseIdempotencyKey(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	reservationID := uuid.New()
+	mock.ExpectExec(`DELETE FROM idempotency_keys`).
+		WithArgs("key-1", reservationID).
+		WillReturnError(errors.New("database error"))
+
+	if err := db.ReleaseIdempotencyKey(context.Background(), "key-1", reservationID); err == nil {
+		t.Error("Expected error, got nil")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCompleteIdempotency(t *testing.T) {
+	reservationID := uuid.New()
+	body := []byte(`{"message":"ok"}`)
+	expiresAt := time.Now().UTC().Add(24 * time.Hour)
+	completion := &models.IdempotencyCompletion{
+		Key:           "key-1",
+		ReservationID: reservationID,
+		ExpiresAt:     expiresAt,
+		Response: func() (int, []byte, error) {
+			return 201, body, nil
+		},
+	}
+
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		wantErr      error
+	}{
+		{name: "reservation held", rowsAffected: 1},
+		{name: "reservation replaced", rowsAffected: 0, wantErr: models.ErrIdempotencyKeyInUse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`UPDATE idempotency_keys`).
+				WithArgs(201, body, expiresAt, "key-1", reservationID).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+			mock.ExpectRollback()
+
+			sqlTx, err := db.conn.Begin()
+			if err != nil {
+				t.Fatalf("Failed to begin: %v", err)
+			}
+			err = completeIdempotency(context.Background(), sqlTx, completion)
+			sqlTx.Rollback()
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
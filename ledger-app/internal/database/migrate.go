@@ -0,0 +1,489 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFileName matches e.g. 0002_add_outbox.up.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migrationLockLease is how long a migration lock is held before another
+// process may take it over from a holder that died mid-migration
+const migrationLockLease = 10 * time.Minute
+
+// baselineVersion is the migration that adopts tables created by the Helm
+// init job
+const baselineVersion = 1
+
+var (
+	// migrationLockWait is how long to wait for another process's migrations
+	migrationLockWait = 5 * time.Minute
+	// migrationLockRetry is how often a waiting process retries the lock
+	migrationLockRetry = 2 * time.Second
+	// migrationLockRenewal is how often the holder extends its lease while
+	// migrations run
+	migrationLockRenewal = migrationLockLease / 3
+)
+
+var (
+	// ErrSchemaVersion is returned when the database schema doesn't match the
+	// migrations this build was compiled with
+	ErrSchemaVersion = errors.New("incompatible database schema")
+	// ErrMigrationLocked is returned when another process keeps the migration
+	// lock for longer than migrationLockWait
+	ErrMigrationLocked = errors.New("migration lock is held by another process")
+	// ErrLegacySchema is returned when existing tables can't be adopted by the
+	// baseline migration
+	ErrLegacySchema = errors.New("existing tables don't match the baseline schema")
+	// ErrMigrationLockLost is returned when another process takes the
+	// migration lock over while migrations run
+	ErrMigrationLockLost = errors.New("migration lock was taken over by another process")
+)
+
+// Migration is a versioned schema change with the SQL to apply and revert it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// migrations are the embedded migrations in version order
+var migrations = mustLoadMigrations(migrationFiles)
+
+// Migrations returns the migrations compiled into this build, in version order
+func Migrations() []Migration {
+	return migrations
+}
+
+// LatestSchemaVersion returns the schema version this build expects
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func mustLoadMigrations(fsys fs.FS) []Migration {
+	loaded, err := loadMigrations(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return loaded
+}
+
+// loadMigrations reads the up and down SQL of every migration in fsys
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		base := file[len("migrations/"):]
+		match := migrationFileName.FindStringSubmatch(base)
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", base)
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	loaded := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		loaded = append(loaded, *m)
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Version < loaded[j].Version })
+	return loaded, nil
+}
+
+// MigrateUp applies every pending migration and returns how many were applied.
+// Only one process migrates at a time; others wait for the lock and then
+// find nothing left to do.
+func (db *DB) MigrateUp() (int, error) {
+	ctx, release, err := db.lockMigrations()
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if m.Version == baselineVersion {
+			if err := db.checkBaselineTables(); err != nil {
+				return count, err
+			}
+		}
+		if err := db.runMigration(ctx, m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			return count, fmt.Errorf("failed to apply migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		db.logger.Info("Applied migration", zap.Int("version", m.Version), zap.String("name", m.Name))
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown reverts the latest steps applied migrations and returns how
+// many were reverted
+func (db *DB) MigrateDown(steps int) (int, error) {
+	ctx, release, err := db.lockMigrations()
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	count := 0
+	for _, version := range versions {
+		if count == steps {
+			break
+		}
+		m, ok := known[version]
+		if !ok {
+			return count, fmt.Errorf("%w: migration %d is not part of this build", ErrSchemaVersion, version)
+		}
+		if err := db.runMigration(ctx, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+			return count, fmt.Errorf("failed to revert migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		db.logger.Info("Reverted migration", zap.Int("version", m.Version), zap.String("name", m.Name))
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrationStatus lists the migrations of this build and any applied
+// migrations it doesn't know about, in version order
+func (db *DB) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.AppliedAt = &appliedAt
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for version, appliedAt := range applied {
+		appliedAt := appliedAt
+		statuses = append(statuses, MigrationStatus{Version: version, Name: "(unknown)", AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// checkSchemaVersion refuses a schema that is missing any of this build's
+// migrations. A schema with newer migrations is accepted, as migrations must
+// stay compatible with the previous release during a rolling deploy.
+func (db *DB) checkSchemaVersion() error {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			return fmt.Errorf("%w: migration %d_%s has not been applied; run `ledger-app migrate up`",
+				ErrSchemaVersion, m.Version, m.Name)
+		}
+	}
+
+	latest := LatestSchemaVersion()
+	for version := range applied {
+		if version > latest {
+			db.logger.Warn("Database schema is newer than this build",
+				zap.Int("schema_version", version),
+				zap.Int("build_version", latest),
+			)
+			break
+		}
+	}
+	return nil
+}
+
+// appliedMigrations returns when each applied migration was applied. A
+// database that has never been migrated has none.
+func (db *DB) appliedMigrations() (map[int]time.Time, error) {
+	rows, err := db.conn.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "42P01" { // undefined_table
+			return map[int]time.Time{}, nil
+		}
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema version: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schema versions: %w", err)
+	}
+	return applied, nil
+}
+
+// baselineColumns are the columns of tables created by older versions of the
+// Helm init job that the baseline migration can't bring up to date: money
+// columns that were DECIMAL(19,2), which CockroachDB can't rewrite within the
+// migration's transaction, and currency columns, whose value for existing rows
+// only the operator knows
+var baselineColumns = []struct {
+	table, column string
+	money         bool
+}{
+	{"accounts", "balance", true},
+	{"accounts", "overdraft_limit", true},
+	{"transactions", "amount", true},
+	{"transactions", "currency", false},
+	{"entries", "amount", true},
+	{"entries", "currency", false},
+}
+
+// checkBaselineTables refuses to adopt existing tables whose columns the
+// baseline migration can't fix, naming the statements that would fix them
+func (db *DB) checkBaselineTables() error {
+	rows, err := db.conn.Query(`
+		SELECT table_name, column_name, COALESCE(numeric_precision, 0), COALESCE(numeric_scale, 0)
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name IN ('accounts', 'transactions', 'entries')
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to read existing tables: %w", err)
+	}
+	defer rows.Close()
+
+	type numeric struct{ precision, scale int }
+	tables := make(map[string]bool)
+	columns := make(map[string]numeric)
+	for rows.Next() {
+		var table, column string
+		var n numeric
+		if err := rows.Scan(&table, &column, &n.precision, &n.scale); err != nil {
+			return fmt.Errorf("failed to scan existing columns: %w", err)
+		}
+		tables[table] = true
+		columns[table+"."+column] = n
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating existing columns: %w", err)
+	}
+
+	var fixes []string
+	for _, c := range baselineColumns {
+		if !tables[c.table] {
+			continue
+		}
+		n, ok := columns[c.table+"."+c.column]
+		switch {
+		case !ok && !c.money:
+			fixes = append(fixes, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s STRING(3) NOT NULL DEFAULT '<currency of existing rows>'", c.table, c.column))
+		case ok && c.money && (n.precision != 28 || n.scale != 8):
+			fixes = append(fixes, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE DECIMAL(28,8)", c.table, c.column))
+		}
+	}
+	if len(fixes) > 0 {
+		return fmt.Errorf("%w; bring them up to date, outside a transaction, with: %s",
+			ErrLegacySchema, strings.Join(fixes, "; "))
+	}
+	return nil
+}
+
+// runMigration runs the SQL of a migration and records it in schema_migrations
+// in a single transaction. The transaction is rolled back if ctx is cancelled
+// because the migration lock was lost.
+func (db *DB) runMigration(ctx context.Context, statements, record string, args ...interface{}) (err error) {
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+	}()
+
+	sqlTx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer sqlTx.Rollback()
+
+	if _, err := sqlTx.ExecContext(ctx, statements); err != nil {
+		return err
+	}
+	if _, err := sqlTx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return sqlTx.Commit()
+}
+
+// lockMigrations takes the migration lock, creating the bookkeeping tables on
+// first use, and returns the function that releases it. CockroachDB has no
+// advisory locks, so the lock is a leased row: a holder that dies keeps it
+// only until its lease runs out. The lease is renewed until the lock is
+// released, so migrations may take longer than one lease. The returned
+// context is cancelled with ErrMigrationLockLost if the lease is taken over
+// anyway, so that migrations stop rather than run alongside another process.
+func (db *DB) lockMigrations() (context.Context, func(), error) {
+	if _, err := db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name STRING NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT now()
+		);
+		CREATE TABLE IF NOT EXISTS schema_migration_lock (
+			id INT PRIMARY KEY,
+			holder STRING NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		);
+	`); err != nil {
+		return nil, nil, fmt.Errorf("failed to create migration tables: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	holder := hostname + "/" + uuid.New().String()
+	deadline := time.Now().Add(migrationLockWait)
+
+	for {
+		result, err := db.conn.Exec(`
+			INSERT INTO schema_migration_lock (id, holder, expires_at)
+			VALUES (1, $1, now() + $2 * INTERVAL '1 second')
+			ON CONFLICT (id) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+			WHERE schema_migration_lock.expires_at < now()
+		`, holder, int(migrationLockLease.Seconds()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows == 1 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, nil, ErrMigrationLocked
+		}
+		db.logger.Info("Waiting for migration lock")
+		time.Sleep(migrationLockRetry)
+	}
+
+	ctx, lost := context.WithCancelCause(context.Background())
+	stop := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		db.renewMigrationLock(holder, stop, lost)
+	}()
+
+	return ctx, func() {
+		close(stop)
+		<-renewed
+		lost(nil)
+		if _, err := db.conn.Exec(`DELETE FROM schema_migration_lock WHERE id = 1 AND holder = $1`, holder); err != nil {
+			db.logger.Warn("Failed to release migration lock", zap.Error(err))
+		}
+	}, nil
+}
+
+// renewMigrationLock extends holder's lease on the migration lock every
+// migrationLockRenewal until stop is closed. If the lease was taken over, it
+// calls lost and stops.
+func (db *DB) renewMigrationLock(holder string, stop <-chan struct{}, lost context.CancelCauseFunc) {
+	ticker := time.NewTicker(migrationLockRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		result, err := db.conn.Exec(`
+			UPDATE schema_migration_lock SET expires_at = now() + $2 * INTERVAL '1 second'
+			WHERE id = 1 AND holder = $1
+		`, holder, int(migrationLockLease.Seconds()))
+		if err != nil {
+			db.logger.Warn("Failed to renew migration lock", zap.Error(err))
+			continue
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+			db.logger.Error("Lost migration lock to another process; aborting migrations")
+			lost(ErrMigrationLockLost)
+			return
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// expectMigrationLock expects the bookkeeping tables and a lock that is free
+func expectMigrationLock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// baselineColumnRows returns existing columns as read by checkBaselineTables
+func baselineColumnRows(columns ...[]interface{}) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"table_name", "column_name", "numeric_precision", "numeric_scale"})
+	for _, column := range columns {
+		rows.AddRow(column[0], column[1], column[2], column[3])
+	}
+	return rows
+}
+
+func appliedRows(versions ...int) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range versions {
+		rows.AddRow(version, time.Now())
+	}
+	return rows
+}
+
+func TestMigrations_Embedded(t *testing.T) {
+	all := Migrations()
+	if len(all) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %d", i, i+1, m.Version)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("Migration %d_%s has an empty up or down script", m.Version, m.Name)
+		}
+	}
+	if !strings.Contains(all[0].Up, "CREATE TABLE IF NOT EXISTS transactions") {
+		t.Error("Expected the baseline migration to create the transactions table")
+	}
+	if LatestSchemaVersion() != all[len(all)-1].Version {
+		t.Errorf("Expected latest version %d, got %d", all[len(all)-1].Version, LatestSchemaVersion())
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"migrations/0001_init.up.sql": {Data: []byte("CREATE TABLE a (id INT)")},
+		},
+		"bad name": {
+			"migrations/init.sql": {Data: []byte("CREATE TABLE a (id INT)")},
+		},
+		"conflicting names": {
+			"migrations/0001_init.up.sql":    {Data: []byte("CREATE TABLE a (id INT)")},
+			"migrations/0001_other.down.sql": {Data: []byte("DROP TABLE a")},
+		},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := loadMigrations(fsys); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestMigrateUp_AppliesPending(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	expectMigrationLock(mock)
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).WillReturnRows(appliedRows())
+	mock.ExpectQuery(`FROM information_schema.columns`).WillReturnRows(baselineColumnRows())
+	for _, m := range Migrations() {
+		mock.ExpectBegin()
+		mock.ExpectExec(`.+`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO schema_migrations`).
+			WithArgs(m.Version, m.Name).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec(`DELETE FROM schema_migration_lock WHERE id = 1 AND holder = \$1`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	applied, err := db.MigrateUp()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if applied != len(Migrations()) {
+		t.Errorf("Expected %d migrations applied, got %d", len(Migrations()), applied)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrateUp_AdoptsBaselineTables(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Tables from an init job that predates overdrafts and FX; the baseline
+	// migration adds the missing columns itself
+	expectMigrationLock(mock)
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).WillReturnRows(appliedRows())
+	mock.ExpectQuery(`FROM information_schema.columns`).WillReturnRows(baselineColumnRows(
+		[]interface{}{"accounts", "balance", 28, 8},
+		[]interface{}{"transactions", "amount", 28, 8},
+		[]interface{}{"transactions", "currency", 0, 0},
+		[]interface{}{"entries", "amount", 28, 8},
+		[]interface{}{"entries", "currency", 0, 0},
+	))
+	for _, m := range Migrations() {
+		statements := `.+`
+		if m.Version == baselineVersion {
+			statements = `ALTER TABLE transactions ADD COLUMN IF NOT EXISTS converted_amount`
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec(statements).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(m.Version, m.Name).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec(`DELETE FROM schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := db.MigrateUp(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrateUp_RefusesLegacyTables(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// The transactions table of the original init job
+	expectMigrationLock(mock)
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).WillReturnRows(appliedRows())
+	mock.ExpectQuery(`FROM information_schema.columns`).WillReturnRows(baselineColumnRows(
+		[]interface{}{"transactions", "id", 0, 0},
+		[]interface{}{"transactions", "amount", 19, 2},
+	))
+	mock.ExpectExec(`DELETE FROM schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	applied, err := db.MigrateUp()
+	if !errors.Is(err, ErrLegacySchema) {
+		t.Fatalf("Expected ErrLegacySchema, got: %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("Expected no migration to be applied, got %d", applied)
+	}
+	for _, fix := range []string{"ALTER TABLE transactions ALTER COLUMN amount TYPE DECIMAL(28,8)", "ALTER TABLE transactions ADD COLUMN currency"} {
+		if !strings.Contains(err.Error(), fix) {
+			t.Errorf("Expected the error to name %q, got: %v", fix, err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestLockMigrations_RenewsLease(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	defer func(renewal time.Duration) { migrationLockRenewal = renewal }(migrationLockRenewal)
+	migrationLockRenewal = time.Millisecond
+
+	expectMigrationLock(mock)
+	mock.ExpectExec(`UPDATE schema_migration_lock SET expires_at`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, release, err := db.lockMigrations()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Renewals after the expected one fail against the mock and are retried
+	time.Sleep(50 * time.Millisecond)
+	release()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrateUp_AbortsWhenLockLost(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	defer func(renewal time.Duration) { migrationLockRenewal = renewal }(migrationLockRenewal)
+	migrationLockRenewal = 10 * time.Millisecond
+
+	latest := LatestSchemaVersion()
+	expectMigrationLock(mock)
+	// Another process takes the lock over before the pending migration starts
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(appliedRows(versionsUpTo(latest - 1)...))
+	mock.ExpectExec(`UPDATE schema_migration_lock SET expires_at`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := db.MigrateUp()
+	if !errors.Is(err, ErrMigrationLockLost) {
+		t.Fatalf("Expected ErrMigrationLockLost, got: %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("Expected nothing applied, got %d", applied)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrateUp_WaitsForLock(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	defer func(retry time.Duration) { migrationLockRetry = retry }(migrationLockRetry)
+	migrationLockRetry = time.Millisecond
+
+	// The other region holds the lock at first and has applied everything by
+	// the time it lets go
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).WillReturnRows(appliedRows(versionsUpTo(LatestSchemaVersion())...))
+	mock.ExpectExec(`DELETE FROM schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	applied, err := db.MigrateUp()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("Expected nothing left to apply, got %d", applied)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigrateUp_LockTimeout(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	defer func(wait, retry time.Duration) { migrationLockWait, migrationLockRetry = wait, retry }(migrationLockWait, migrationLockRetry)
+	migrationLockWait, migrationLockRetry = 0, time.Millisecond
+
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := db.MigrateUp(); !errors.Is(err, ErrMigrationLocked) {
+		t.Errorf("Expected ErrMigrationLocked, got: %v", err)
+	}
+}
+
+func TestMigrateDown_RevertsLatest(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	latest := Migrations()[len(Migrations())-1]
+	expectMigrationLock(mock)
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).WillReturnRows(appliedRows(latest.Version))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(latest.Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).
+		WithArgs(latest.Version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`DELETE FROM schema_migration_lock`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	reverted, err := db.MigrateDown(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if reverted != 1 {
+		t.Errorf("Expected 1 migration reverted, got %d", reverted)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	latest := LatestSchemaVersion()
+	tests := []struct {
+		name    string
+		applied []int
+		wantErr bool
+	}{
+		{"up to date", versionsUpTo(latest), false},
+		{"newer schema", versionsUpTo(latest + 1), false},
+		{"behind", versionsUpTo(latest - 1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).WillReturnRows(appliedRows(tt.applied...))
+
+			err := db.checkSchemaVersion()
+			if tt.wantErr && !errors.Is(err, ErrSchemaVersion) {
+				t.Errorf("Expected ErrSchemaVersion, got: %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckSchemaVersion_NeverMigrated(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT version, applied_at FROM schema_migrations`).
+		WillReturnError(&pq.Error{Code: "42P01", Message: `relation "schema_migrations" does not exist`})
+
+	if err := db.checkSchemaVersion(); !errors.Is(err, ErrSchemaVersion) {
+		t.Errorf("Expected ErrSchemaVersion, got: %v", err)
+	}
+}
+
+// versionsUpTo returns the versions 1 to latest
+func versionsUpTo(latest int) []int {
+	versions := make([]int, 0, latest)
+	for v := 1; v <= latest; v++ {
+		versions = append(versions, v)
+	}
+	return versions
+}
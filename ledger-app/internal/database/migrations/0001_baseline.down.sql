@@ -0,0 +1,9 @@
+-- Rolling back the baseline drops every ledger table and all of its data.
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS schedule_runs;
+DROP TABLE IF EXISTS schedules;
+DROP TABLE IF EXISTS fx_quotes;
+DROP TABLE IF EXISTS holds;
+DROP TABLE IF EXISTS entries;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,145 @@
+-- Baseline schema: the tables and indexes previously created by the Helm
+-- init job. IF NOT EXISTS lets clusters initialised that way adopt it, and
+-- the ALTER TABLE steps below add the columns older versions of the init job
+-- didn't create. Tables this script can't bring up to date are refused before
+-- it runs (see checkBaselineTables).
+
+-- Create accounts table (running balance maintained by the ledger)
+CREATE TABLE IF NOT EXISTS accounts (
+    id STRING PRIMARY KEY,
+    owner STRING NOT NULL,
+    currency STRING(3) NOT NULL,
+    status STRING NOT NULL DEFAULT 'active',
+    balance DECIMAL(28,8) NOT NULL DEFAULT 0,
+    held DECIMAL(28,8) NOT NULL DEFAULT 0,
+    overdraft_limit DECIMAL(28,8) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP DEFAULT now()
+);
+
+-- Create transactions table with regional locality
+CREATE TABLE IF NOT EXISTS transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    region STRING NOT NULL,
+    amount DECIMAL(28,8) NOT NULL,
+    currency STRING(3) NOT NULL,
+    from_account STRING NOT NULL,
+    to_account STRING NOT NULL,
+    timestamp TIMESTAMP DEFAULT now(),
+    status STRING DEFAULT 'pending',
+    reversal_of UUID REFERENCES transactions(id),
+    converted_amount DECIMAL(28,8),
+    converted_currency STRING(3),
+    fx_rate DECIMAL(28,12),
+    quote_id UUID
+) LOCALITY REGIONAL BY ROW AS region;
+
+-- Create double-entry postings table (debits negative, credits positive)
+CREATE TABLE IF NOT EXISTS entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id),
+    account STRING NOT NULL REFERENCES accounts(id),
+    amount DECIMAL(28,8) NOT NULL,
+    currency STRING(3) NOT NULL,
+    region STRING NOT NULL,
+    timestamp TIMESTAMP DEFAULT now()
+) LOCALITY REGIONAL BY ROW AS region;
+
+-- Create authorization holds table (amount reserved on from_account until
+-- captured, voided or expired)
+CREATE TABLE IF NOT EXISTS holds (
+    id UUID PRIMARY KEY,
+    region STRING NOT NULL,
+    amount DECIMAL(28,8) NOT NULL,
+    currency STRING(3) NOT NULL,
+    from_account STRING NOT NULL REFERENCES accounts(id),
+    to_account STRING NOT NULL REFERENCES accounts(id),
+    status STRING NOT NULL DEFAULT 'active',
+    captured_amount DECIMAL(28,8),
+    transaction_id UUID REFERENCES transactions(id),
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+) LOCALITY REGIONAL BY ROW AS region;
+
+-- Create FX quotes table (shared by both regions; a quote is claimed by
+-- setting transaction_id, so it can fund at most one transfer)
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id UUID PRIMARY KEY,
+    from_currency STRING(3) NOT NULL,
+    to_currency STRING(3) NOT NULL,
+    rate DECIMAL(28,12) NOT NULL,
+    source_amount DECIMAL(28,8) NOT NULL,
+    target_amount DECIMAL(28,8) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    transaction_id UUID
+);
+
+-- Create scheduled transfers tables (shared by both regions; the unique run
+-- per schedule and time makes each execution happen exactly once)
+CREATE TABLE IF NOT EXISTS schedules (
+    id UUID PRIMARY KEY,
+    region STRING NOT NULL,
+    from_account STRING NOT NULL REFERENCES accounts(id),
+    to_account STRING NOT NULL REFERENCES accounts(id),
+    amount DECIMAL(28,8) NOT NULL,
+    currency STRING(3) NOT NULL,
+    spec STRING NOT NULL DEFAULT '',
+    status STRING NOT NULL DEFAULT 'active',
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP,
+    max_runs INT,
+    run_count INT NOT NULL DEFAULT 0,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS schedule_runs (
+    id UUID PRIMARY KEY,
+    schedule_id UUID NOT NULL REFERENCES schedules(id),
+    scheduled_for TIMESTAMP NOT NULL,
+    region STRING NOT NULL,
+    status STRING NOT NULL,
+    transaction_id UUID REFERENCES transactions(id),
+    error STRING,
+    executed_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (schedule_id, scheduled_for)
+);
+
+-- Create idempotency keys table (shared by both regions; expired rows are
+-- ignored by the application and purged by row-level TTL)
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key STRING PRIMARY KEY,
+    fingerprint STRING NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    response_body BYTES,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL
+) WITH (ttl_expiration_expression = 'expires_at');
+
+-- Add the columns of tables created by older versions of the init job
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS overdraft_limit DECIMAL(28,8) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS held DECIMAL(28,8) NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversal_of UUID REFERENCES transactions(id);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS converted_amount DECIMAL(28,8);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS converted_currency STRING(3);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_rate DECIMAL(28,12);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS quote_id UUID;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_timestamp ON transactions(timestamp);
+CREATE INDEX IF NOT EXISTS idx_transactions_page ON transactions(timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_from_account ON transactions(from_account, timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_to_account ON transactions(to_account, timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_status_page ON transactions(status, timestamp DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_amount ON transactions(amount DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_status ON transactions(status);
+CREATE INDEX IF NOT EXISTS idx_region ON transactions(region);
+CREATE INDEX IF NOT EXISTS idx_reversal_of ON transactions(reversal_of);
+CREATE INDEX IF NOT EXISTS idx_entries_transaction ON entries(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_entries_account ON entries(account, timestamp);
+CREATE INDEX IF NOT EXISTS idx_holds_expiry ON holds(status, expires_at);
+CREATE INDEX IF NOT EXISTS idx_schedules_due ON schedules(status, next_run_at);
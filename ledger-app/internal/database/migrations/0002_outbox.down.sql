@@ -0,0 +1,2 @@
+-- Undelivered side effects are lost with the table.
+DROP TABLE IF EXISTS outbox;
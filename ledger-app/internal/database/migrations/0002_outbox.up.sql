@@ -0,0 +1,19 @@
+-- Create outbox table: side effects of ledger writes, stored in the same
+-- transaction as the write and delivered to S3 and SQS by the relay of their
+-- region. available_at is when a message may next be claimed; a relay that
+-- claims it pushes it forward by the claim lease. Delivered rows are purged
+-- by row-level TTL a week after delivery.
+CREATE TABLE IF NOT EXISTS outbox (
+    id UUID PRIMARY KEY,
+    region STRING NOT NULL,
+    kind STRING NOT NULL,
+    key STRING,
+    payload BYTES NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error STRING,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    available_at TIMESTAMP NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMP
+) WITH (ttl_expiration_expression = 'delivered_at + INTERVAL ''7 days''');
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(region, available_at) WHERE delivered_at IS NULL;
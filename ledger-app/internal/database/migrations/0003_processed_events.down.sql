@@ -0,0 +1,2 @@
+-- Events delivered again after this are handled again.
+DROP TABLE IF EXISTS processed_events;
@@ -0,0 +1,10 @@
+-- Create processed_events table: the events the consumer has handled, keyed
+-- by event ID. A handler records its event in the same transaction as its
+-- writes, so an event delivered again is skipped instead of handled twice.
+-- Rows are purged by row-level TTL after 30 days, longer than a message can
+-- spend in the queue and its dead-letter queue before it is redriven.
+CREATE TABLE IF NOT EXISTS processed_events (
+    event_id UUID PRIMARY KEY,
+    event_type STRING NOT NULL,
+    processed_at TIMESTAMP NOT NULL DEFAULT now()
+) WITH (ttl_expiration_expression = 'processed_at + INTERVAL ''30 days''');
@@ -0,0 +1,2 @@
+-- Requests then complete whichever reservation holds their key.
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS reservation_id;
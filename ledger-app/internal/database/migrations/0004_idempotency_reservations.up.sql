@@ -0,0 +1,4 @@
+-- Identify each reservation of an idempotency key, so a request can only
+-- complete or release its own reservation and never one that replaced it
+-- after its lease ran out. Keys reserved before this have none.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS reservation_id UUID;
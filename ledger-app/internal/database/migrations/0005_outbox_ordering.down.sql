@@ -0,0 +1,3 @@
+-- Messages are then claimed by availability again, regardless of order.
+DROP INDEX IF EXISTS outbox@idx_outbox_ordering;
+ALTER TABLE outbox DROP COLUMN IF EXISTS ordering_key;
@@ -0,0 +1,7 @@
+-- Record the ordering key of each outbox message, so that a relay never
+-- claims a message while an earlier one with the same key is still waiting
+-- to be delivered. Audit logs and messages saved before this have none and
+-- are claimed in creation order without waiting.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS ordering_key STRING;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_ordering ON outbox(region, ordering_key, created_at) WHERE delivered_at IS NULL;
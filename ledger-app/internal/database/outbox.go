@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// outboxColumns lists the outbox columns in the order scanOutboxMessage reads them
+const outboxColumns = "id, region, kind, key, ordering_key, payload, attempts, last_error, created_at, delivered_at"
+
+// scanOutboxMessage reads a row selected with outboxColumns into msg
+func scanOutboxMessage(row rowScanner, msg *models.OutboxMessage) error {
+	var key, orderingKey, lastError sql.NullString
+	var deliveredAt sql.NullTime
+	if err := row.Scan(
+		&msg.ID,
+		&msg.Region,
+		&msg.Kind,
+		&key,
+		&orderingKey,
+		&msg.Payload,
+		&msg.Attempts,
+		&lastError,
+		&msg.CreatedAt,
+		&deliveredAt,
+	); err != nil {
+		return err
+	}
+
+	msg.Key, msg.OrderingKey, msg.LastError, msg.DeliveredAt = key.String, orderingKey.String, lastError.String, nil
+	if deliveredAt.Valid {
+		msg.DeliveredAt = &deliveredAt.Time
+	}
+	return nil
+}
+
+// insertOutbox stores the side effects of a write within its open database
+// transaction, so they are committed or rolled back together with it
+func insertOutbox(ctx context.Context, sqlTx *sql.Tx, messages []*models.OutboxMessage) error {
+	query := `
+		INSERT INTO outbox (id, region, kind, key, ordering_key, payload, created_at, available_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+	`
+
+	for _, msg := range messages {
+		var key, orderingKey interface{}
+		if msg.Key != "" {
+			key = msg.Key
+		}
+		if msg.OrderingKey != "" {
+			orderingKey = msg.OrderingKey
+		}
+		if _, err := sqlTx.ExecContext(ctx, query, msg.ID, msg.Region, msg.Kind, key, orderingKey, msg.Payload, msg.CreatedAt); err != nil {
+			return fmt.Errorf("failed to add %s %s to outbox: %w", msg.Kind, msg.ID.String(), err)
+		}
+	}
+	return nil
+}
+
+// buildOutbox builds the messages of outbox, if there is one, and stores them
+// like insertOutbox
+func buildOutbox(ctx context.Context, sqlTx *sql.Tx, outbox models.OutboxBuilder) error {
+	if outbox == nil {
+		return nil
+	}
+	messages, err := outbox()
+	if err != nil {
+		return fmt.Errorf("failed to build outbox messages: %w", err)
+	}
+	return insertOutbox(ctx, sqlTx, messages)
+}
+
+// ClaimOutbox claims up to limit undelivered messages of region that are
+// available at now, in the order they were created, and counts the attempt. A
+// message isn't claimed while an earlier one with the same ordering key is
+// held back, because it is waiting for a retry or claimed by another relay;
+// earlier ones that are available are claimed with it, ahead of it. A claimed
+// message isn't handed to another relay until lease has passed, so a relay
+// that dies while delivering it only delays it.
+func (db *DB) ClaimOutbox(ctx context.Context, region string, now time.Time, lease time.Duration, limit int) ([]*models.OutboxMessage, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		WITH claimed AS (
+			UPDATE outbox
+			SET attempts = attempts + 1, available_at = $3
+			WHERE id IN (
+				SELECT o.id FROM outbox o
+				WHERE o.region = $1 AND o.delivered_at IS NULL AND o.available_at <= $2
+					AND NOT EXISTS (
+						SELECT 1 FROM outbox earlier
+						WHERE earlier.region = o.region
+							AND earlier.ordering_key = o.ordering_key
+							AND earlier.delivered_at IS NULL
+							AND earlier.available_at > $2
+							AND (earlier.created_at, earlier.id) < (o.created_at, o.id)
+					)
+				ORDER BY o.created_at, o.id
+				LIMIT $4
+			)
+			RETURNING ` + outboxColumns + `
+		)
+		SELECT ` + outboxColumns + ` FROM claimed
+		ORDER BY created_at, id`
+
+	rows, err := db.conn.QueryContext(ctx, query, region, now, now.Add(lease), limit)
+	if err != nil {
+		db.logger.Error("Failed to claim outbox messages", zap.Error(err))
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*models.OutboxMessage
+	for rows.Next() {
+		var msg models.OutboxMessage
+		if err := scanOutboxMessage(rows, &msg); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		messages = append(messages, &msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// MarkOutboxDelivered records that a claimed message was delivered at deliveredAt
+func (db *DB) MarkOutboxDelivered(ctx context.Context, id uuid.UUID, deliveredAt time.Time) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE outbox SET delivered_at = $2, last_error = NULL WHERE id = $1`
+	if _, err := db.conn.ExecContext(ctx, query, id, deliveredAt); err != nil {
+		return fmt.Errorf("failed to mark outbox message delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkOutboxFailed records why delivering a claimed message failed and makes
+// it available again at retryAt
+func (db *DB) MarkOutboxFailed(ctx context.Context, id uuid.UUID, retryAt time.Time, cause string) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE outbox SET available_at = $2, last_error = $3 WHERE id = $1 AND delivered_at IS NULL`
+	if _, err := db.conn.ExecContext(ctx, query, id, retryAt, cause); err != nil {
+		return fmt.Errorf("failed to reschedule outbox message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+var outboxColumnNames = []string{"id", "region", "kind", "key", "ordering_key", "payload", "attempts", "last_error", "created_at", "delivered_at"}
+
+// expectTransfer expects the account checks and writes of a plain transfer
+func expectTransfer(mock sqlmock.Sqlmock, tx *models.Transaction) {
+	amount := tx.Amount.Value
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs(tx.FromAccount).
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "1000.00", "0", "0"))
+	expectCreditedLock(mock, tx.ToAccount, "USD")
+	mock.ExpectQuery(`INSERT INTO transactions`).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).
+			AddRow(tx.ID, tx.Region, amount, "USD", tx.FromAccount, tx.ToAccount, tx.Status, tx.Timestamp, nil, nil, nil, nil, nil))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(amount.Neg(), tx.FromAccount).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(amount, tx.ToAccount).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func newOutboxTestTransaction() *models.Transaction {
+	return &models.Transaction{
+		ID:          uuid.New(),
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: decimal.NewFromInt(10), Currency: "USD"},
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Status:      models.StatusPending,
+		Timestamp:   time.Now(),
+	}
+}
+
+func TestCreateTransaction_Outbox(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tx := newOutboxTestTransaction()
+	audit := models.NewOutboxAuditLog("us-east-1", "transactions/us-east-1/tx.json", []byte(`{}`))
+	message := models.NewOutboxMessage("us-east-1", "acc1", []byte(`{"action":"transaction_created"}`))
+
+	expectTxBegin(mock)
+	expectTransfer(mock, tx)
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(audit.ID, "us-east-1", models.OutboxKindAuditLog, audit.Key, nil, audit.Payload, audit.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(message.ID, "us-east-1", models.OutboxKindMessage, nil, "acc1", message.Payload, message.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	if err := db.CreateTransaction(context.Background(), tx, nil, audit, message); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTransaction_OutboxFailureRollsBack(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tx := newOutboxTestTransaction()
+	dbErr := errors.New("outbox unavailable")
+
+	expectTxBegin(mock)
+	expectTransfer(mock, tx)
+	mock.ExpectExec(`INSERT INTO outbox`).WillReturnError(dbErr)
+	mock.ExpectRollback()
+
+	err := db.CreateTransaction(context.Background(), tx, nil, models.NewOutboxMessage("us-east-1", "acc1", []byte(`{}`)))
+	if !errors.Is(err, dbErr) {
+		t.Errorf("Expected the outbox error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestClaimOutbox(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().UTC()
+	auditID, messageID := uuid.New(), uuid.New()
+	// Claimed in creation order, skipping messages behind an earlier one with
+	// their ordering key that is held back
+	mock.ExpectQuery(`UPDATE outbox\s+SET attempts = attempts \+ 1, available_at = \$3\s+WHERE id IN \(` +
+		`.*NOT EXISTS \(.*earlier\.ordering_key = o\.ordering_key.*earlier\.available_at > \$2.*\)` +
+		`\s+ORDER BY o\.created_at, o\.id\s+LIMIT \$4\s+\)\s+RETURNING .*ORDER BY created_at, id`).
+		WithArgs("us-east-1", now, now.Add(time.Minute), 10).
+		WillReturnRows(sqlmock.NewRows(outboxColumnNames).
+			AddRow(auditID, "us-east-1", models.OutboxKindAuditLog, "transactions/us-east-1/tx.json", nil, []byte(`{}`), 1, nil, now, nil).
+			AddRow(messageID, "us-east-1", models.OutboxKindMessage, nil, "acc1", []byte(`{}`), 3, "timeout", now, nil))
+
+	messages, err := db.ClaimOutbox(context.Background(), "us-east-1", now, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].ID != auditID || messages[0].Key != "transactions/us-east-1/tx.json" || messages[0].Attempts != 1 {
+		t.Errorf("Unexpected audit log %+v", messages[0])
+	}
+	if messages[1].ID != messageID || messages[1].Key != "" || messages[1].OrderingKey != "acc1" || messages[1].LastError != "timeout" || messages[1].DeliveredAt != nil {
+		t.Errorf("Unexpected message %+v", messages[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMarkOutbox(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	id := uuid.New()
+	now := time.Now().UTC()
+	mock.ExpectExec(`UPDATE outbox SET delivered_at = \$2, last_error = NULL WHERE id = \$1`).
+		WithArgs(id, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE outbox SET available_at = \$2, last_error = \$3 WHERE id = \$1 AND delivered_at IS NULL`).
+		WithArgs(id, now.Add(time.Second), "timeout").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := db.MarkOutboxDelivered(context.Background(), id, now); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if err := db.MarkOutboxFailed(context.Background(), id, now.Add(time.Second), "timeout"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// ProcessEvent runs fn in a transaction that also records the event in
+// processed_events, so fn's writes and the record commit or roll back
+// together. An event that was already processed isn't handled again:
+// fn isn't run and models.ErrEventAlreadyProcessed is returned. Errors from
+// fn are returned unchanged.
+func (db *DB) ProcessEvent(ctx context.Context, eventID uuid.UUID, eventType string, fn func(*sql.Tx) error) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	// The insert comes first, so a concurrent delivery of the same event
+	// waits for this transaction and then finds the event processed
+	query := `
+		INSERT INTO processed_events (event_id, event_type, processed_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id) DO NOTHING
+	`
+
+	return db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		result, err := sqlTx.ExecContext(ctx, query, eventID, eventType, time.Now().UTC())
+		if err != nil {
+			db.logger.Error("Failed to record processed event",
+				zap.Error(err),
+				zap.String("event_id", eventID.String()),
+			)
+			return fmt.Errorf("failed to record processed event: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: %s", models.ErrEventAlreadyProcessed, eventID)
+		}
+
+		return fn(sqlTx)
+	})
+}
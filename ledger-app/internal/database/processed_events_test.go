@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+)
+
+func TestProcessEvent(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	eventID := uuid.New()
+	expectTxBegin(mock)
+	mock.ExpectExec(`INSERT INTO processed_events`).
+		WithArgs(eventID, "TransactionCreated", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	err := db.ProcessEvent(context.Background(), eventID, "TransactionCreated", func(sqlTx *sql.Tx) error {
+		_, err := sqlTx.Exec(`UPDATE accounts SET balance = balance + 1`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestProcessEvent_AlreadyProcessed(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	expectTxBegin(mock)
+	mock.ExpectExec(`INSERT INTO processed_events`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	ran := false
+	err := db.ProcessEvent(context.Background(), uuid.New(), "TransactionCreated", func(sqlTx *sql.Tx) error {
+		ran = true
+		return nil
+	})
+	if !errors.Is(err, models.ErrEventAlreadyProcessed) {
+		t.Errorf("Expected ErrEventAlreadyProcessed, got: %v", err)
+	}
+	if ran {
+		t.Error("Expected the handler not to run again")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestProcessEvent_HandlerFailureRollsBack(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	handlerErr := errors.New("account not found")
+	expectTxBegin(mock)
+	mock.ExpectExec(`INSERT INTO processed_events`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	// The event stays unprocessed, so its next delivery is handled
+	err := db.ProcessEvent(context.Background(), uuid.New(), "TransactionCreated", func(sqlTx *sql.Tx) error {
+		return handlerErr
+	})
+	if !errors.Is(err, handlerErr) {
+		t.Errorf("Expected the handler error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
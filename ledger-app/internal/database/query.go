@@ -0,0 +1,110 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/project-atlas/ledger-app/internal/models"
+)
+
+// transactionSortColumns whitelists the columns a transaction search may be
+// ordered by. Only these names are ever written into the query text; every
+// filter value is passed as a placeholder argument.
+var transactionSortColumns = map[string]string{
+	"":                     "timestamp",
+	models.SortByTimestamp: "timestamp",
+	models.SortByAmount:    "amount",
+}
+
+// queryBuilder accumulates WHERE conditions and their placeholder arguments
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg binds value and returns its placeholder
+func (b *queryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// where adds a condition; each %s in condition is replaced by the
+// placeholder of the matching value
+func (b *queryBuilder) where(condition string, values ...interface{}) {
+	placeholders := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = b.arg(value)
+	}
+	b.conditions = append(b.conditions, fmt.Sprintf(condition, placeholders...))
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// buildTransactionQuery validates q and returns the SELECT statement and
+// arguments that run it
+func buildTransactionQuery(q models.TransactionQuery) (string, []interface{}, error) {
+	if err := q.Validate(); err != nil {
+		return "", nil, err
+	}
+	column, ok := transactionSortColumns[q.SortBy]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: cannot sort by %q", models.ErrInvalidQuery, q.SortBy)
+	}
+
+	var b queryBuilder
+	f := q.Filter
+	if f.Account != "" {
+		account := b.arg(f.Account)
+		b.conditions = append(b.conditions, fmt.Sprintf("(from_account = %s OR to_account = %s)", account, account))
+	}
+	if f.Status != "" {
+		b.where("status = %s", f.Status)
+	}
+	if f.Region != "" {
+		b.where("region = %s", f.Region)
+	}
+	if f.Currency != "" {
+		b.where("currency = %s", f.Currency)
+	}
+	if f.MinAmount != nil {
+		b.where("amount >= %s", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		b.where("amount <= %s", *f.MaxAmount)
+	}
+	if f.From != nil {
+		b.where("timestamp >= %s", *f.From)
+	}
+	if f.To != nil {
+		b.where("timestamp < %s", *f.To)
+	}
+
+	direction, comparison := "DESC", "<"
+	if q.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if q.Cursor != nil {
+		var position interface{} = q.Cursor.Timestamp
+		if column == "amount" {
+			position = *q.Cursor.Amount
+		}
+		b.where("("+column+", id) "+comparison+" (%s, %s)", position, q.Cursor.ID)
+	}
+
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		` + b.whereClause() + `
+		ORDER BY ` + column + ` ` + direction + `, id ` + direction + `
+		LIMIT ` + b.arg(q.Limit)
+	if q.Cursor == nil {
+		query += ` OFFSET ` + b.arg(q.Offset)
+	}
+
+	return query, b.args, nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestBuildTransactionQuery_Unfiltered(t *testing.T) {
+	query, args, err := buildTransactionQuery(models.TransactionQuery{Limit: 50, Offset: 100})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if strings.Contains(query, "WHERE") {
+		t.Errorf("Expected no WHERE clause, got %s", query)
+	}
+	if !strings.Contains(query, "ORDER BY timestamp DESC, id DESC") || !strings.Contains(query, "LIMIT $1 OFFSET $2") {
+		t.Errorf("Unexpected query %s", query)
+	}
+	if !reflect.DeepEqual(args, []interface{}{50, 100}) {
+		t.Errorf("Unexpected args %v", args)
+	}
+}
+
+func TestBuildTransactionQuery_AmountCursorAscending(t *testing.T) {
+	amount := decimal.RequireFromString("12.50")
+	cursor := models.TransactionCursor{Timestamp: time.Now(), Amount: &amount, ID: uuid.New()}
+	to := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	query, args, err := buildTransactionQuery(models.TransactionQuery{
+		Filter:    models.TransactionFilter{Region: "us-east-1", To: &to},
+		SortBy:    models.SortByAmount,
+		Ascending: true,
+		Cursor:    &cursor,
+		Limit:     10,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, part := range []string{
+		"WHERE region = $1 AND timestamp < $2 AND (amount, id) > ($3, $4)",
+		"ORDER BY amount ASC, id ASC",
+		"LIMIT $5",
+	} {
+		if !strings.Contains(query, part) {
+			t.Errorf("Expected query to contain %q, got %s", part, query)
+		}
+	}
+	if strings.Contains(query, "OFFSET") {
+		t.Errorf("Expected no OFFSET with a cursor, got %s", query)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"us-east-1", to, amount, cursor.ID, 10}) {
+		t.Errorf("Unexpected args %v", args)
+	}
+}
+
+func TestBuildTransactionQuery_Invalid(t *testing.T) {
+	low, high := decimal.NewFromInt(1), decimal.NewFromInt(2)
+	tests := []struct {
+		name  string
+		query models.TransactionQuery
+		err   error
+	}{
+		{"unknown sort", models.TransactionQuery{SortBy: "to_account", Limit: 10}, models.ErrInvalidQuery},
+		{"unknown status", models.TransactionQuery{Filter: models.TransactionFilter{Status: "done"}, Limit: 10}, models.ErrInvalidStatus},
+		{"inverted amounts", models.TransactionQuery{Filter: models.TransactionFilter{MinAmount: &high, MaxAmount: &low}, Limit: 10}, models.ErrInvalidQuery},
+		{"no limit", models.TransactionQuery{}, models.ErrInvalidQuery},
+		{"cursor without amount", models.TransactionQuery{SortBy: models.SortByAmount, Cursor: &models.TransactionCursor{ID: uuid.New()}, Limit: 10}, models.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := buildTransactionQuery(tt.query); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got: %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// scheduleColumns lists the schedules columns in the order scanSchedule reads them
+const scheduleColumns = "id, region, from_account, to_account, amount, currency, spec, status, start_at, end_at, " +
+	"max_runs, run_count, next_run_at, last_run_at, created_at, updated_at"
+
+// scheduleRunColumns lists the schedule_runs columns in the order scanScheduleRun reads them
+const scheduleRunColumns = "id, schedule_id, scheduled_for, region, status, transaction_id, error, executed_at"
+
+// scanSchedule reads a row selected with scheduleColumns into schedule
+func scanSchedule(row rowScanner, schedule *models.Schedule) error {
+	var endAt, nextRunAt, lastRunAt sql.NullTime
+	var maxRuns sql.NullInt64
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.Region,
+		&schedule.FromAccount,
+		&schedule.ToAccount,
+		&schedule.Amount.Value,
+		&schedule.Amount.Currency,
+		&schedule.Spec,
+		&schedule.Status,
+		&schedule.StartAt,
+		&endAt,
+		&maxRuns,
+		&schedule.RunCount,
+		&nextRunAt,
+		&lastRunAt,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	schedule.EndAt = nullTime(endAt)
+	schedule.NextRunAt = nullTime(nextRunAt)
+	schedule.LastRunAt = nullTime(lastRunAt)
+	schedule.MaxRuns = nil
+	if maxRuns.Valid {
+		n := int(maxRuns.Int64)
+		schedule.MaxRuns = &n
+	}
+	return nil
+}
+
+// scanScheduleRun reads a row selected with scheduleRunColumns into run
+func scanScheduleRun(row rowScanner, run *models.ScheduleRun) error {
+	var transactionID uuid.NullUUID
+	var runErr sql.NullString
+	err := row.Scan(
+		&run.ID,
+		&run.ScheduleID,
+		&run.ScheduledFor,
+		&run.Region,
+		&run.Status,
+		&transactionID,
+		&runErr,
+		&run.ExecutedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	run.TransactionID = nil
+	if transactionID.Valid {
+		run.TransactionID = &transactionID.UUID
+	}
+	run.Error = runErr.String
+	return nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// CreateSchedule stores a new schedule together with the outbox messages for
+// it and the completion of the request's idempotency key
+func (db *DB) CreateSchedule(ctx context.Context, schedule *models.Schedule, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO schedules (id, region, from_account, to_account, amount, currency, spec, status, start_at, end_at,
+			max_runs, run_count, next_run_at, last_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING ` + scheduleColumns
+
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		err := scanSchedule(sqlTx.QueryRowContext(ctx,
+			query,
+			schedule.ID,
+			schedule.Region,
+			schedule.FromAccount,
+			schedule.ToAccount,
+			schedule.Amount.Value,
+			schedule.Amount.Currency,
+			schedule.Spec,
+			schedule.Status,
+			schedule.StartAt,
+			schedule.EndAt,
+			schedule.MaxRuns,
+			schedule.RunCount,
+			schedule.NextRunAt,
+			schedule.LastRunAt,
+			schedule.CreatedAt,
+			schedule.UpdatedAt,
+		), schedule)
+		if err != nil {
+			db.logger.Error("Failed to create schedule",
+				zap.Error(err),
+				zap.String("schedule_id", schedule.ID.String()),
+			)
+			return fmt.Errorf("failed to create schedule: %w", err)
+		}
+
+		if err := insertOutbox(ctx, sqlTx, outbox); err != nil {
+			return err
+		}
+		return completeIdempotency(ctx, sqlTx, completion)
+	}); err != nil {
+		return err
+	}
+
+	db.logger.Info("Schedule created",
+		zap.String("schedule_id", schedule.ID.String()),
+		zap.String("spec", schedule.Spec),
+	)
+
+	return nil
+}
+
+// GetSchedule retrieves a schedule by ID
+func (db *DB) GetSchedule(ctx context.Context, id uuid.UUID) (*models.Schedule, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var schedule models.Schedule
+	query := `SELECT ` + scheduleColumns + ` FROM schedules WHERE id = $1`
+
+	err := scanSchedule(db.conn.QueryRowContext(ctx, query, id), &schedule)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", models.ErrScheduleNotFound, id.String())
+	}
+	if err != nil {
+		db.logger.Error("Failed to get schedule",
+			zap.Error(err),
+			zap.String("schedule_id", id.String()),
+		)
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	return &schedule, nil
+}
+
+// CancelSchedule stops an active schedule from running again. schedule is
+// refreshed with the cancelled state before outbox is called, and its
+// messages are stored in the same database transaction.
+func (db *DB) CancelSchedule(ctx context.Context, schedule *models.Schedule, outbox models.OutboxBuilder) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE schedules
+		SET status = $1, next_run_at = NULL, updated_at = $2
+		WHERE id = $3 AND status = $4
+		RETURNING ` + scheduleColumns
+
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		err := scanSchedule(sqlTx.QueryRowContext(ctx,
+			query,
+			models.ScheduleStatusCancelled,
+			time.Now().UTC(),
+			schedule.ID,
+			models.ScheduleStatusActive,
+		), schedule)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", models.ErrScheduleNotActive, schedule.ID.String())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to cancel schedule: %w", err)
+		}
+
+		return buildOutbox(ctx, sqlTx, outbox)
+	}); err != nil {
+		return err
+	}
+
+	db.logger.Info("Schedule cancelled", zap.String("schedule_id", schedule.ID.String()))
+
+	return nil
+}
+
+// ListScheduleRuns returns the most recent runs of a schedule, newest first
+func (db *DB) ListScheduleRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]models.ScheduleRun, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + scheduleRunColumns + `
+		FROM schedule_runs
+		WHERE schedule_id = $1
+		ORDER BY scheduled_for DESC
+		LIMIT $2
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedule runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.ScheduleRun
+	for rows.Next() {
+		var run models.ScheduleRun
+		if err := scanScheduleRun(rows, &run); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schedule runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// DueSchedules returns up to limit active schedules whose next execution is
+// at or before now, oldest first
+func (db *DB) DueSchedules(ctx context.Context, now time.Time, limit int) ([]*models.Schedule, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM schedules
+		WHERE status = $1 AND next_run_at <= $2
+		ORDER BY next_run_at
+		LIMIT $3
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, models.ScheduleStatusActive, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find due schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*models.Schedule
+	for rows.Next() {
+		var schedule models.Schedule
+		if err := scanSchedule(rows, &schedule); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, &schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// ExecuteSchedule runs the due execution of schedule as read by DueSchedules:
+// it posts tx, records the run and moves the schedule on to next, or completes
+// it when next is nil, all in one SERIALIZABLE database transaction.
+//
+// Schedulers in both regions may pick up the same execution. The schedule row
+// is locked and its next run time compared with the one that was read, and a
+// run can be recorded only once per scheduled time, so whichever scheduler
+// loses gets models.ErrScheduleRunClaimed and nothing is posted twice.
+//
+// A transfer that is rejected, for example for insufficient funds, is
+// recorded as a failed run and the schedule still moves on. outbox is called
+// with the run only when the transfer is posted, and its messages are stored
+// together with it.
+func (db *DB) ExecuteSchedule(ctx context.Context, schedule *models.Schedule, tx *models.Transaction, next *time.Time,
+	outbox func(run *models.ScheduleRun) ([]*models.OutboxMessage, error)) (*models.ScheduleRun, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if schedule.NextRunAt == nil {
+		return nil, fmt.Errorf("%w: %s", models.ErrScheduleNotActive, schedule.ID.String())
+	}
+	scheduledFor := *schedule.NextRunAt
+
+	var locked models.Schedule
+	var run *models.ScheduleRun
+	var status string
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		query := `SELECT ` + scheduleColumns + ` FROM schedules WHERE id = $1 FOR UPDATE`
+		err := scanSchedule(sqlTx.QueryRowContext(ctx, query, schedule.ID), &locked)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", models.ErrScheduleNotFound, schedule.ID.String())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock schedule: %w", err)
+		}
+		if !locked.IsActive() || locked.NextRunAt == nil || !locked.NextRunAt.Equal(scheduledFor) {
+			return fmt.Errorf("%w: %s at %s", models.ErrScheduleRunClaimed, schedule.ID.String(),
+				scheduledFor.Format(time.RFC3339))
+		}
+
+		run = &models.ScheduleRun{
+			ID:            uuid.New(),
+			ScheduleID:    schedule.ID,
+			ScheduledFor:  scheduledFor,
+			Region:        tx.Region,
+			Status:        models.ScheduleRunSucceeded,
+			TransactionID: &tx.ID,
+			ExecutedAt:    time.Now().UTC(),
+		}
+
+		// The transfer is posted under a savepoint so a rejected one can be
+		// undone while the run is still recorded
+		if _, err := sqlTx.ExecContext(ctx, `SAVEPOINT schedule_run`); err != nil {
+			return fmt.Errorf("failed to execute schedule: %w", err)
+		}
+		postErr := prepareEntries(tx)
+		if postErr == nil {
+			postErr = db.postTransaction(ctx, sqlTx, tx)
+		}
+		if postErr == nil && outbox != nil {
+			if err := buildOutbox(ctx, sqlTx, func() ([]*models.OutboxMessage, error) { return outbox(run) }); err != nil {
+				return err
+			}
+		}
+		if postErr != nil {
+			// A serialization conflict aborts the whole transaction, so it is
+			// retried rather than recorded as a failed run
+			if isRetryable(postErr) {
+				return postErr
+			}
+			if _, err := sqlTx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT schedule_run`); err != nil {
+				return fmt.Errorf("failed to roll back schedule run: %w", err)
+			}
+			db.logger.Warn("Scheduled transfer rejected",
+				zap.Error(postErr),
+				zap.String("schedule_id", schedule.ID.String()),
+			)
+			run.Status = models.ScheduleRunFailed
+			run.TransactionID = nil
+			run.Error = postErr.Error()
+		} else if _, err := sqlTx.ExecContext(ctx, `RELEASE SAVEPOINT schedule_run`); err != nil {
+			return fmt.Errorf("failed to execute schedule: %w", err)
+		}
+
+		query = `
+			INSERT INTO schedule_runs (id, schedule_id, scheduled_for, region, status, transaction_id, error, executed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+			ON CONFLICT (schedule_id, scheduled_for) DO NOTHING
+		`
+		result, err := sqlTx.ExecContext(ctx, query, run.ID, run.ScheduleID, run.ScheduledFor, run.Region, run.Status,
+			run.TransactionID, run.Error, run.ExecutedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record schedule run: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: %s at %s", models.ErrScheduleRunClaimed, schedule.ID.String(),
+				scheduledFor.Format(time.RFC3339))
+		}
+
+		status = models.ScheduleStatusActive
+		if next == nil {
+			status = models.ScheduleStatusCompleted
+		}
+		query = `
+			UPDATE schedules
+			SET status = $1, next_run_at = $2, last_run_at = $3, run_count = run_count + 1, updated_at = $4
+			WHERE id = $5
+		`
+		if _, err := sqlTx.ExecContext(ctx, query, status, next, scheduledFor, run.ExecutedAt, schedule.ID); err != nil {
+			return fmt.Errorf("failed to advance schedule: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	schedule.Status = status
+	schedule.NextRunAt = next
+	schedule.LastRunAt = &scheduledFor
+	schedule.RunCount = locked.RunCount + 1
+	schedule.UpdatedAt = run.ExecutedAt
+
+	db.logger.Info("Schedule executed",
+		zap.String("schedule_id", schedule.ID.String()),
+		zap.String("run_status", run.Status),
+		zap.Time("scheduled_for", scheduledFor),
+	)
+
+	return run, nil
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+var scheduleColumnNames = []string{"id", "region", "from_account", "to_account", "amount", "currency", "spec", "status",
+	"start_at", "end_at", "max_runs", "run_count", "next_run_at", "last_run_at", "created_at", "updated_at"}
+
+func newDueSchedule() *models.Schedule {
+	next := time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+	return models.NewSchedule("acc1", "landlord", models.Money{Value: decimal.NewFromInt(1200), Currency: "USD"},
+		"FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=9;BYMINUTE=0", "us-east-1", next.AddDate(0, -1, 0), next)
+}
+
+// scheduleRow returns schedule as a row of scheduleColumnNames
+func scheduleRow(schedule *models.Schedule) *sqlmock.Rows {
+	return sqlmock.NewRows(scheduleColumnNames).AddRow(
+		schedule.ID, schedule.Region, schedule.FromAccount, schedule.ToAccount, schedule.Amount.Value,
+		schedule.Amount.Currency, schedule.Spec, schedule.Status, schedule.StartAt, nil, nil, schedule.RunCount,
+		*schedule.NextRunAt, nil, schedule.CreatedAt, schedule.UpdatedAt,
+	)
+}
+
+func TestExecuteSchedule_Success(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	schedule := newDueSchedule()
+	scheduledFor := *schedule.NextRunAt
+	next := scheduledFor.AddDate(0, 1, 0)
+	tx := schedule.NewExecution("us-east-1")
+	amount := tx.Amount.Value
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT .* FROM schedules WHERE id = \$1 FOR UPDATE`).
+		WithArgs(schedule.ID).
+		WillReturnRows(scheduleRow(schedule))
+	mock.ExpectExec(`SAVEPOINT schedule_run`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "5000.00", "0", "0"))
+	expectCreditedLock(mock, schedule.ToAccount, "USD")
+	mock.ExpectQuery(`INSERT INTO transactions`).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).
+			AddRow(tx.ID, "us-east-1", amount, "USD", "acc1", "landlord", "pending", tx.Timestamp, nil, nil, nil, nil, nil))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(amount.Neg(), "acc1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(amount, "landlord").WillReturnResult(sqlmock.NewResult(0, 1))
+	// The outbox is saved with the transfer, under the run's savepoint
+	mock.ExpectExec(`INSERT INTO outbox`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT schedule_run`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schedule_runs`).
+		WithArgs(sqlmock.AnyArg(), schedule.ID, scheduledFor, "us-east-1", "succeeded", &tx.ID, "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE schedules`).
+		WithArgs("active", &next, scheduledFor, sqlmock.AnyArg(), schedule.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	var described *models.ScheduleRun
+	run, err := db.ExecuteSchedule(context.Background(), schedule, tx, &next, func(run *models.ScheduleRun) ([]*models.OutboxMessage, error) {
+		described = run
+		return []*models.OutboxMessage{models.NewOutboxMessage("us-east-1", "acc1", []byte(`{}`))}, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if run.Status != models.ScheduleRunSucceeded || run.TransactionID == nil || *run.TransactionID != tx.ID {
+		t.Errorf("Expected a succeeded run for %s, got %+v", tx.ID, run)
+	}
+	if described != run {
+		t.Errorf("Expected the outbox to describe the recorded run, got %+v", described)
+	}
+	if schedule.RunCount != 1 || !schedule.NextRunAt.Equal(next) {
+		t.Errorf("Expected schedule to move on to %s, got %+v", next, schedule)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExecuteSchedule_RejectedTransferIsRecorded(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	schedule := newDueSchedule()
+	scheduledFor := *schedule.NextRunAt
+	tx := schedule.NewExecution("us-east-1")
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT .* FROM schedules WHERE id = \$1 FOR UPDATE`).
+		WithArgs(schedule.ID).
+		WillReturnRows(scheduleRow(schedule))
+	mock.ExpectExec(`SAVEPOINT schedule_run`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "100.00", "0", "0"))
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT schedule_run`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schedule_runs`).
+		WithArgs(sqlmock.AnyArg(), schedule.ID, scheduledFor, "us-east-1", "failed", nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// The last run completes the schedule
+	mock.ExpectExec(`UPDATE schedules`).
+		WithArgs("completed", nil, scheduledFor, sqlmock.AnyArg(), schedule.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	run, err := db.ExecuteSchedule(context.Background(), schedule, tx, nil, func(*models.ScheduleRun) ([]*models.OutboxMessage, error) {
+		t.Error("Expected no outbox for a rejected transfer")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if run.Status != models.ScheduleRunFailed || run.TransactionID != nil || run.Error == "" {
+		t.Errorf("Expected a failed run with an error, got %+v", run)
+	}
+	if schedule.Status != models.ScheduleStatusCompleted {
+		t.Errorf("Expected schedule to be completed, got %s", schedule.Status)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExecuteSchedule_AlreadyClaimed(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	schedule := newDueSchedule()
+	next := schedule.NextRunAt.AddDate(0, 1, 0)
+
+	// The other region has already run this execution and moved the schedule on
+	claimed := *schedule
+	claimed.NextRunAt = &next
+	claimed.RunCount = 1
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT .* FROM schedules WHERE id = \$1 FOR UPDATE`).
+		WithArgs(schedule.ID).
+		WillReturnRows(scheduleRow(&claimed))
+	mock.ExpectRollback()
+
+	_, err := db.ExecuteSchedule(context.Background(), schedule, schedule.NewExecution("us-west-2"), &next, nil)
+	if !errors.Is(err, models.ErrScheduleRunClaimed) {
+		t.Errorf("Expected ErrScheduleRunClaimed, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCancelSchedule_NotActive(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	schedule := newDueSchedule()
+	expectTxBegin(mock)
+	mock.ExpectQuery(`UPDATE schedules`).
+		WithArgs("cancelled", sqlmock.AnyArg(), schedule.ID, "active").
+		WillReturnRows(sqlmock.NewRows(scheduleColumnNames))
+	mock.ExpectRollback()
+
+	err := db.CancelSchedule(context.Background(), schedule, func() ([]*models.OutboxMessage, error) {
+		t.Error("Expected no outbox for a schedule that isn't cancelled")
+		return nil, nil
+	})
+	if !errors.Is(err, models.ErrScheduleNotActive) {
+		t.Errorf("Expected ErrScheduleNotActive, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
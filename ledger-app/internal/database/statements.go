@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// postingColumns selects an account's entries with the account on the other
+// side of each transfer, in the order scanPosting reads them
+const postingColumns = `e.id, e.transaction_id, e.account, e.amount, e.currency, e.region, e.timestamp,
+	CASE WHEN t.from_account = e.account THEN t.to_account ELSE t.from_account END AS counterparty`
+
+// ListAccountPostings retrieves a page of an account's postings, newest first,
+// each with the account's balance once it was applied
+func (db *DB) ListAccountPostings(ctx context.Context, account string, limit, offset int) ([]*models.Posting, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, transaction_id, account, amount, currency, region, timestamp, counterparty, balance
+		FROM (
+			SELECT ` + postingColumns + `,
+				SUM(e.amount) OVER (ORDER BY e.timestamp, e.id) AS balance
+			FROM entries e
+			JOIN transactions t ON t.id = e.transaction_id
+			WHERE e.account = $1
+		) AS postings
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, account, limit, offset)
+	if err != nil {
+		db.logger.Error("Failed to list account postings", zap.Error(err), zap.String("account", account))
+		return nil, fmt.Errorf("failed to list account postings: %w", err)
+	}
+	defer rows.Close()
+
+	var postings []*models.Posting
+	for rows.Next() {
+		var p models.Posting
+		if err := scanPosting(rows, &p, &p.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan posting: %w", err)
+		}
+		postings = append(postings, &p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating postings: %w", err)
+	}
+
+	return postings, nil
+}
+
+// GetStatement builds the statement of account for [from, to). The opening
+// balance and the postings are read in one transaction so they agree.
+func (db *DB) GetStatement(ctx context.Context, account *models.Account, from, to time.Time) (*models.Statement, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	sqlTx, err := db.conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer sqlTx.Rollback()
+
+	var opening decimal.Decimal
+	if err := sqlTx.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM entries WHERE account = $1 AND timestamp < $2`,
+		account.ID, from,
+	).Scan(&opening); err != nil {
+		db.logger.Error("Failed to get opening balance", zap.Error(err), zap.String("account", account.ID))
+		return nil, fmt.Errorf("failed to get opening balance: %w", err)
+	}
+
+	rows, err := sqlTx.QueryContext(ctx, `
+		SELECT `+postingColumns+`
+		FROM entries e
+		JOIN transactions t ON t.id = e.transaction_id
+		WHERE e.account = $1 AND e.timestamp >= $2 AND e.timestamp < $3
+		ORDER BY e.timestamp, e.id
+	`, account.ID, from, to)
+	if err != nil {
+		db.logger.Error("Failed to get statement postings", zap.Error(err), zap.String("account", account.ID))
+		return nil, fmt.Errorf("failed to get statement postings: %w", err)
+	}
+	defer rows.Close()
+
+	var postings []*models.Posting
+	for rows.Next() {
+		var p models.Posting
+		if err := scanPosting(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan posting: %w", err)
+		}
+		postings = append(postings, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating postings: %w", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return models.NewStatement(account, from, to, opening, postings), nil
+}
+
+// scanPosting reads a row of postingColumns followed by any extra columns
+func scanPosting(row rowScanner, p *models.Posting, extra ...interface{}) error {
+	dest := append([]interface{}{
+		&p.ID,
+		&p.TransactionID,
+		&p.Account,
+		&p.Amount,
+		&p.Currency,
+		&p.Region,
+		&p.Timestamp,
+		&p.Counterparty,
+	}, extra...)
+	return row.Scan(dest...)
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+var postingColumnNames = []string{"id", "transaction_id", "account", "amount", "currency", "region", "timestamp", "counterparty"}
+
+func TestListAccountPostings_RunningBalance(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().UTC()
+	rows := sqlmock.NewRows(append(postingColumnNames, "balance")).
+		AddRow(uuid.New(), uuid.New(), "acc1", decimal.NewFromInt(-30), "USD", "us-east-1", now, "acc2", decimal.NewFromInt(70)).
+		AddRow(uuid.New(), uuid.New(), "acc1", decimal.NewFromInt(100), "USD", "us-east-1", now.Add(-time.Hour), "acc3", decimal.NewFromInt(100))
+
+	mock.ExpectQuery(`SUM\(e.amount\) OVER \(ORDER BY e.timestamp, e.id\) AS balance`).
+		WithArgs("acc1", 50, 0).
+		WillReturnRows(rows)
+
+	postings, err := db.ListAccountPostings(context.Background(), "acc1", 50, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(postings) != 2 || !postings[0].Balance.Equal(decimal.NewFromInt(70)) || postings[0].Counterparty != "acc2" {
+		t.Errorf("Unexpected postings %+v", postings)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetStatement(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account := &models.Account{ID: "acc1", Owner: "alice", Currency: "USD"}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM entries WHERE account = \$1 AND timestamp < \$2`).
+		WithArgs("acc1", from).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("250.00"))
+	mock.ExpectQuery(`WHERE e.account = \$1 AND e.timestamp >= \$2 AND e.timestamp < \$3\s+ORDER BY e.timestamp, e.id`).
+		WithArgs("acc1", from, to).
+		WillReturnRows(sqlmock.NewRows(postingColumnNames).
+			AddRow(uuid.New(), uuid.New(), "acc1", decimal.NewFromInt(-100), "USD", "us-east-1", from.Add(time.Hour), "acc2").
+			AddRow(uuid.New(), uuid.New(), "acc1", decimal.NewFromInt(40), "USD", "us-east-1", from.Add(2*time.Hour), "acc3"))
+	mock.ExpectCommit()
+
+	statement, err := db.GetStatement(context.Background(), account, from, to)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !statement.OpeningBalance.Equal(decimal.NewFromInt(250)) || !statement.ClosingBalance.Equal(decimal.NewFromInt(190)) {
+		t.Errorf("Expected balances 250 -> 190, got %s -> %s", statement.OpeningBalance, statement.ClosingBalance)
+	}
+	if !statement.Postings[0].Balance.Equal(decimal.NewFromInt(150)) {
+		t.Errorf("Expected a running balance of 150 after the first posting, got %s", statement.Postings[0].Balance)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
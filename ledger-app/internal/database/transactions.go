@@ -1,79 +1,319 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
-// CreateTransaction creates a new transaction in the database
-func (db *DB) CreateTransaction(tx *models.Transaction) error {
+// CreateTransaction creates a new transaction in the database together with its
+// balanced debit and credit entries. The funds check, the transaction row, its
+// entries and the balance updates all happen in a single SERIALIZABLE database
+// transaction, together with the outbox messages for its side effects and the
+// completion of the request's idempotency key, if it has one.
+func (db *DB) CreateTransaction(ctx context.Context, tx *models.Transaction, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if err := prepareEntries(tx); err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		if err := db.postTransaction(ctx, sqlTx, tx); err != nil {
+			return err
+		}
+		if err := insertOutbox(ctx, sqlTx, outbox); err != nil {
+			return err
+		}
+		return completeIdempotency(ctx, sqlTx, completion)
+	}); err != nil {
+		return err
+	}
+
+	db.logger.Info("Transaction created",
+		zap.String("transaction_id", tx.ID.String()),
+		zap.String("region", tx.Region),
+		zap.String("status", tx.Status),
+		zap.Int("entries", len(tx.Entries)),
+	)
+
+	return nil
+}
+
+// CreateTransactions creates a batch of transactions atomically: either every
+// transaction is written or none is. Each one is checked against the balances
+// left by the ones before it, all in a single SERIALIZABLE database
+// transaction that also stores the outbox messages for the batch and completes
+// its idempotency key. Errors caused by a single transaction are returned as a
+// *models.BatchItemError carrying its index.
+func (db *DB) CreateTransactions(ctx context.Context, txs []*models.Transaction, completion *models.IdempotencyCompletion, outbox ...*models.OutboxMessage) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	for i, tx := range txs {
+		if err := prepareEntries(tx); err != nil {
+			return &models.BatchItemError{Index: i, Err: err}
+		}
+	}
+
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		for i, tx := range txs {
+			if err := db.postTransaction(ctx, sqlTx, tx); err != nil {
+				return &models.BatchItemError{Index: i, Err: err}
+			}
+		}
+		if err := insertOutbox(ctx, sqlTx, outbox); err != nil {
+			return err
+		}
+		return completeIdempotency(ctx, sqlTx, completion)
+	}); err != nil {
+		return err
+	}
+
+	db.logger.Info("Transaction batch created",
+		zap.Int("size", len(txs)),
+	)
+
+	return nil
+}
+
+// prepareEntries builds the postings of a new transaction unless the caller
+// supplied them, and checks that they balance
+func prepareEntries(tx *models.Transaction) error {
+	if len(tx.Entries) == 0 {
+		if tx.IsFX() {
+			tx.Entries = models.NewFXTransferEntries(tx)
+		} else {
+			tx.Entries = models.NewTransferEntries(tx)
+		}
+	}
+	return models.ValidateEntries(tx.Entries)
+}
+
+// postTransaction locks and checks the accounts on both sides, claims the FX
+// quote if any, and writes the transaction row, its entries and the balance
+// updates within an open SERIALIZABLE database transaction. System accounts
+// are refused as either side of the transfer.
+func (db *DB) postTransaction(ctx context.Context, sqlTx *sql.Tx, tx *models.Transaction) error {
+	for _, id := range []string{tx.FromAccount, tx.ToAccount} {
+		if models.IsSystemAccount(id) {
+			return fmt.Errorf("failed to create transaction: %w: %s", models.ErrSystemAccount, id)
+		}
+	}
+
+	if err := checkPostings(ctx, sqlTx, tx, tx.Entries); err != nil {
+		db.logger.Warn("Transaction rejected by account check",
+			zap.Error(err),
+			zap.String("transaction_id", tx.ID.String()),
+		)
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if tx.QuoteID != nil {
+		if err := claimFXQuote(ctx, sqlTx, *tx.QuoteID, tx.ID); err != nil {
+			db.logger.Warn("Transaction rejected by fx quote check",
+				zap.Error(err),
+				zap.String("transaction_id", tx.ID.String()),
+			)
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+	}
+
+	if err := ensureSystemAccounts(ctx, sqlTx, tx.Entries); err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if err := insertTransaction(ctx, sqlTx, tx); err != nil {
+		db.logger.Error("Failed to create transaction",
+			zap.Error(err),
+			zap.String("transaction_id", tx.ID.String()),
+		)
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if err := insertEntries(ctx, sqlTx, tx.Entries); err != nil {
+		db.logger.Error("Failed to create transaction entries",
+			zap.Error(err),
+			zap.String("transaction_id", tx.ID.String()),
+		)
+		return fmt.Errorf("failed to create transaction entries: %w", err)
+	}
+
+	if err := applyEntries(ctx, sqlTx, tx.Entries); err != nil {
+		db.logger.Error("Failed to apply entries to account balances",
+			zap.Error(err),
+			zap.String("transaction_id", tx.ID.String()),
+		)
+		return fmt.Errorf("failed to update account balances: %w", err)
+	}
+
+	return nil
+}
+
+// transactionColumns lists the transactions columns in the order scanTransaction reads them
+const transactionColumns = "id, region, amount, currency, from_account, to_account, status, timestamp, reversal_of, " +
+	"converted_amount, converted_currency, fx_rate, quote_id"
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTransaction reads a row selected with transactionColumns into tx
+func scanTransaction(row rowScanner, tx *models.Transaction) error {
+	var reversalOf, quoteID uuid.NullUUID
+	var convertedAmount, fxRate decimal.NullDecimal
+	var convertedCurrency sql.NullString
+	if err := row.Scan(
+		&tx.ID,
+		&tx.Region,
+		&tx.Amount.Value,
+		&tx.Amount.Currency,
+		&tx.FromAccount,
+		&tx.ToAccount,
+		&tx.Status,
+		&tx.Timestamp,
+		&reversalOf,
+		&convertedAmount,
+		&convertedCurrency,
+		&fxRate,
+		&quoteID,
+	); err != nil {
+		return err
+	}
+
+	tx.ReversalOf, tx.ConvertedAmount, tx.FXRate, tx.QuoteID = nil, nil, nil, nil
+	if reversalOf.Valid {
+		tx.ReversalOf = &reversalOf.UUID
+	}
+	if convertedAmount.Valid {
+		tx.ConvertedAmount = &models.Money{Value: convertedAmount.Decimal, Currency: convertedCurrency.String}
+	}
+	if fxRate.Valid {
+		tx.FXRate = &fxRate.Decimal
+	}
+	if quoteID.Valid {
+		tx.QuoteID = &quoteID.UUID
+	}
+	return nil
+}
+
+// insertTransaction writes the transaction row within an open database transaction
+func insertTransaction(ctx context.Context, sqlTx *sql.Tx, tx *models.Transaction) error {
 	query := `
-		INSERT INTO transactions (id, region, amount, from_account, to_account, status, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, region, amount, from_account, to_account, status, timestamp
-	`
+		INSERT INTO transactions (id, region, amount, currency, from_account, to_account, status, timestamp, reversal_of,
+			converted_amount, converted_currency, fx_rate, quote_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING ` + transactionColumns
+
+	var convertedAmount, convertedCurrency interface{}
+	if tx.ConvertedAmount != nil {
+		convertedAmount, convertedCurrency = tx.ConvertedAmount.Value, tx.ConvertedAmount.Currency
+	}
 
-	err := db.conn.QueryRow(
+	return scanTransaction(sqlTx.QueryRowContext(ctx,
 		query,
 		tx.ID,
 		tx.Region,
-		tx.Amount,
+		tx.Amount.Value,
+		tx.Amount.Currency,
 		tx.FromAccount,
 		tx.ToAccount,
 		tx.Status,
 		tx.Timestamp,
-	).Scan(
-		&tx.ID,
-		&tx.Region,
-		&tx.Amount,
-		&tx.FromAccount,
-		&tx.ToAccount,
-		&tx.Status,
-		&tx.Timestamp,
-	)
+		tx.ReversalOf,
+		convertedAmount,
+		convertedCurrency,
+		tx.FXRate,
+		tx.QuoteID,
+	), tx)
+}
 
+// insertEntries writes the postings of a transaction within an open database transaction
+func insertEntries(ctx context.Context, sqlTx *sql.Tx, entries []*models.Entry) error {
+	query := `
+		INSERT INTO entries (id, transaction_id, account, amount, currency, region, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	for _, e := range entries {
+		if _, err := sqlTx.ExecContext(ctx, query, e.ID, e.TransactionID, e.Account, e.Amount, e.Currency, e.Region, e.Timestamp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTransactionEntries retrieves the postings belonging to a transaction
+func (db *DB) GetTransactionEntries(ctx context.Context, transactionID uuid.UUID) ([]*models.Entry, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, transaction_id, account, amount, currency, region, timestamp
+		FROM entries
+		WHERE transaction_id = $1
+		ORDER BY amount ASC
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, transactionID)
 	if err != nil {
-		db.logger.Error("Failed to create transaction",
+		db.logger.Error("Failed to get transaction entries",
 			zap.Error(err),
-			zap.String("transaction_id", tx.ID.String()),
+			zap.String("transaction_id", transactionID.String()),
 		)
-		return fmt.Errorf("failed to create transaction: %w", err)
+		return nil, fmt.Errorf("failed to get transaction entries: %w", err)
 	}
+	defer rows.Close()
 
-	db.logger.Info("Transaction created",
-		zap.String("transaction_id", tx.ID.String()),
-		zap.String("region", tx.Region),
-		zap.String("status", tx.Status),
-	)
+	var entries []*models.Entry
+	for rows.Next() {
+		var e models.Entry
+		if err := rows.Scan(
+			&e.ID,
+			&e.TransactionID,
+			&e.Account,
+			&e.Amount,
+			&e.Currency,
+			&e.Region,
+			&e.Timestamp,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, &e)
+	}
 
-	return nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	return entries, nil
 }
 
 // GetTransaction retrieves a transaction by ID
-func (db *DB) GetTransaction(id uuid.UUID) (*models.Transaction, error) {
+func (db *DB) GetTransaction(ctx context.Context, id uuid.UUID) (*models.Transaction, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	var tx models.Transaction
 	query := `
-		SELECT id, region, amount, from_account, to_account, status, timestamp
+		SELECT ` + transactionColumns + `
 		FROM transactions
 		WHERE id = $1
 	`
 
-	err := db.conn.QueryRow(query, id).Scan(
-		&tx.ID,
-		&tx.Region,
-		&tx.Amount,
-		&tx.FromAccount,
-		&tx.ToAccount,
-		&tx.Status,
-		&tx.Timestamp,
-	)
+	err := scanTransaction(db.conn.QueryRowContext(ctx, query, id), &tx)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("transaction not found: %s", id.String())
+		return nil, fmt.Errorf("%w: %s", models.ErrTransactionNotFound, id.String())
 	}
 	if err != nil {
 		db.logger.Error("Failed to get transaction",
@@ -87,33 +327,37 @@ func (db *DB) GetTransaction(id uuid.UUID) (*models.Transaction, error) {
 }
 
 // ListTransactions retrieves transactions with pagination
-func (db *DB) ListTransactions(limit, offset int) ([]*models.Transaction, error) {
-	query := `
-		SELECT id, region, amount, from_account, to_account, status, timestamp
-		FROM transactions
-		ORDER BY timestamp DESC
-		LIMIT $1 OFFSET $2
-	`
+func (db *DB) ListTransactions(ctx context.Context, limit, offset int) ([]*models.Transaction, error) {
+	return db.SearchTransactions(ctx, models.TransactionQuery{Limit: limit, Offset: offset})
+}
+
+// SearchTransactions retrieves the page of transactions matching q. Pages
+// with a cursor continue after it; others start at q.Offset.
+func (db *DB) SearchTransactions(ctx context.Context, q models.TransactionQuery) ([]*models.Transaction, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query, args, err := buildTransactionQuery(q)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := db.conn.Query(query, limit, offset)
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		db.logger.Error("Failed to list transactions", zap.Error(err))
 		return nil, fmt.Errorf("failed to list transactions: %w", err)
 	}
 	defer rows.Close()
 
+	return db.scanTransactions(rows)
+}
+
+// scanTransactions reads the transactions selected with transactionColumns
+func (db *DB) scanTransactions(rows *sql.Rows) ([]*models.Transaction, error) {
 	var transactions []*models.Transaction
 	for rows.Next() {
 		var tx models.Transaction
-		if err := rows.Scan(
-			&tx.ID,
-			&tx.Region,
-			&tx.Amount,
-			&tx.FromAccount,
-			&tx.ToAccount,
-			&tx.Status,
-			&tx.Timestamp,
-		); err != nil {
+		if err := scanTransaction(rows, &tx); err != nil {
 			db.logger.Error("Failed to scan transaction", zap.Error(err))
 			continue
 		}
@@ -127,48 +371,270 @@ func (db *DB) ListTransactions(limit, offset int) ([]*models.Transaction, error)
 	return transactions, nil
 }
 
-// UpdateTransactionStatus updates the status of a transaction
-func (db *DB) UpdateTransactionStatus(id uuid.UUID, status string) error {
+// UpdateTransactionStatus moves a transaction from one status to another.
+// The update is a compare-and-set on the expected current status, so two
+// concurrent updates can't both succeed. Moving into a status that releases
+// funds posts compensating entries in the same database transaction, which
+// also stores the outbox messages for the change.
+func (db *DB) UpdateTransactionStatus(ctx context.Context, id uuid.UUID, from, to string, outbox ...*models.OutboxMessage) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if !models.CanTransition(from, to) {
+		return fmt.Errorf("%w: %s -> %s", models.ErrInvalidTransition, from, to)
+	}
+	if to == models.StatusReversed {
+		return fmt.Errorf("%w: reversals must go through ReverseTransaction", models.ErrInvalidTransition)
+	}
+
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		query := `
+			UPDATE transactions
+			SET status = $1
+			WHERE id = $2 AND status = $3
+		`
+
+		result, err := sqlTx.ExecContext(ctx, query, to, id, from)
+		if err != nil {
+			db.logger.Error("Failed to update transaction status",
+				zap.Error(err),
+				zap.String("transaction_id", id.String()),
+				zap.String("status", to),
+			)
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			var current string
+			err := sqlTx.QueryRowContext(ctx, "SELECT status FROM transactions WHERE id = $1", id).Scan(&current)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: %s", models.ErrTransactionNotFound, id.String())
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get transaction status: %w", err)
+			}
+			return fmt.Errorf("%w: expected %s, found %s", models.ErrStatusConflict, from, current)
+		}
+
+		if models.ReleasesFunds(to) {
+			if err := releaseEntries(ctx, sqlTx, id); err != nil {
+				db.logger.Error("Failed to release transaction funds",
+					zap.Error(err),
+					zap.String("transaction_id", id.String()),
+				)
+				return fmt.Errorf("failed to release transaction funds: %w", err)
+			}
+		}
+
+		return insertOutbox(ctx, sqlTx, outbox)
+	}); err != nil {
+		return err
+	}
+
+	db.logger.Info("Transaction status updated",
+		zap.String("transaction_id", id.String()),
+		zap.String("from_status", from),
+		zap.String("status", to),
+	)
+
+	return nil
+}
+
+// releaseEntries posts the negation of a transaction's entries against the
+// same accounts, undoing its effect on their balances. The accounts debited
+// by the negation go through the same funds check as any other debit.
+func releaseEntries(ctx context.Context, sqlTx *sql.Tx, transactionID uuid.UUID) error {
+	// The transaction tells the FX legs of its entries apart for the funds check
+	var tx models.Transaction
 	query := `
-		UPDATE transactions
-		SET status = $1
-		WHERE id = $2
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE id = $1
 	`
+	if err := scanTransaction(sqlTx.QueryRowContext(ctx, query, transactionID), &tx); err != nil {
+		return err
+	}
 
-	result, err := db.conn.Exec(query, status, id)
+	query = `
+		SELECT account, amount, currency, region
+		FROM entries
+		WHERE transaction_id = $1
+	`
+
+	rows, err := sqlTx.QueryContext(ctx, query, transactionID)
 	if err != nil {
-		db.logger.Error("Failed to update transaction status",
-			zap.Error(err),
-			zap.String("transaction_id", id.String()),
-			zap.String("status", status),
-		)
-		return fmt.Errorf("failed to update transaction status: %w", err)
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	now := time.Now().UTC()
+	var compensating []*models.Entry
+	for rows.Next() {
+		e := &models.Entry{ID: uuid.New(), TransactionID: transactionID, Timestamp: now}
+		if err := rows.Scan(&e.Account, &e.Amount, &e.Currency, &e.Region); err != nil {
+			rows.Close()
+			return err
+		}
+		e.Amount = e.Amount.Neg()
+		compensating = append(compensating, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("transaction not found: %s", id.String())
+	if err := models.ValidateEntries(compensating); err != nil {
+		return err
 	}
+	if err := checkFunds(ctx, sqlTx, &tx, compensating); err != nil {
+		return err
+	}
+	if err := insertEntries(ctx, sqlTx, compensating); err != nil {
+		return err
+	}
+	return applyEntries(ctx, sqlTx, compensating)
+}
 
-	db.logger.Info("Transaction status updated",
-		zap.String("transaction_id", id.String()),
-		zap.String("status", status),
+// ReverseTransaction records reversal, a compensating transaction built with
+// models.NewReversal, against the transaction it references. A zero amount
+// reverses whatever is left of the original. The original is locked, the
+// running total of earlier reversals is checked so it can never be refunded
+// more than once, and the original moves to reversed, all in the same
+// SERIALIZABLE database transaction as the compensating entries.
+// Cross-currency transfers are reversed at their original rate. outbox is
+// called once reversal is filled in, and its messages are stored in the same
+// transaction, as is the completion of the request's idempotency key.
+func (db *DB) ReverseTransaction(ctx context.Context, reversal *models.Transaction, completion *models.IdempotencyCompletion, outbox models.OutboxBuilder) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	if reversal.ReversalOf == nil {
+		return fmt.Errorf("%w: reversal does not reference a transaction", models.ErrNotReversible)
+	}
+	originalID := *reversal.ReversalOf
+	requested := reversal.Amount
+
+	var remaining decimal.Decimal
+	var refunded models.Money
+	if err := db.ExecuteTx(ctx, func(sqlTx *sql.Tx) error {
+		reversal.Amount = requested
+
+		var original models.Transaction
+		query := `
+			SELECT ` + transactionColumns + `
+			FROM transactions
+			WHERE id = $1
+			FOR UPDATE
+		`
+		err := scanTransaction(sqlTx.QueryRowContext(ctx, query, originalID), &original)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", models.ErrTransactionNotFound, originalID.String())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+
+		if !original.CanBeReversed() {
+			return fmt.Errorf("%w: %s is %s", models.ErrNotReversible, originalID.String(), original.Status)
+		}
+
+		// Reversals of cross-currency transfers debit the target currency and
+		// refund the source currency, so the source side is their converted amount
+		var reversed, reversedTarget decimal.Decimal
+		err = sqlTx.QueryRowContext(ctx,
+			"SELECT COALESCE(SUM(COALESCE(converted_amount, amount)), 0), COALESCE(SUM(amount), 0) FROM transactions WHERE reversal_of = $1",
+			originalID,
+		).Scan(&reversed, &reversedTarget)
+		if err != nil {
+			return fmt.Errorf("failed to get reversed amount: %w", err)
+		}
+
+		remaining = original.Amount.Value.Sub(reversed)
+		if reversal.Amount.IsZero() {
+			reversal.Amount.Value = remaining
+		}
+		if !remaining.IsPositive() || reversal.Amount.Value.GreaterThan(remaining) {
+			return fmt.Errorf("%w: requested %s, remaining %s", models.ErrOverRefund, reversal.Amount.Value, remaining)
+		}
+
+		reversal.FromAccount = original.ToAccount
+		reversal.ToAccount = original.FromAccount
+		reversal.Amount.Currency = original.Amount.Currency
+		refunded = reversal.Amount
+		if original.IsFX() {
+			if err := models.ApplyFXReversal(reversal, &original, remaining, reversedTarget); err != nil {
+				return fmt.Errorf("failed to reverse transaction: %w", err)
+			}
+			reversal.Entries = models.NewFXTransferEntries(reversal)
+		} else {
+			reversal.Entries = models.NewTransferEntries(reversal)
+		}
+		if err := models.ValidateEntries(reversal.Entries); err != nil {
+			return fmt.Errorf("failed to reverse transaction: %w", err)
+		}
+
+		if err := checkFunds(ctx, sqlTx, reversal, reversal.Entries); err != nil {
+			return fmt.Errorf("failed to reverse transaction: %w", err)
+		}
+		if err := ensureSystemAccounts(ctx, sqlTx, reversal.Entries); err != nil {
+			return fmt.Errorf("failed to reverse transaction: %w", err)
+		}
+		if err := insertTransaction(ctx, sqlTx, reversal); err != nil {
+			db.logger.Error("Failed to create reversal",
+				zap.Error(err),
+				zap.String("transaction_id", originalID.String()),
+			)
+			return fmt.Errorf("failed to create reversal: %w", err)
+		}
+		if err := insertEntries(ctx, sqlTx, reversal.Entries); err != nil {
+			return fmt.Errorf("failed to create reversal entries: %w", err)
+		}
+		if err := applyEntries(ctx, sqlTx, reversal.Entries); err != nil {
+			return fmt.Errorf("failed to update account balances: %w", err)
+		}
+
+		if original.Status == models.StatusCompleted {
+			_, err := sqlTx.ExecContext(ctx,
+				"UPDATE transactions SET status = $1 WHERE id = $2",
+				models.StatusReversed, originalID,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update transaction status: %w", err)
+			}
+		}
+
+		if err := buildOutbox(ctx, sqlTx, outbox); err != nil {
+			return err
+		}
+		return completeIdempotency(ctx, sqlTx, completion)
+	}); err != nil {
+		return err
+	}
+
+	db.logger.Info("Transaction reversed",
+		zap.String("transaction_id", originalID.String()),
+		zap.String("reversal_id", reversal.ID.String()),
+		zap.String("amount", refunded.String()),
+		zap.String("remaining", remaining.Sub(refunded.Value).String()),
 	)
 
 	return nil
 }
 
 // GetTransactionStats returns statistics about transactions
-func (db *DB) GetTransactionStats() (map[string]interface{}, error) {
+func (db *DB) GetTransactionStats(ctx context.Context) (map[string]interface{}, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
 	stats := make(map[string]interface{})
 
 	// Total transactions
 	var total int
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM transactions").Scan(&total)
+	err := db.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions").Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total transactions: %w", err)
 	}
@@ -180,7 +646,7 @@ func (db *DB) GetTransactionStats() (map[string]interface{}, error) {
 		FROM transactions
 		GROUP BY status
 	`
-	rows, err := db.conn.Query(statusQuery)
+	rows, err := db.conn.QueryContext(ctx, statusQuery)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get status stats: %w", err)
 	}
@@ -203,7 +669,7 @@ func (db *DB) GetTransactionStats() (map[string]interface{}, error) {
 		FROM transactions
 		GROUP BY region
 	`
-	rows, err = db.conn.Query(regionQuery)
+	rows, err = db.conn.QueryContext(ctx, regionQuery)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get region stats: %w", err)
 	}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"testing"
@@ -13,6 +14,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// transactionColumnNames are the columns returned for transactionColumns
+var transactionColumnNames = []string{"id", "region", "amount", "currency", "from_account", "to_account", "status", "timestamp", "reversal_of",
+	"converted_amount", "converted_currency", "fx_rate", "quote_id"}
+
 // setupTestDB creates a mock database connection for testing
 func setupTestDB(t *testing.T) (*DB, sqlmock.Sqlmock, func()) {
 	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
@@ -44,25 +49,47 @@ func TestCreateTransaction_Success(t *testing.T) {
 	tx := &models.Transaction{
 		ID:          txID,
 		Region:      "us-east-1",
-		Amount:      amount,
+		Amount:      models.Money{Value: amount, Currency: "USD"},
 		FromAccount: "acc1",
 		ToAccount:   "acc2",
 		Status:      "pending",
 		Timestamp:   now,
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "region", "amount", "from_account", "to_account", "status", "timestamp"}).
-		AddRow(txID, "us-east-1", amount, "acc1", "acc2", "pending", now)
+	rows := sqlmock.NewRows(transactionColumnNames).
+		AddRow(txID, "us-east-1", amount, "USD", "acc1", "acc2", "pending", now, nil, nil, nil, nil, nil)
 
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "1000.00", "0", "0"))
+	expectCreditedLock(mock, "acc2", "USD")
 	mock.ExpectQuery(`INSERT INTO transactions`).
-		WithArgs(txID, "us-east-1", amount, "acc1", "acc2", "pending", now).
+		WithArgs(txID, "us-east-1", amount, "USD", "acc1", "acc2", "pending", now, nil, nil, nil, nil, nil).
 		WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO entries`).
+		WithArgs(sqlmock.AnyArg(), txID, "acc1", amount.Neg(), "USD", "us-east-1", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO entries`).
+		WithArgs(sqlmock.AnyArg(), txID, "acc2", amount, "USD", "us-east-1", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).
+		WithArgs(amount.Neg(), "acc1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).
+		WithArgs(amount, "acc2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
 
-	err := db.CreateTransaction(tx)
+	err := db.CreateTransaction(context.Background(), tx, nil)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
 
+	if len(tx.Entries) != 2 {
+		t.Errorf("Expected 2 entries to be attached, got %d", len(tx.Entries))
+	}
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %v", err)
 	}
@@ -79,18 +106,24 @@ func TestCreateTransaction_DatabaseError(t *testing.T) {
 	tx := &models.Transaction{
 		ID:          txID,
 		Region:      "us-east-1",
-		Amount:      amount,
+		Amount:      models.Money{Value: amount, Currency: "USD"},
 		FromAccount: "acc1",
 		ToAccount:   "acc2",
 		Status:      "pending",
 		Timestamp:   now,
 	}
 
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "1000.00", "0", "0"))
+	expectCreditedLock(mock, "acc2", "USD")
 	mock.ExpectQuery(`INSERT INTO transactions`).
-		WithArgs(txID, "us-east-1", amount, "acc1", "acc2", "pending", now).
+		WithArgs(txID, "us-east-1", amount, "USD", "acc1", "acc2", "pending", now, nil, nil, nil, nil, nil).
 		WillReturnError(errors.New("database connection failed"))
+	mock.ExpectRollback()
 
-	err := db.CreateTransaction(tx)
+	err := db.CreateTransaction(context.Background(), tx, nil)
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -104,6 +137,387 @@ func TestCreateTransaction_DatabaseError(t *testing.T) {
 	}
 }
 
+func TestCreateTransaction_EntryInsertErrorRollsBack(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+	now := time.Now()
+	amount := decimal.NewFromInt(50)
+
+	tx := &models.Transaction{
+		ID:          txID,
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: amount, Currency: "USD"},
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Status:      "pending",
+		Timestamp:   now,
+	}
+
+	rows := sqlmock.NewRows(transactionColumnNames).
+		AddRow(txID, "us-east-1", amount, "USD", "acc1", "acc2", "pending", now, nil, nil, nil, nil, nil)
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "1000.00", "0", "0"))
+	expectCreditedLock(mock, "acc2", "USD")
+	mock.ExpectQuery(`INSERT INTO transactions`).WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnError(errors.New("entries table unavailable"))
+	mock.ExpectRollback()
+
+	err := db.CreateTransaction(context.Background(), tx, nil)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTransaction_UnknownAccountRollsBack(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+	now := time.Now()
+	amount := decimal.NewFromInt(50)
+
+	tx := &models.Transaction{
+		ID:          txID,
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: amount, Currency: "USD"},
+		FromAccount: "acc1",
+		ToAccount:   "missing",
+		Status:      "pending",
+		Timestamp:   now,
+	}
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "1000.00", "0", "0"))
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err := db.CreateTransaction(context.Background(), tx, nil)
+	if !errors.Is(err, models.ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTransaction_InsufficientFundsRollsBack(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tx := &models.Transaction{
+		ID:          uuid.New(),
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: decimal.NewFromInt(500), Currency: "USD"},
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Status:      "pending",
+		Timestamp:   time.Now(),
+	}
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "100.00", "0", "50.00"))
+	mock.ExpectRollback()
+
+	err := db.CreateTransaction(context.Background(), tx, nil)
+	if !errors.Is(err, models.ErrInsufficientFunds) {
+		t.Errorf("Expected ErrInsufficientFunds, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTransaction_SystemAccountRejected(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tx := &models.Transaction{
+		ID:          uuid.New(),
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: decimal.NewFromInt(500), Currency: "USD"},
+		FromAccount: models.FXPositionAccount("USD"),
+		ToAccount:   "acc2",
+		Status:      "pending",
+		Timestamp:   time.Now(),
+	}
+
+	expectTxBegin(mock)
+	mock.ExpectRollback()
+
+	err := db.CreateTransaction(context.Background(), tx, nil)
+	if !errors.Is(err, models.ErrSystemAccount) {
+		t.Errorf("Expected ErrSystemAccount, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTransaction_UnbalancedEntries(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+	tx := &models.Transaction{
+		ID:          txID,
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: decimal.NewFromInt(100), Currency: "USD"},
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Status:      "pending",
+		Timestamp:   time.Now(),
+		Entries: []*models.Entry{
+			{ID: uuid.New(), TransactionID: txID, Account: "acc1", Amount: decimal.NewFromInt(-100), Currency: "USD"},
+			{ID: uuid.New(), TransactionID: txID, Account: "acc2", Amount: decimal.NewFromInt(99), Currency: "USD"},
+		},
+	}
+
+	// No database calls are expected: the invariant is checked before BEGIN
+	err := db.CreateTransaction(context.Background(), tx, nil)
+	if !errors.Is(err, models.ErrUnbalancedEntries) {
+		t.Errorf("Expected ErrUnbalancedEntries, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTransaction_CommitError(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+	now := time.Now()
+	amount := decimal.NewFromInt(50)
+
+	tx := &models.Transaction{
+		ID:          txID,
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: amount, Currency: "USD"},
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Status:      "pending",
+		Timestamp:   now,
+	}
+
+	rows := sqlmock.NewRows(transactionColumnNames).
+		AddRow(txID, "us-east-1", amount, "USD", "acc1", "acc2", "pending", now, nil, nil, nil, nil, nil)
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc1").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "1000.00", "0", "0"))
+	expectCreditedLock(mock, "acc2", "USD")
+	mock.ExpectQuery(`INSERT INTO transactions`).WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock).WillReturnError(errors.New("commit failed"))
+
+	err := db.CreateTransaction(context.Background(), tx, nil)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// expectPosted expects tx, a pending USD transfer from an account with enough
+// funds, to be posted within an open database transaction. It expects the
+// source account to sort before the destination, as the accounts are locked
+// in ID order.
+func expectPosted(mock sqlmock.Sqlmock, tx *models.Transaction) {
+	amount := tx.Amount.Value
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs(tx.FromAccount).
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "1000.00", "0", "0"))
+	expectCreditedLock(mock, tx.ToAccount, "USD")
+	mock.ExpectQuery(`INSERT INTO transactions`).
+		WithArgs(tx.ID, "us-east-1", amount, "USD", tx.FromAccount, tx.ToAccount, "pending", tx.Timestamp, nil, nil, nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).
+			AddRow(tx.ID, "us-east-1", amount, "USD", tx.FromAccount, tx.ToAccount, "pending", tx.Timestamp, nil, nil, nil, nil, nil))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(amount.Neg(), tx.FromAccount).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WithArgs(amount, tx.ToAccount).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func newBatchTransfer(from, to string, amount int64) *models.Transaction {
+	return &models.Transaction{
+		ID:          uuid.New(),
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: decimal.NewFromInt(amount), Currency: "USD"},
+		FromAccount: from,
+		ToAccount:   to,
+		Status:      "pending",
+		Timestamp:   time.Now(),
+	}
+}
+
+func TestCreateTransactions_Success(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txs := []*models.Transaction{
+		newBatchTransfer("acc1", "acc2", 100),
+		newBatchTransfer("acc2", "acc3", 40),
+	}
+
+	// Both transfers are posted in the same database transaction
+	expectTxBegin(mock)
+	for _, tx := range txs {
+		expectPosted(mock, tx)
+	}
+	expectTxCommit(mock)
+
+	if err := db.CreateTransactions(context.Background(), txs, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for i, tx := range txs {
+		if len(tx.Entries) != 2 {
+			t.Errorf("Expected 2 entries attached to item %d, got %d", i, len(tx.Entries))
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTransactions_ItemFailureRollsBackBatch(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txs := []*models.Transaction{
+		newBatchTransfer("acc1", "acc2", 100),
+		newBatchTransfer("acc3", "acc2", 500),
+	}
+
+	expectTxBegin(mock)
+	expectPosted(mock, txs[0])
+	// acc2 sorts first, so it is locked before the failing debit
+	expectCreditedLock(mock, "acc2", "USD")
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc3").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "100.00", "0", "0"))
+	mock.ExpectRollback()
+
+	err := db.CreateTransactions(context.Background(), txs, nil)
+	if !errors.Is(err, models.ErrInsufficientFunds) {
+		t.Errorf("Expected ErrInsufficientFunds, got: %v", err)
+	}
+
+	var itemErr *models.BatchItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != 1 {
+		t.Errorf("Expected error for item 1, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTransactions_InvalidItemSkipsDatabase(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txs := []*models.Transaction{
+		newBatchTransfer("acc1", "acc2", 100),
+		newBatchTransfer("acc1", "acc2", 0),
+	}
+
+	err := db.CreateTransactions(context.Background(), txs, nil)
+	var itemErr *models.BatchItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != 1 {
+		t.Errorf("Expected error for item 1, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetTransactionEntries_Success(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+	now := time.Now()
+	amount := decimal.NewFromInt(75)
+
+	rows := sqlmock.NewRows([]string{"id", "transaction_id", "account", "amount", "currency", "region", "timestamp"}).
+		AddRow(uuid.New(), txID, "acc1", amount.Neg(), "USD", "us-east-1", now).
+		AddRow(uuid.New(), txID, "acc2", amount, "USD", "us-east-1", now)
+
+	mock.ExpectQuery(`SELECT id, transaction_id, account, amount, currency, region, timestamp`).
+		WithArgs(txID).
+		WillReturnRows(rows)
+
+	entries, err := db.GetTransactionEntries(context.Background(), txID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	if err := models.ValidateEntries(entries); err != nil {
+		t.Errorf("Expected stored entries to be balanced, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetTransactionEntries_DatabaseError(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+
+	mock.ExpectQuery(`SELECT id, transaction_id, account, amount, currency, region, timestamp`).
+		WithArgs(txID).
+		WillReturnError(errors.New("database error"))
+
+	entries, err := db.GetTransactionEntries(context.Background(), txID)
+	if err == nil {
+		t.Error("Expected error, got nil")
+	}
+
+	if entries != nil {
+		t.Errorf("Expected nil entries, got %v", entries)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestGetTransaction_Success(t *testing.T) {
 	db, mock, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -112,14 +526,14 @@ func TestGetTransaction_Success(t *testing.T) {
 	now := time.Now()
 	amount := decimal.NewFromInt(10050).Div(decimal.NewFromInt(100))
 
-	rows := sqlmock.NewRows([]string{"id", "region", "amount", "from_account", "to_account", "status", "timestamp"}).
-		AddRow(txID, "us-east-1", amount, "acc1", "acc2", "pending", now)
+	rows := sqlmock.NewRows(transactionColumnNames).
+		AddRow(txID, "us-east-1", amount, "USD", "acc1", "acc2", "pending", now, nil, nil, nil, nil, nil)
 
-	mock.ExpectQuery(`SELECT id, region, amount, from_account, to_account, status, timestamp`).
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, timestamp`).
 		WithArgs(txID).
 		WillReturnRows(rows)
 
-	tx, err := db.GetTransaction(txID)
+	tx, err := db.GetTransaction(context.Background(), txID)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -147,11 +561,11 @@ func TestGetTransaction_NotFound(t *testing.T) {
 
 	txID := uuid.New()
 
-	mock.ExpectQuery(`SELECT id, region, amount, from_account, to_account, status, timestamp`).
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, timestamp`).
 		WithArgs(txID).
 		WillReturnError(sql.ErrNoRows)
 
-	tx, err := db.GetTransaction(txID)
+	tx, err := db.GetTransaction(context.Background(), txID)
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -176,11 +590,11 @@ func TestGetTransaction_DatabaseError(t *testing.T) {
 
 	txID := uuid.New()
 
-	mock.ExpectQuery(`SELECT id, region, amount, from_account, to_account, status, timestamp`).
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, timestamp`).
 		WithArgs(txID).
 		WillReturnError(errors.New("database error"))
 
-	tx, err := db.GetTransaction(txID)
+	tx, err := db.GetTransaction(context.Background(), txID)
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -204,15 +618,15 @@ func TestListTransactions_Success(t *testing.T) {
 	amount1 := decimal.NewFromInt(10050).Div(decimal.NewFromInt(100))
 	amount2 := decimal.NewFromInt(20000).Div(decimal.NewFromInt(100))
 
-	rows := sqlmock.NewRows([]string{"id", "region", "amount", "from_account", "to_account", "status", "timestamp"}).
-		AddRow(txID1, "us-east-1", amount1, "acc1", "acc2", "pending", now).
-		AddRow(txID2, "eu-central-1", amount2, "acc3", "acc4", "completed", now.Add(time.Hour))
+	rows := sqlmock.NewRows(transactionColumnNames).
+		AddRow(txID1, "us-east-1", amount1, "USD", "acc1", "acc2", "pending", now, nil, nil, nil, nil, nil).
+		AddRow(txID2, "eu-central-1", amount2, "USD", "acc3", "acc4", "completed", now.Add(time.Hour), nil, nil, nil, nil, nil)
 
-	mock.ExpectQuery(`SELECT id, region, amount, from_account, to_account, status, timestamp`).
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, timestamp`).
 		WithArgs(10, 0).
 		WillReturnRows(rows)
 
-	transactions, err := db.ListTransactions(10, 0)
+	transactions, err := db.ListTransactions(context.Background(), 10, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -234,17 +648,86 @@ func TestListTransactions_Success(t *testing.T) {
 	}
 }
 
+func TestSearchTransactions_Cursor(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cursor := models.TransactionCursor{Timestamp: time.Now().UTC(), ID: uuid.New()}
+	txID := uuid.New()
+	amount := decimal.NewFromInt(25)
+
+	rows := sqlmock.NewRows(transactionColumnNames).
+		AddRow(txID, "us-east-1", amount, "USD", "acc1", "acc2", "pending", cursor.Timestamp, nil, nil, nil, nil, nil)
+
+	mock.ExpectQuery(`WHERE \(timestamp, id\) < \(\$1, \$2\)\s+ORDER BY timestamp DESC, id DESC\s+LIMIT \$3`).
+		WithArgs(cursor.Timestamp, cursor.ID, 20).
+		WillReturnRows(rows)
+
+	transactions, err := db.SearchTransactions(context.Background(), models.TransactionQuery{Cursor: &cursor, Limit: 20})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(transactions) != 1 || transactions[0].ID != txID {
+		t.Errorf("Expected transaction %s, got %+v", txID, transactions)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSearchTransactions_Filtered(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	minAmount := decimal.NewFromInt(100)
+
+	mock.ExpectQuery(`WHERE \(from_account = \$1 OR to_account = \$1\) AND status = \$2 AND amount >= \$3 AND timestamp >= \$4\s+`+
+		`ORDER BY amount DESC, id DESC\s+LIMIT \$5 OFFSET \$6`).
+		WithArgs("acc1", "completed", minAmount, from, 25, 0).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames))
+
+	_, err := db.SearchTransactions(context.Background(), models.TransactionQuery{
+		Filter: models.TransactionFilter{Account: "acc1", Status: "completed", MinAmount: &minAmount, From: &from},
+		SortBy: models.SortByAmount,
+		Limit:  25,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSearchTransactions_InvalidQuery(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.SearchTransactions(context.Background(), models.TransactionQuery{SortBy: "from_account; DROP TABLE transactions", Limit: 10})
+	if !errors.Is(err, models.ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestListTransactions_EmptyResult(t *testing.T) {
 	db, mock, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	rows := sqlmock.NewRows([]string{"id", "region", "amount", "from_account", "to_account", "status", "timestamp"})
+	rows := sqlmock.NewRows(transactionColumnNames)
 
-	mock.ExpectQuery(`SELECT id, region, amount, from_account, to_account, status, timestamp`).
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, timestamp`).
 		WithArgs(10, 0).
 		WillReturnRows(rows)
 
-	transactions, err := db.ListTransactions(10, 0)
+	transactions, err := db.ListTransactions(context.Background(), 10, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -262,11 +745,11 @@ func TestListTransactions_DatabaseError(t *testing.T) {
 	db, mock, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	mock.ExpectQuery(`SELECT id, region, amount, from_account, to_account, status, timestamp`).
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, timestamp`).
 		WithArgs(10, 0).
 		WillReturnError(errors.New("database error"))
 
-	transactions, err := db.ListTransactions(10, 0)
+	transactions, err := db.ListTransactions(context.Background(), 10, 0)
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -285,14 +768,14 @@ func TestListTransactions_ScanError(t *testing.T) {
 	defer cleanup()
 
 	// Return rows with invalid data type to cause scan error
-	rows := sqlmock.NewRows([]string{"id", "region", "amount", "from_account", "to_account", "status", "timestamp"}).
-		AddRow("invalid-uuid", "us-east-1", "invalid-amount", "acc1", "acc2", "pending", "invalid-time")
+	rows := sqlmock.NewRows(transactionColumnNames).
+		AddRow("invalid-uuid", "us-east-1", "invalid-amount", "USD", "acc1", "acc2", "pending", "invalid-time", nil, nil, nil, nil, nil)
 
-	mock.ExpectQuery(`SELECT id, region, amount, from_account, to_account, status, timestamp`).
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, timestamp`).
 		WithArgs(10, 0).
 		WillReturnRows(rows)
 
-	transactions, err := db.ListTransactions(10, 0)
+	transactions, err := db.ListTransactions(context.Background(), 10, 0)
 	// The function continues on scan errors, so we should get empty result
 	if err != nil {
 		t.Errorf("Expected no error (scan errors are logged but not returned), got: %v", err)
@@ -312,15 +795,15 @@ func TestListTransactions_RowsError(t *testing.T) {
 	db, mock, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	rows := sqlmock.NewRows([]string{"id", "region", "amount", "from_account", "to_account", "status", "timestamp"}).
-		AddRow(uuid.New(), "us-east-1", decimal.NewFromInt(100), "acc1", "acc2", "pending", time.Now()).
+	rows := sqlmock.NewRows(transactionColumnNames).
+		AddRow(uuid.New(), "us-east-1", decimal.NewFromInt(100), "USD", "acc1", "acc2", "pending", time.Now(), nil, nil, nil, nil, nil).
 		RowError(0, errors.New("row error"))
 
-	mock.ExpectQuery(`SELECT id, region, amount, from_account, to_account, status, timestamp`).
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, timestamp`).
 		WithArgs(10, 0).
 		WillReturnRows(rows)
 
-	transactions, err := db.ListTransactions(10, 0)
+	transactions, err := db.ListTransactions(context.Background(), 10, 0)
 	if err == nil {
 		t.Error("Expected error from rows.Err(), got nil")
 	}
@@ -339,12 +822,18 @@ func TestUpdateTransactionStatus_Success(t *testing.T) {
 	defer cleanup()
 
 	txID := uuid.New()
+	message := models.NewOutboxMessage("us-east-1", "acc1", []byte(`{"type":"transaction.status_changed"}`))
 
-	mock.ExpectExec(`UPDATE transactions`).
-		WithArgs("completed", txID).
+	expectTxBegin(mock)
+	mock.ExpectExec(`UPDATE transactions\s+SET status = \$1\s+WHERE id = \$2 AND status = \$3`).
+		WithArgs("completed", txID, "pending").
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(message.ID, "us-east-1", models.OutboxKindMessage, nil, "acc1", message.Payload, message.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
 
-	err := db.UpdateTransactionStatus(txID, "completed")
+	err := db.UpdateTransactionStatus(context.Background(), txID, "pending", "completed", message)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -360,11 +849,16 @@ func TestUpdateTransactionStatus_NotFound(t *testing.T) {
 
 	txID := uuid.New()
 
+	expectTxBegin(mock)
 	mock.ExpectExec(`UPDATE transactions`).
-		WithArgs("completed", txID).
+		WithArgs("completed", txID, "pending").
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status FROM transactions`).
+		WithArgs(txID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
 
-	err := db.UpdateTransactionStatus(txID, "completed")
+	err := db.UpdateTransactionStatus(context.Background(), txID, "pending", "completed")
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -379,17 +873,144 @@ func TestUpdateTransactionStatus_NotFound(t *testing.T) {
 	}
 }
 
+func TestUpdateTransactionStatus_ConcurrentChange(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+
+	expectTxBegin(mock)
+	mock.ExpectExec(`UPDATE transactions`).
+		WithArgs("completed", txID, "pending").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT status FROM transactions`).
+		WithArgs(txID).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("cancelled"))
+	mock.ExpectRollback()
+
+	err := db.UpdateTransactionStatus(context.Background(), txID, "pending", "completed")
+	if !errors.Is(err, models.ErrStatusConflict) {
+		t.Errorf("Expected ErrStatusConflict, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateTransactionStatus_InvalidTransition(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Rejected before touching the database
+	err := db.UpdateTransactionStatus(context.Background(), uuid.New(), "failed", "completed")
+	if !errors.Is(err, models.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// expectReleasedTransaction expects releaseEntries to read the acc1 to acc2
+// transfer it releases
+func expectReleasedTransaction(mock sqlmock.Sqlmock, txID uuid.UUID, amount decimal.Decimal) {
+	mock.ExpectQuery(`SELECT id, region, amount`).
+		WithArgs(txID).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).
+			AddRow(txID, "us-east-1", amount, "USD", "acc1", "acc2", "failed", time.Now(), nil, nil, nil, nil, nil))
+}
+
+func TestUpdateTransactionStatus_FailedReleasesFunds(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+	amount := decimal.NewFromInt(40)
+
+	expectTxBegin(mock)
+	mock.ExpectExec(`UPDATE transactions`).
+		WithArgs("failed", txID, "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectReleasedTransaction(mock, txID, amount)
+	mock.ExpectQuery(`SELECT account, amount, currency, region\s+FROM entries`).
+		WithArgs(txID).
+		WillReturnRows(sqlmock.NewRows([]string{"account", "amount", "currency", "region"}).
+			AddRow("acc1", amount.Neg(), "USD", "us-east-1").
+			AddRow("acc2", amount, "USD", "us-east-1"))
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc2").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "40.00", "0", "0"))
+	mock.ExpectExec(`INSERT INTO entries`).
+		WithArgs(sqlmock.AnyArg(), txID, "acc1", amount, "USD", "us-east-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO entries`).
+		WithArgs(sqlmock.AnyArg(), txID, "acc2", amount.Neg(), "USD", "us-east-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).
+		WithArgs(amount, "acc1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).
+		WithArgs(amount.Neg(), "acc2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	if err := db.UpdateTransactionStatus(context.Background(), txID, "pending", "failed"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateTransactionStatus_ReleaseChecksFunds(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	txID := uuid.New()
+	amount := decimal.NewFromInt(40)
+
+	expectTxBegin(mock)
+	mock.ExpectExec(`UPDATE transactions`).
+		WithArgs("failed", txID, "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectReleasedTransaction(mock, txID, amount)
+	mock.ExpectQuery(`SELECT account, amount, currency, region\s+FROM entries`).
+		WithArgs(txID).
+		WillReturnRows(sqlmock.NewRows([]string{"account", "amount", "currency", "region"}).
+			AddRow("acc1", amount.Neg(), "USD", "us-east-1").
+			AddRow("acc2", amount, "USD", "us-east-1"))
+	// The recipient has already spent the money
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc2").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "10.00", "0", "0"))
+	mock.ExpectRollback()
+
+	err := db.UpdateTransactionStatus(context.Background(), txID, "pending", "failed")
+	if !errors.Is(err, models.ErrInsufficientFunds) {
+		t.Errorf("Expected ErrInsufficientFunds, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestUpdateTransactionStatus_DatabaseError(t *testing.T) {
 	db, mock, cleanup := setupTestDB(t)
 	defer cleanup()
 
 	txID := uuid.New()
 
+	expectTxBegin(mock)
 	mock.ExpectExec(`UPDATE transactions`).
-		WithArgs("completed", txID).
+		WithArgs("completed", txID, "pending").
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	err := db.UpdateTransactionStatus(txID, "completed")
+	err := db.UpdateTransactionStatus(context.Background(), txID, "pending", "completed")
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -406,11 +1027,13 @@ func TestUpdateTransactionStatus_RowsAffectedError(t *testing.T) {
 	txID := uuid.New()
 
 	result := sqlmock.NewErrorResult(errors.New("rows affected error"))
+	expectTxBegin(mock)
 	mock.ExpectExec(`UPDATE transactions`).
-		WithArgs("completed", txID).
+		WithArgs("completed", txID, "pending").
 		WillReturnResult(result)
+	mock.ExpectRollback()
 
-	err := db.UpdateTransactionStatus(txID, "completed")
+	err := db.UpdateTransactionStatus(context.Background(), txID, "pending", "completed")
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -420,6 +1043,223 @@ func TestUpdateTransactionStatus_RowsAffectedError(t *testing.T) {
 	}
 }
 
+func TestUpdateTransactionStatus_ReversedRequiresReversal(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	err := db.UpdateTransactionStatus(context.Background(), uuid.New(), "completed", "reversed")
+	if !errors.Is(err, models.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+// expectLockOriginal expects the original transaction to be locked and its
+// earlier reversals summed
+func expectLockOriginal(mock sqlmock.Sqlmock, original *models.Transaction, reversed decimal.Decimal) {
+	mock.ExpectQuery(`SELECT id, region, amount, currency, from_account, to_account, status, timestamp, reversal_of, converted_amount, converted_currency, fx_rate, quote_id\s+FROM transactions\s+WHERE id = \$1\s+FOR UPDATE`).
+		WithArgs(original.ID).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).AddRow(
+			original.ID, original.Region, original.Amount.Value, "USD", original.FromAccount,
+			original.ToAccount, original.Status, original.Timestamp, nil, nil, nil, nil, nil,
+		))
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(COALESCE\(converted_amount, amount\)\), 0\), COALESCE\(SUM\(amount\), 0\) FROM transactions WHERE reversal_of = \$1`).
+		WithArgs(original.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"reversed", "reversed_target"}).AddRow(reversed, reversed))
+}
+
+func newCompletedTransaction() *models.Transaction {
+	return &models.Transaction{
+		ID:          uuid.New(),
+		Region:      "us-east-1",
+		Amount:      models.Money{Value: decimal.NewFromInt(100), Currency: "USD"},
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Status:      "completed",
+		Timestamp:   time.Now().UTC(),
+	}
+}
+
+func TestReverseTransaction_Full(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	original := newCompletedTransaction()
+	reversal := models.NewReversal(original, decimal.Zero, "eu-central-1")
+
+	expectTxBegin(mock)
+	expectLockOriginal(mock, original, decimal.Zero)
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc2").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "100.00", "0", "0"))
+	mock.ExpectQuery(`INSERT INTO transactions`).
+		WithArgs(reversal.ID, "eu-central-1", original.Amount.Value, "USD", "acc2", "acc1", "completed", sqlmock.AnyArg(), original.ID, nil, nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).AddRow(
+			reversal.ID, "eu-central-1", original.Amount.Value, "USD", "acc2", "acc1", "completed", reversal.Timestamp, original.ID.String(), nil, nil, nil, nil,
+		))
+	mock.ExpectExec(`INSERT INTO entries`).
+		WithArgs(sqlmock.AnyArg(), reversal.ID, "acc2", original.Amount.Value.Neg(), "USD", "eu-central-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO entries`).
+		WithArgs(sqlmock.AnyArg(), reversal.ID, "acc1", original.Amount.Value, "USD", "eu-central-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).
+		WithArgs(original.Amount.Value.Neg(), "acc2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).
+		WithArgs(original.Amount.Value, "acc1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE transactions SET status = \$1 WHERE id = \$2`).
+		WithArgs("reversed", original.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	// The outbox is built once the amount left to reverse is known
+	var described models.Money
+	outbox := func() ([]*models.OutboxMessage, error) {
+		described = reversal.Amount
+		return []*models.OutboxMessage{models.NewOutboxMessage("eu-central-1", "acc1", []byte(`{}`))}, nil
+	}
+	if err := db.ReverseTransaction(context.Background(), reversal, nil, outbox); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !described.Equal(original.Amount) {
+		t.Errorf("Expected the outbox to describe the full amount %s, got %s", original.Amount, described)
+	}
+
+	if !reversal.Amount.Equal(original.Amount) {
+		t.Errorf("Expected reversal of the full amount %s, got %s", original.Amount, reversal.Amount)
+	}
+	if reversal.ReversalOf == nil || *reversal.ReversalOf != original.ID {
+		t.Errorf("Expected reversal to reference %s, got %v", original.ID, reversal.ReversalOf)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReverseTransaction_PartialOfAlreadyReversed(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	original := newCompletedTransaction()
+	original.Status = "reversed"
+	amount := decimal.NewFromInt(30)
+	reversal := models.NewReversal(original, amount, "us-east-1")
+
+	expectTxBegin(mock)
+	expectLockOriginal(mock, original, decimal.NewFromInt(60))
+	mock.ExpectQuery(`SELECT status, currency, balance, held, overdraft_limit`).
+		WithArgs("acc2").
+		WillReturnRows(sqlmock.NewRows(fundsColumns).AddRow("active", "USD", "100.00", "0", "0"))
+	mock.ExpectQuery(`INSERT INTO transactions`).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).AddRow(
+			reversal.ID, "us-east-1", amount, "USD", "acc2", "acc1", "completed", reversal.Timestamp, original.ID.String(), nil, nil, nil, nil,
+		))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO entries`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE accounts`).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Already reversed, so the status is left alone
+	expectTxCommit(mock)
+
+	if err := db.ReverseTransaction(context.Background(), reversal, nil, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReverseTransaction_OverRefund(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   decimal.Decimal
+		reversed decimal.Decimal
+	}{
+		{"more than original", decimal.NewFromInt(101), decimal.Zero},
+		{"more than remaining", decimal.NewFromInt(50), decimal.NewFromInt(60)},
+		{"nothing left", decimal.Zero, decimal.NewFromInt(100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			original := newCompletedTransaction()
+			original.Status = "reversed"
+
+			expectTxBegin(mock)
+			expectLockOriginal(mock, original, tt.reversed)
+			mock.ExpectRollback()
+
+			err := db.ReverseTransaction(context.Background(), models.NewReversal(original, tt.amount, "us-east-1"), nil, nil)
+			if !errors.Is(err, models.ErrOverRefund) {
+				t.Errorf("Expected ErrOverRefund, got: %v", err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestReverseTransaction_NotReversible(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	original := newCompletedTransaction()
+	original.Status = "pending"
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT id, region, amount`).
+		WithArgs(original.ID).
+		WillReturnRows(sqlmock.NewRows(transactionColumnNames).AddRow(
+			original.ID, original.Region, original.Amount.Value, "USD", original.FromAccount,
+			original.ToAccount, original.Status, original.Timestamp, nil, nil, nil, nil, nil,
+		))
+	mock.ExpectRollback()
+
+	err := db.ReverseTransaction(context.Background(), models.NewReversal(original, decimal.Zero, "us-east-1"), nil, nil)
+	if !errors.Is(err, models.ErrNotReversible) {
+		t.Errorf("Expected ErrNotReversible, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReverseTransaction_NotFound(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	original := newCompletedTransaction()
+
+	expectTxBegin(mock)
+	mock.ExpectQuery(`SELECT id, region, amount`).
+		WithArgs(original.ID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err := db.ReverseTransaction(context.Background(), models.NewReversal(original, decimal.Zero, "us-east-1"), nil, nil)
+	if !errors.Is(err, models.ErrTransactionNotFound) {
+		t.Errorf("Expected ErrTransactionNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 func TestGetTransactionStats_Success(t *testing.T) {
 	db, mock, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -443,7 +1283,7 @@ func TestGetTransactionStats_Success(t *testing.T) {
 	mock.ExpectQuery(`SELECT region, COUNT\(\*\) as count`).
 		WillReturnRows(regionRows)
 
-	stats, err := db.GetTransactionStats()
+	stats, err := db.GetTransactionStats(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -490,7 +1330,7 @@ func TestGetTransactionStats_TotalQueryError(t *testing.T) {
 	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transactions`).
 		WillReturnError(errors.New("database error"))
 
-	stats, err := db.GetTransactionStats()
+	stats, err := db.GetTransactionStats(context.Background())
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -517,7 +1357,7 @@ func TestGetTransactionStats_StatusQueryError(t *testing.T) {
 	mock.ExpectQuery(`SELECT status, COUNT\(\*\) as count`).
 		WillReturnError(errors.New("database error"))
 
-	stats, err := db.GetTransactionStats()
+	stats, err := db.GetTransactionStats(context.Background())
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -550,7 +1390,7 @@ func TestGetTransactionStats_RegionQueryError(t *testing.T) {
 	mock.ExpectQuery(`SELECT region, COUNT\(\*\) as count`).
 		WillReturnError(errors.New("database error"))
 
-	stats, err := db.GetTransactionStats()
+	stats, err := db.GetTransactionStats(context.Background())
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -583,7 +1423,7 @@ func TestGetTransactionStats_EmptyResults(t *testing.T) {
 	mock.ExpectQuery(`SELECT region, COUNT\(\*\) as count`).
 		WillReturnRows(regionRows)
 
-	stats, err := db.GetTransactionStats()
+	stats, err := db.GetTransactionStats(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -633,7 +1473,7 @@ func TestGetTransactionStats_ScanErrorInStatus(t *testing.T) {
 	mock.ExpectQuery(`SELECT region, COUNT\(\*\) as count`).
 		WillReturnRows(regionRows)
 
-	stats, err := db.GetTransactionStats()
+	stats, err := db.GetTransactionStats(context.Background())
 	// Function should still succeed, just skip invalid rows
 	if err != nil {
 		t.Errorf("Expected no error (scan errors are skipped), got: %v", err)
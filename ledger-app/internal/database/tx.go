@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Retry settings for ExecuteTx
+var (
+	// txMaxAttempts is how many times a conflicting transaction is tried
+	txMaxAttempts = 10
+	// txRetryBaseDelay is the backoff before the first retry; it doubles with
+	// every further retry up to txRetryMaxDelay
+	txRetryBaseDelay = 10 * time.Millisecond
+	txRetryMaxDelay  = time.Second
+)
+
+// retryableErrorCode is the SQLSTATE CockroachDB returns when a transaction
+// lost a serialization conflict and can be retried
+const retryableErrorCode = "40001"
+
+// ExecuteTx runs fn in a SERIALIZABLE transaction and commits it. When
+// CockroachDB aborts the work with a retryable serialization error, fn is run
+// again after a backoff using the SAVEPOINT cockroach_restart protocol, which
+// keeps the transaction's priority so it eventually wins against the
+// transactions it conflicts with. fn may therefore run more than once and
+// must not keep state from an earlier attempt. Errors from fn are returned
+// unchanged.
+//
+// Writes that are a single statement run as implicit transactions instead,
+// which CockroachDB retries on the server without involving the client.
+func (db *DB) ExecuteTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	sqlTx, err := db.conn.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		db.logger.Error("Failed to begin database transaction", zap.Error(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer sqlTx.Rollback()
+
+	if _, err := sqlTx.ExecContext(ctx, "SAVEPOINT cockroach_restart"); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(sqlTx)
+		if err == nil {
+			if _, err = sqlTx.ExecContext(ctx, "RELEASE SAVEPOINT cockroach_restart"); err == nil {
+				if err := sqlTx.Commit(); err != nil {
+					db.logger.Error("Failed to commit database transaction", zap.Error(err))
+					return fmt.Errorf("failed to commit transaction: %w", err)
+				}
+				return nil
+			}
+			if !isRetryable(err) {
+				return fmt.Errorf("failed to commit transaction: %w", err)
+			}
+		}
+
+		if !isRetryable(err) {
+			return err
+		}
+		if attempt == txMaxAttempts {
+			db.logger.Error("Giving up on conflicting transaction", zap.Error(err), zap.Int("attempts", attempt))
+			return fmt.Errorf("transaction still conflicting after %d attempts: %w", attempt, err)
+		}
+
+		db.logger.Warn("Retrying transaction after serialization conflict", zap.Error(err), zap.Int("attempt", attempt))
+		if _, err := sqlTx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT cockroach_restart"); err != nil {
+			return fmt.Errorf("failed to restart transaction: %w", err)
+		}
+		if err := sleepContext(ctx, retryDelay(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// isRetryable reports whether err is a serialization conflict that the
+// transaction can be retried after
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == retryableErrorCode
+}
+
+// retryDelay returns a jittered exponential backoff for the given attempt
+func retryDelay(attempt int) time.Duration {
+	delay := txRetryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > txRetryMaxDelay {
+		delay = txRetryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
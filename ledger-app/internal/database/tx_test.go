@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// expectTxBegin expects ExecuteTx to open a transaction and its restart savepoint
+func expectTxBegin(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`^SAVEPOINT cockroach_restart$`).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// expectTxCommit expects ExecuteTx to release the restart savepoint and commit
+func expectTxCommit(mock sqlmock.Sqlmock) *sqlmock.ExpectedCommit {
+	mock.ExpectExec(`^RELEASE SAVEPOINT cockroach_restart$`).WillReturnResult(sqlmock.NewResult(0, 0))
+	return mock.ExpectCommit()
+}
+
+// expectTxRestart expects ExecuteTx to roll back to the restart savepoint
+func expectTxRestart(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`^ROLLBACK TO SAVEPOINT cockroach_restart$`).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// fastTxRetries shortens the ExecuteTx backoff for the duration of a test
+func fastTxRetries(t *testing.T) {
+	baseDelay, maxDelay := txRetryBaseDelay, txRetryMaxDelay
+	txRetryBaseDelay, txRetryMaxDelay = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { txRetryBaseDelay, txRetryMaxDelay = baseDelay, maxDelay })
+}
+
+var errSerialization = &pq.Error{Code: "40001", Message: "restart transaction: TransactionRetryWithProtoRefreshError"}
+
+func TestExecuteTx_Commits(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	expectTxBegin(mock)
+	mock.ExpectExec(`UPDATE accounts`).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	err := db.ExecuteTx(context.Background(), func(sqlTx *sql.Tx) error {
+		_, err := sqlTx.Exec(`UPDATE accounts SET balance = 1`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExecuteTx_RetriesSerializationError(t *testing.T) {
+	fastTxRetries(t)
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	expectTxBegin(mock)
+	mock.ExpectExec(`UPDATE accounts`).WillReturnError(errSerialization)
+	expectTxRestart(mock)
+	mock.ExpectExec(`UPDATE accounts`).WillReturnResult(sqlmock.NewResult(0, 1))
+	// The conflict can also surface when the savepoint is released
+	mock.ExpectExec(`^RELEASE SAVEPOINT cockroach_restart$`).WillReturnError(errSerialization)
+	expectTxRestart(mock)
+	mock.ExpectExec(`UPDATE accounts`).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTxCommit(mock)
+
+	attempts := 0
+	err := db.ExecuteTx(context.Background(), func(sqlTx *sql.Tx) error {
+		attempts++
+		_, err := sqlTx.Exec(`UPDATE accounts SET balance = 1`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExecuteTx_DoesNotRetryOtherErrors(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fnErr := errors.New("insufficient funds")
+	expectTxBegin(mock)
+	mock.ExpectRollback()
+
+	attempts := 0
+	err := db.ExecuteTx(context.Background(), func(sqlTx *sql.Tx) error {
+		attempts++
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("Expected the error from fn, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExecuteTx_GivesUp(t *testing.T) {
+	fastTxRetries(t)
+	maxAttempts := txMaxAttempts
+	txMaxAttempts = 2
+	t.Cleanup(func() { txMaxAttempts = maxAttempts })
+
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	expectTxBegin(mock)
+	mock.ExpectExec(`UPDATE accounts`).WillReturnError(errSerialization)
+	expectTxRestart(mock)
+	mock.ExpectExec(`UPDATE accounts`).WillReturnError(errSerialization)
+	mock.ExpectRollback()
+
+	err := db.ExecuteTx(context.Background(), func(sqlTx *sql.Tx) error {
+		_, err := sqlTx.Exec(`UPDATE accounts SET balance = 1`)
+		return err
+	})
+	if !isRetryable(err) {
+		t.Errorf("Expected the serialization error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
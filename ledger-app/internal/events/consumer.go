@@ -0,0 +1,407 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// receiveRetryDelay is how long the consumer waits after a failed receive
+var receiveRetryDelay = 5 * time.Second
+
+// maxAckBatch is the most messages the consumer acks in one batch
+const maxAckBatch = 10
+
+// HandlerFunc handles an event of one type. The message is acked when it
+// returns nil and delivered again after the visibility timeout otherwise.
+type HandlerFunc func(ctx context.Context, event *Envelope) error
+
+// TxHandlerFunc handles an event of one type within the database
+// transaction that records the event as processed
+type TxHandlerFunc func(ctx context.Context, tx *sql.Tx, event *Envelope) error
+
+// ProcessedStore records the events the consumer has handled. ProcessEvent
+// runs fn in a transaction that records eventID, and returns
+// models.ErrEventAlreadyProcessed without running fn if it was recorded
+// before.
+type ProcessedStore interface {
+	ProcessEvent(ctx context.Context, eventID uuid.UUID, eventType string, fn func(*sql.Tx) error) error
+}
+
+// ConsumerConfig holds consumer configuration
+type ConsumerConfig struct {
+	// Workers is how many messages are handled at once
+	Workers int
+	// VisibilityTimeout is how long a message stays hidden from other
+	// consumers. It is extended while a handler is still working on it.
+	VisibilityTimeout time.Duration
+}
+
+// Consumer receives messages from a Subscriber and hands them to the handler
+// registered for their event type on a pool of workers. Deliveries with an
+// ordering key always go to the same worker, so they are handled in order.
+type Consumer struct {
+	subscriber Subscriber
+	handlers   map[string]HandlerFunc
+	workers    int
+	visibility time.Duration
+	logger     *zap.Logger
+
+	// acks queues the handled messages of a subscriber that acks in batches
+	acks chan pendingAck
+
+	stopOnce sync.Once
+	stopping chan struct{}
+	done     chan struct{}
+}
+
+// pendingAck is a handled message waiting to be acked
+type pendingAck struct {
+	delivery *Delivery
+	logger   *zap.Logger
+}
+
+// NewConsumer creates a consumer of subscriber's messages
+func NewConsumer(subscriber Subscriber, config ConsumerConfig, logger *zap.Logger) *Consumer {
+	workers := config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	visibility := config.VisibilityTimeout
+	if visibility < time.Second {
+		visibility = 30 * time.Second
+	}
+
+	return &Consumer{
+		subscriber: subscriber,
+		handlers:   make(map[string]HandlerFunc),
+		workers:    workers,
+		visibility: visibility,
+		logger:     logger,
+		stopping:   make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Handle registers the handler of an event type. It must be called before Run.
+func (c *Consumer) Handle(eventType string, handler HandlerFunc) {
+	c.handlers[eventType] = handler
+}
+
+// HandleOnce registers the handler of an event type that runs within the
+// transaction recording the event in store. Its writes commit only once per
+// event: an event delivered again after they committed, because its ack
+// failed or another consumer received it too, is acked without running the
+// handler. It must be called before Run.
+func (c *Consumer) HandleOnce(eventType string, store ProcessedStore, handler TxHandlerFunc) {
+	c.Handle(eventType, func(ctx context.Context, event *Envelope) error {
+		err := store.ProcessEvent(ctx, event.EventID, event.Type, func(tx *sql.Tx) error {
+			return handler(ctx, tx, event)
+		})
+		if errors.Is(err, models.ErrEventAlreadyProcessed) {
+			c.logger.Info("Skipping event already processed",
+				zap.String("event_id", event.EventID.String()),
+				zap.String("event_type", event.Type),
+			)
+			return nil
+		}
+		return err
+	})
+}
+
+// Run receives and handles messages until Shutdown is called or ctx is
+// cancelled, and returns once every received message has been handled.
+// Cancelling ctx also cancels the handlers still running.
+func (c *Consumer) Run(ctx context.Context) {
+	defer close(c.done)
+
+	if acker, ok := c.subscriber.(BatchAcker); ok {
+		c.acks = make(chan pendingAck, c.workers)
+		acked := make(chan struct{})
+		go func() {
+			defer close(acked)
+			c.ackBatches(ctx, acker, c.acks)
+		}()
+		defer func() {
+			close(c.acks)
+			<-acked
+		}()
+	}
+
+	deliveries := make(chan *Delivery)
+	ordered := make([]chan *Delivery, c.workers)
+	var wg sync.WaitGroup
+	for i := range ordered {
+		ordered[i] = make(chan *Delivery)
+		wg.Add(1)
+		go func(ordered <-chan *Delivery) {
+			defer wg.Done()
+			c.work(ctx, deliveries, ordered)
+		}(ordered[i])
+	}
+
+	c.receive(ctx, deliveries, ordered)
+	close(deliveries)
+	for _, ch := range ordered {
+		close(ch)
+	}
+	wg.Wait()
+}
+
+// work processes deliveries from the shared channel and from the worker's
+// own channel of ordered deliveries until both are closed. Once an ordered
+// delivery fails, the later ones with its key from the same receive are
+// released unhandled, so they are delivered again after it.
+func (c *Consumer) work(ctx context.Context, deliveries, ordered <-chan *Delivery) {
+	failed := make(map[string]bool)
+	batch := 0
+	for deliveries != nil || ordered != nil {
+		select {
+		case delivery, ok := <-deliveries:
+			if !ok {
+				deliveries = nil
+				continue
+			}
+			c.process(ctx, delivery)
+		case delivery, ok := <-ordered:
+			if !ok {
+				ordered = nil
+				continue
+			}
+			if delivery.batch != batch {
+				batch = delivery.batch
+				failed = make(map[string]bool)
+			}
+			if failed[delivery.OrderingKey] {
+				c.release([]*Delivery{delivery})
+				continue
+			}
+			if !c.process(ctx, delivery) {
+				failed[delivery.OrderingKey] = true
+			}
+		}
+	}
+}
+
+// Shutdown stops receiving messages and waits for the messages in flight to
+// be handled, or for ctx to be done
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	c.stopOnce.Do(func() { close(c.stopping) })
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// receive passes received messages to the workers until the consumer stops,
+// ordered ones to the worker their key belongs to. Messages received but not
+// yet handed to a worker are released for other consumers to receive
+// straight away.
+func (c *Consumer) receive(ctx context.Context, deliveries chan<- *Delivery, ordered []chan *Delivery) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.stopping:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for batch := 1; ctx.Err() == nil; batch++ {
+		// Receive no more than the workers can start on, so messages don't
+		// wait invisible for a free worker
+		received, err := c.subscriber.Receive(ctx, c.workers, c.visibility)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Warn("Failed to receive messages", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(receiveRetryDelay):
+			}
+			continue
+		}
+
+		for i, delivery := range received {
+			delivery.batch = batch
+			out := deliveries
+			if delivery.OrderingKey != "" {
+				out = ordered[workerOf(delivery.OrderingKey, len(ordered))]
+			}
+			select {
+			case out <- delivery:
+			case <-ctx.Done():
+				c.release(received[i:])
+				return
+			}
+		}
+	}
+}
+
+// workerOf returns the worker that handles the deliveries with key
+func workerOf(key string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// release makes messages available to other consumers again
+func (c *Consumer) release(deliveries []*Delivery) {
+	ctx := context.Background()
+	for _, delivery := range deliveries {
+		if err := c.subscriber.Release(ctx, delivery, 0); err != nil {
+			c.logger.Warn("Failed to release message",
+				zap.Error(err),
+				zap.String("event_id", delivery.Envelope.EventID.String()),
+			)
+		}
+	}
+}
+
+// process runs the handler of an event's type and acks the message once it
+// succeeds. Events of a known type without a handler are acked straight away.
+// It reports whether the message was acked.
+func (c *Consumer) process(ctx context.Context, delivery *Delivery) bool {
+	event := delivery.Envelope
+	logger := c.logger.With(
+		zap.String("event_id", event.EventID.String()),
+		zap.String("event_type", event.Type),
+		zap.Int("schema_version", event.SchemaVersion),
+		zap.String("subject", event.Subject),
+		zap.Int("receive_count", delivery.ReceiveCount),
+	)
+	logger.Info("Processing message")
+
+	handler, ok := c.handlers[event.Type]
+	if !ok {
+		if !IsKnownType(event.Type) {
+			// Leave it for a consumer that knows the event type, such as a
+			// newer release mid-deploy
+			logger.Warn("Unknown event type")
+			c.retry(ctx, delivery, logger)
+			return false
+		}
+		// Nothing here reacts to this type of event
+		logger.Debug("No handler for event type")
+		c.ack(ctx, delivery, logger)
+		return true
+	}
+
+	stopHeartbeat := c.heartbeat(ctx, delivery, logger)
+	err := handler(ctx, event)
+	stopHeartbeat()
+
+	if err != nil {
+		logger.Warn("Failed to process message", zap.Error(err))
+		c.retry(ctx, delivery, logger)
+		return false
+	}
+
+	c.ack(ctx, delivery, logger)
+	return true
+}
+
+// ack acks a handled message, in a batch if the subscriber takes batches
+func (c *Consumer) ack(ctx context.Context, delivery *Delivery, logger *zap.Logger) {
+	if c.acks != nil {
+		c.acks <- pendingAck{delivery: delivery, logger: logger}
+		return
+	}
+	// Acking must not be cancelled along with a handler that finished
+	logAck(logger, c.subscriber.Ack(context.WithoutCancel(ctx), delivery))
+}
+
+// ackBatches acks the messages queued on acks until it is closed. It takes
+// whatever is queued, up to maxAckBatch messages, so acks are only batched
+// while an earlier batch is being acked.
+func (c *Consumer) ackBatches(ctx context.Context, acker BatchAcker, acks <-chan pendingAck) {
+	for first := range acks {
+		batch := []pendingAck{first}
+	queued:
+		for len(batch) < maxAckBatch {
+			select {
+			case next, ok := <-acks:
+				if !ok {
+					break queued
+				}
+				batch = append(batch, next)
+			default:
+				break queued
+			}
+		}
+
+		deliveries := make([]*Delivery, len(batch))
+		for i, pending := range batch {
+			deliveries[i] = pending.delivery
+		}
+		errs := EntryErrors(acker.AckBatch(context.WithoutCancel(ctx), deliveries), len(batch))
+		for i, pending := range batch {
+			logAck(pending.logger, errs[i])
+		}
+	}
+}
+
+// logAck logs the outcome of acking a handled message
+func logAck(logger *zap.Logger, err error) {
+	if err != nil {
+		// The message is delivered again and handled a second time
+		logger.Error("Failed to ack message after processing", zap.Error(err))
+		return
+	}
+	logger.Info("Message acked after processing")
+}
+
+// retry hands a message back to be delivered again once the visibility
+// timeout has passed
+func (c *Consumer) retry(ctx context.Context, delivery *Delivery, logger *zap.Logger) {
+	if delivery.FinalDelivery {
+		logger.Error("Message is moving to the dead-letter queue")
+	}
+	if err := c.subscriber.Release(context.WithoutCancel(ctx), delivery, c.visibility); err != nil {
+		logger.Warn("Failed to release message", zap.Error(err))
+	}
+}
+
+// heartbeat keeps a message hidden from other consumers while its handler
+// runs, by extending its visibility timeout every third of the timeout. The
+// returned function stops it.
+func (c *Consumer) heartbeat(ctx context.Context, delivery *Delivery, logger *zap.Logger) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(c.visibility / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := c.subscriber.Extend(ctx, delivery, c.visibility); err != nil && ctx.Err() == nil {
+				logger.Warn("Failed to extend message visibility", zap.Error(err))
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
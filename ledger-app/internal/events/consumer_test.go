@@ -0,0 +1,503 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// recordingBroker is a MemoryBroker that records acks, extensions and
+// releases
+type recordingBroker struct {
+	*MemoryBroker
+	mu       sync.Mutex
+	acked    []string
+	extended chan string
+	released []time.Duration
+}
+
+func newRecordingBroker() *recordingBroker {
+	return &recordingBroker{MemoryBroker: NewMemoryBroker(), extended: make(chan string, 10)}
+}
+
+func (b *recordingBroker) Ack(ctx context.Context, delivery *Delivery) error {
+	b.mu.Lock()
+	b.acked = append(b.acked, delivery.Envelope.Subject)
+	b.mu.Unlock()
+	return b.MemoryBroker.Ack(ctx, delivery)
+}
+
+func (b *recordingBroker) Extend(ctx context.Context, delivery *Delivery, visibility time.Duration) error {
+	b.extended <- delivery.Envelope.Subject
+	return nil
+}
+
+func (b *recordingBroker) Release(ctx context.Context, delivery *Delivery, delay time.Duration) error {
+	b.mu.Lock()
+	b.released = append(b.released, delay)
+	b.mu.Unlock()
+	return b.MemoryBroker.Release(ctx, delivery, delay)
+}
+
+func (b *recordingBroker) ackedCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.acked)
+}
+
+func (b *recordingBroker) releasedCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.released)
+}
+
+// publish publishes one event per type
+func publish(t *testing.T, broker Publisher, eventTypes ...string) {
+	t.Helper()
+	for _, eventType := range eventTypes {
+		event := &Envelope{EventID: uuid.New(), Type: eventType, Subject: "tx-" + eventType}
+		if err := broker.Publish(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runConsumer runs consumer until stop is called, which drains it
+func runConsumer(t *testing.T, consumer *Consumer) (stop func()) {
+	t.Helper()
+	go consumer.Run(context.Background())
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := consumer.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown: %v", err)
+		}
+	}
+}
+
+func TestConsumer_HandlesAndAcks(t *testing.T) {
+	broker := newRecordingBroker()
+	publish(t, broker, TypeTransactionCreated, TypeTransactionReversed)
+
+	handled := make(chan string, 2)
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 2}, zap.NewNop())
+	for _, eventType := range []string{TypeTransactionCreated, TypeTransactionReversed} {
+		consumer.Handle(eventType, func(ctx context.Context, event *Envelope) error {
+			handled <- event.Subject
+			return nil
+		})
+	}
+
+	stop := runConsumer(t, consumer)
+	got := map[string]bool{<-handled: true, <-handled: true}
+	stop()
+
+	if !got["tx-"+TypeTransactionCreated] || !got["tx-"+TypeTransactionReversed] {
+		t.Errorf("Expected both messages to be handled, got %v", got)
+	}
+	if acked := broker.ackedCount(); acked != 2 {
+		t.Errorf("Expected 2 messages to be acked, got %d", acked)
+	}
+}
+
+func TestConsumer_HandlesConcurrently(t *testing.T) {
+	broker := newRecordingBroker()
+	publish(t, broker, "a", "b", "c")
+
+	// Every handler waits for the other two, so they only finish when all
+	// three run at once
+	var started sync.WaitGroup
+	started.Add(3)
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 3}, zap.NewNop())
+	for _, eventType := range []string{"a", "b", "c"} {
+		consumer.Handle(eventType, func(ctx context.Context, event *Envelope) error {
+			started.Done()
+			select {
+			case <-allStarted:
+				return nil
+			case <-time.After(5 * time.Second):
+				return errors.New("handlers did not run concurrently")
+			}
+		})
+	}
+
+	stop := runConsumer(t, consumer)
+	select {
+	case <-allStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected three handlers to run at once")
+	}
+	stop()
+}
+
+func TestConsumer_RetriesFailedAndUnknownEvents(t *testing.T) {
+	broker := newRecordingBroker()
+	publish(t, broker, TypeTransactionCreated, "UnknownEvent")
+
+	handled := make(chan struct{})
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 2, VisibilityTimeout: time.Minute}, zap.NewNop())
+	consumer.Handle(TypeTransactionCreated, func(ctx context.Context, event *Envelope) error {
+		defer close(handled)
+		return errors.New("database unavailable")
+	})
+
+	stop := runConsumer(t, consumer)
+	<-handled
+	for deadline := time.Now().Add(5 * time.Second); broker.releasedCount() < 2 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	if acked := broker.ackedCount(); acked != 0 {
+		t.Errorf("Expected no message to be acked, got %d", acked)
+	}
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.released) != 2 || broker.released[0] != time.Minute || broker.released[1] != time.Minute {
+		t.Errorf("Expected both messages to be released for a minute, got %v", broker.released)
+	}
+}
+
+func TestConsumer_AcksKnownEventsWithoutHandler(t *testing.T) {
+	broker := newRecordingBroker()
+	publish(t, broker, TypeHoldVoided, TypeScheduleCreated)
+
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 2}, zap.NewNop())
+	consumer.Handle(TypeTransactionCreated, func(ctx context.Context, event *Envelope) error {
+		t.Error("Handler of another event type should not run")
+		return nil
+	})
+
+	stop := runConsumer(t, consumer)
+	for deadline := time.Now().Add(5 * time.Second); broker.ackedCount() < 2 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	if acked := broker.ackedCount(); acked != 2 {
+		t.Errorf("Expected both messages to be acked, got %d", acked)
+	}
+	if released := broker.releasedCount(); released != 0 {
+		t.Errorf("Expected no message to be released, got %d", released)
+	}
+}
+
+// orderedBroker is a recordingBroker that gives every delivery the same
+// ordering key
+type orderedBroker struct {
+	*recordingBroker
+}
+
+func (b orderedBroker) Receive(ctx context.Context, max int, visibility time.Duration) ([]*Delivery, error) {
+	deliveries, err := b.recordingBroker.Receive(ctx, max, visibility)
+	for _, delivery := range deliveries {
+		delivery.OrderingKey = "acc1"
+	}
+	return deliveries, err
+}
+
+func TestConsumer_HandlesOrderedDeliveriesInOrder(t *testing.T) {
+	broker := orderedBroker{newRecordingBroker()}
+	publish(t, broker, "a", "b", "c")
+
+	handled := make(chan string, 3)
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 3}, zap.NewNop())
+	for _, eventType := range []string{"a", "b", "c"} {
+		consumer.Handle(eventType, func(ctx context.Context, event *Envelope) error {
+			// The first event takes longest; the others must still wait for it
+			if event.Type == "a" {
+				time.Sleep(50 * time.Millisecond)
+			}
+			handled <- event.Type
+			return nil
+		})
+	}
+
+	stop := runConsumer(t, consumer)
+	got := []string{<-handled, <-handled, <-handled}
+	stop()
+
+	if got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("Expected the events to be handled in order, got %v", got)
+	}
+}
+
+func TestConsumer_ReleasesOrderedDeliveriesAfterFailure(t *testing.T) {
+	broker := orderedBroker{newRecordingBroker()}
+	publish(t, broker, "a", "b", "c")
+
+	var mu sync.Mutex
+	var handled []string
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 3, VisibilityTimeout: time.Minute}, zap.NewNop())
+	for _, eventType := range []string{"a", "b", "c"} {
+		consumer.Handle(eventType, func(ctx context.Context, event *Envelope) error {
+			mu.Lock()
+			handled = append(handled, event.Type)
+			mu.Unlock()
+			if event.Type == "a" {
+				return errors.New("database unavailable")
+			}
+			return nil
+		})
+	}
+
+	stop := runConsumer(t, consumer)
+	for deadline := time.Now().Add(5 * time.Second); broker.releasedCount() < 3 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	// The failed event is retried later; the events after it are handed back
+	// straight away without being handled out of order
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.released) < 3 || broker.released[0] != time.Minute || broker.released[1] != 0 || broker.released[2] != 0 {
+		t.Errorf("Expected the failed event released for a minute and the rest at once, got %v", broker.released)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) == 0 || handled[0] != "a" {
+		t.Errorf("Expected only the first event to be handled before the failure, got %v", handled)
+	}
+}
+
+// batchAckBroker is a recordingBroker that acks in batches. The first batch
+// waits for release, so the acks after it queue up.
+type batchAckBroker struct {
+	*recordingBroker
+	release chan struct{}
+	batches chan int
+}
+
+func (b *batchAckBroker) AckBatch(ctx context.Context, deliveries []*Delivery) error {
+	if len(b.batches) == 0 {
+		<-b.release
+	}
+	b.batches <- len(deliveries)
+	for _, delivery := range deliveries {
+		if err := b.MemoryBroker.Ack(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestConsumer_AcksInBatches(t *testing.T) {
+	broker := &batchAckBroker{recordingBroker: newRecordingBroker(), release: make(chan struct{}), batches: make(chan int, 5)}
+	publish(t, broker, "a", "b", "c", "d", "e")
+
+	handled := make(chan struct{}, 5)
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 5}, zap.NewNop())
+	for _, eventType := range []string{"a", "b", "c", "d", "e"} {
+		consumer.Handle(eventType, func(ctx context.Context, event *Envelope) error {
+			handled <- struct{}{}
+			return nil
+		})
+	}
+
+	stop := runConsumer(t, consumer)
+	for i := 0; i < 5; i++ {
+		<-handled
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(broker.release)
+	stop()
+	close(broker.batches)
+
+	var sizes []int
+	total := 0
+	for size := range broker.batches {
+		sizes = append(sizes, size)
+		total += size
+	}
+	if total != 5 || len(sizes) != 2 {
+		t.Errorf("Expected the acks queued behind the first to go in one batch, got batches of %v", sizes)
+	}
+	if acked := broker.ackedCount(); acked != 0 {
+		t.Errorf("Expected no single acks, got %d", acked)
+	}
+}
+
+func TestEntryErrors(t *testing.T) {
+	failure := errors.New("throttled")
+
+	errs := EntryErrors(&BatchError{Failed: map[int]error{1: failure}, Total: 3}, 3)
+	if errs[0] != nil || errs[1] != failure || errs[2] != nil {
+		t.Errorf("Expected only entry 1 to fail, got %v", errs)
+	}
+	errs = EntryErrors(failure, 2)
+	if errs[0] != failure || errs[1] != failure {
+		t.Errorf("Expected every entry to fail, got %v", errs)
+	}
+	if errs := EntryErrors(nil, 2); errs[0] != nil || errs[1] != nil {
+		t.Errorf("Expected no entry to fail, got %v", errs)
+	}
+}
+
+// fakeProcessedStore records processed events in memory. The event is only
+// recorded when fn succeeds, as if its transaction committed.
+type fakeProcessedStore struct {
+	mu        sync.Mutex
+	processed map[uuid.UUID]bool
+}
+
+func (s *fakeProcessedStore) ProcessEvent(ctx context.Context, eventID uuid.UUID, eventType string, fn func(*sql.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.processed[eventID] {
+		return models.ErrEventAlreadyProcessed
+	}
+	if err := fn(nil); err != nil {
+		return err
+	}
+	s.processed[eventID] = true
+	return nil
+}
+
+func TestConsumer_HandleOnce(t *testing.T) {
+	broker := newRecordingBroker()
+	event := &Envelope{EventID: uuid.New(), Type: TypeTransactionCreated, Subject: "tx-1"}
+	// The event is in the queue twice, as if it was sent again
+	for i := 0; i < 2; i++ {
+		if err := broker.Publish(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	calls := 0
+	store := &fakeProcessedStore{processed: make(map[uuid.UUID]bool)}
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 1, VisibilityTimeout: time.Second}, zap.NewNop())
+	consumer.HandleOnce(TypeTransactionCreated, store, func(ctx context.Context, tx *sql.Tx, event *Envelope) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+
+	stop := runConsumer(t, consumer)
+	for deadline := time.Now().Add(5 * time.Second); broker.ackedCount() < 2 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+
+	// The first delivery fails and is retried; the delivery after the
+	// success is acked without running the handler
+	if acked := broker.ackedCount(); acked != 2 {
+		t.Errorf("Expected both copies acked, got %d", acked)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("Expected the handler to run for the failed delivery and once more, got %d calls", calls)
+	}
+}
+
+func TestConsumer_ShutdownDrainsInFlightMessages(t *testing.T) {
+	broker := newRecordingBroker()
+	publish(t, broker, TypeTransactionCreated)
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 1}, zap.NewNop())
+	consumer.Handle(TypeTransactionCreated, func(ctx context.Context, event *Envelope) error {
+		close(started)
+		<-finish
+		return ctx.Err()
+	})
+
+	go consumer.Run(context.Background())
+	<-started
+
+	stopped := make(chan error)
+	go func() { stopped <- consumer.Shutdown(context.Background()) }()
+
+	select {
+	case <-stopped:
+		t.Fatal("Expected Shutdown to wait for the message in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(finish)
+	if err := <-stopped; err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if acked := broker.ackedCount(); acked != 1 {
+		t.Errorf("Expected the message in flight to be acked, got %d acks", acked)
+	}
+}
+
+func TestConsumer_ShutdownTimeout(t *testing.T) {
+	broker := newRecordingBroker()
+	publish(t, broker, TypeTransactionCreated)
+
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 1}, zap.NewNop())
+	consumer.Handle(TypeTransactionCreated, func(ctx context.Context, event *Envelope) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	go consumer.Run(ctx)
+	<-started
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer shutdownCancel()
+	if err := consumer.Shutdown(shutdownCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestConsumer_HeartbeatExtendsVisibility(t *testing.T) {
+	broker := newRecordingBroker()
+	publish(t, broker, TypeTransactionCreated)
+
+	handled := make(chan error, 1)
+	consumer := NewConsumer(broker, ConsumerConfig{Workers: 1, VisibilityTimeout: 3 * time.Second}, zap.NewNop())
+	consumer.Handle(TypeTransactionCreated, func(ctx context.Context, event *Envelope) error {
+		var err error
+		select {
+		case <-broker.extended:
+		case <-time.After(5 * time.Second):
+			err = errors.New("visibility was not extended")
+		}
+		handled <- err
+		return err
+	})
+
+	stop := runConsumer(t, consumer)
+	if err := <-handled; err != nil {
+		t.Error(err)
+	}
+	stop()
+}
+
+func TestNewConsumer_Defaults(t *testing.T) {
+	consumer := NewConsumer(NewMemoryBroker(), ConsumerConfig{}, zap.NewNop())
+
+	if consumer.workers != 1 {
+		t.Errorf("Expected 1 worker, got %d", consumer.workers)
+	}
+	if consumer.visibility != 30*time.Second {
+		t.Errorf("Expected visibility timeout of 30s, got %v", consumer.visibility)
+	}
+}
@@ -0,0 +1,151 @@
+// Package events defines the broker-neutral interfaces for publishing and
+// consuming ledger events. The SQS, Kafka and in-memory brokers implement them.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Envelope is a ledger event as it travels through the message broker. Its
+// payload is one of the typed events of this package, encoded at the schema
+// version of its type.
+type Envelope struct {
+	EventID       uuid.UUID `json:"event_id"`
+	Type          string    `json:"type"`
+	SchemaVersion int       `json:"schema_version"`
+	// Subject is the ID of the transaction, batch, hold or schedule the event
+	// is about
+	Subject string `json:"subject"`
+	// SourceAccount is the account whose money the event moves or reserves.
+	// Brokers that order events keep the events of one account in order.
+	SourceAccount string `json:"source_account,omitempty"`
+	// CorrelationID is shared by every event of one request or workflow
+	CorrelationID string `json:"correlation_id"`
+	// CausationID is the ID of the event or command that caused this one
+	CausationID    string          `json:"causation_id,omitempty"`
+	ProducerRegion string          `json:"producer_region"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// OrderingKey returns the key brokers keep events in order by: the source
+// account, so the events of an account are delivered in order. Events without
+// one are ordered with the other events about their subject.
+func (e *Envelope) OrderingKey() string {
+	if e.SourceAccount != "" {
+		return e.SourceAccount
+	}
+	if e.Subject != "" {
+		return e.Subject
+	}
+	return e.EventID.String()
+}
+
+// Delivery is an event received from a Subscriber
+type Delivery struct {
+	Envelope *Envelope
+	// Receipt identifies this delivery to the Subscriber that made it
+	Receipt string
+	// ReceiveCount is how often the message has been received, including
+	// this time, or zero when the broker doesn't count receives
+	ReceiveCount int
+	// FinalDelivery is set when the broker gives up on the message, for
+	// example by moving it to a dead-letter queue, unless it is acked now
+	FinalDelivery bool
+	// OrderingKey is set by brokers that deliver the messages sharing a key
+	// in order, such as SQS FIFO queues; the consumer handles them one at a
+	// time, in the order received
+	OrderingKey string
+
+	// batch numbers the receive that returned the delivery
+	batch int
+}
+
+// Publisher publishes events to the broker
+type Publisher interface {
+	Publish(ctx context.Context, event *Envelope) error
+	Health(ctx context.Context) error
+}
+
+// Subscriber receives the messages published to the broker. A received
+// message is redelivered unless it is acked.
+type Subscriber interface {
+	// Receive waits for messages and returns up to max of them, each hidden
+	// from other receivers for visibility where the broker supports it
+	Receive(ctx context.Context, max int, visibility time.Duration) ([]*Delivery, error)
+	// Ack removes a handled message from the broker
+	Ack(ctx context.Context, delivery *Delivery) error
+	// Extend hides a message being handled for another visibility
+	Extend(ctx context.Context, delivery *Delivery, visibility time.Duration) error
+	// Release hands a message back to be delivered again after delay
+	Release(ctx context.Context, delivery *Delivery, delay time.Duration) error
+}
+
+// BatchPublisher is implemented by publishers that can send many events in
+// fewer calls to the broker. A failure of some of the events is reported as
+// a *BatchError.
+type BatchPublisher interface {
+	PublishBatch(ctx context.Context, events []*Envelope) error
+}
+
+// BatchAcker is implemented by subscribers that can ack many messages in
+// fewer calls to the broker. A failure of some of the messages is reported
+// as a *BatchError.
+type BatchAcker interface {
+	AckBatch(ctx context.Context, deliveries []*Delivery) error
+}
+
+// BatchError reports the entries of a batch operation that failed, by their
+// index in the batch. The entries without an error succeeded.
+type BatchError struct {
+	Failed map[int]error
+	Total  int
+}
+
+func (e *BatchError) Error() string {
+	indexes := make([]int, 0, len(e.Failed))
+	for i := range e.Failed {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	if len(indexes) == 0 {
+		return fmt.Sprintf("0 of %d batch entries failed", e.Total)
+	}
+	return fmt.Sprintf("%d of %d batch entries failed; entry %d: %v", len(indexes), e.Total, indexes[0], e.Failed[indexes[0]])
+}
+
+// EntryErrors returns the error of each entry of a batch of n entries after
+// the batch operation returned err, nil for the entries that succeeded
+func EntryErrors(err error, n int) []error {
+	errs := make([]error, n)
+	if err == nil {
+		return errs
+	}
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	for i, entryErr := range batchErr.Failed {
+		if i >= 0 && i < n {
+			errs[i] = entryErr
+		}
+	}
+	return errs
+}
+
+// Broker is a message broker connection that both publishes and receives
+type Broker interface {
+	Publisher
+	Subscriber
+	Close() error
+}
@@ -0,0 +1,238 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/models"
+)
+
+// Ledger event types
+const (
+	TypeTransactionCreated       = "TransactionCreated"
+	TypeTransactionBatchCreated  = "TransactionBatchCreated"
+	TypeTransactionStatusChanged = "TransactionStatusChanged"
+	TypeTransactionReversed      = "TransactionReversed"
+	TypeHoldCreated              = "HoldCreated"
+	TypeHoldCaptured             = "HoldCaptured"
+	TypeHoldVoided               = "HoldVoided"
+	TypeScheduleCreated          = "ScheduleCreated"
+	TypeScheduleCancelled        = "ScheduleCancelled"
+)
+
+// TransactionCreated is published when a transfer is posted. Amounts are
+// decimal strings with the scale of their currency.
+type TransactionCreated struct {
+	TransactionID uuid.UUID `json:"transaction_id"`
+	FromAccount   string    `json:"from_account"`
+	ToAccount     string    `json:"to_account"`
+	Amount        string    `json:"amount"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	// Set on cross-currency transfers: the amount credited to ToAccount
+	ConvertedAmount   string `json:"converted_amount,omitempty"`
+	ConvertedCurrency string `json:"converted_currency,omitempty"`
+	// ScheduleID is set on transactions created by a schedule
+	ScheduleID *uuid.UUID `json:"schedule_id,omitempty"`
+}
+
+// NewTransactionCreated describes the creation of tx
+func NewTransactionCreated(tx *models.Transaction) *TransactionCreated {
+	event := &TransactionCreated{
+		TransactionID: tx.ID,
+		FromAccount:   tx.FromAccount,
+		ToAccount:     tx.ToAccount,
+		Amount:        tx.Amount.Format(),
+		Currency:      tx.Amount.Currency,
+		Status:        tx.Status,
+	}
+	if tx.ConvertedAmount != nil {
+		event.ConvertedAmount = tx.ConvertedAmount.Format()
+		event.ConvertedCurrency = tx.ConvertedAmount.Currency
+	}
+	return event
+}
+
+func (e *TransactionCreated) EventType() string     { return TypeTransactionCreated }
+func (e *TransactionCreated) Subject() string       { return e.TransactionID.String() }
+func (e *TransactionCreated) SourceAccount() string { return e.FromAccount }
+
+// TransactionBatchCreated is published when a batch of transfers is posted
+type TransactionBatchCreated struct {
+	BatchID        uuid.UUID   `json:"batch_id"`
+	TransactionIDs []uuid.UUID `json:"transaction_ids"`
+}
+
+func (e *TransactionBatchCreated) EventType() string     { return TypeTransactionBatchCreated }
+func (e *TransactionBatchCreated) Subject() string       { return e.BatchID.String() }
+func (e *TransactionBatchCreated) SourceAccount() string { return "" }
+
+// TransactionStatusChanged is published when a transaction moves to another
+// status
+type TransactionStatusChanged struct {
+	TransactionID  uuid.UUID `json:"transaction_id"`
+	FromAccount    string    `json:"from_account,omitempty"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+}
+
+// NewTransactionStatusChanged describes tx moving from previous to its
+// current status
+func NewTransactionStatusChanged(tx *models.Transaction, previous string) *TransactionStatusChanged {
+	return &TransactionStatusChanged{
+		TransactionID:  tx.ID,
+		FromAccount:    tx.FromAccount,
+		PreviousStatus: previous,
+		Status:         tx.Status,
+	}
+}
+
+func (e *TransactionStatusChanged) EventType() string     { return TypeTransactionStatusChanged }
+func (e *TransactionStatusChanged) Subject() string       { return e.TransactionID.String() }
+func (e *TransactionStatusChanged) SourceAccount() string { return e.FromAccount }
+
+// TransactionReversed is published when a transaction is reversed in full or
+// in part. TransactionID is the reversal, which moves Amount back.
+type TransactionReversed struct {
+	TransactionID uuid.UUID `json:"transaction_id"`
+	ReversalOf    uuid.UUID `json:"reversal_of"`
+	FromAccount   string    `json:"from_account,omitempty"`
+	Amount        string    `json:"amount"`
+	Currency      string    `json:"currency"`
+	Reason        string    `json:"reason,omitempty"`
+}
+
+// NewTransactionReversed describes reversal, a reversal of another transaction
+func NewTransactionReversed(reversal *models.Transaction, reason string) *TransactionReversed {
+	event := &TransactionReversed{
+		TransactionID: reversal.ID,
+		FromAccount:   reversal.FromAccount,
+		Amount:        reversal.Amount.Format(),
+		Currency:      reversal.Amount.Currency,
+		Reason:        reason,
+	}
+	if reversal.ReversalOf != nil {
+		event.ReversalOf = *reversal.ReversalOf
+	}
+	return event
+}
+
+func (e *TransactionReversed) EventType() string     { return TypeTransactionReversed }
+func (e *TransactionReversed) Subject() string       { return e.TransactionID.String() }
+func (e *TransactionReversed) SourceAccount() string { return e.FromAccount }
+
+// HoldCreated is published when funds are reserved by a hold
+type HoldCreated struct {
+	HoldID      uuid.UUID `json:"hold_id"`
+	FromAccount string    `json:"from_account"`
+	ToAccount   string    `json:"to_account"`
+	Amount      string    `json:"amount"`
+	Currency    string    `json:"currency"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// NewHoldCreated describes the creation of hold
+func NewHoldCreated(hold *models.Hold) *HoldCreated {
+	return &HoldCreated{
+		HoldID:      hold.ID,
+		FromAccount: hold.FromAccount,
+		ToAccount:   hold.ToAccount,
+		Amount:      hold.Amount.Format(),
+		Currency:    hold.Amount.Currency,
+		ExpiresAt:   hold.ExpiresAt,
+	}
+}
+
+func (e *HoldCreated) EventType() string     { return TypeHoldCreated }
+func (e *HoldCreated) Subject() string       { return e.HoldID.String() }
+func (e *HoldCreated) SourceAccount() string { return e.FromAccount }
+
+// HoldCaptured is published when a hold is captured into a transaction
+type HoldCaptured struct {
+	HoldID        uuid.UUID `json:"hold_id"`
+	TransactionID uuid.UUID `json:"transaction_id"`
+	FromAccount   string    `json:"from_account,omitempty"`
+	Amount        string    `json:"amount"`
+	Currency      string    `json:"currency"`
+}
+
+// NewHoldCaptured describes the capture of hold into capture
+func NewHoldCaptured(hold *models.Hold, capture *models.Transaction) *HoldCaptured {
+	return &HoldCaptured{
+		HoldID:        hold.ID,
+		TransactionID: capture.ID,
+		FromAccount:   hold.FromAccount,
+		Amount:        capture.Amount.Format(),
+		Currency:      capture.Amount.Currency,
+	}
+}
+
+func (e *HoldCaptured) EventType() string     { return TypeHoldCaptured }
+func (e *HoldCaptured) Subject() string       { return e.HoldID.String() }
+func (e *HoldCaptured) SourceAccount() string { return e.FromAccount }
+
+// HoldVoided is published when a hold is released without moving money
+type HoldVoided struct {
+	HoldID      uuid.UUID `json:"hold_id"`
+	FromAccount string    `json:"from_account"`
+	Amount      string    `json:"amount"`
+	Currency    string    `json:"currency"`
+}
+
+// NewHoldVoided describes voiding hold
+func NewHoldVoided(hold *models.Hold) *HoldVoided {
+	return &HoldVoided{
+		HoldID:      hold.ID,
+		FromAccount: hold.FromAccount,
+		Amount:      hold.Amount.Format(),
+		Currency:    hold.Amount.Currency,
+	}
+}
+
+func (e *HoldVoided) EventType() string     { return TypeHoldVoided }
+func (e *HoldVoided) Subject() string       { return e.HoldID.String() }
+func (e *HoldVoided) SourceAccount() string { return e.FromAccount }
+
+// ScheduleCreated is published when a recurring transfer is scheduled
+type ScheduleCreated struct {
+	ScheduleID  uuid.UUID  `json:"schedule_id"`
+	FromAccount string     `json:"from_account"`
+	ToAccount   string     `json:"to_account"`
+	Amount      string     `json:"amount"`
+	Currency    string     `json:"currency"`
+	Schedule    string     `json:"schedule"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+}
+
+// NewScheduleCreated describes the creation of sched
+func NewScheduleCreated(sched *models.Schedule) *ScheduleCreated {
+	return &ScheduleCreated{
+		ScheduleID:  sched.ID,
+		FromAccount: sched.FromAccount,
+		ToAccount:   sched.ToAccount,
+		Amount:      sched.Amount.Format(),
+		Currency:    sched.Amount.Currency,
+		Schedule:    sched.Spec,
+		NextRunAt:   sched.NextRunAt,
+	}
+}
+
+func (e *ScheduleCreated) EventType() string     { return TypeScheduleCreated }
+func (e *ScheduleCreated) Subject() string       { return e.ScheduleID.String() }
+func (e *ScheduleCreated) SourceAccount() string { return e.FromAccount }
+
+// ScheduleCancelled is published when a schedule is cancelled
+type ScheduleCancelled struct {
+	ScheduleID  uuid.UUID `json:"schedule_id"`
+	FromAccount string    `json:"from_account,omitempty"`
+	RunCount    int       `json:"run_count"`
+}
+
+// NewScheduleCancelled describes the cancellation of sched
+func NewScheduleCancelled(sched *models.Schedule) *ScheduleCancelled {
+	return &ScheduleCancelled{ScheduleID: sched.ID, FromAccount: sched.FromAccount, RunCount: sched.RunCount}
+}
+
+func (e *ScheduleCancelled) EventType() string     { return TypeScheduleCancelled }
+func (e *ScheduleCancelled) Subject() string       { return e.ScheduleID.String() }
+func (e *ScheduleCancelled) SourceAccount() string { return e.FromAccount }
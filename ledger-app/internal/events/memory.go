@@ -0,0 +1,152 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrUnknownDelivery is returned for a receipt the broker didn't hand out or
+// has already settled
+var ErrUnknownDelivery = errors.New("unknown delivery")
+
+// MemoryBroker is an in-process Broker for tests and single-node development.
+// Messages only live as long as the process and ignore visibility timeouts:
+// a received message stays hidden until it is acked or released.
+type MemoryBroker struct {
+	mu       sync.Mutex
+	queue    []*memoryMessage
+	inFlight map[string]*memoryMessage
+	nextID   int
+	ready    chan struct{}
+}
+
+// memoryMessage is a message held by a MemoryBroker
+type memoryMessage struct {
+	id       string
+	env      Envelope
+	receives int
+}
+
+// NewMemoryBroker creates an empty in-memory broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		inFlight: make(map[string]*memoryMessage),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// Publish queues a copy of event
+func (b *MemoryBroker) Publish(ctx context.Context, event *Envelope) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	b.queue = append(b.queue, &memoryMessage{id: strconv.Itoa(b.nextID), env: *event})
+	b.signal()
+	return nil
+}
+
+// Receive waits until messages are queued or ctx is done
+func (b *MemoryBroker) Receive(ctx context.Context, max int, visibility time.Duration) ([]*Delivery, error) {
+	for {
+		if deliveries := b.take(max); len(deliveries) > 0 {
+			return deliveries, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-b.ready:
+		}
+	}
+}
+
+// take moves up to max queued messages in flight
+func (b *MemoryBroker) take(max int) []*Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if max < 1 {
+		max = 1
+	}
+	if max > len(b.queue) {
+		max = len(b.queue)
+	}
+
+	deliveries := make([]*Delivery, 0, max)
+	for _, m := range b.queue[:max] {
+		m.receives++
+		b.inFlight[m.id] = m
+		env := m.env
+		deliveries = append(deliveries, &Delivery{Envelope: &env, Receipt: m.id, ReceiveCount: m.receives})
+	}
+	b.queue = b.queue[max:]
+
+	// Wake the next receiver if messages are left
+	if len(b.queue) > 0 {
+		b.signal()
+	}
+	return deliveries
+}
+
+// signal wakes a waiting receiver; the caller holds b.mu
+func (b *MemoryBroker) signal() {
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ack removes a message in flight
+func (b *MemoryBroker) Ack(ctx context.Context, delivery *Delivery) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.inFlight[delivery.Receipt]; !ok {
+		return ErrUnknownDelivery
+	}
+	delete(b.inFlight, delivery.Receipt)
+	return nil
+}
+
+// Extend does nothing; messages in flight never become visible by themselves
+func (b *MemoryBroker) Extend(ctx context.Context, delivery *Delivery, visibility time.Duration) error {
+	return nil
+}
+
+// Release queues a message in flight again after delay
+func (b *MemoryBroker) Release(ctx context.Context, delivery *Delivery, delay time.Duration) error {
+	b.mu.Lock()
+	m, ok := b.inFlight[delivery.Receipt]
+	if !ok {
+		b.mu.Unlock()
+		return ErrUnknownDelivery
+	}
+	delete(b.inFlight, delivery.Receipt)
+	b.mu.Unlock()
+
+	requeue := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.queue = append(b.queue, m)
+		b.signal()
+	}
+	if delay <= 0 {
+		requeue()
+	} else {
+		time.AfterFunc(delay, requeue)
+	}
+	return nil
+}
+
+// Health always succeeds
+func (b *MemoryBroker) Health(ctx context.Context) error {
+	return nil
+}
+
+// Close does nothing; queued messages are dropped with the broker
+func (b *MemoryBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,89 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryBroker_PublishReceiveAck(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx := context.Background()
+	publish(t, broker, "a", "b", "c")
+
+	deliveries, err := broker.Receive(ctx, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].Envelope.Type != "a" || deliveries[1].Envelope.Type != "b" {
+		t.Fatalf("Expected the first two messages in order, got %+v", deliveries)
+	}
+	if deliveries[0].ReceiveCount != 1 {
+		t.Errorf("Expected receive count 1, got %d", deliveries[0].ReceiveCount)
+	}
+
+	if err := broker.Ack(ctx, deliveries[0]); err != nil {
+		t.Errorf("Ack: %v", err)
+	}
+	if err := broker.Ack(ctx, deliveries[0]); !errors.Is(err, ErrUnknownDelivery) {
+		t.Errorf("Expected ErrUnknownDelivery acking twice, got: %v", err)
+	}
+
+	deliveries, err = broker.Receive(ctx, 10, time.Minute)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Envelope.Type != "c" {
+		t.Fatalf("Expected the last message, got %+v, %v", deliveries, err)
+	}
+}
+
+func TestMemoryBroker_ReceiveWaits(t *testing.T) {
+	broker := NewMemoryBroker()
+
+	received := make(chan []*Delivery)
+	go func() {
+		deliveries, _ := broker.Receive(context.Background(), 1, time.Minute)
+		received <- deliveries
+	}()
+
+	select {
+	case <-received:
+		t.Fatal("Expected Receive to wait for a message")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	publish(t, broker, "a")
+	if deliveries := <-received; len(deliveries) != 1 {
+		t.Errorf("Expected 1 delivery, got %d", len(deliveries))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := broker.Receive(ctx, 1, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestMemoryBroker_Release(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx := context.Background()
+	publish(t, broker, "a")
+
+	deliveries, _ := broker.Receive(ctx, 1, time.Minute)
+	if err := broker.Release(ctx, deliveries[0], 20*time.Millisecond); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if _, err := broker.Receive(waitCtx, 1, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the released message to wait for its delay, got: %v", err)
+	}
+
+	deliveries, err := broker.Receive(ctx, 1, time.Minute)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("Expected the released message, got %+v, %v", deliveries, err)
+	}
+	if deliveries[0].ReceiveCount != 2 {
+		t.Errorf("Expected receive count 2, got %d", deliveries[0].ReceiveCount)
+	}
+}
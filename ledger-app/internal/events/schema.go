@@ -0,0 +1,225 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors returned when reading envelopes
+var (
+	ErrUnknownEventType   = errors.New("unknown event type")
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+)
+
+// Event is the typed payload of an envelope
+type Event interface {
+	// EventType names the event on the wire
+	EventType() string
+	// Subject returns the ID of the entity the event is about
+	Subject() string
+	// SourceAccount returns the account whose money the event moves or
+	// reserves, or "" when it concerns many accounts
+	SourceAccount() string
+}
+
+// schemaVersions is the schema version this release writes, and reads after
+// upcasting, for each event type. Changing a payload incompatibly means
+// bumping its version here and registering an upcaster from the old one.
+var schemaVersions = map[string]int{
+	TypeTransactionCreated:       1,
+	TypeTransactionBatchCreated:  1,
+	TypeTransactionStatusChanged: 1,
+	TypeTransactionReversed:      1,
+	TypeHoldCreated:              1,
+	TypeHoldCaptured:             1,
+	TypeHoldVoided:               1,
+	TypeScheduleCreated:          1,
+	TypeScheduleCancelled:        1,
+}
+
+// Upcaster rewrites a payload from one schema version of its type to the next
+type Upcaster func(payload json.RawMessage) (json.RawMessage, error)
+
+// schemaKey identifies one schema version of an event type
+type schemaKey struct {
+	eventType string
+	version   int
+}
+
+// upcasters holds the upcaster from each version of an event type to the
+// next. Version 0 is the message format used before envelopes.
+var upcasters = map[schemaKey]Upcaster{
+	{TypeTransactionCreated, 0}:       upcastLegacy("transaction_id"),
+	{TypeTransactionBatchCreated, 0}:  upcastLegacy("batch_id"),
+	{TypeTransactionStatusChanged, 0}: upcastLegacy("transaction_id"),
+	{TypeTransactionReversed, 0}:      upcastLegacy("transaction_id"),
+	{TypeHoldCreated, 0}:              upcastLegacy("hold_id"),
+	{TypeHoldCaptured, 0}:             upcastLegacy("transaction_id"),
+	{TypeHoldVoided, 0}:               upcastLegacy("hold_id"),
+	{TypeScheduleCreated, 0}:          upcastLegacy("schedule_id"),
+	{TypeScheduleCancelled, 0}:        upcastLegacy("schedule_id"),
+}
+
+// IsKnownType reports whether eventType is an event type of this release
+func IsKnownType(eventType string) bool {
+	_, ok := schemaVersions[eventType]
+	return ok
+}
+
+// NewEnvelope wraps event at the current schema version of its type.
+// correlationID ties it to the request or workflow that produced it; when it
+// is empty the event starts a workflow of its own and is correlated to itself.
+func NewEnvelope(region, correlationID string, event Event) (*Envelope, error) {
+	version, ok := schemaVersions[event.EventType()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, event.EventType())
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", event.EventType(), err)
+	}
+
+	id := uuid.New()
+	if correlationID == "" {
+		correlationID = id.String()
+	}
+	return &Envelope{
+		EventID:        id,
+		Type:           event.EventType(),
+		SchemaVersion:  version,
+		Subject:        event.Subject(),
+		SourceAccount:  event.SourceAccount(),
+		CorrelationID:  correlationID,
+		ProducerRegion: region,
+		OccurredAt:     time.Now().UTC(),
+		Payload:        payload,
+	}, nil
+}
+
+// Parse reads an envelope and upcasts its payload to the schema version this
+// release reads. Messages written before envelopes existed are read as
+// version 0 of their type. Envelopes of an unknown type or a newer version
+// are returned as they are; decoding their payload fails.
+func Parse(body []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, err
+	}
+	if env.Type == "" {
+		legacy, err := parseLegacy(body)
+		if err != nil {
+			return nil, err
+		}
+		env = *legacy
+	}
+
+	current, ok := schemaVersions[env.Type]
+	if !ok {
+		return &env, nil
+	}
+	for env.SchemaVersion < current {
+		upcast, ok := upcasters[schemaKey{env.Type, env.SchemaVersion}]
+		if !ok {
+			return nil, fmt.Errorf("%w: no upcaster from %s version %d", ErrUnsupportedVersion, env.Type, env.SchemaVersion)
+		}
+		payload, err := upcast(env.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upcast %s version %d: %w", env.Type, env.SchemaVersion, err)
+		}
+		env.Payload = payload
+		env.SchemaVersion++
+	}
+	return &env, nil
+}
+
+// Decode unmarshals the payload into event, which must be of the envelope's
+// type and at the version this release reads
+func (e *Envelope) Decode(event Event) error {
+	if event.EventType() != e.Type {
+		return fmt.Errorf("cannot decode a %s event as %s", e.Type, event.EventType())
+	}
+	current, ok := schemaVersions[e.Type]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEventType, e.Type)
+	}
+	if e.SchemaVersion != current {
+		return fmt.Errorf("%w: %s version %d", ErrUnsupportedVersion, e.Type, e.SchemaVersion)
+	}
+	if err := json.Unmarshal(e.Payload, event); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", e.Type, err)
+	}
+	return nil
+}
+
+// legacyMessage is the message format used before envelopes. Data held the
+// audit log of the event.
+type legacyMessage struct {
+	TransactionID string    `json:"transaction_id"`
+	Region        string    `json:"region"`
+	Action        string    `json:"action"`
+	Timestamp     time.Time `json:"timestamp"`
+	Data          string    `json:"data"`
+}
+
+// legacyTypes maps the actions of legacy messages to event types
+var legacyTypes = map[string]string{
+	"transaction_created":        TypeTransactionCreated,
+	"transaction_batch_created":  TypeTransactionBatchCreated,
+	"transaction_status_changed": TypeTransactionStatusChanged,
+	"transaction_reversed":       TypeTransactionReversed,
+	"hold_created":               TypeHoldCreated,
+	"hold_captured":              TypeHoldCaptured,
+	"hold_voided":                TypeHoldVoided,
+	"schedule_created":           TypeScheduleCreated,
+	"schedule_cancelled":         TypeScheduleCancelled,
+}
+
+// legacyNamespace derives the event IDs of legacy messages, so a message
+// read twice gets the same ID
+var legacyNamespace = uuid.MustParse("6f1b7f3e-3c1d-4c38-9d35-2c4f0d6a8e51")
+
+// parseLegacy wraps a legacy message in a version 0 envelope whose payload is
+// the whole message
+func parseLegacy(body []byte) (*Envelope, error) {
+	var msg legacyMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	eventType, ok := legacyTypes[msg.Action]
+	if !ok {
+		return nil, fmt.Errorf("%w: legacy action %q", ErrUnknownEventType, msg.Action)
+	}
+
+	id := uuid.NewSHA1(legacyNamespace, body)
+	return &Envelope{
+		EventID:        id,
+		Type:           eventType,
+		SchemaVersion:  0,
+		Subject:        msg.TransactionID,
+		CorrelationID:  id.String(),
+		ProducerRegion: msg.Region,
+		OccurredAt:     msg.Timestamp,
+		Payload:        body,
+	}, nil
+}
+
+// upcastLegacy returns the upcaster from a legacy message to version 1 of its
+// type. Legacy messages only carry the ID the event is about, which is
+// written to field; the rest of the payload is left empty.
+func upcastLegacy(field string) Upcaster {
+	return func(payload json.RawMessage) (json.RawMessage, error) {
+		var msg legacyMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return nil, err
+		}
+		fields := map[string]string{}
+		if msg.TransactionID != "" {
+			fields[field] = msg.TransactionID
+		}
+		return json.Marshal(fields)
+	}
+}
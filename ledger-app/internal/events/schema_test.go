@@ -0,0 +1,246 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenEvents has one event of every type. The golden files hold their
+// envelopes exactly as they go on the wire; a change to them is a change to
+// the schema and needs a new version and an upcaster.
+func goldenEvents() []Event {
+	txID := uuid.MustParse("7d3f2c4e-9b1a-4f8e-a6d2-1c5b8e9f0a21")
+	otherID := uuid.MustParse("2a9e6b1c-4d7f-4e3a-b8c5-9f0d1e2a3b45")
+	scheduleID := uuid.MustParse("c4b8a2d6-1e3f-4a5b-9c7d-8e0f1a2b3c4d")
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	return []Event{
+		&TransactionCreated{
+			TransactionID: txID, FromAccount: "acc1", ToAccount: "acc2",
+			Amount: "100.50", Currency: "USD", Status: "pending",
+			ConvertedAmount: "92.61", ConvertedCurrency: "EUR", ScheduleID: &scheduleID,
+		},
+		&TransactionBatchCreated{BatchID: otherID, TransactionIDs: []uuid.UUID{txID}},
+		&TransactionStatusChanged{TransactionID: txID, FromAccount: "acc1", PreviousStatus: "pending", Status: "completed"},
+		&TransactionReversed{TransactionID: otherID, ReversalOf: txID, FromAccount: "acc2", Amount: "25.00", Currency: "USD", Reason: "duplicate"},
+		&HoldCreated{HoldID: otherID, FromAccount: "acc1", ToAccount: "acc2", Amount: "40.00", Currency: "USD", ExpiresAt: at},
+		&HoldCaptured{HoldID: otherID, TransactionID: txID, FromAccount: "acc1", Amount: "30.00", Currency: "USD"},
+		&HoldVoided{HoldID: otherID, FromAccount: "acc1", Amount: "40.00", Currency: "USD"},
+		&ScheduleCreated{
+			ScheduleID: scheduleID, FromAccount: "acc1", ToAccount: "acc2",
+			Amount: "10.00", Currency: "USD", Schedule: "@monthly", NextRunAt: &at,
+		},
+		&ScheduleCancelled{ScheduleID: scheduleID, FromAccount: "acc1", RunCount: 3},
+	}
+}
+
+// checkGolden compares got with the golden file name, or rewrites it with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s changed:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// marshalIndent encodes env the way the golden files hold it
+func marshalIndent(t *testing.T, env *Envelope) []byte {
+	t.Helper()
+	body, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(body, '\n')
+}
+
+func TestEnvelope_WireFormat(t *testing.T) {
+	for _, event := range goldenEvents() {
+		t.Run(event.EventType(), func(t *testing.T) {
+			env, err := NewEnvelope("us-east-1", "request-1", event)
+			if err != nil {
+				t.Fatalf("NewEnvelope: %v", err)
+			}
+			env.EventID = uuid.MustParse("0b6d8e2f-5a4c-4b3d-8e1f-6a7b8c9d0e1f")
+			env.CausationID = "command-1"
+			env.OccurredAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+			body := marshalIndent(t, env)
+			checkGolden(t, event.EventType()+".v1.json", body)
+
+			// What is written must read back as the same event
+			parsed, err := Parse(body)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			decoded := reflect.New(reflect.TypeOf(event).Elem()).Interface().(Event)
+			if err := parsed.Decode(decoded); err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, event) {
+				t.Errorf("Expected %+v, got %+v", event, decoded)
+			}
+		})
+	}
+}
+
+func TestNewEnvelope(t *testing.T) {
+	event := &HoldVoided{HoldID: uuid.New(), FromAccount: "acc1"}
+
+	env, err := NewEnvelope("eu-west-1", "", event)
+	if err != nil {
+		t.Fatalf("NewEnvelope: %v", err)
+	}
+	if env.Type != TypeHoldVoided || env.SchemaVersion != 1 || env.Subject != event.HoldID.String() || env.SourceAccount != "acc1" {
+		t.Errorf("Unexpected envelope %+v", env)
+	}
+	if env.CorrelationID != env.EventID.String() {
+		t.Errorf("Expected an uncorrelated event to be correlated to itself, got %s", env.CorrelationID)
+	}
+	if env.ProducerRegion != "eu-west-1" {
+		t.Errorf("Expected producer region eu-west-1, got %s", env.ProducerRegion)
+	}
+}
+
+func TestParse_LegacyMessage(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "legacy_transaction_created.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := Parse(body)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	checkGolden(t, "legacy_transaction_created.golden.json", marshalIndent(t, env))
+
+	// The same message always gets the same event ID
+	again, err := Parse(body)
+	if err != nil || again.EventID != env.EventID {
+		t.Errorf("Expected event ID %s reading the message again, got %v (%v)", env.EventID, again, err)
+	}
+
+	var event TransactionCreated
+	if err := env.Decode(&event); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if event.TransactionID.String() != env.Subject {
+		t.Errorf("Expected transaction %s, got %s", env.Subject, event.TransactionID)
+	}
+}
+
+func TestParse_LegacyActions(t *testing.T) {
+	id := uuid.New()
+	cases := []struct {
+		action string
+		event  Event
+		want   func(Event) uuid.UUID
+	}{
+		{"hold_captured", &HoldCaptured{}, func(e Event) uuid.UUID { return e.(*HoldCaptured).TransactionID }},
+		{"hold_voided", &HoldVoided{}, func(e Event) uuid.UUID { return e.(*HoldVoided).HoldID }},
+		{"schedule_cancelled", &ScheduleCancelled{}, func(e Event) uuid.UUID { return e.(*ScheduleCancelled).ScheduleID }},
+		{"transaction_batch_created", &TransactionBatchCreated{}, func(e Event) uuid.UUID { return e.(*TransactionBatchCreated).BatchID }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.action, func(t *testing.T) {
+			body, _ := json.Marshal(&legacyMessage{TransactionID: id.String(), Region: "us-east-1", Action: tc.action})
+			env, err := Parse(body)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if env.Type != tc.event.EventType() {
+				t.Fatalf("Expected type %s, got %s", tc.event.EventType(), env.Type)
+			}
+			if err := env.Decode(tc.event); err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if got := tc.want(tc.event); got != id {
+				t.Errorf("Expected ID %s in the payload, got %s", id, got)
+			}
+		})
+	}
+
+	if _, err := Parse([]byte(`{"action":"account_opened"}`)); !errors.Is(err, ErrUnknownEventType) {
+		t.Errorf("Expected ErrUnknownEventType for an unknown legacy action, got: %v", err)
+	}
+}
+
+// renamed is a test event whose version 1 split the name in two
+type renamed struct {
+	Name string `json:"name"`
+}
+
+func (e *renamed) EventType() string     { return "Renamed" }
+func (e *renamed) Subject() string       { return e.Name }
+func (e *renamed) SourceAccount() string { return "" }
+
+func TestParse_UpcastsOlderVersions(t *testing.T) {
+	schemaVersions["Renamed"] = 2
+	upcasters[schemaKey{"Renamed", 1}] = func(payload json.RawMessage) (json.RawMessage, error) {
+		var v1 struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+		}
+		if err := json.Unmarshal(payload, &v1); err != nil {
+			return nil, err
+		}
+		return json.Marshal(&renamed{Name: v1.First + " " + v1.Last})
+	}
+	t.Cleanup(func() {
+		delete(schemaVersions, "Renamed")
+		delete(upcasters, schemaKey{"Renamed", 1})
+	})
+
+	env, err := Parse([]byte(`{"type":"Renamed","schema_version":1,"payload":{"first":"Ada","last":"Lovelace"}}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if env.SchemaVersion != 2 {
+		t.Errorf("Expected version 2, got %d", env.SchemaVersion)
+	}
+	var event renamed
+	if err := env.Decode(&event); err != nil || event.Name != "Ada Lovelace" {
+		t.Errorf("Expected the upcast name, got %q (%v)", event.Name, err)
+	}
+
+	// Versions without an upcaster can't be read
+	if _, err := Parse([]byte(`{"type":"Renamed","schema_version":0,"payload":{}}`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got: %v", err)
+	}
+}
+
+func TestParse_NewerVersion(t *testing.T) {
+	env, err := Parse([]byte(`{"type":"TransactionCreated","schema_version":2,"payload":{}}`))
+	if err != nil {
+		t.Fatalf("Expected a newer version to parse, got: %v", err)
+	}
+
+	var event TransactionCreated
+	if err := env.Decode(&event); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion, got: %v", err)
+	}
+	if err := env.Decode(&HoldCreated{}); err == nil {
+		t.Error("Expected decoding as another type to fail")
+	}
+}
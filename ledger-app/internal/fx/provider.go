@@ -0,0 +1,74 @@
+// Package fx provides exchange rates for cross-currency transfers
+package fx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// ErrRateUnavailable is returned when a provider has no rate for a currency pair
+var ErrRateUnavailable = errors.New("fx rate unavailable")
+
+// RateProvider returns the rate that converts one unit of from into to
+type RateProvider interface {
+	Rate(from, to string) (decimal.Decimal, error)
+}
+
+// StaticProvider serves rates from a fixed table. Pairs are keyed "FROM/TO";
+// the inverse of a pair is derived when only one direction is listed.
+type StaticProvider struct {
+	rates map[string]decimal.Decimal
+}
+
+// NewStaticProvider creates a provider from a table of "FROM/TO" rates
+func NewStaticProvider(rates map[string]decimal.Decimal) (*StaticProvider, error) {
+	table := make(map[string]decimal.Decimal, len(rates))
+	for pair, rate := range rates {
+		from, to, ok := strings.Cut(pair, "/")
+		if !ok || !models.IsValidCurrencyCode(from) || !models.IsValidCurrencyCode(to) {
+			return nil, fmt.Errorf("invalid currency pair %q", pair)
+		}
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("invalid rate %s for %s", rate.String(), pair)
+		}
+		table[pair] = rate
+	}
+
+	return &StaticProvider{rates: table}, nil
+}
+
+// LoadFile creates a static provider from a JSON file mapping "FROM/TO" pairs
+// to rates, e.g. {"USD/EUR": "0.92"}
+func LoadFile(path string) (*StaticProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fx rates file: %w", err)
+	}
+
+	var rates map[string]decimal.Decimal
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("failed to parse fx rates file: %w", err)
+	}
+
+	return NewStaticProvider(rates)
+}
+
+// Rate implements RateProvider
+func (p *StaticProvider) Rate(from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	if rate, ok := p.rates[from+"/"+to]; ok {
+		return rate, nil
+	}
+	if inverse, ok := p.rates[to+"/"+from]; ok {
+		return decimal.NewFromInt(1).DivRound(inverse, models.RateScale), nil
+	}
+	return decimal.Zero, fmt.Errorf("%w: %s/%s", ErrRateUnavailable, from, to)
+}
@@ -0,0 +1,88 @@
+package fx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestStaticProvider_Rate(t *testing.T) {
+	provider, err := NewStaticProvider(map[string]decimal.Decimal{
+		"USD/EUR": decimal.RequireFromString("0.8"),
+		"USD/JPY": decimal.RequireFromString("150"),
+	})
+	if err != nil {
+		t.Fatalf("NewStaticProvider() error = %v", err)
+	}
+
+	tests := []struct {
+		from, to string
+		want     string
+		wantErr  error
+	}{
+		{"USD", "EUR", "0.8", nil},
+		{"EUR", "USD", "1.25", nil},
+		{"JPY", "USD", "0.006666666667", nil},
+		{"USD", "USD", "1", nil},
+		{"EUR", "JPY", "", ErrRateUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"/"+tt.to, func(t *testing.T) {
+			rate, err := provider.Rate(tt.from, tt.to)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Rate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rate() unexpected error: %v", err)
+			}
+			if !rate.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("Rate() = %s, want %s", rate, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStaticProvider_Invalid(t *testing.T) {
+	tests := map[string]map[string]decimal.Decimal{
+		"missing separator": {"USDEUR": decimal.NewFromInt(1)},
+		"unknown currency":  {"USD/XYZ": decimal.NewFromInt(1)},
+		"zero rate":         {"USD/EUR": decimal.Zero},
+		"negative rate":     {"USD/EUR": decimal.NewFromInt(-1)},
+	}
+
+	for name, rates := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewStaticProvider(rates); err == nil {
+				t.Error("NewStaticProvider() expected error but got none")
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"GBP/USD": "1.27"}`), 0o600); err != nil {
+		t.Fatalf("failed to write rates file: %v", err)
+	}
+
+	provider, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	rate, err := provider.Rate("GBP", "USD")
+	if err != nil || !rate.Equal(decimal.RequireFromString("1.27")) {
+		t.Errorf("Rate() = %s, %v, want 1.27", rate, err)
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadFile() expected error for missing file")
+	}
+}
@@ -0,0 +1,497 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// messageWriter defines the Kafka producer operations we need
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// messageReader defines the Kafka consumer group operations we need
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Client publishes to and consumes from a Kafka or Redpanda topic
+type Client struct {
+	writer  messageWriter
+	reader  messageReader
+	brokers []string
+	topic   string
+	timeout time.Duration
+	logger  *zap.Logger
+
+	mu         sync.Mutex
+	partitions map[int]*partitionOffsets
+	// keys holds the fetched messages that haven't been acked, by key
+	keys map[string]*keyMessages
+	// inFlight holds the messages handed out by Receive, by receipt
+	inFlight map[string]*message
+	// ready wakes Receive when a message may be due
+	ready chan struct{}
+}
+
+// Config holds Kafka configuration
+type Config struct {
+	Brokers []string
+	Topic   string
+	// GroupID is the consumer group; every replica of a region shares it
+	GroupID string
+	// Timeout bounds publishing, committing and health checks
+	Timeout time.Duration
+}
+
+// New creates a new Kafka client. The topic is created on first publish if
+// the cluster allows it.
+func New(config Config, logger *zap.Logger) (*Client, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("no Kafka brokers configured")
+	}
+
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(config.Brokers...),
+		Topic:                  config.Topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: config.Brokers,
+		GroupID: config.GroupID,
+		Topic:   config.Topic,
+	})
+
+	logger.Info("Kafka client initialized",
+		zap.Strings("brokers", config.Brokers),
+		zap.String("topic", config.Topic),
+		zap.String("group_id", config.GroupID),
+	)
+
+	return newClient(writer, reader, config, logger), nil
+}
+
+// newClient creates a client on top of writer and reader
+func newClient(writer messageWriter, reader messageReader, config Config, logger *zap.Logger) *Client {
+	return &Client{
+		writer:     writer,
+		reader:     reader,
+		brokers:    config.Brokers,
+		topic:      config.Topic,
+		timeout:    config.Timeout,
+		logger:     logger,
+		partitions: make(map[int]*partitionOffsets),
+		keys:       make(map[string]*keyMessages),
+		inFlight:   make(map[string]*message),
+		ready:      make(chan struct{}, 1),
+	}
+}
+
+// withTimeout derives the context of a single call from ctx, bounded by timeout
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Publish writes an event to the topic. Events are keyed by their ordering
+// key, the same key SQS groups them by, so the events of one account stay in
+// order on one partition.
+func (c *Client) Publish(ctx context.Context, event *events.Envelope) error {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	err = c.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.OrderingKey()),
+		Value: body,
+		Headers: []kafka.Header{
+			{Key: "Region", Value: []byte(event.ProducerRegion)},
+			{Key: "EventType", Value: []byte(event.Type)},
+			{Key: "SchemaVersion", Value: []byte(strconv.Itoa(event.SchemaVersion))},
+		},
+	})
+	if err != nil {
+		c.logger.Error("Failed to publish message to Kafka",
+			zap.Error(err),
+			zap.String("event_id", event.EventID.String()),
+		)
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	c.logger.Info("Message published",
+		zap.String("event_id", event.EventID.String()),
+		zap.String("event_type", event.Type),
+		zap.String("subject", event.Subject),
+	)
+
+	return nil
+}
+
+// Receive waits for the next messages of the consumer group and returns up
+// to max of them. Messages sharing a key are handed out one at a time, in
+// order: the next one is only returned once the one before it is acked. A
+// message that isn't acked, released or extended within visibility is
+// returned again, like an SQS message whose visibility timeout ran out.
+func (c *Client) Receive(ctx context.Context, max int, visibility time.Duration) ([]*events.Delivery, error) {
+	for {
+		if deliveries := c.take(max, visibility); len(deliveries) > 0 {
+			return deliveries, nil
+		}
+
+		kafkaMsg, err := c.fetch(ctx)
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, errWoken) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to fetch message: %w", err)
+		}
+		c.track(kafkaMsg)
+
+		event, err := events.Parse(kafkaMsg.Value)
+		if err != nil {
+			// No consumer can read it; skip it rather than hold up the partition
+			c.logger.Error("Skipping unreadable Kafka message",
+				zap.Error(err),
+				zap.Int("partition", kafkaMsg.Partition),
+				zap.Int64("offset", kafkaMsg.Offset),
+			)
+			if err := c.commit(ctx, kafkaMsg.Partition, kafkaMsg.Offset); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		key := string(kafkaMsg.Key)
+		if key == "" {
+			key = event.OrderingKey()
+		}
+		c.queue(&message{
+			key:       key,
+			partition: kafkaMsg.Partition,
+			offset:    kafkaMsg.Offset,
+			env:       *event,
+		})
+	}
+}
+
+// errWoken ends a fetch so that Receive can hand out a message that became
+// due meanwhile
+var errWoken = errors.New("woken for a due message")
+
+// fetch waits for the next message of the consumer group, or until a message
+// that was already fetched may be due
+func (c *Client) fetch(ctx context.Context) (kafka.Message, error) {
+	fetchCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		select {
+		case <-c.ready:
+			cancel(errWoken)
+		case <-fetchCtx.Done():
+		}
+	}()
+
+	kafkaMsg, err := c.reader.FetchMessage(fetchCtx)
+	if err != nil && errors.Is(context.Cause(fetchCtx), errWoken) {
+		return kafka.Message{}, errWoken
+	}
+	return kafkaMsg, err
+}
+
+// track starts tracking the offset of a fetched message
+func (c *Client) track(kafkaMsg kafka.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	partition, ok := c.partitions[kafkaMsg.Partition]
+	if !ok {
+		partition = &partitionOffsets{acked: make(map[int64]bool)}
+		c.partitions[kafkaMsg.Partition] = partition
+	}
+	partition.fetch(kafkaMsg.Offset)
+}
+
+// queue puts a fetched message behind the earlier messages of its key
+func (c *Client) queue(m *message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.keys[m.key]
+	if !ok {
+		key = &keyMessages{}
+		c.keys[m.key] = key
+	}
+	key.waiting = append(key.waiting, m)
+}
+
+// take hands out up to max messages whose key has no message in flight or
+// waiting out a release delay, and hides each for visibility
+func (c *Client) take(max int, visibility time.Duration) []*events.Delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if max < 1 {
+		max = 1
+	}
+	var deliveries []*events.Delivery
+	for _, key := range c.keys {
+		if len(deliveries) == max {
+			break
+		}
+		if !key.due() {
+			continue
+		}
+
+		m := key.waiting[0]
+		key.waiting = key.waiting[1:]
+		key.inFlight = m
+		m.receives++
+		m.receipt = fmt.Sprintf("%d:%d:%d", m.partition, m.offset, m.receives)
+		c.inFlight[m.receipt] = m
+		c.hide(m, visibility)
+
+		env := m.env
+		deliveries = append(deliveries, &events.Delivery{
+			Envelope:     &env,
+			Receipt:      m.receipt,
+			ReceiveCount: m.receives,
+			OrderingKey:  m.key,
+		})
+	}
+	return deliveries
+}
+
+// hide keeps a message in flight for visibility, after which it is handed
+// out again; the caller holds c.mu
+func (c *Client) hide(m *message, visibility time.Duration) {
+	if m.lapse != nil {
+		m.lapse.Stop()
+		m.lapse = nil
+	}
+	if visibility <= 0 {
+		return
+	}
+	receipt := m.receipt
+	m.lapse = time.AfterFunc(visibility, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.inFlight[receipt] != m {
+			return
+		}
+		c.logger.Warn("Kafka message not settled within its visibility timeout; delivering it again",
+			zap.Int("partition", m.partition),
+			zap.Int64("offset", m.offset),
+		)
+		c.requeue(m, 0)
+	})
+}
+
+// settle takes the message of a receipt out of flight; the caller holds c.mu
+func (c *Client) settle(receipt string) (*message, error) {
+	m, ok := c.inFlight[receipt]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", events.ErrUnknownDelivery, receipt)
+	}
+	delete(c.inFlight, receipt)
+	if m.lapse != nil {
+		m.lapse.Stop()
+		m.lapse = nil
+	}
+	c.keys[m.key].inFlight = nil
+	return m, nil
+}
+
+// requeue puts a message in flight back at the head of its key, to be handed
+// out again after delay; the caller holds c.mu
+func (c *Client) requeue(m *message, delay time.Duration) {
+	delete(c.inFlight, m.receipt)
+	key := c.keys[m.key]
+	key.inFlight = nil
+	key.waiting = append([]*message{m}, key.waiting...)
+	if delay <= 0 {
+		c.signal()
+		return
+	}
+
+	key.delayed = true
+	time.AfterFunc(delay, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		key.delayed = false
+		c.signal()
+	})
+}
+
+// signal wakes a waiting Receive; the caller holds c.mu
+func (c *Client) signal() {
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ack marks a message handled and hands out the next message of its key.
+// Kafka commits an offset for a whole partition, so the consumer group's
+// offset only moves past messages once every earlier message of the
+// partition is acked too.
+func (c *Client) Ack(ctx context.Context, delivery *events.Delivery) error {
+	c.mu.Lock()
+	m, err := c.settle(delivery.Receipt)
+	if err == nil {
+		if key := c.keys[m.key]; len(key.waiting) == 0 && !key.delayed {
+			delete(c.keys, m.key)
+		}
+		c.signal()
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return c.commit(ctx, m.partition, m.offset)
+}
+
+// commit marks the message at offset of partition handled and commits the
+// partition's offset as far as its handled messages allow
+func (c *Client) commit(ctx context.Context, partition int, offset int64) error {
+	c.mu.Lock()
+	commit := int64(-1)
+	if tracked, ok := c.partitions[partition]; ok {
+		commit = tracked.ack(offset)
+	}
+	c.mu.Unlock()
+	if commit < 0 {
+		return nil
+	}
+
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+	if err := c.reader.CommitMessages(ctx, kafka.Message{Topic: c.topic, Partition: partition, Offset: commit}); err != nil {
+		return fmt.Errorf("failed to commit offset: %w", err)
+	}
+	return nil
+}
+
+// Extend keeps a message in flight for another visibility, so it isn't
+// handed out again while its handler is still running
+func (c *Client) Extend(ctx context.Context, delivery *events.Delivery, visibility time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, ok := c.inFlight[delivery.Receipt]
+	if !ok {
+		return fmt.Errorf("%w: %q", events.ErrUnknownDelivery, delivery.Receipt)
+	}
+	c.hide(m, visibility)
+	return nil
+}
+
+// Release hands a message back to be delivered again after delay. It stays
+// ahead of the later messages of its key, which wait for it, and its offset
+// isn't committed until it is acked.
+func (c *Client) Release(ctx context.Context, delivery *events.Delivery, delay time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, err := c.settle(delivery.Receipt)
+	if err != nil {
+		return err
+	}
+	c.requeue(m, delay)
+	return nil
+}
+
+// Health checks if a broker is reachable
+func (c *Client) Health(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var err error
+	for _, broker := range c.brokers {
+		var conn *kafka.Conn
+		if conn, err = kafka.DialContext(ctx, "tcp", broker); err == nil {
+			conn.Close()
+			return nil
+		}
+	}
+	return fmt.Errorf("Kafka health check failed: %w", err)
+}
+
+// Close closes the producer and leaves the consumer group
+func (c *Client) Close() error {
+	return errors.Join(c.writer.Close(), c.reader.Close())
+}
+
+// message is a fetched message that hasn't been acked
+type message struct {
+	key       string
+	partition int
+	offset    int64
+	env       events.Envelope
+	receives  int
+	// receipt identifies the message's current delivery
+	receipt string
+	// lapse hands the message out again once its visibility runs out
+	lapse *time.Timer
+}
+
+// keyMessages holds the unacked messages of one key, so they are handled one
+// at a time and in order
+type keyMessages struct {
+	inFlight *message
+	// waiting holds the messages to hand out next, in order
+	waiting []*message
+	// delayed is set while the head of waiting waits out a release delay
+	delayed bool
+}
+
+// due reports whether the next message of the key can be handed out
+func (k *keyMessages) due() bool {
+	return k.inFlight == nil && !k.delayed && len(k.waiting) > 0
+}
+
+// partitionOffsets tracks the messages of one partition that were fetched
+// but not committed yet
+type partitionOffsets struct {
+	pending []int64
+	acked   map[int64]bool
+}
+
+// fetch records a fetched offset; offsets arrive in order
+func (p *partitionOffsets) fetch(offset int64) {
+	p.pending = append(p.pending, offset)
+}
+
+// ack marks offset handled and returns the highest offset whose earlier
+// offsets are all handled, or -1 when the commit can't move yet
+func (p *partitionOffsets) ack(offset int64) int64 {
+	p.acked[offset] = true
+
+	commit := int64(-1)
+	for len(p.pending) > 0 && p.acked[p.pending[0]] {
+		commit = p.pending[0]
+		delete(p.acked, commit)
+		p.pending = p.pending[1:]
+	}
+	return commit
+}
@@ -0,0 +1,307 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+type fakeWriter struct {
+	written []kafka.Message
+	err     error
+}
+
+func (f *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.written = append(f.written, msgs...)
+	return nil
+}
+
+func (f *fakeWriter) Close() error { return nil }
+
+type fakeReader struct {
+	messages  []kafka.Message
+	committed []kafka.Message
+}
+
+func (f *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(f.messages) == 0 {
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+	msg := f.messages[0]
+	f.messages = f.messages[1:]
+	return msg, nil
+}
+
+func (f *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	f.committed = append(f.committed, msgs...)
+	return nil
+}
+
+func (f *fakeReader) Close() error { return nil }
+
+func newTestClient(writer *fakeWriter, reader *fakeReader) *Client {
+	return newClient(writer, reader, Config{Topic: "transactions"}, zap.NewNop())
+}
+
+// newTestEvent returns a TransactionCreated event about subject
+func newTestEvent(subject string) *events.Envelope {
+	return &events.Envelope{
+		EventID:        uuid.New(),
+		Type:           events.TypeTransactionCreated,
+		SchemaVersion:  1,
+		Subject:        subject,
+		ProducerRegion: "us-east-1",
+		Payload:        json.RawMessage(`{}`),
+	}
+}
+
+func kafkaMessage(t *testing.T, partition int, offset int64, subject string) kafka.Message {
+	t.Helper()
+	return keyedMessage(t, partition, offset, subject, subject)
+}
+
+// keyedMessage returns a message about subject published under key
+func keyedMessage(t *testing.T, partition int, offset int64, key, subject string) kafka.Message {
+	t.Helper()
+	body, err := json.Marshal(newTestEvent(subject))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kafka.Message{Topic: "transactions", Partition: partition, Offset: offset, Key: []byte(key), Value: body}
+}
+
+// receiveOne receives a single message, failing the test if none arrives
+// within a second
+func receiveOne(t *testing.T, client *Client, visibility time.Duration) *events.Delivery {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	deliveries, err := client.Receive(ctx, 1, visibility)
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	return deliveries[0]
+}
+
+// expectNothing fails the test if a message is received within wait
+func expectNothing(t *testing.T, client *Client, wait time.Duration) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+	if deliveries, err := client.Receive(ctx, 1, 0); err == nil {
+		t.Fatalf("Expected no message, got %s", deliveries[0].Envelope.Subject)
+	}
+}
+
+func TestClient_Publish(t *testing.T) {
+	writer := &fakeWriter{}
+	client := newTestClient(writer, &fakeReader{})
+
+	event := newTestEvent("tx-1")
+	event.SourceAccount = "acc-1"
+	err := client.Publish(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(writer.written) != 1 {
+		t.Fatalf("Expected 1 message written, got %d", len(writer.written))
+	}
+	written := writer.written[0]
+	if string(written.Key) != "acc-1" {
+		t.Errorf("Expected the message to be keyed by source account, got %q", written.Key)
+	}
+	if event, err := events.Parse(written.Value); err != nil || event.Type != events.TypeTransactionCreated {
+		t.Errorf("Unexpected message body %s", written.Value)
+	}
+	headers := map[string]string{}
+	for _, header := range written.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	if headers["Region"] != "us-east-1" || headers["EventType"] != events.TypeTransactionCreated || headers["SchemaVersion"] != "1" {
+		t.Errorf("Unexpected headers %v", headers)
+	}
+}
+
+func TestClient_Publish_Error(t *testing.T) {
+	client := newTestClient(&fakeWriter{err: errors.New("leader not available")}, &fakeReader{})
+
+	if err := client.Publish(context.Background(), newTestEvent("tx-1")); err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
+
+func TestClient_AckCommitsContiguousOffsets(t *testing.T) {
+	reader := &fakeReader{messages: []kafka.Message{
+		kafkaMessage(t, 0, 5, "tx-5"),
+		kafkaMessage(t, 0, 6, "tx-6"),
+		kafkaMessage(t, 0, 7, "tx-7"),
+	}}
+	client := newTestClient(&fakeWriter{}, reader)
+	ctx := context.Background()
+
+	var deliveries []*events.Delivery
+	for i := 0; i < 3; i++ {
+		received, err := client.Receive(ctx, 10, 0)
+		if err != nil {
+			t.Fatalf("Receive: %v", err)
+		}
+		if len(received) != 1 {
+			t.Fatalf("Expected one message at a time, got %d", len(received))
+		}
+		deliveries = append(deliveries, received[0])
+	}
+	if deliveries[1].Envelope.Subject != "tx-6" {
+		t.Errorf("Expected tx-6, got %s", deliveries[1].Envelope.Subject)
+	}
+
+	// Offset 6 can't be committed while 5 is still being handled
+	if err := client.Ack(ctx, deliveries[1]); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if len(reader.committed) != 0 {
+		t.Fatalf("Expected no commit yet, got %v", reader.committed)
+	}
+
+	if err := client.Ack(ctx, deliveries[0]); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if len(reader.committed) != 1 || reader.committed[0].Offset != 6 {
+		t.Fatalf("Expected offset 6 to be committed, got %v", reader.committed)
+	}
+
+	if err := client.Ack(ctx, deliveries[2]); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if len(reader.committed) != 2 || reader.committed[1].Offset != 7 {
+		t.Errorf("Expected offset 7 to be committed, got %v", reader.committed)
+	}
+}
+
+func TestClient_Receive_SkipsUnreadableMessages(t *testing.T) {
+	reader := &fakeReader{messages: []kafka.Message{
+		{Topic: "transactions", Partition: 2, Offset: 10, Value: []byte("not json")},
+		kafkaMessage(t, 2, 11, "tx-11"),
+	}}
+	client := newTestClient(&fakeWriter{}, reader)
+
+	deliveries, err := client.Receive(context.Background(), 1, 0)
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if deliveries[0].Envelope.Subject != "tx-11" {
+		t.Errorf("Expected tx-11, got %s", deliveries[0].Envelope.Subject)
+	}
+	if len(reader.committed) != 1 || reader.committed[0].Partition != 2 || reader.committed[0].Offset != 10 {
+		t.Errorf("Expected the unreadable message to be committed, got %v", reader.committed)
+	}
+}
+
+func TestClient_Ack_InvalidReceipt(t *testing.T) {
+	client := newTestClient(&fakeWriter{}, &fakeReader{})
+
+	for _, receipt := range []string{"", "garbage", "1:x", "3:4:1"} {
+		if err := client.Ack(context.Background(), &events.Delivery{Receipt: receipt}); !errors.Is(err, events.ErrUnknownDelivery) {
+			t.Errorf("Expected ErrUnknownDelivery for receipt %q, got %v", receipt, err)
+		}
+	}
+}
+
+func TestClient_Release_RedeliversAfterDelay(t *testing.T) {
+	reader := &fakeReader{messages: []kafka.Message{kafkaMessage(t, 0, 3, "tx-3")}}
+	client := newTestClient(&fakeWriter{}, reader)
+	ctx := context.Background()
+
+	first := receiveOne(t, client, 0)
+	if err := client.Release(ctx, first, 50*time.Millisecond); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	expectNothing(t, client, 20*time.Millisecond)
+
+	second := receiveOne(t, client, 0)
+	if second.Envelope.Subject != "tx-3" || second.ReceiveCount != 2 {
+		t.Fatalf("Expected tx-3 on its second delivery, got %s on delivery %d", second.Envelope.Subject, second.ReceiveCount)
+	}
+	if len(reader.committed) != 0 {
+		t.Fatalf("Expected no commit for a released message, got %v", reader.committed)
+	}
+
+	// The first delivery's receipt is stale
+	if err := client.Ack(ctx, first); !errors.Is(err, events.ErrUnknownDelivery) {
+		t.Errorf("Expected ErrUnknownDelivery, got %v", err)
+	}
+	if err := client.Ack(ctx, second); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if len(reader.committed) != 1 || reader.committed[0].Offset != 3 {
+		t.Errorf("Expected offset 3 to be committed, got %v", reader.committed)
+	}
+}
+
+func TestClient_Receive_HoldsBackLaterMessagesOfKey(t *testing.T) {
+	reader := &fakeReader{messages: []kafka.Message{
+		keyedMessage(t, 0, 1, "acc-1", "tx-1"),
+		keyedMessage(t, 0, 2, "acc-1", "tx-2"),
+		keyedMessage(t, 0, 3, "acc-2", "tx-3"),
+	}}
+	client := newTestClient(&fakeWriter{}, reader)
+	ctx := context.Background()
+
+	first := receiveOne(t, client, 0)
+	if first.Envelope.Subject != "tx-1" || first.OrderingKey != "acc-1" {
+		t.Fatalf("Expected tx-1 keyed acc-1, got %s keyed %s", first.Envelope.Subject, first.OrderingKey)
+	}
+	// tx-2 waits behind tx-1, but acc-2 isn't held up
+	if other := receiveOne(t, client, 0); other.Envelope.Subject != "tx-3" {
+		t.Fatalf("Expected tx-3, got %s", other.Envelope.Subject)
+	}
+
+	if err := client.Release(ctx, first, 0); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	retried := receiveOne(t, client, 0)
+	if retried.Envelope.Subject != "tx-1" {
+		t.Fatalf("Expected tx-1 to be delivered again before tx-2, got %s", retried.Envelope.Subject)
+	}
+	expectNothing(t, client, 20*time.Millisecond)
+
+	if err := client.Ack(ctx, retried); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if next := receiveOne(t, client, 0); next.Envelope.Subject != "tx-2" {
+		t.Errorf("Expected tx-2 once tx-1 is acked, got %s", next.Envelope.Subject)
+	}
+}
+
+func TestClient_Extend_PostponesRedelivery(t *testing.T) {
+	reader := &fakeReader{messages: []kafka.Message{kafkaMessage(t, 0, 8, "tx-8")}}
+	client := newTestClient(&fakeWriter{}, reader)
+	ctx := context.Background()
+
+	delivery := receiveOne(t, client, 40*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if err := client.Extend(ctx, delivery, 100*time.Millisecond); err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	// Without the extension the message would be handed out again by now
+	expectNothing(t, client, 50*time.Millisecond)
+
+	redelivered := receiveOne(t, client, 0)
+	if redelivered.Envelope.Subject != "tx-8" || redelivered.ReceiveCount != 2 {
+		t.Fatalf("Expected tx-8 on its second delivery, got %s on delivery %d", redelivered.Envelope.Subject, redelivered.ReceiveCount)
+	}
+	if err := client.Extend(ctx, delivery, time.Second); !errors.Is(err, events.ErrUnknownDelivery) {
+		t.Errorf("Expected ErrUnknownDelivery for a lapsed delivery, got %v", err)
+	}
+}
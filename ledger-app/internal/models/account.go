@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Account statuses
+const (
+	AccountStatusActive = "active"
+	AccountStatusClosed = "closed"
+)
+
+var (
+	// ErrAccountNotFound is returned when an account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrAccountExists is returned when creating an account whose ID is already taken
+	ErrAccountExists = errors.New("account already exists")
+	// ErrAccountClosed is returned when a closed account is used in a transfer
+	ErrAccountClosed = errors.New("account is closed")
+	// ErrInsufficientFunds is returned when a debit would take an account
+	// below its overdraft limit
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// Account represents a ledger account that transfers are posted against
+type Account struct {
+	ID             string          `json:"id" db:"id"`
+	Owner          string          `json:"owner" db:"owner"`
+	Currency       string          `json:"currency" db:"currency"`
+	Status         string          `json:"status" db:"status"`
+	Balance        decimal.Decimal `json:"balance" db:"balance"`
+	Held           decimal.Decimal `json:"held" db:"held"`
+	OverdraftLimit decimal.Decimal `json:"overdraft_limit" db:"overdraft_limit"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// AccountRequest represents an incoming account creation request
+type AccountRequest struct {
+	ID             string `json:"id,omitempty"`
+	Owner          string `json:"owner"`
+	Currency       string `json:"currency"`
+	OverdraftLimit string `json:"overdraft_limit,omitempty"`
+}
+
+// AccountResponse represents the API response for account operations
+type AccountResponse struct {
+	Account *Account `json:"account,omitempty"`
+	Message string   `json:"message,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// AccountBalance represents the balance of an account at a point in time.
+// Balance is the ledger balance, the sum of all postings; Available is what
+// can still be spent once active holds are taken out.
+type AccountBalance struct {
+	AccountID string          `json:"account_id"`
+	Currency  string          `json:"currency"`
+	Balance   decimal.Decimal `json:"balance"`
+	Held      decimal.Decimal `json:"held"`
+	Available decimal.Decimal `json:"available"`
+	AsOf      time.Time       `json:"as_of"`
+}
+
+// IsActive reports whether the account can take part in transfers
+func (a *Account) IsActive() bool {
+	return a.Status == AccountStatusActive
+}
+
+// Available returns the ledger balance less the funds reserved by active holds
+func (a *Account) Available() decimal.Decimal {
+	return a.Balance.Sub(a.Held)
+}
+
+// CanDebit reports whether amount can be taken from the account's available
+// balance without going below its overdraft limit
+func (a *Account) CanDebit(amount decimal.Decimal) bool {
+	return a.Available().Sub(amount).GreaterThanOrEqual(a.OverdraftLimit.Neg())
+}
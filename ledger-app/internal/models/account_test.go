@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAccount_IsActive(t *testing.T) {
+	if !(&Account{Status: AccountStatusActive}).IsActive() {
+		t.Error("IsActive() = false for active account")
+	}
+	if (&Account{Status: AccountStatusClosed}).IsActive() {
+		t.Error("IsActive() = true for closed account")
+	}
+}
+
+func TestIsValidCurrencyCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"USD", true},
+		{"EUR", true},
+		{"usd", false},
+		{"US", false},
+		{"USDT", false},
+		{"JPY", true},
+		{"BTC", true},
+		{"XYZ", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsValidCurrencyCode(tt.code); got != tt.want {
+			t.Errorf("IsValidCurrencyCode(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestAccount_CanDebit(t *testing.T) {
+	tests := []struct {
+		name      string
+		balance   string
+		held      string
+		overdraft string
+		amount    string
+		want      bool
+	}{
+		{"within balance", "100", "0", "0", "40", true},
+		{"exact balance", "100", "0", "0", "100", true},
+		{"over balance without overdraft", "100", "0", "0", "100.01", false},
+		{"into overdraft", "100", "0", "50", "150", true},
+		{"beyond overdraft", "100", "0", "50", "150.01", false},
+		{"already overdrawn", "-10", "0", "50", "41", false},
+		{"within available", "100", "60", "0", "40", true},
+		{"over available", "100", "60", "0", "40.01", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &Account{
+				Balance:        decimal.RequireFromString(tt.balance),
+				Held:           decimal.RequireFromString(tt.held),
+				OverdraftLimit: decimal.RequireFromString(tt.overdraft),
+			}
+			if got := account.CanDebit(decimal.RequireFromString(tt.amount)); got != tt.want {
+				t.Errorf("CanDebit(%s) = %v, want %v", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxBatchSize is the largest number of transfers accepted in one batch
+// when no limit is configured
+const DefaultMaxBatchSize = 500
+
+// BatchRequest represents an incoming batch of transfers
+type BatchRequest struct {
+	Transactions []TransactionRequest `json:"transactions"`
+}
+
+// BatchItemResult is the outcome of one transfer in a batch, in request order
+type BatchItemResult struct {
+	Index       int          `json:"index"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Code        string       `json:"code,omitempty"`
+}
+
+// BatchResponse represents the API response for batch operations
+type BatchResponse struct {
+	BatchID uuid.UUID         `json:"batch_id"`
+	Results []BatchItemResult `json:"results,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Code    string            `json:"code,omitempty"`
+}
+
+// BatchItemError ties an error to the transfer in a batch that caused it
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+// Error implements error
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("batch item %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error so errors.Is sees through it
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// BatchAuditLog represents the audit log entry for a whole batch in S3
+type BatchAuditLog struct {
+	BatchID        uuid.UUID   `json:"batch_id"`
+	Region         string      `json:"region"`
+	Action         string      `json:"action"`
+	Timestamp      time.Time   `json:"timestamp"`
+	TransactionIDs []uuid.UUID `json:"transaction_ids"`
+	Details        string      `json:"details"`
+}
+
+// ToJSON converts BatchAuditLog to JSON string
+func (a *BatchAuditLog) ToJSON() (string, error) {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
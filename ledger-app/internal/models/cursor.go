@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TransactionCursor marks a position in the transaction list, which is
+// ordered by timestamp or amount and then ID. The next page starts with
+// the first transaction after the one the cursor was taken from.
+type TransactionCursor struct {
+	Timestamp time.Time        `json:"t"`
+	Amount    *decimal.Decimal `json:"a,omitempty"`
+	ID        uuid.UUID        `json:"id"`
+}
+
+// CursorAfter returns the cursor for the page that follows tx
+func CursorAfter(tx *Transaction) TransactionCursor {
+	amount := tx.Amount.Value
+	return TransactionCursor{Timestamp: tx.Timestamp, Amount: &amount, ID: tx.ID}
+}
+
+// Encode returns the cursor as an opaque, URL-safe token
+func (c TransactionCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token returned by TransactionCursor.Encode
+func DecodeCursor(token string) (TransactionCursor, error) {
+	var cursor TransactionCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if cursor.Timestamp.IsZero() || cursor.ID == uuid.Nil {
+		return cursor, fmt.Errorf("%w: missing position", ErrInvalidCursor)
+	}
+	return cursor, nil
+}
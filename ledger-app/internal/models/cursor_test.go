@@ -0,0 +1,37 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTransactionCursor_RoundTrip(t *testing.T) {
+	tx := &Transaction{ID: uuid.New(), Timestamp: time.Date(2026, 3, 1, 12, 30, 0, 123456000, time.UTC)}
+
+	cursor, err := DecodeCursor(CursorAfter(tx).Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cursor.ID != tx.ID || !cursor.Timestamp.Equal(tx.Timestamp) {
+		t.Errorf("Expected cursor at (%s, %s), got (%s, %s)", tx.Timestamp, tx.ID, cursor.Timestamp, cursor.ID)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	tokens := []string{
+		"not base64!",
+		"bm90IGpzb24", // "not json"
+		"e30",         // "{}"
+		TransactionCursor{ID: uuid.New()}.Encode(), // no timestamp
+	}
+
+	for _, token := range tokens {
+		if _, err := DecodeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q): expected ErrInvalidCursor, got %v", token, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrUnbalancedEntries is returned when a set of postings does not sum to zero
+var ErrUnbalancedEntries = errors.New("ledger entries are not balanced")
+
+// Entry represents a single posting against an account in the double-entry journal.
+// Debits are stored as negative amounts and credits as positive amounts, so the
+// entries of every transaction must sum to zero.
+type Entry struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	TransactionID uuid.UUID       `json:"transaction_id" db:"transaction_id"`
+	Account       string          `json:"account" db:"account"`
+	Amount        decimal.Decimal `json:"amount" db:"amount"`
+	Currency      string          `json:"currency" db:"currency"`
+	Region        string          `json:"region" db:"region"`
+	Timestamp     time.Time       `json:"timestamp" db:"timestamp"`
+}
+
+// IsDebit reports whether the entry takes value out of its account
+func (e *Entry) IsDebit() bool {
+	return e.Amount.IsNegative()
+}
+
+// NewTransferEntries builds the debit and credit postings for a transfer
+func NewTransferEntries(tx *Transaction) []*Entry {
+	return []*Entry{
+		{
+			ID:            uuid.New(),
+			TransactionID: tx.ID,
+			Account:       tx.FromAccount,
+			Amount:        tx.Amount.Value.Neg(),
+			Currency:      tx.Amount.Currency,
+			Region:        tx.Region,
+			Timestamp:     tx.Timestamp,
+		},
+		{
+			ID:            uuid.New(),
+			TransactionID: tx.ID,
+			Account:       tx.ToAccount,
+			Amount:        tx.Amount.Value,
+			Currency:      tx.Amount.Currency,
+			Region:        tx.Region,
+			Timestamp:     tx.Timestamp,
+		},
+	}
+}
+
+// ValidateEntries checks the double-entry invariant: at least one debit and one
+// credit, no zero postings, every posting within its currency's scale, and the
+// postings of each currency summing to zero
+func ValidateEntries(entries []*Entry) error {
+	if len(entries) < 2 {
+		return fmt.Errorf("%w: expected at least 2 entries, got %d", ErrUnbalancedEntries, len(entries))
+	}
+
+	sums := make(map[string]decimal.Decimal)
+	var currencies []string
+	var debits, credits int
+	for _, e := range entries {
+		if e.Amount.IsZero() {
+			return fmt.Errorf("%w: zero amount posting for account %s", ErrUnbalancedEntries, e.Account)
+		}
+		if err := (Money{Value: e.Amount, Currency: e.Currency}).Validate(); err != nil {
+			return fmt.Errorf("invalid posting for account %s: %w", e.Account, err)
+		}
+		if e.IsDebit() {
+			debits++
+		} else {
+			credits++
+		}
+		if _, seen := sums[e.Currency]; !seen {
+			currencies = append(currencies, e.Currency)
+		}
+		sums[e.Currency] = sums[e.Currency].Add(e.Amount)
+	}
+
+	if debits == 0 || credits == 0 {
+		return fmt.Errorf("%w: entries must contain both debits and credits", ErrUnbalancedEntries)
+	}
+	for _, currency := range currencies {
+		if !sums[currency].IsZero() {
+			return fmt.Errorf("%w: %s entries sum to %s", ErrUnbalancedEntries, currency, sums[currency].String())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestNewTransferEntries(t *testing.T) {
+	tx := &Transaction{
+		ID:          uuid.New(),
+		Region:      "us-east-1",
+		Amount:      Money{Value: decimal.RequireFromString("100.50"), Currency: "USD"},
+		FromAccount: "account-1",
+		ToAccount:   "account-2",
+		Timestamp:   parseTime("2024-01-01T00:00:00Z"),
+	}
+
+	entries := NewTransferEntries(tx)
+	if len(entries) != 2 {
+		t.Fatalf("NewTransferEntries() returned %d entries, want 2", len(entries))
+	}
+
+	debit, credit := entries[0], entries[1]
+	if debit.Account != "account-1" || !debit.IsDebit() {
+		t.Errorf("first entry should debit account-1, got %s %s", debit.Account, debit.Amount)
+	}
+	if credit.Account != "account-2" || credit.IsDebit() {
+		t.Errorf("second entry should credit account-2, got %s %s", credit.Account, credit.Amount)
+	}
+	for _, e := range entries {
+		if e.TransactionID != tx.ID {
+			t.Errorf("entry TransactionID = %v, want %v", e.TransactionID, tx.ID)
+		}
+		if e.Currency != "USD" {
+			t.Errorf("entry Currency = %s, want USD", e.Currency)
+		}
+	}
+
+	if err := ValidateEntries(entries); err != nil {
+		t.Errorf("ValidateEntries() on transfer entries error = %v", err)
+	}
+}
+
+func TestValidateEntries(t *testing.T) {
+	entry := func(account, amount string) *Entry {
+		return &Entry{ID: uuid.New(), Account: account, Amount: decimal.RequireFromString(amount), Currency: "USD"}
+	}
+	inCurrency := func(e *Entry, currency string) *Entry {
+		e.Currency = currency
+		return e
+	}
+
+	tests := []struct {
+		name      string
+		entries   []*Entry
+		wantError bool
+	}{
+		{
+			name:    "balanced transfer",
+			entries: []*Entry{entry("a", "-10.00"), entry("b", "10.00")},
+		},
+		{
+			name:    "balanced split across several credits",
+			entries: []*Entry{entry("a", "-10.00"), entry("b", "7.50"), entry("c", "2.50")},
+		},
+		{
+			name: "balanced in each currency",
+			entries: []*Entry{
+				entry("a", "-10.00"), entry("b", "10.00"),
+				inCurrency(entry("c", "-1500"), "JPY"), inCurrency(entry("d", "1500"), "JPY"),
+			},
+		},
+		{
+			name: "balanced overall but not per currency",
+			entries: []*Entry{
+				entry("a", "-10.00"), inCurrency(entry("b", "10.00"), "EUR"),
+			},
+			wantError: true,
+		},
+		{
+			name:      "no entries",
+			entries:   nil,
+			wantError: true,
+		},
+		{
+			name:      "single entry",
+			entries:   []*Entry{entry("a", "-10.00")},
+			wantError: true,
+		},
+		{
+			name:      "sum is not zero",
+			entries:   []*Entry{entry("a", "-10.00"), entry("b", "9.99")},
+			wantError: true,
+		},
+		{
+			name:      "zero posting",
+			entries:   []*Entry{entry("a", "-10.00"), entry("b", "10.00"), entry("c", "0")},
+			wantError: true,
+		},
+		{
+			name:      "only credits",
+			entries:   []*Entry{entry("a", "10.00"), entry("b", "10.00")},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEntries(tt.entries)
+			if tt.wantError {
+				if !errors.Is(err, ErrUnbalancedEntries) {
+					t.Errorf("ValidateEntries() error = %v, want ErrUnbalancedEntries", err)
+				}
+			} else if err != nil {
+				t.Errorf("ValidateEntries() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateEntries_Scale(t *testing.T) {
+	entries := []*Entry{
+		{ID: uuid.New(), Account: "a", Amount: decimal.RequireFromString("-1.5"), Currency: "JPY"},
+		{ID: uuid.New(), Account: "b", Amount: decimal.RequireFromString("1.5"), Currency: "JPY"},
+	}
+
+	if err := ValidateEntries(entries); !errors.Is(err, ErrInvalidScale) {
+		t.Errorf("ValidateEntries() error = %v, want ErrInvalidScale", err)
+	}
+}
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// RateScale is the number of decimal places FX rates are stored with
+const RateScale = 12
+
+// fxPositionPrefix marks the system accounts that hold the ledger's position
+// in each currency. Cross-currency transfers go through them so the postings
+// of each currency still sum to zero.
+const fxPositionPrefix = "fx-position-"
+
+var (
+	// ErrQuoteNotFound is returned when an FX quote does not exist
+	ErrQuoteNotFound = errors.New("fx quote not found")
+	// ErrQuoteExpired is returned when an FX quote is used after it expired
+	ErrQuoteExpired = errors.New("fx quote has expired")
+	// ErrQuoteUsed is returned when an FX quote has already funded a transfer
+	ErrQuoteUsed = errors.New("fx quote has already been used")
+	// ErrQuoteMismatch is returned when a transfer doesn't match the quote it uses
+	ErrQuoteMismatch = errors.New("transfer does not match fx quote")
+	// ErrSystemAccount is returned when a transfer names a system account as
+	// its source or destination
+	ErrSystemAccount = errors.New("system accounts cannot be used in transfers")
+)
+
+// FXQuote locks an exchange rate for converting a specific amount until it expires
+type FXQuote struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	Rate          decimal.Decimal `json:"rate" db:"rate"`
+	SourceAmount  Money           `json:"source_amount" db:"source_amount"`
+	TargetAmount  Money           `json:"target_amount" db:"target_amount"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	ExpiresAt     time.Time       `json:"expires_at" db:"expires_at"`
+	TransactionID *uuid.UUID      `json:"transaction_id,omitempty" db:"transaction_id"`
+}
+
+// fxQuoteJSON carries the currencies of a quote's amounts on the wire
+type fxQuoteJSON struct {
+	SourceCurrency string `json:"source_currency"`
+	TargetCurrency string `json:"target_currency"`
+}
+
+// MarshalJSON sends the currencies of SourceAmount and TargetAmount as
+// source_currency and target_currency
+func (q FXQuote) MarshalJSON() ([]byte, error) {
+	type quote FXQuote
+	return json.Marshal(struct {
+		quote
+		fxQuoteJSON
+	}{quote(q), fxQuoteJSON{SourceCurrency: q.SourceAmount.Currency, TargetCurrency: q.TargetAmount.Currency}})
+}
+
+// UnmarshalJSON decodes a quote written by MarshalJSON
+func (q *FXQuote) UnmarshalJSON(data []byte) error {
+	type quote FXQuote
+	in := struct {
+		*quote
+		fxQuoteJSON
+	}{quote: (*quote)(q)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	q.SourceAmount.Currency = in.SourceCurrency
+	q.TargetAmount.Currency = in.TargetCurrency
+	return nil
+}
+
+// FXQuoteRequest represents an incoming quote request
+type FXQuoteRequest struct {
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	Amount       string `json:"amount"`
+}
+
+// FXQuoteResponse represents the API response for quote operations
+type FXQuoteResponse struct {
+	Quote   *FXQuote `json:"quote,omitempty"`
+	Message string   `json:"message,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// NewFXQuote converts source at rate and builds a quote valid for ttl
+func NewFXQuote(source Money, toCurrency string, rate decimal.Decimal, ttl time.Duration) (*FXQuote, error) {
+	target, err := Convert(source, toCurrency, rate)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &FXQuote{
+		ID:           uuid.New(),
+		Rate:         rate.Round(RateScale),
+		SourceAmount: source,
+		TargetAmount: target,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+	}, nil
+}
+
+// IsExpired reports whether the quote can no longer be used at now
+func (q *FXQuote) IsExpired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
+
+// Convert converts source into currency at rate, rounding half-to-even to the
+// target currency's scale
+func Convert(source Money, currency string, rate decimal.Decimal) (Money, error) {
+	scale, ok := CurrencyScale(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+	if !rate.IsPositive() {
+		return Money{}, fmt.Errorf("invalid fx rate %s", rate.String())
+	}
+
+	converted := Money{Value: source.Value.Mul(rate).RoundBank(scale), Currency: currency}
+	if converted.IsZero() && !source.IsZero() {
+		return Money{}, fmt.Errorf("%w: %s converts to zero %s", ErrInvalidScale, source, currency)
+	}
+	return converted, nil
+}
+
+// FXPositionAccount returns the ID of the system account holding the ledger's
+// position in currency
+func FXPositionAccount(currency string) string {
+	return fxPositionPrefix + currency
+}
+
+// IsSystemAccount reports whether id belongs to an account the ledger manages
+// itself. System accounts can't be created through the API or used as either
+// side of a transfer; only the FX legs of a transfer post to them.
+func IsSystemAccount(id string) bool {
+	return strings.HasPrefix(id, fxPositionPrefix)
+}
+
+// IsFXLeg reports whether e is one of the FX position postings that
+// NewFXTransferEntries adds to t. Those positions may go negative, so their
+// debits are not funds checked.
+func (t *Transaction) IsFXLeg(e *Entry) bool {
+	if !t.IsFX() || e.Account == t.FromAccount || e.Account == t.ToAccount {
+		return false
+	}
+	return e.Account == FXPositionAccount(t.Amount.Currency) || e.Account == FXPositionAccount(t.ConvertedAmount.Currency)
+}
+
+// IsFX reports whether the transaction converts between currencies
+func (t *Transaction) IsFX() bool {
+	return t.ConvertedAmount != nil
+}
+
+// NewFXTransferEntries builds the postings for a cross-currency transfer. The
+// source account is debited in its currency and the destination credited in
+// its own, with the FX position account of each currency taking the other
+// side so each currency balances on its own.
+func NewFXTransferEntries(tx *Transaction) []*Entry {
+	source, target := tx.Amount, *tx.ConvertedAmount
+	posting := func(account string, amount decimal.Decimal, currency string) *Entry {
+		return &Entry{
+			ID:            uuid.New(),
+			TransactionID: tx.ID,
+			Account:       account,
+			Amount:        amount,
+			Currency:      currency,
+			Region:        tx.Region,
+			Timestamp:     tx.Timestamp,
+		}
+	}
+
+	return []*Entry{
+		posting(tx.FromAccount, source.Value.Neg(), source.Currency),
+		posting(FXPositionAccount(source.Currency), source.Value, source.Currency),
+		posting(FXPositionAccount(target.Currency), target.Value.Neg(), target.Currency),
+		posting(tx.ToAccount, target.Value, target.Currency),
+	}
+}
+
+// ApplyFXReversal turns the reversal of a cross-currency transfer into a
+// transfer back at the original rate. reversal.Amount is what the original
+// sender gets back in the source currency; it becomes the converted amount,
+// and the destination account is debited the matching amount in the target
+// currency. remaining is the source amount not yet reversed and reversedTarget
+// the target amount already debited by earlier reversals, so reversing
+// everything that's left returns exactly the rest of the converted amount and
+// rounding never strands a remainder.
+func ApplyFXReversal(reversal, original *Transaction, remaining, reversedTarget decimal.Decimal) error {
+	if !original.IsFX() || original.FXRate == nil {
+		return fmt.Errorf("%w: %s is not a cross-currency transfer", ErrNotReversible, original.ID.String())
+	}
+
+	refund := reversal.Amount
+	target := *original.ConvertedAmount
+
+	debit := Money{Value: target.Value.Sub(reversedTarget), Currency: target.Currency}
+	if !refund.Value.Equal(remaining) {
+		converted, err := Convert(refund, target.Currency, *original.FXRate)
+		if err != nil {
+			return err
+		}
+		debit = converted
+	}
+	if !debit.IsPositive() || debit.Value.GreaterThan(target.Value.Sub(reversedTarget)) {
+		return fmt.Errorf("%w: %s would be debited, %s remaining",
+			ErrOverRefund, debit, target.Value.Sub(reversedTarget).String())
+	}
+
+	inverse := decimal.NewFromInt(1).DivRound(*original.FXRate, RateScale)
+	reversal.Amount = debit
+	reversal.ConvertedAmount = &refund
+	reversal.FXRate = &inverse
+	return nil
+}
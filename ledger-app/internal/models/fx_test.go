@@ -0,0 +1,177 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   Money
+		currency string
+		rate     string
+		expected string
+		wantErr  bool
+	}{
+		{"usd to eur", Money{Value: decimal.NewFromInt(100), Currency: "USD"}, "EUR", "0.9", "90.00 EUR", false},
+		{"rounds half to even", Money{Value: decimal.RequireFromString("0.05"), Currency: "USD"}, "EUR", "0.5", "0.02 EUR", false},
+		{"to zero decimal currency", Money{Value: decimal.NewFromInt(10), Currency: "USD"}, "JPY", "149.567", "1496 JPY", false},
+		{"to three decimal currency", Money{Value: decimal.NewFromInt(10), Currency: "USD"}, "KWD", "0.30712", "3.071 KWD", false},
+		{"unsupported currency", Money{Value: decimal.NewFromInt(10), Currency: "USD"}, "XYZ", "1", "", true},
+		{"non-positive rate", Money{Value: decimal.NewFromInt(10), Currency: "USD"}, "EUR", "0", "", true},
+		{"converts to zero", Money{Value: decimal.RequireFromString("0.01"), Currency: "USD"}, "JPY", "1.2", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.source, tt.currency, decimal.RequireFromString(tt.rate))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Convert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewFXQuote(t *testing.T) {
+	quote, err := NewFXQuote(Money{Value: decimal.NewFromInt(100), Currency: "USD"}, "EUR", decimal.RequireFromString("0.9"), 30*time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if quote.TargetAmount.String() != "90.00 EUR" {
+		t.Errorf("Expected target 90.00 EUR, got %s", quote.TargetAmount)
+	}
+	if quote.IsExpired(quote.CreatedAt) || !quote.IsExpired(quote.CreatedAt.Add(30*time.Second)) {
+		t.Errorf("Expected quote to be valid for 30s, expires at %s", quote.ExpiresAt)
+	}
+}
+
+func newFXTransfer() *Transaction {
+	rate := decimal.RequireFromString("0.9")
+	return &Transaction{
+		ID:              uuid.New(),
+		Region:          "us-east-1",
+		Amount:          Money{Value: decimal.NewFromInt(100), Currency: "USD"},
+		ConvertedAmount: &Money{Value: decimal.NewFromInt(90), Currency: "EUR"},
+		FXRate:          &rate,
+		FromAccount:     "acc1",
+		ToAccount:       "acc2",
+		Status:          StatusCompleted,
+	}
+}
+
+func TestNewFXTransferEntries(t *testing.T) {
+	tx := newFXTransfer()
+	entries := NewFXTransferEntries(tx)
+
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(entries))
+	}
+	if err := ValidateEntries(entries); err != nil {
+		t.Errorf("Expected entries to balance per currency, got: %v", err)
+	}
+
+	expected := []struct {
+		account string
+		amount  string
+	}{
+		{"acc1", "-100"},
+		{"fx-position-USD", "100"},
+		{"fx-position-EUR", "-90"},
+		{"acc2", "90"},
+	}
+	for i, e := range expected {
+		if entries[i].Account != e.account || entries[i].Amount.String() != e.amount {
+			t.Errorf("Entry %d: expected %s %s, got %s %s", i, e.account, e.amount, entries[i].Account, entries[i].Amount)
+		}
+	}
+}
+
+func TestIsSystemAccount(t *testing.T) {
+	if !IsSystemAccount(FXPositionAccount("EUR")) {
+		t.Error("Expected FX position account to be a system account")
+	}
+	if IsSystemAccount("acc1") {
+		t.Error("Expected acc1 not to be a system account")
+	}
+}
+
+func TestTransaction_IsFXLeg(t *testing.T) {
+	tx := newFXTransfer()
+	entries := NewFXTransferEntries(tx)
+
+	for i, want := range []bool{false, true, true, false} {
+		if got := tx.IsFXLeg(entries[i]); got != want {
+			t.Errorf("Entry %d (%s): expected IsFXLeg %v, got %v", i, entries[i].Account, want, got)
+		}
+	}
+
+	// A same-currency transfer has no FX legs, whatever its entries post to
+	plain := &Transaction{Amount: Money{Value: decimal.NewFromInt(10), Currency: "USD"}, FromAccount: FXPositionAccount("USD"), ToAccount: "acc2"}
+	if plain.IsFXLeg(NewTransferEntries(plain)[0]) {
+		t.Error("Expected a same-currency transfer to have no FX legs")
+	}
+}
+
+func TestApplyFXReversal(t *testing.T) {
+	tests := []struct {
+		name           string
+		refund         string
+		remaining      string
+		reversedTarget string
+		expectedDebit  string
+		wantErr        error
+	}{
+		{"full", "100", "100", "0", "90.00 EUR", nil},
+		{"partial at original rate", "33.33", "100", "0", "30.00 EUR", nil},
+		{"rest after partial", "66.67", "66.67", "30", "60.00 EUR", nil},
+		{"over remaining", "50", "40", "54", "", ErrOverRefund},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := newFXTransfer()
+			reversal := NewReversal(original, decimal.RequireFromString(tt.refund), "us-east-1")
+
+			err := ApplyFXReversal(reversal, original,
+				decimal.RequireFromString(tt.remaining), decimal.RequireFromString(tt.reversedTarget))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if reversal.Amount.String() != tt.expectedDebit {
+				t.Errorf("Expected debit %s, got %s", tt.expectedDebit, reversal.Amount)
+			}
+			if reversal.ConvertedAmount.Currency != "USD" || reversal.ConvertedAmount.Value.String() != tt.refund {
+				t.Errorf("Expected refund %s USD, got %s", tt.refund, reversal.ConvertedAmount)
+			}
+			if err := ValidateEntries(NewFXTransferEntries(reversal)); err != nil {
+				t.Errorf("Expected reversal entries to balance, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyFXReversal_NotFX(t *testing.T) {
+	original := newFXTransfer()
+	original.ConvertedAmount = nil
+	reversal := NewReversal(original, decimal.NewFromInt(10), "us-east-1")
+
+	if err := ApplyFXReversal(reversal, original, decimal.NewFromInt(100), decimal.Zero); !errors.Is(err, ErrNotReversible) {
+		t.Errorf("Expected ErrNotReversible, got: %v", err)
+	}
+}
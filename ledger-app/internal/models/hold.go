@@ -0,0 +1,147 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Hold statuses
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusVoided   = "voided"
+	HoldStatusExpired  = "expired"
+)
+
+var (
+	// ErrHoldNotFound is returned when a hold does not exist
+	ErrHoldNotFound = errors.New("hold not found")
+	// ErrHoldNotActive is returned when capturing or voiding a hold that has
+	// already been captured, voided or expired
+	ErrHoldNotActive = errors.New("hold is not active")
+	// ErrHoldExpired is returned when capturing a hold past its expiry time
+	ErrHoldExpired = errors.New("hold has expired")
+	// ErrOverCapture is returned when a capture exceeds the held amount
+	ErrOverCapture = errors.New("capture exceeds held amount")
+)
+
+// Hold reserves funds on an account for a later transfer. While a hold is
+// active its amount is taken out of the account's available balance, but no
+// money moves until it is captured.
+type Hold struct {
+	ID             uuid.UUID        `json:"id" db:"id"`
+	Region         string           `json:"region" db:"region"`
+	Amount         Money            `json:"amount" db:"amount"`
+	FromAccount    string           `json:"from_account" db:"from_account"`
+	ToAccount      string           `json:"to_account" db:"to_account"`
+	Status         string           `json:"status" db:"status"`
+	CapturedAmount *decimal.Decimal `json:"captured_amount,omitempty" db:"captured_amount"`
+	TransactionID  *uuid.UUID       `json:"transaction_id,omitempty" db:"transaction_id"`
+	CreatedAt      time.Time        `json:"created_at" db:"created_at"`
+	ExpiresAt      time.Time        `json:"expires_at" db:"expires_at"`
+	UpdatedAt      time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// MarshalJSON sends the currency of Amount as currency
+func (h Hold) MarshalJSON() ([]byte, error) {
+	type hold Hold
+	return json.Marshal(struct {
+		hold
+		Currency string `json:"currency"`
+	}{hold(h), h.Amount.Currency})
+}
+
+// UnmarshalJSON decodes a hold written by MarshalJSON
+func (h *Hold) UnmarshalJSON(data []byte) error {
+	type hold Hold
+	in := struct {
+		*hold
+		Currency string `json:"currency"`
+	}{hold: (*hold)(h)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	h.Amount.Currency = in.Currency
+	return nil
+}
+
+// HoldRequest represents an incoming hold request. ExpiresIn is a duration
+// such as "72h"; the configured default applies when it is empty.
+type HoldRequest struct {
+	FromAccount string `json:"from_account"`
+	ToAccount   string `json:"to_account"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency,omitempty"`
+	ExpiresIn   string `json:"expires_in,omitempty"`
+}
+
+// CaptureRequest represents an incoming capture request. An empty amount
+// captures the full hold.
+type CaptureRequest struct {
+	Amount string `json:"amount,omitempty"`
+}
+
+// HoldResponse represents the API response for hold operations
+type HoldResponse struct {
+	Hold        *Hold        `json:"hold,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Message     string       `json:"message,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Code        string       `json:"code,omitempty"`
+}
+
+// NewHold builds an active hold of amount on from, payable to to, that
+// expires after ttl
+func NewHold(from, to string, amount Money, region string, ttl time.Duration) *Hold {
+	now := time.Now().UTC()
+	return &Hold{
+		ID:          uuid.New(),
+		Region:      region,
+		Amount:      amount,
+		FromAccount: from,
+		ToAccount:   to,
+		Status:      HoldStatusActive,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+		UpdatedAt:   now,
+	}
+}
+
+// IsActive reports whether the hold still reserves funds
+func (h *Hold) IsActive() bool {
+	return h.Status == HoldStatusActive
+}
+
+// IsExpired reports whether the hold can no longer be captured at now
+func (h *Hold) IsExpired(now time.Time) bool {
+	return !now.Before(h.ExpiresAt)
+}
+
+// NewCapture builds the completed transfer that settles amount of the hold.
+// A zero amount captures the full hold. Whatever isn't captured is released.
+func (h *Hold) NewCapture(amount decimal.Decimal, region string) (*Transaction, error) {
+	if amount.IsZero() {
+		amount = h.Amount.Value
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("invalid capture amount %s", amount.String())
+	}
+	if amount.GreaterThan(h.Amount.Value) {
+		return nil, fmt.Errorf("%w: capturing %s of %s", ErrOverCapture, amount.String(), h.Amount)
+	}
+
+	return &Transaction{
+		ID:          uuid.New(),
+		Region:      region,
+		Amount:      Money{Value: amount, Currency: h.Amount.Currency},
+		FromAccount: h.FromAccount,
+		ToAccount:   h.ToAccount,
+		Status:      StatusCompleted,
+		Timestamp:   time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNewHold(t *testing.T) {
+	hold := NewHold("acc1", "merchant", Money{Value: decimal.NewFromInt(100), Currency: "USD"}, "us-east-1", time.Hour)
+
+	if !hold.IsActive() {
+		t.Errorf("Expected new hold to be active, got %s", hold.Status)
+	}
+	if hold.IsExpired(hold.CreatedAt) || !hold.IsExpired(hold.CreatedAt.Add(time.Hour)) {
+		t.Errorf("Expected hold to be valid for 1h, expires at %s", hold.ExpiresAt)
+	}
+}
+
+func TestHold_NewCapture(t *testing.T) {
+	hold := NewHold("acc1", "merchant", Money{Value: decimal.NewFromInt(100), Currency: "USD"}, "us-east-1", time.Hour)
+
+	tests := []struct {
+		name     string
+		amount   string
+		expected string
+		wantErr  error
+	}{
+		{"full by default", "0", "100.00 USD", nil},
+		{"partial", "60.25", "60.25 USD", nil},
+		{"exact", "100", "100.00 USD", nil},
+		{"more than held", "100.01", "", ErrOverCapture},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture, err := hold.NewCapture(decimal.RequireFromString(tt.amount), "eu-central-1")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if capture.Amount.String() != tt.expected {
+				t.Errorf("Expected capture of %s, got %s", tt.expected, capture.Amount)
+			}
+			if capture.FromAccount != "acc1" || capture.ToAccount != "merchant" || capture.Status != StatusCompleted {
+				t.Errorf("Unexpected capture: %+v", capture)
+			}
+		})
+	}
+}
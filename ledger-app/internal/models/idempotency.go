@@ -0,0 +1,66 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyHeader is the request header clients use to make retries safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// MaxIdempotencyKeyLength bounds the size of client-supplied idempotency keys
+const MaxIdempotencyKeyLength = 255
+
+var (
+	// ErrIdempotencyRecordNotFound is returned when no live record exists for a key
+	ErrIdempotencyRecordNotFound = errors.New("idempotency record not found")
+	// ErrIdempotencyKeyInUse is returned when another request already holds a key
+	ErrIdempotencyKeyInUse = errors.New("idempotency key is in use")
+)
+
+// IdempotencyRecord stores the outcome of a request made with an Idempotency-Key.
+// A record with a zero StatusCode is a reservation for a request still in
+// flight, identified by its ReservationID.
+type IdempotencyRecord struct {
+	Key           string    `json:"key" db:"key"`
+	Fingerprint   string    `json:"fingerprint" db:"fingerprint"`
+	ReservationID uuid.UUID `json:"reservation_id" db:"reservation_id"`
+	StatusCode    int       `json:"status_code" db:"status_code"`
+	ResponseBody  []byte    `json:"response_body" db:"response_body"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// IdempotencyCompletion records the response to a write as the outcome of the
+// reservation its request holds. It is stored in the write's own database
+// transaction, so a write that committed always has its response recorded and
+// a reservation still in progress means its write never committed.
+type IdempotencyCompletion struct {
+	Key           string
+	ReservationID uuid.UUID
+	ExpiresAt     time.Time
+	// Response renders the response once the write has run
+	Response func() (statusCode int, body []byte, err error)
+}
+
+// IsComplete reports whether the original request has finished and its
+// response can be replayed
+func (r *IdempotencyRecord) IsComplete() bool {
+	return r.StatusCode != 0
+}
+
+// RequestFingerprint identifies a request by method, path and body so a key
+// reused for a different request can be detected
+func RequestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,18 @@
+package models
+
+import "testing"
+
+func TestRequestFingerprint(t *testing.T) {
+	body := []byte(`{"amount":"10.00"}`)
+	base := RequestFingerprint("POST", "/transactions", body)
+
+	if base != RequestFingerprint("POST", "/transactions", []byte(`{"amount":"10.00"}`)) {
+		t.Error("RequestFingerprint() is not deterministic")
+	}
+	if base == RequestFingerprint("POST", "/transactions", []byte(`{"amount":"10.01"}`)) {
+		t.Error("RequestFingerprint() should differ for different bodies")
+	}
+	if base == RequestFingerprint("POST", "/accounts", body) {
+		t.Error("RequestFingerprint() should differ for different paths")
+	}
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrUnsupportedCurrency is returned for currency codes the ledger doesn't know
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrInvalidScale is returned when an amount has more decimal places than
+	// its currency allows
+	ErrInvalidScale = errors.New("amount has too many decimal places for currency")
+	// ErrCurrencyMismatch is returned when an amount is posted to an account
+	// held in a different currency
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// MaxScale is the largest number of decimal places any currency may use.
+// Amount columns in the database are declared with this scale.
+const MaxScale = 8
+
+// currencyScales maps each supported currency to its number of minor units
+// (decimal places). Codes follow ISO-4217; BTC is not an ISO code but is
+// accepted so 8-decimal instruments can be held.
+var currencyScales = map[string]int32{
+	// Zero-decimal currencies
+	"CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "VND": 0,
+	// Two-decimal currencies
+	"AED": 2, "AUD": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2,
+	"DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "ILS": 2, "INR": 2,
+	"MXN": 2, "NOK": 2, "NZD": 2, "PLN": 2, "SAR": 2, "SEK": 2, "SGD": 2,
+	"TRY": 2, "USD": 2, "ZAR": 2,
+	// Three-decimal currencies
+	"BHD": 3, "JOD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+	// Eight-decimal instruments
+	"BTC": 8,
+}
+
+// CurrencyScale returns the number of decimal places used by currency
+func CurrencyScale(currency string) (int32, bool) {
+	scale, ok := currencyScales[currency]
+	return scale, ok
+}
+
+// IsValidCurrencyCode reports whether code is a supported ISO-4217 style currency code
+func IsValidCurrencyCode(code string) bool {
+	if !currencyCodePattern.MatchString(code) {
+		return false
+	}
+	_, ok := currencyScales[code]
+	return ok
+}
+
+// Money is an amount in a specific currency
+type Money struct {
+	Value    decimal.Decimal
+	Currency string
+}
+
+// NewMoney builds a Money after checking the currency is supported and the
+// value fits its scale
+func NewMoney(value decimal.Decimal, currency string) (Money, error) {
+	m := Money{Value: value, Currency: currency}
+	if err := m.Validate(); err != nil {
+		return Money{}, err
+	}
+	return m, nil
+}
+
+// Validate checks the currency is supported and the value has no more
+// decimal places than the currency allows
+func (m Money) Validate() error {
+	scale, ok := CurrencyScale(m.Currency)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedCurrency, m.Currency)
+	}
+	if !m.Value.Equal(m.Value.Truncate(scale)) {
+		return fmt.Errorf("%w: %s allows %d, got %s", ErrInvalidScale, m.Currency, scale, m.Value.String())
+	}
+	return nil
+}
+
+// IsZero reports whether the value is zero
+func (m Money) IsZero() bool {
+	return m.Value.IsZero()
+}
+
+// IsPositive reports whether the value is greater than zero
+func (m Money) IsPositive() bool {
+	return m.Value.IsPositive()
+}
+
+// Equal reports whether both the value and the currency are the same
+func (m Money) Equal(other Money) bool {
+	return m.Currency == other.Currency && m.Value.Equal(other.Value)
+}
+
+// Neg returns the money with its value negated
+func (m Money) Neg() Money {
+	return Money{Value: m.Value.Neg(), Currency: m.Currency}
+}
+
+// Format returns the value with exactly as many decimal places as the currency uses
+func (m Money) Format() string {
+	scale, ok := CurrencyScale(m.Currency)
+	if !ok {
+		return m.Value.String()
+	}
+	return m.Value.StringFixed(scale)
+}
+
+// String returns the value and currency, e.g. "100.50 USD"
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Format()
+	}
+	return m.Format() + " " + m.Currency
+}
+
+// MarshalJSON encodes the value as a string, e.g. "100.50", so no precision
+// is lost in JSON. Amounts have always been sent as plain strings; the types
+// holding Money send its currency in a field next to it.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Format())
+}
+
+// UnmarshalJSON decodes a value written by MarshalJSON. The currency is left
+// as it is, for the type holding the Money to fill in.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	value, err := decimal.NewFromString(raw)
+	if err != nil {
+		return err
+	}
+	m.Value = value
+	return nil
+}
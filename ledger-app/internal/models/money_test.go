@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNewMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		currency string
+		wantErr  error
+	}{
+		{"two decimals", "100.50", "USD", nil},
+		{"trailing zeros beyond scale", "100.5000", "USD", nil},
+		{"too many decimals", "100.505", "USD", ErrInvalidScale},
+		{"zero-decimal currency", "1500", "JPY", nil},
+		{"fraction of zero-decimal currency", "1500.5", "JPY", ErrInvalidScale},
+		{"three-decimal currency", "1.125", "KWD", nil},
+		{"eight decimals", "0.00000001", "BTC", nil},
+		{"beyond eight decimals", "0.000000001", "BTC", ErrInvalidScale},
+		{"unknown currency", "1", "XYZ", ErrUnsupportedCurrency},
+		{"empty currency", "1", "", ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMoney(decimal.RequireFromString(tt.value), tt.currency)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("NewMoney() unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewMoney() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	tests := []struct {
+		money    Money
+		expected string
+	}{
+		{Money{Value: decimal.RequireFromString("100.5"), Currency: "USD"}, `"100.50"`},
+		{Money{Value: decimal.NewFromInt(1500), Currency: "JPY"}, `"1500"`},
+		{Money{Value: decimal.RequireFromString("0.1"), Currency: "BTC"}, `"0.10000000"`},
+	}
+
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.money)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("Marshal() = %s, want %s", data, tt.expected)
+		}
+
+		decoded := Money{Currency: tt.money.Currency}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if !decoded.Equal(tt.money) {
+			t.Errorf("Unmarshal() = %s, want %s", decoded, tt.money)
+		}
+	}
+}
+
+func TestTransaction_JSON(t *testing.T) {
+	tx := &Transaction{
+		Amount:          Money{Value: decimal.RequireFromString("100.5"), Currency: "USD"},
+		ConvertedAmount: &Money{Value: decimal.NewFromInt(15000), Currency: "JPY"},
+	}
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var wire map[string]interface{}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		t.Fatal(err)
+	}
+	if wire["amount"] != "100.50" || wire["currency"] != "USD" {
+		t.Errorf("Expected amount and currency as sibling strings, got %s", data)
+	}
+	if wire["converted_amount"] != "15000" || wire["converted_currency"] != "JPY" {
+		t.Errorf("Expected converted amount and currency as sibling strings, got %s", data)
+	}
+
+	var decoded Transaction
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !decoded.Amount.Equal(tx.Amount) || decoded.ConvertedAmount == nil || !decoded.ConvertedAmount.Equal(*tx.ConvertedAmount) {
+		t.Errorf("Unmarshal() = %s -> %v, want %s -> %s", decoded.Amount, decoded.ConvertedAmount, tx.Amount, tx.ConvertedAmount)
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	m := Money{Value: decimal.RequireFromString("-2.5"), Currency: "EUR"}
+	if got := m.String(); got != "-2.50 EUR" {
+		t.Errorf("String() = %s, want -2.50 EUR", got)
+	}
+	if got := m.Neg().String(); got != "2.50 EUR" {
+		t.Errorf("Neg().String() = %s, want 2.50 EUR", got)
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox message kinds
+const (
+	// OutboxKindAuditLog is an audit log to be written to S3 under its key
+	OutboxKindAuditLog = "audit_log"
+	// OutboxKindMessage is an event to be published to the message broker
+	OutboxKindMessage = "message"
+)
+
+// OutboxMessage is a side effect of a ledger write. It is stored in the same
+// database transaction as the write and delivered by the outbox relay of its
+// region once the write has committed, so it is delivered at least once.
+// Messages sharing an OrderingKey are delivered in the order they were
+// created.
+type OutboxMessage struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Region      string     `json:"region" db:"region"`
+	Kind        string     `json:"kind" db:"kind"`
+	Key         string     `json:"key,omitempty" db:"key"`
+	OrderingKey string     `json:"ordering_key,omitempty" db:"ordering_key"`
+	Payload     []byte     `json:"payload" db:"payload"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	LastError   string     `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// NewOutboxAuditLog builds an outbox message that writes content to S3 under key
+func NewOutboxAuditLog(region, key string, content []byte) *OutboxMessage {
+	return &OutboxMessage{
+		ID:        uuid.New(),
+		Region:    region,
+		Kind:      OutboxKindAuditLog,
+		Key:       key,
+		Payload:   content,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// NewOutboxMessage builds an outbox message that publishes payload, an encoded
+// events.Envelope, to the configured message broker, in order with the other
+// messages of orderingKey
+func NewOutboxMessage(region, orderingKey string, payload []byte) *OutboxMessage {
+	return &OutboxMessage{
+		ID:          uuid.New(),
+		Region:      region,
+		Kind:        OutboxKindMessage,
+		OrderingKey: orderingKey,
+		Payload:     payload,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+// OutboxBuilder builds the outbox messages of a write whose content is only
+// known once the write has run, such as the amount a reversal refunds. It is
+// called inside the write's database transaction, again on every retry.
+type OutboxBuilder func() ([]*OutboxMessage, error)
@@ -0,0 +1,7 @@
+package models
+
+import "errors"
+
+// ErrEventAlreadyProcessed is returned when an event delivered again has
+// already been handled
+var ErrEventAlreadyProcessed = errors.New("event already processed")
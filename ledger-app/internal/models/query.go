@@ -0,0 +1,86 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Keys transactions may be sorted by
+const (
+	SortByTimestamp = "timestamp"
+	SortByAmount    = "amount"
+)
+
+// ErrInvalidQuery is returned when transaction search parameters are inconsistent
+var ErrInvalidQuery = errors.New("invalid transaction query")
+
+// TransactionFilter narrows a transaction search. Zero-valued fields match
+// every transaction.
+type TransactionFilter struct {
+	// Account matches transactions on either side of the transfer
+	Account   string
+	Status    string
+	Region    string
+	Currency  string
+	MinAmount *decimal.Decimal
+	MaxAmount *decimal.Decimal
+	// From is inclusive and To exclusive
+	From *time.Time
+	To   *time.Time
+}
+
+// IsZero reports whether the filter matches every transaction
+func (f TransactionFilter) IsZero() bool {
+	return f.Account == "" && f.Status == "" && f.Region == "" && f.Currency == "" &&
+		f.MinAmount == nil && f.MaxAmount == nil && f.From == nil && f.To == nil
+}
+
+// TransactionQuery is a filtered, sorted page of transactions. Pages are
+// either addressed by Offset or continue after Cursor.
+type TransactionQuery struct {
+	Filter    TransactionFilter
+	SortBy    string
+	Ascending bool
+	Cursor    *TransactionCursor
+	Limit     int
+	Offset    int
+}
+
+// IsDefault reports whether the query is an unfiltered, offset-paged listing
+// in the default newest-first order
+func (q TransactionQuery) IsDefault() bool {
+	return q.Filter.IsZero() && (q.SortBy == "" || q.SortBy == SortByTimestamp) && !q.Ascending && q.Cursor == nil
+}
+
+// Validate checks that the query can be run
+func (q TransactionQuery) Validate() error {
+	f := q.Filter
+	switch q.SortBy {
+	case "", SortByTimestamp:
+	case SortByAmount:
+		if q.Cursor != nil && q.Cursor.Amount == nil {
+			return fmt.Errorf("%w: cursor has no amount position", ErrInvalidCursor)
+		}
+	default:
+		return fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, q.SortBy)
+	}
+	if f.Status != "" && !IsValidStatus(f.Status) {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, f.Status)
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && f.MinAmount.GreaterThan(*f.MaxAmount) {
+		return fmt.Errorf("%w: min_amount is greater than max_amount", ErrInvalidQuery)
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	if q.Limit <= 0 {
+		return fmt.Errorf("%w: limit must be positive", ErrInvalidQuery)
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalidQuery)
+	}
+	return nil
+}
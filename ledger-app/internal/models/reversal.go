@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrNotReversible is returned when reversing a transaction that isn't
+	// completed, or that is itself a reversal
+	ErrNotReversible = errors.New("transaction cannot be reversed")
+	// ErrOverRefund is returned when a reversal would return more than the
+	// amount of the original transaction that hasn't been reversed yet
+	ErrOverRefund = errors.New("reversal exceeds remaining amount")
+)
+
+// ReversalRequest represents an incoming reversal request. An empty amount
+// reverses whatever is left of the original transaction.
+type ReversalRequest struct {
+	Amount string `json:"amount,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// IsReversal reports whether the transaction compensates another transaction
+func (t *Transaction) IsReversal() bool {
+	return t.ReversalOf != nil
+}
+
+// CanBeReversed reports whether the transaction is in a state that allows
+// further reversals. A partially reversed transaction is already "reversed"
+// but may be reversed again until nothing is left.
+func (t *Transaction) CanBeReversed() bool {
+	if t.IsReversal() {
+		return false
+	}
+	return t.Status == StatusCompleted || t.Status == StatusReversed
+}
+
+// NewReversal builds a completed transaction that sends amount back from the
+// original recipient to the original sender, in the original's currency
+func NewReversal(original *Transaction, amount decimal.Decimal, region string) *Transaction {
+	originalID := original.ID
+	return &Transaction{
+		ID:          uuid.New(),
+		Region:      region,
+		Amount:      Money{Value: amount, Currency: original.Amount.Currency},
+		FromAccount: original.ToAccount,
+		ToAccount:   original.FromAccount,
+		Status:      StatusCompleted,
+		Timestamp:   time.Now().UTC(),
+		ReversalOf:  &originalID,
+	}
+}
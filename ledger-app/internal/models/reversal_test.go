@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestNewReversal(t *testing.T) {
+	original := &Transaction{
+		ID:          uuid.New(),
+		Region:      "us-east-1",
+		Amount:      Money{Value: decimal.NewFromInt(100), Currency: "JPY"},
+		FromAccount: "acc1",
+		ToAccount:   "acc2",
+		Status:      StatusCompleted,
+	}
+
+	reversal := NewReversal(original, decimal.NewFromInt(40), "eu-central-1")
+
+	if reversal.ID == original.ID {
+		t.Error("Expected reversal to get its own ID")
+	}
+	if reversal.ReversalOf == nil || *reversal.ReversalOf != original.ID {
+		t.Errorf("Expected reversal_of %s, got %v", original.ID, reversal.ReversalOf)
+	}
+	if reversal.FromAccount != "acc2" || reversal.ToAccount != "acc1" {
+		t.Errorf("Expected money to flow acc2 -> acc1, got %s -> %s", reversal.FromAccount, reversal.ToAccount)
+	}
+	if !reversal.Amount.Equal(Money{Value: decimal.NewFromInt(40), Currency: "JPY"}) {
+		t.Errorf("Expected reversal of 40 JPY, got %s", reversal.Amount)
+	}
+	if reversal.Status != StatusCompleted || reversal.Region != "eu-central-1" {
+		t.Errorf("Unexpected reversal: %+v", reversal)
+	}
+	if !reversal.IsReversal() || original.IsReversal() {
+		t.Error("Expected only the reversal to report IsReversal")
+	}
+}
+
+func TestCanBeReversed(t *testing.T) {
+	originalID := uuid.New()
+
+	tests := []struct {
+		name     string
+		tx       *Transaction
+		expected bool
+	}{
+		{"completed", &Transaction{Status: StatusCompleted}, true},
+		{"partially reversed", &Transaction{Status: StatusReversed}, true},
+		{"pending", &Transaction{Status: StatusPending}, false},
+		{"failed", &Transaction{Status: StatusFailed}, false},
+		{"cancelled", &Transaction{Status: StatusCancelled}, false},
+		{"reversal itself", &Transaction{Status: StatusCompleted, ReversalOf: &originalID}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tx.CanBeReversed(); got != tt.expected {
+				t.Errorf("CanBeReversed() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schedule statuses
+const (
+	ScheduleStatusActive    = "active"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusCancelled = "cancelled"
+)
+
+// Schedule run statuses
+const (
+	ScheduleRunSucceeded = "succeeded"
+	ScheduleRunFailed    = "failed"
+)
+
+var (
+	// ErrScheduleNotFound is returned when a schedule does not exist
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrScheduleNotActive is returned when cancelling a schedule that has
+	// already completed or been cancelled
+	ErrScheduleNotActive = errors.New("schedule is not active")
+	// ErrScheduleRunClaimed is returned when a due execution has already been
+	// run, by this scheduler or the one in the other region
+	ErrScheduleRunClaimed = errors.New("schedule run already claimed")
+)
+
+// Schedule is a future-dated or recurring transfer. Each execution is
+// materialized into a normal transaction when it falls due.
+type Schedule struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Region      string     `json:"region" db:"region"`
+	FromAccount string     `json:"from_account" db:"from_account"`
+	ToAccount   string     `json:"to_account" db:"to_account"`
+	Amount      Money      `json:"amount" db:"amount"`
+	Spec        string     `json:"schedule,omitempty" db:"spec"`
+	Status      string     `json:"status" db:"status"`
+	StartAt     time.Time  `json:"start_at" db:"start_at"`
+	EndAt       *time.Time `json:"end_at,omitempty" db:"end_at"`
+	MaxRuns     *int       `json:"max_runs,omitempty" db:"max_runs"`
+	RunCount    int        `json:"run_count" db:"run_count"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty" db:"next_run_at"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// MarshalJSON sends the currency of Amount as currency
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	type schedule Schedule
+	return json.Marshal(struct {
+		schedule
+		Currency string `json:"currency"`
+	}{schedule(s), s.Amount.Currency})
+}
+
+// UnmarshalJSON decodes a schedule written by MarshalJSON
+func (s *Schedule) UnmarshalJSON(data []byte) error {
+	type schedule Schedule
+	in := struct {
+		*schedule
+		Currency string `json:"currency"`
+	}{schedule: (*schedule)(s)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	s.Amount.Currency = in.Currency
+	return nil
+}
+
+// ScheduleRun records one execution of a schedule
+type ScheduleRun struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	ScheduleID    uuid.UUID  `json:"schedule_id" db:"schedule_id"`
+	ScheduledFor  time.Time  `json:"scheduled_for" db:"scheduled_for"`
+	Region        string     `json:"region" db:"region"`
+	Status        string     `json:"status" db:"status"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" db:"transaction_id"`
+	Error         string     `json:"error,omitempty" db:"error"`
+	ExecutedAt    time.Time  `json:"executed_at" db:"executed_at"`
+}
+
+// ScheduleRequest represents an incoming schedule request. Schedule is a
+// cron expression or RRULE; when it is empty the transfer runs once at
+// StartAt.
+type ScheduleRequest struct {
+	FromAccount string     `json:"from_account"`
+	ToAccount   string     `json:"to_account"`
+	Amount      string     `json:"amount"`
+	Currency    string     `json:"currency,omitempty"`
+	Schedule    string     `json:"schedule,omitempty"`
+	StartAt     *time.Time `json:"start_at,omitempty"`
+	EndAt       *time.Time `json:"end_at,omitempty"`
+	MaxRuns     *int       `json:"max_runs,omitempty"`
+}
+
+// ScheduleResponse represents the API response for schedule operations
+type ScheduleResponse struct {
+	Schedule *Schedule     `json:"schedule,omitempty"`
+	Runs     []ScheduleRun `json:"runs,omitempty"`
+	Message  string        `json:"message,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Code     string        `json:"code,omitempty"`
+}
+
+// NewSchedule builds an active schedule of amount from from to to. next is
+// the first execution time.
+func NewSchedule(from, to string, amount Money, spec string, region string, startAt, next time.Time) *Schedule {
+	now := time.Now().UTC()
+	return &Schedule{
+		ID:          uuid.New(),
+		Region:      region,
+		FromAccount: from,
+		ToAccount:   to,
+		Amount:      amount,
+		Spec:        spec,
+		Status:      ScheduleStatusActive,
+		StartAt:     startAt,
+		NextRunAt:   &next,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// IsActive reports whether the schedule still has executions to run
+func (s *Schedule) IsActive() bool {
+	return s.Status == ScheduleStatusActive
+}
+
+// IsDue reports whether the schedule's next execution is at or before now
+func (s *Schedule) IsDue(now time.Time) bool {
+	return s.IsActive() && s.NextRunAt != nil && !now.Before(*s.NextRunAt)
+}
+
+// Allows reports whether the schedule's limits allow an execution at t
+// after the runs it has already made
+func (s *Schedule) Allows(t time.Time, runs int) bool {
+	if t.IsZero() {
+		return false
+	}
+	if s.EndAt != nil && t.After(*s.EndAt) {
+		return false
+	}
+	if s.MaxRuns != nil && runs >= *s.MaxRuns {
+		return false
+	}
+	return true
+}
+
+// NewExecution builds the pending transfer for the schedule's next execution
+func (s *Schedule) NewExecution(region string) *Transaction {
+	return &Transaction{
+		ID:          uuid.New(),
+		Region:      region,
+		Amount:      s.Amount,
+		FromAccount: s.FromAccount,
+		ToAccount:   s.ToAccount,
+		Status:      StatusPending,
+		Timestamp:   time.Now().UTC(),
+	}
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Statement formats
+const (
+	StatementFormatJSON = "json"
+	StatementFormatCSV  = "csv"
+	StatementFormatText = "text"
+)
+
+// ErrInvalidStatementFormat is returned for a statement format that can't be rendered
+var ErrInvalidStatementFormat = errors.New("invalid statement format")
+
+// Posting is an entry in an account's history together with the account's
+// balance once the entry was applied
+type Posting struct {
+	Entry
+	// Counterparty is the account on the other side of the transfer
+	Counterparty string          `json:"counterparty"`
+	Balance      decimal.Decimal `json:"balance"`
+}
+
+// Statement summarises an account's postings over the period [From, To)
+type Statement struct {
+	AccountID      string          `json:"account_id"`
+	Owner          string          `json:"owner"`
+	Currency       string          `json:"currency"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance decimal.Decimal `json:"opening_balance"`
+	ClosingBalance decimal.Decimal `json:"closing_balance"`
+	TotalCredits   decimal.Decimal `json:"total_credits"`
+	TotalDebits    decimal.Decimal `json:"total_debits"`
+	Postings       []*Posting      `json:"postings"`
+	GeneratedAt    time.Time       `json:"generated_at"`
+}
+
+// NewStatement builds the statement of account for the period [from, to)
+// from the balance at from and the postings made during the period, oldest
+// first. Running balances and totals are filled in from the postings.
+func NewStatement(account *Account, from, to time.Time, opening decimal.Decimal, postings []*Posting) *Statement {
+	s := &Statement{
+		AccountID:      account.ID,
+		Owner:          account.Owner,
+		Currency:       account.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		TotalCredits:   decimal.Zero,
+		TotalDebits:    decimal.Zero,
+		Postings:       postings,
+		GeneratedAt:    time.Now().UTC(),
+	}
+	if s.Postings == nil {
+		s.Postings = []*Posting{}
+	}
+
+	for _, p := range postings {
+		s.ClosingBalance = s.ClosingBalance.Add(p.Amount)
+		p.Balance = s.ClosingBalance
+		if p.IsDebit() {
+			s.TotalDebits = s.TotalDebits.Add(p.Amount.Neg())
+		} else {
+			s.TotalCredits = s.TotalCredits.Add(p.Amount)
+		}
+	}
+	return s
+}
+
+// IsValidStatementFormat reports whether format is one a statement can be rendered as
+func IsValidStatementFormat(format string) bool {
+	switch format {
+	case StatementFormatJSON, StatementFormatCSV, StatementFormatText:
+		return true
+	}
+	return false
+}
+
+// StatementContentType returns the MIME type of a statement rendered as format
+func StatementContentType(format string) string {
+	switch format {
+	case StatementFormatCSV:
+		return "text/csv; charset=utf-8"
+	case StatementFormatText:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// Render writes the statement to w in the given format
+func (s *Statement) Render(w io.Writer, format string) error {
+	switch format {
+	case StatementFormatJSON:
+		return s.writeJSON(w)
+	case StatementFormatCSV:
+		return s.writeCSV(w)
+	case StatementFormatText:
+		return s.writeText(w)
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidStatementFormat, format)
+}
+
+func (s *Statement) writeJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// writeCSV writes one row per posting; the opening and closing balances are
+// the first and last rows so the file reconciles on its own
+func (s *Statement) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{
+		{"timestamp", "transaction_id", "counterparty", "amount", "currency", "balance"},
+		{s.From.Format(time.RFC3339), "", "opening balance", "", s.Currency, s.format(s.OpeningBalance)},
+	}
+	for _, p := range s.Postings {
+		records = append(records, []string{
+			p.Timestamp.UTC().Format(time.RFC3339Nano),
+			p.TransactionID.String(),
+			p.Counterparty,
+			s.format(p.Amount),
+			s.Currency,
+			s.format(p.Balance),
+		})
+	}
+	records = append(records, []string{s.To.Format(time.RFC3339), "", "closing balance", "", s.Currency, s.format(s.ClosingBalance)})
+
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write statement: %w", err)
+	}
+	return nil
+}
+
+func (s *Statement) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "Statement for account %s (%s)\n", s.AccountID, s.Owner)
+	fmt.Fprintf(w, "Period %s to %s, amounts in %s\n\n", s.From.Format(time.RFC3339), s.To.Format(time.RFC3339), s.Currency)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Date\tTransaction\tCounterparty\tAmount\tBalance\t\n")
+	fmt.Fprintf(tw, "\t\tOpening balance\t\t%s\t\n", s.format(s.OpeningBalance))
+	for _, p := range s.Postings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", p.Timestamp.UTC().Format(time.RFC3339), p.TransactionID,
+			p.Counterparty, s.format(p.Amount), s.format(p.Balance))
+	}
+	fmt.Fprintf(tw, "\t\tClosing balance\t\t%s\t\n\n", s.format(s.ClosingBalance))
+	fmt.Fprintf(tw, "Total credits\t%s\t\n", s.format(s.TotalCredits))
+	fmt.Fprintf(tw, "Total debits\t%s\t\n", s.format(s.TotalDebits))
+	return tw.Flush()
+}
+
+func (s *Statement) format(value decimal.Decimal) string {
+	return Money{Value: value, Currency: s.Currency}.Format()
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func newTestStatement() *Statement {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	account := &Account{ID: "acc1", Owner: "alice", Currency: "JPY"}
+	return NewStatement(account, from, from.AddDate(0, 1, 0), decimal.NewFromInt(1000), []*Posting{
+		{Entry: Entry{TransactionID: uuid.New(), Amount: decimal.NewFromInt(500), Timestamp: from.Add(time.Hour)}, Counterparty: "acc2"},
+		{Entry: Entry{TransactionID: uuid.New(), Amount: decimal.NewFromInt(-1200), Timestamp: from.Add(2 * time.Hour)}, Counterparty: "acc3"},
+		{Entry: Entry{TransactionID: uuid.New(), Amount: decimal.NewFromInt(-50), Timestamp: from.Add(3 * time.Hour)}, Counterparty: "acc2"},
+	})
+}
+
+func TestNewStatement_Totals(t *testing.T) {
+	s := newTestStatement()
+
+	if !s.ClosingBalance.Equal(decimal.NewFromInt(250)) {
+		t.Errorf("Expected closing balance 250, got %s", s.ClosingBalance)
+	}
+	if !s.TotalCredits.Equal(decimal.NewFromInt(500)) || !s.TotalDebits.Equal(decimal.NewFromInt(1250)) {
+		t.Errorf("Expected credits 500 and debits 1250, got %s and %s", s.TotalCredits, s.TotalDebits)
+	}
+
+	balances := []int64{1500, 300, 250}
+	for i, p := range s.Postings {
+		if !p.Balance.Equal(decimal.NewFromInt(balances[i])) {
+			t.Errorf("Posting %d: expected balance %d, got %s", i, balances[i], p.Balance)
+		}
+	}
+}
+
+func TestStatement_Render(t *testing.T) {
+	s := newTestStatement()
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{StatementFormatJSON, []string{`"opening_balance":"1000"`, `"closing_balance":"250"`}},
+		{StatementFormatCSV, []string{"timestamp,transaction_id,counterparty,amount,currency,balance", ",opening balance,,JPY,1000", ",closing balance,,JPY,250"}},
+		{StatementFormatText, []string{"Statement for account acc1 (alice)", "Opening balance", "Closing balance", "Total debits"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := s.Render(&buf, tt.format); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+
+	if err := s.Render(&bytes.Buffer{}, "pdf"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import "errors"
+
+// Transaction statuses
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+	StatusReversed  = "reversed"
+)
+
+var (
+	// ErrTransactionNotFound is returned when a transaction does not exist
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrInvalidStatus is returned for a status outside the state machine
+	ErrInvalidStatus = errors.New("invalid transaction status")
+	// ErrInvalidTransition is returned when the state machine forbids a change
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrStatusConflict is returned when a transaction's status changed
+	// concurrently between reading it and updating it
+	ErrStatusConflict = errors.New("transaction status changed concurrently")
+)
+
+// statusTransitions lists the statuses each status may move to.
+// Statuses without an entry are terminal.
+var statusTransitions = map[string][]string{
+	StatusPending:   {StatusCompleted, StatusFailed, StatusCancelled},
+	StatusCompleted: {StatusReversed},
+}
+
+// StatusUpdateRequest represents an incoming status change request
+type StatusUpdateRequest struct {
+	Status string `json:"status"`
+}
+
+// IsValidStatus reports whether status is part of the transaction state machine
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusCompleted, StatusFailed, StatusCancelled, StatusReversed:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether a transaction may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminalStatus reports whether no further transitions are possible
+func IsTerminalStatus(status string) bool {
+	return len(statusTransitions[status]) == 0
+}
+
+// ReleasesFunds reports whether moving into status undoes the transaction's
+// postings, returning the money to the source account
+func ReleasesFunds(status string) bool {
+	return status == StatusFailed || status == StatusCancelled
+}
@@ -0,0 +1,65 @@
+package models
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{StatusPending, StatusCompleted, true},
+		{StatusPending, StatusFailed, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusCompleted, StatusReversed, true},
+		{StatusPending, StatusReversed, false},
+		{StatusPending, StatusPending, false},
+		{StatusCompleted, StatusPending, false},
+		{StatusCompleted, StatusFailed, false},
+		{StatusFailed, StatusCompleted, false},
+		{StatusCancelled, StatusCompleted, false},
+		{StatusReversed, StatusCompleted, false},
+		{"unknown", StatusCompleted, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidStatus(t *testing.T) {
+	for _, status := range []string{StatusPending, StatusCompleted, StatusFailed, StatusCancelled, StatusReversed} {
+		if !IsValidStatus(status) {
+			t.Errorf("IsValidStatus(%q) = false, want true", status)
+		}
+	}
+	for _, status := range []string{"", "done", "PENDING"} {
+		if IsValidStatus(status) {
+			t.Errorf("IsValidStatus(%q) = true, want false", status)
+		}
+	}
+}
+
+func TestIsTerminalStatus(t *testing.T) {
+	if IsTerminalStatus(StatusPending) || IsTerminalStatus(StatusCompleted) {
+		t.Error("pending and completed should not be terminal")
+	}
+	for _, status := range []string{StatusFailed, StatusCancelled, StatusReversed} {
+		if !IsTerminalStatus(status) {
+			t.Errorf("IsTerminalStatus(%q) = false, want true", status)
+		}
+	}
+}
+
+func TestReleasesFunds(t *testing.T) {
+	if !ReleasesFunds(StatusFailed) || !ReleasesFunds(StatusCancelled) {
+		t.Error("failed and cancelled should release funds")
+	}
+	if ReleasesFunds(StatusCompleted) || ReleasesFunds(StatusReversed) {
+		t.Error("completed and reversed should not release funds")
+	}
+}
@@ -11,13 +11,61 @@ import (
 
 // Transaction represents a financial transaction in the ledger
 type Transaction struct {
-	ID          uuid.UUID       `json:"id" db:"id"`
-	Region      string          `json:"region" db:"region"`
-	Amount      decimal.Decimal `json:"amount" db:"amount"`
-	FromAccount string          `json:"from_account" db:"from_account"`
-	ToAccount   string          `json:"to_account" db:"to_account"`
-	Status      string          `json:"status" db:"status"`
-	Timestamp   time.Time       `json:"timestamp" db:"timestamp"`
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Region      string     `json:"region" db:"region"`
+	Amount      Money      `json:"amount" db:"amount"`
+	FromAccount string     `json:"from_account" db:"from_account"`
+	ToAccount   string     `json:"to_account" db:"to_account"`
+	Status      string     `json:"status" db:"status"`
+	Timestamp   time.Time  `json:"timestamp" db:"timestamp"`
+	ReversalOf  *uuid.UUID `json:"reversal_of,omitempty" db:"reversal_of"`
+
+	// Set on cross-currency transfers: the amount credited to the destination
+	// account, the rate applied and the quote that locked it
+	ConvertedAmount *Money           `json:"converted_amount,omitempty" db:"converted_amount"`
+	FXRate          *decimal.Decimal `json:"fx_rate,omitempty" db:"fx_rate"`
+	QuoteID         *uuid.UUID       `json:"quote_id,omitempty" db:"quote_id"`
+
+	Entries []*Entry `json:"entries,omitempty" db:"-"`
+}
+
+// transactionJSON is the wire format of a Transaction: amounts are strings,
+// with their currencies in fields next to them
+type transactionJSON struct {
+	Currency          string `json:"currency"`
+	ConvertedCurrency string `json:"converted_currency,omitempty"`
+}
+
+// MarshalJSON sends the currencies of Amount and ConvertedAmount as
+// currency and converted_currency
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	type transaction Transaction
+	out := struct {
+		transaction
+		transactionJSON
+	}{transaction: transaction(t)}
+	out.Currency = t.Amount.Currency
+	if t.ConvertedAmount != nil {
+		out.ConvertedCurrency = t.ConvertedAmount.Currency
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a transaction written by MarshalJSON
+func (t *Transaction) UnmarshalJSON(data []byte) error {
+	type transaction Transaction
+	in := struct {
+		*transaction
+		transactionJSON
+	}{transaction: (*transaction)(t)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	t.Amount.Currency = in.Currency
+	if t.ConvertedAmount != nil {
+		t.ConvertedAmount.Currency = in.ConvertedCurrency
+	}
+	return nil
 }
 
 // TransactionRequest represents an incoming transaction request
@@ -25,13 +73,29 @@ type TransactionRequest struct {
 	FromAccount string `json:"from_account"`
 	ToAccount   string `json:"to_account"`
 	Amount      string `json:"amount"`
+	Currency    string `json:"currency,omitempty"`
+	QuoteID     string `json:"quote_id,omitempty"`
 }
 
+// Error codes returned alongside error messages so clients can tell
+// business rule rejections apart
+const (
+	ErrorCodeInsufficientFunds = "insufficient_funds"
+	ErrorCodeOverRefund        = "over_refund"
+	ErrorCodeCurrencyMismatch  = "currency_mismatch"
+	ErrorCodeQuoteExpired      = "quote_expired"
+	ErrorCodeQuoteUsed         = "quote_used"
+	ErrorCodeHoldExpired       = "hold_expired"
+	ErrorCodeOverCapture       = "over_capture"
+	ErrorCodeBatchRejected     = "batch_rejected"
+)
+
 // TransactionResponse represents the API response
 type TransactionResponse struct {
 	Transaction *Transaction `json:"transaction,omitempty"`
 	Message     string       `json:"message,omitempty"`
 	Error       string       `json:"error,omitempty"`
+	Code        string       `json:"code,omitempty"`
 }
 
 // AuditLog represents an audit log entry for S3
@@ -52,14 +116,14 @@ func (a *AuditLog) ToJSON() (string, error) {
 	return string(data), nil
 }
 
-// ParseAmount parses a string amount into a decimal.Decimal
-// Validates that the amount is a valid decimal number
-func ParseAmount(amountStr string) (decimal.Decimal, error) {
+// ParseAmount parses a string amount in the given currency into Money
+// Validates that the amount is a valid decimal number and fits the currency's scale
+func ParseAmount(amountStr, currency string) (Money, error) {
 	amount, err := decimal.NewFromString(amountStr)
 	if err != nil {
-		return decimal.Zero, err
+		return Money{}, err
 	}
-	return amount, nil
+	return NewMoney(amount, currency)
 }
 
 // UUIDArray is a custom type for PostgreSQL UUID arrays
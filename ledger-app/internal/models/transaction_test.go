@@ -49,11 +49,16 @@ func TestParseAmount(t *testing.T) {
 			input:     "100.50abc",
 			wantError: true,
 		},
+		{
+			name:      "too many decimal places",
+			input:     "100.505",
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := ParseAmount(tt.input)
+			result, err := ParseAmount(tt.input, "USD")
 			if tt.wantError {
 				if err == nil {
 					t.Errorf("ParseAmount() expected error but got none")
@@ -62,7 +67,7 @@ func TestParseAmount(t *testing.T) {
 				if err != nil {
 					t.Errorf("ParseAmount() unexpected error: %v", err)
 				}
-				if !result.Equal(tt.expected) {
+				if !result.Value.Equal(tt.expected) || result.Currency != "USD" {
 					t.Errorf("ParseAmount() = %v, want %v", result, tt.expected)
 				}
 			}
@@ -105,7 +110,7 @@ func TestTransaction_JSONSerialization(t *testing.T) {
 	tx := &Transaction{
 		ID:          uuid.New(),
 		Region:      "us-east-1",
-		Amount:      decimal.NewFromInt(10050).Div(decimal.NewFromInt(100)),
+		Amount:      Money{Value: decimal.NewFromInt(10050).Div(decimal.NewFromInt(100)), Currency: "USD"},
 		FromAccount: "account-1",
 		ToAccount:   "account-2",
 		Status:      "pending",
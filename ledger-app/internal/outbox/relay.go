@@ -0,0 +1,202 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+var (
+	// claimLease is how long a claimed message is kept from other relays. It
+	// must be longer than delivering a message can take.
+	claimLease = time.Minute
+	// retryBaseDelay is the backoff after the first failed delivery; it doubles
+	// with every further failure up to retryMaxDelay
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 5 * time.Minute
+)
+
+// errHeldBack is the delivery error of a message left undelivered because an
+// earlier message with its ordering key failed in the same claim
+var errHeldBack = errors.New("held back behind an earlier message with the same ordering key")
+
+// Store is the database access the relay needs
+type Store interface {
+	ClaimOutbox(ctx context.Context, region string, now time.Time, lease time.Duration, limit int) ([]*models.OutboxMessage, error)
+	MarkOutboxDelivered(ctx context.Context, id uuid.UUID, deliveredAt time.Time) error
+	MarkOutboxFailed(ctx context.Context, id uuid.UUID, retryAt time.Time, cause string) error
+}
+
+// AuditWriter writes the audit logs held in the outbox
+type AuditWriter interface {
+	WriteAuditLog(ctx context.Context, key string, content []byte) error
+}
+
+// Publisher sends the events held in the outbox. Publishers that also
+// implement events.BatchPublisher get the events of a claim in one batch.
+type Publisher interface {
+	Publish(ctx context.Context, event *events.Envelope) error
+}
+
+// Relay delivers the outbox messages of its region to S3 and the message broker
+type Relay struct {
+	store     Store
+	audit     AuditWriter
+	publisher Publisher
+	region    string
+	logger    *zap.Logger
+}
+
+// NewRelay creates a relay for the outbox messages of region
+func NewRelay(store Store, audit AuditWriter, publisher Publisher, region string, logger *zap.Logger) *Relay {
+	return &Relay{
+		store:     store,
+		audit:     audit,
+		publisher: publisher,
+		region:    region,
+		logger:    logger,
+	}
+}
+
+// RunPending claims up to limit messages that are due at now, delivers them
+// and returns how many it claimed. A message that fails is retried after a
+// backoff that grows with its attempts. The later messages of its ordering key
+// are not delivered before it: they are left undelivered and available, and
+// the store doesn't claim them again until it has been delivered. A message
+// may be delivered more than once if its relay dies before marking it
+// delivered.
+func (r *Relay) RunPending(ctx context.Context, now time.Time, limit int) (int, error) {
+	messages, err := r.store.ClaimOutbox(ctx, r.region, now, claimLease, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	errs := r.deliverAll(ctx, messages)
+	for i, msg := range messages {
+		if err := errs[i]; err != nil {
+			retryAt := time.Now().UTC().Add(retryDelay(msg.Attempts))
+			if errors.Is(err, errHeldBack) {
+				retryAt = time.Now().UTC()
+			}
+			r.logger.Warn("Failed to deliver outbox message",
+				zap.Error(err),
+				zap.String("outbox_id", msg.ID.String()),
+				zap.String("kind", msg.Kind),
+				zap.Int("attempts", msg.Attempts),
+				zap.Time("retry_at", retryAt),
+			)
+			if err := r.store.MarkOutboxFailed(ctx, msg.ID, retryAt, err.Error()); err != nil {
+				r.logger.Error("Failed to reschedule outbox message", zap.Error(err), zap.String("outbox_id", msg.ID.String()))
+			}
+			continue
+		}
+
+		// The side effect already happened; if this fails the message is
+		// delivered again once its claim runs out
+		if err := r.store.MarkOutboxDelivered(ctx, msg.ID, time.Now().UTC()); err != nil {
+			r.logger.Error("Failed to mark outbox message delivered", zap.Error(err), zap.String("outbox_id", msg.ID.String()))
+		}
+	}
+
+	return len(messages), nil
+}
+
+// deliverAll performs the side effects held by messages, in order, and
+// returns the error of each. Once a message fails, the later messages with its
+// ordering key fail with errHeldBack without being delivered. The events go to
+// the broker in one batch if the publisher can publish batches; it holds back
+// the later events of a failed one itself.
+func (r *Relay) deliverAll(ctx context.Context, messages []*models.OutboxMessage) []error {
+	errs := make([]error, len(messages))
+	failed := make(map[string]bool)
+	heldBack := func(msg *models.OutboxMessage) bool {
+		return msg.OrderingKey != "" && failed[msg.OrderingKey]
+	}
+	fail := func(i int, err error) {
+		errs[i] = err
+		if err != nil && messages[i].OrderingKey != "" {
+			failed[messages[i].OrderingKey] = true
+		}
+	}
+
+	batcher, ok := r.publisher.(events.BatchPublisher)
+	if !ok {
+		for i, msg := range messages {
+			if heldBack(msg) {
+				errs[i] = errHeldBack
+				continue
+			}
+			fail(i, r.deliver(ctx, msg))
+		}
+		return errs
+	}
+
+	var batch []*events.Envelope
+	var indexes []int
+	for i, msg := range messages {
+		if msg.Kind != models.OutboxKindMessage {
+			errs[i] = r.deliver(ctx, msg)
+			continue
+		}
+		if heldBack(msg) {
+			errs[i] = errHeldBack
+			continue
+		}
+		event, err := parseMessage(msg)
+		if err != nil {
+			fail(i, err)
+			continue
+		}
+		batch = append(batch, event)
+		indexes = append(indexes, i)
+	}
+	if len(batch) > 0 {
+		for j, err := range events.EntryErrors(batcher.PublishBatch(ctx, batch), len(batch)) {
+			errs[indexes[j]] = err
+		}
+	}
+	return errs
+}
+
+// deliver performs the side effect held by msg
+func (r *Relay) deliver(ctx context.Context, msg *models.OutboxMessage) error {
+	switch msg.Kind {
+	case models.OutboxKindAuditLog:
+		return r.audit.WriteAuditLog(ctx, msg.Key, msg.Payload)
+	case models.OutboxKindMessage:
+		event, err := parseMessage(msg)
+		if err != nil {
+			return err
+		}
+		return r.publisher.Publish(ctx, event)
+	}
+	return fmt.Errorf("unknown outbox message kind %q", msg.Kind)
+}
+
+// parseMessage reads the event held by msg. Messages saved by older releases
+// are upcast on the way out.
+func parseMessage(msg *models.OutboxMessage) (*events.Envelope, error) {
+	event, err := events.Parse(msg.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message payload: %w", err)
+	}
+	return event, nil
+}
+
+// retryDelay returns the backoff after a message's attempts-th failed delivery
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
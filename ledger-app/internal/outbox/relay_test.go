@@ -0,0 +1,257 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	pending   []*models.OutboxMessage
+	region    string
+	lease     time.Duration
+	delivered []uuid.UUID
+	failed    map[uuid.UUID]time.Time
+}
+
+func (f *fakeStore) ClaimOutbox(ctx context.Context, region string, now time.Time, lease time.Duration, limit int) ([]*models.OutboxMessage, error) {
+	f.region, f.lease = region, lease
+	claimed := f.pending
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+	for _, msg := range claimed {
+		msg.Attempts++
+	}
+	f.pending = f.pending[len(claimed):]
+	return claimed, nil
+}
+
+func (f *fakeStore) MarkOutboxDelivered(ctx context.Context, id uuid.UUID, deliveredAt time.Time) error {
+	f.delivered = append(f.delivered, id)
+	return nil
+}
+
+func (f *fakeStore) MarkOutboxFailed(ctx context.Context, id uuid.UUID, retryAt time.Time, cause string) error {
+	if f.failed == nil {
+		f.failed = make(map[uuid.UUID]time.Time)
+	}
+	f.failed[id] = retryAt
+	return nil
+}
+
+type fakeAudit struct {
+	keys []string
+	err  error
+}
+
+func (f *fakeAudit) WriteAuditLog(ctx context.Context, key string, content []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.keys = append(f.keys, key)
+	return nil
+}
+
+// fakePublisher publishes events, failing those of failSubjects
+type fakePublisher struct {
+	messages     []*events.Envelope
+	failSubjects map[string]bool
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, event *events.Envelope) error {
+	if f.failSubjects[event.Subject] {
+		return errors.New("throttled")
+	}
+	f.messages = append(f.messages, event)
+	return nil
+}
+
+// fakeBatchPublisher publishes batches, failing the events of failSubjects
+type fakeBatchPublisher struct {
+	fakePublisher
+	batches [][]*events.Envelope
+}
+
+func (f *fakeBatchPublisher) PublishBatch(ctx context.Context, batch []*events.Envelope) error {
+	f.batches = append(f.batches, batch)
+	failed := make(map[int]error)
+	for i, event := range batch {
+		if f.failSubjects[event.Subject] {
+			failed[i] = errors.New("throttled")
+		}
+	}
+	if len(failed) > 0 {
+		return &events.BatchError{Failed: failed, Total: len(batch)}
+	}
+	return nil
+}
+
+func newTestMessage(t *testing.T, event events.Event) *models.OutboxMessage {
+	env, err := events.NewEnvelope("us-east-1", "", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("Failed to encode message: %v", err)
+	}
+	return models.NewOutboxMessage("us-east-1", env.OrderingKey(), payload)
+}
+
+func TestRunPending_Delivers(t *testing.T) {
+	audit := models.NewOutboxAuditLog("us-east-1", "transactions/us-east-1/tx.json", []byte(`{}`))
+	message := newTestMessage(t, &events.TransactionCreated{TransactionID: uuid.New()})
+	store := &fakeStore{pending: []*models.OutboxMessage{audit, message}}
+	auditWriter, publisher := &fakeAudit{}, &fakePublisher{}
+	relay := NewRelay(store, auditWriter, publisher, "us-east-1", zap.NewNop())
+
+	claimed, err := relay.RunPending(context.Background(), time.Now().UTC(), 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if claimed != 2 {
+		t.Errorf("Expected 2 messages claimed, got %d", claimed)
+	}
+	if store.region != "us-east-1" || store.lease != claimLease {
+		t.Errorf("Expected a claim for us-east-1 with the claim lease, got %s and %v", store.region, store.lease)
+	}
+	if len(auditWriter.keys) != 1 || auditWriter.keys[0] != audit.Key {
+		t.Errorf("Expected the audit log at %s, got %v", audit.Key, auditWriter.keys)
+	}
+	if len(publisher.messages) != 1 || publisher.messages[0].Type != events.TypeTransactionCreated {
+		t.Errorf("Expected the TransactionCreated event, got %+v", publisher.messages)
+	}
+	if len(store.delivered) != 2 || len(store.failed) != 0 {
+		t.Errorf("Expected both messages marked delivered, got %v delivered and %v failed", store.delivered, store.failed)
+	}
+}
+
+func TestRunPending_UpcastsLegacyMessages(t *testing.T) {
+	// Saved by a release from before event envelopes
+	legacy := models.NewOutboxMessage("us-east-1", "",
+		[]byte(`{"transaction_id":"7d3f2c4e-9b1a-4f8e-a6d2-1c5b8e9f0a21","region":"us-east-1","action":"transaction_reversed","timestamp":"2024-03-01T12:00:00Z","data":"{}"}`))
+	store := &fakeStore{pending: []*models.OutboxMessage{legacy}}
+	publisher := &fakePublisher{}
+	relay := NewRelay(store, &fakeAudit{}, publisher, "us-east-1", zap.NewNop())
+
+	if _, err := relay.RunPending(context.Background(), time.Now().UTC(), 10); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(publisher.messages) != 1 {
+		t.Fatalf("Expected 1 event published, got %d", len(publisher.messages))
+	}
+	event := publisher.messages[0]
+	if event.Type != events.TypeTransactionReversed || event.SchemaVersion != 1 || event.Subject != "7d3f2c4e-9b1a-4f8e-a6d2-1c5b8e9f0a21" {
+		t.Errorf("Expected a version 1 TransactionReversed event, got %+v", event)
+	}
+}
+
+func TestRunPending_RetriesFailures(t *testing.T) {
+	audit := models.NewOutboxAuditLog("us-east-1", "transactions/us-east-1/tx.json", []byte(`{}`))
+	audit.Attempts = 2
+	unknown := &models.OutboxMessage{ID: uuid.New(), Region: "us-east-1", Kind: "email"}
+	message := newTestMessage(t, &events.TransactionCreated{TransactionID: uuid.New()})
+	store := &fakeStore{pending: []*models.OutboxMessage{audit, unknown, message}}
+	publisher := &fakePublisher{}
+	relay := NewRelay(store, &fakeAudit{err: errors.New("s3 unavailable")}, publisher, "us-east-1", zap.NewNop())
+
+	before := time.Now().UTC()
+	if _, err := relay.RunPending(context.Background(), before, 10); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// A failure doesn't hold up the messages after it
+	if len(store.delivered) != 1 || store.delivered[0] != message.ID || len(publisher.messages) != 1 {
+		t.Errorf("Expected only the SQS message delivered, got %v", store.delivered)
+	}
+	if retryAt, ok := store.failed[audit.ID]; !ok || retryAt.Before(before.Add(4*time.Second)) {
+		t.Errorf("Expected the third attempt at the audit log to back off at least 4s, got %v", retryAt)
+	}
+	if _, ok := store.failed[unknown.ID]; !ok {
+		t.Error("Expected the message of unknown kind to be rescheduled")
+	}
+}
+
+func TestRunPending_PublishesBatch(t *testing.T) {
+	audit := models.NewOutboxAuditLog("us-east-1", "transactions/us-east-1/tx.json", []byte(`{}`))
+	first := newTestMessage(t, &events.TransactionCreated{TransactionID: uuid.New()})
+	failing := &events.TransactionCreated{TransactionID: uuid.New()}
+	second := newTestMessage(t, failing)
+	third := newTestMessage(t, &events.TransactionCreated{TransactionID: uuid.New()})
+	store := &fakeStore{pending: []*models.OutboxMessage{first, audit, second, third}}
+	publisher := &fakeBatchPublisher{fakePublisher: fakePublisher{failSubjects: map[string]bool{failing.Subject(): true}}}
+	relay := NewRelay(store, &fakeAudit{}, publisher, "us-east-1", zap.NewNop())
+
+	if _, err := relay.RunPending(context.Background(), time.Now().UTC(), 10); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(publisher.batches) != 1 || len(publisher.batches[0]) != 3 || len(publisher.messages) != 0 {
+		t.Fatalf("Expected the three events in one batch, got %d batches and %d single events", len(publisher.batches), len(publisher.messages))
+	}
+	// Only the event that failed in the batch is retried
+	if _, ok := store.failed[second.ID]; !ok || len(store.failed) != 1 {
+		t.Errorf("Expected only the failed event rescheduled, got %v", store.failed)
+	}
+	if len(store.delivered) != 3 {
+		t.Errorf("Expected the audit log and two events delivered, got %v", store.delivered)
+	}
+}
+
+func TestRunPending_HoldsBackLaterMessagesOfFailedKey(t *testing.T) {
+	failing := &events.TransactionCreated{TransactionID: uuid.New(), FromAccount: "acc1"}
+	first := newTestMessage(t, failing)
+	second := newTestMessage(t, &events.TransactionCreated{TransactionID: uuid.New(), FromAccount: "acc1"})
+	other := newTestMessage(t, &events.TransactionCreated{TransactionID: uuid.New(), FromAccount: "acc2"})
+	store := &fakeStore{pending: []*models.OutboxMessage{first, second, other}}
+	publisher := &fakePublisher{failSubjects: map[string]bool{failing.Subject(): true}}
+	relay := NewRelay(store, &fakeAudit{}, publisher, "us-east-1", zap.NewNop())
+
+	before := time.Now().UTC()
+	if _, err := relay.RunPending(context.Background(), before, 10); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// The later event of acc1 isn't sent ahead of the one that failed
+	if len(publisher.messages) != 1 || publisher.messages[0].SourceAccount != "acc2" {
+		t.Errorf("Expected only the event of acc2 published, got %+v", publisher.messages)
+	}
+	if retryAt, ok := store.failed[first.ID]; !ok || retryAt.Before(before.Add(time.Second)) {
+		t.Errorf("Expected the failed event to back off, got %v", retryAt)
+	}
+	// It is available again straight away; the store claims it once the
+	// event ahead of it is delivered
+	if retryAt, ok := store.failed[second.ID]; !ok || retryAt.After(time.Now().UTC()) {
+		t.Errorf("Expected the held back event to be available again, got %v", retryAt)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{9, 256 * time.Second},
+		{10, 5 * time.Minute},
+		{1000, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
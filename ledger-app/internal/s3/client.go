@@ -2,11 +2,13 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"go.uber.org/zap"
@@ -16,6 +18,7 @@ import (
 type Client struct {
 	s3Client s3API
 	bucket   string
+	timeout  time.Duration
 	logger   *zap.Logger
 }
 
@@ -24,6 +27,8 @@ type Config struct {
 	Endpoint string
 	Region   string
 	Bucket   string
+	// Timeout bounds every S3 call; the context passed to a call can end it sooner
+	Timeout time.Duration
 }
 
 // New creates a new S3 client
@@ -42,7 +47,9 @@ func New(config Config, logger *zap.Logger) (*Client, error) {
 	s3Client := s3.New(sess)
 
 	// Ensure bucket exists
-	if err := ensureBucket(s3Client, config.Bucket); err != nil {
+	ctx, cancel := withTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	if err := ensureBucket(ctx, s3Client, config.Bucket); err != nil {
 		return nil, fmt.Errorf("failed to ensure bucket exists: %w", err)
 	}
 
@@ -55,21 +62,30 @@ func New(config Config, logger *zap.Logger) (*Client, error) {
 	return &Client{
 		s3Client: s3Client,
 		bucket:   config.Bucket,
+		timeout:  config.Timeout,
 		logger:   logger,
 	}, nil
 }
 
 // s3API defines the S3 operations we need
 type s3API interface {
-	HeadBucket(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error)
-	CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error)
-	PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error)
+	HeadBucketWithContext(ctx aws.Context, input *s3.HeadBucketInput, opts ...request.Option) (*s3.HeadBucketOutput, error)
+	CreateBucketWithContext(ctx aws.Context, input *s3.CreateBucketInput, opts ...request.Option) (*s3.CreateBucketOutput, error)
+	PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error)
+}
+
+// withTimeout derives the context of a single call from ctx, bounded by timeout
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 // ensureBucket creates the bucket if it doesn't exist
-func ensureBucket(s3Client s3API, bucketName string) error {
+func ensureBucket(ctx context.Context, s3Client s3API, bucketName string) error {
 	// Check if bucket exists
-	_, err := s3Client.HeadBucket(&s3.HeadBucketInput{
+	_, err := s3Client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
 		Bucket: aws.String(bucketName),
 	})
 	if err == nil {
@@ -78,13 +94,13 @@ func ensureBucket(s3Client s3API, bucketName string) error {
 	}
 
 	// Try to create the bucket
-	_, err = s3Client.CreateBucket(&s3.CreateBucketInput{
+	_, err = s3Client.CreateBucketWithContext(ctx, &s3.CreateBucketInput{
 		Bucket: aws.String(bucketName),
 	})
 	if err != nil {
 		// Bucket might have been created by another instance
 		// Check again
-		_, checkErr := s3Client.HeadBucket(&s3.HeadBucketInput{
+		_, checkErr := s3Client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
 			Bucket: aws.String(bucketName),
 		})
 		if checkErr != nil {
@@ -96,8 +112,11 @@ func ensureBucket(s3Client s3API, bucketName string) error {
 }
 
 // WriteAuditLog writes an audit log entry to S3
-func (c *Client) WriteAuditLog(key string, content []byte) error {
-	_, err := c.s3Client.PutObject(&s3.PutObjectInput{
+func (c *Client) WriteAuditLog(ctx context.Context, key string, content []byte) error {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(c.bucket),
 		Key:         aws.String(key),
 		Body:        bytes.NewReader(content),
@@ -121,15 +140,18 @@ func (c *Client) WriteAuditLog(key string, content []byte) error {
 }
 
 // WriteAuditLogWithTimestamp writes an audit log with a timestamp-based key
-func (c *Client) WriteAuditLogWithTimestamp(prefix string, content []byte) error {
+func (c *Client) WriteAuditLogWithTimestamp(ctx context.Context, prefix string, content []byte) error {
 	timestamp := time.Now().UTC().Format("2006-01-02T15-04-05")
 	key := fmt.Sprintf("%s/%s-%d.json", prefix, timestamp, time.Now().UnixNano())
-	return c.WriteAuditLog(key, content)
+	return c.WriteAuditLog(ctx, key, content)
 }
 
 // Health checks if S3 is accessible
-func (c *Client) Health() error {
-	_, err := c.s3Client.HeadBucket(&s3.HeadBucketInput{
+func (c *Client) Health(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.s3Client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
 		Bucket: aws.String(c.bucket),
 	})
 	if err != nil {
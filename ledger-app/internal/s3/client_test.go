@@ -1,10 +1,14 @@
 package s3
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
@@ -15,24 +19,24 @@ type mockS3API struct {
 	mock.Mock
 }
 
-func (m *mockS3API) HeadBucket(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
-	args := m.Called(input)
+func (m *mockS3API) HeadBucketWithContext(ctx aws.Context, input *s3.HeadBucketInput, opts ...request.Option) (*s3.HeadBucketOutput, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*s3.HeadBucketOutput), args.Error(1)
 }
 
-func (m *mockS3API) CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
-	args := m.Called(input)
+func (m *mockS3API) CreateBucketWithContext(ctx aws.Context, input *s3.CreateBucketInput, opts ...request.Option) (*s3.CreateBucketOutput, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*s3.CreateBucketOutput), args.Error(1)
 }
 
-func (m *mockS3API) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
-	args := m.Called(input)
+func (m *mockS3API) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -56,11 +60,11 @@ func TestClient_WriteAuditLog_Success(t *testing.T) {
 	key := "transactions/test-key.json"
 	content := []byte(`{"test": "data"}`)
 
-	mockAPI.On("PutObject", mock.MatchedBy(func(input *s3.PutObjectInput) bool {
+	mockAPI.On("PutObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.PutObjectInput) bool {
 		return *input.Bucket == "test-bucket" && *input.Key == key
 	})).Return(&s3.PutObjectOutput{}, nil)
 
-	err := client.WriteAuditLog(key, content)
+	err := client.WriteAuditLog(context.Background(), key, content)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -76,9 +80,9 @@ func TestClient_WriteAuditLog_Error(t *testing.T) {
 	key := "transactions/test-key.json"
 	content := []byte(`{"test": "data"}`)
 
-	mockAPI.On("PutObject", mock.Anything).Return(nil, errors.New("S3 error"))
+	mockAPI.On("PutObjectWithContext", mock.Anything, mock.Anything).Return(nil, errors.New("S3 error"))
 
-	err := client.WriteAuditLog(key, content)
+	err := client.WriteAuditLog(context.Background(), key, content)
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -89,6 +93,23 @@ func TestClient_WriteAuditLog_Error(t *testing.T) {
 	mockAPI.AssertExpectations(t)
 }
 
+func TestClient_WriteAuditLog_Timeout(t *testing.T) {
+	mockAPI := new(mockS3API)
+	client := newTestableClient(mockAPI, "test-bucket", zap.NewNop())
+	client.timeout = time.Second
+
+	mockAPI.On("PutObjectWithContext", mock.MatchedBy(func(ctx aws.Context) bool {
+		deadline, ok := ctx.Deadline()
+		return ok && time.Until(deadline) <= time.Second
+	}), mock.Anything).Return(&s3.PutObjectOutput{}, nil)
+
+	if err := client.WriteAuditLog(context.Background(), "transactions/test-key.json", []byte(`{}`)); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
 func TestClient_WriteAuditLogWithTimestamp(t *testing.T) {
 	mockAPI := new(mockS3API)
 	logger := zap.NewNop()
@@ -97,13 +118,13 @@ func TestClient_WriteAuditLogWithTimestamp(t *testing.T) {
 	prefix := "transactions"
 	content := []byte(`{"test": "data"}`)
 
-	mockAPI.On("PutObject", mock.MatchedBy(func(input *s3.PutObjectInput) bool {
+	mockAPI.On("PutObjectWithContext", mock.Anything, mock.MatchedBy(func(input *s3.PutObjectInput) bool {
 		return *input.Bucket == "test-bucket" && 
 			len(*input.Key) > len(prefix) &&
 			(*input.Key)[:len(prefix)] == prefix
 	})).Return(&s3.PutObjectOutput{}, nil)
 
-	err := client.WriteAuditLogWithTimestamp(prefix, content)
+	err := client.WriteAuditLogWithTimestamp(context.Background(), prefix, content)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -116,11 +137,11 @@ func TestClient_Health_Success(t *testing.T) {
 	logger := zap.NewNop()
 	client := newTestableClient(mockAPI, "test-bucket", logger)
 
-	mockAPI.On("HeadBucket", mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
+	mockAPI.On("HeadBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
 		return *input.Bucket == "test-bucket"
 	})).Return(&s3.HeadBucketOutput{}, nil)
 
-	err := client.Health()
+	err := client.Health(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -133,9 +154,9 @@ func TestClient_Health_Error(t *testing.T) {
 	logger := zap.NewNop()
 	client := newTestableClient(mockAPI, "test-bucket", logger)
 
-	mockAPI.On("HeadBucket", mock.Anything).Return(nil, errors.New("bucket not found"))
+	mockAPI.On("HeadBucketWithContext", mock.Anything, mock.Anything).Return(nil, errors.New("bucket not found"))
 
-	err := client.Health()
+	err := client.Health(context.Background())
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -149,11 +170,11 @@ func TestClient_Health_Error(t *testing.T) {
 func TestEnsureBucket_BucketExists(t *testing.T) {
 	mockAPI := new(mockS3API)
 
-	mockAPI.On("HeadBucket", mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
+	mockAPI.On("HeadBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
 		return *input.Bucket == "existing-bucket"
 	})).Return(&s3.HeadBucketOutput{}, nil)
 
-	err := ensureBucket(mockAPI, "existing-bucket")
+	err := ensureBucket(context.Background(), mockAPI, "existing-bucket")
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -166,16 +187,16 @@ func TestEnsureBucket_BucketDoesNotExist_CreateSuccess(t *testing.T) {
 	mockAPI := new(mockS3API)
 
 	// First HeadBucket fails (bucket doesn't exist)
-	mockAPI.On("HeadBucket", mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
+	mockAPI.On("HeadBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
 		return *input.Bucket == "new-bucket"
 	})).Return(nil, awserr.New("NotFound", "bucket not found", nil))
 
 	// CreateBucket succeeds
-	mockAPI.On("CreateBucket", mock.MatchedBy(func(input *s3.CreateBucketInput) bool {
+	mockAPI.On("CreateBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CreateBucketInput) bool {
 		return *input.Bucket == "new-bucket"
 	})).Return(&s3.CreateBucketOutput{}, nil)
 
-	err := ensureBucket(mockAPI, "new-bucket")
+	err := ensureBucket(context.Background(), mockAPI, "new-bucket")
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -187,21 +208,21 @@ func TestEnsureBucket_BucketDoesNotExist_CreateFailsButBucketExists(t *testing.T
 	mockAPI := new(mockS3API)
 
 	// First HeadBucket fails (bucket doesn't exist)
-	mockAPI.On("HeadBucket", mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
+	mockAPI.On("HeadBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
 		return *input.Bucket == "new-bucket"
 	})).Return(nil, awserr.New("NotFound", "bucket not found", nil)).Once()
 
 	// CreateBucket fails (maybe race condition)
-	mockAPI.On("CreateBucket", mock.MatchedBy(func(input *s3.CreateBucketInput) bool {
+	mockAPI.On("CreateBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CreateBucketInput) bool {
 		return *input.Bucket == "new-bucket"
 	})).Return(nil, errors.New("bucket already exists"))
 
 	// Second HeadBucket succeeds (bucket was created by another instance)
-	mockAPI.On("HeadBucket", mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
+	mockAPI.On("HeadBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
 		return *input.Bucket == "new-bucket"
 	})).Return(&s3.HeadBucketOutput{}, nil).Once()
 
-	err := ensureBucket(mockAPI, "new-bucket")
+	err := ensureBucket(context.Background(), mockAPI, "new-bucket")
 	if err != nil {
 		t.Errorf("Expected no error (bucket exists after failed create), got: %v", err)
 	}
@@ -213,21 +234,21 @@ func TestEnsureBucket_BucketDoesNotExist_CreateFailsAndBucketStillMissing(t *tes
 	mockAPI := new(mockS3API)
 
 	// First HeadBucket fails (bucket doesn't exist)
-	mockAPI.On("HeadBucket", mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
+	mockAPI.On("HeadBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
 		return *input.Bucket == "new-bucket"
 	})).Return(nil, awserr.New("NotFound", "bucket not found", nil)).Once()
 
 	// CreateBucket fails
-	mockAPI.On("CreateBucket", mock.MatchedBy(func(input *s3.CreateBucketInput) bool {
+	mockAPI.On("CreateBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.CreateBucketInput) bool {
 		return *input.Bucket == "new-bucket"
 	})).Return(nil, errors.New("create failed"))
 
 	// Second HeadBucket also fails (bucket still doesn't exist)
-	mockAPI.On("HeadBucket", mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
+	mockAPI.On("HeadBucketWithContext", mock.Anything, mock.MatchedBy(func(input *s3.HeadBucketInput) bool {
 		return *input.Bucket == "new-bucket"
 	})).Return(nil, awserr.New("NotFound", "bucket not found", nil)).Once()
 
-	err := ensureBucket(mockAPI, "new-bucket")
+	err := ensureBucket(context.Background(), mockAPI, "new-bucket")
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -0,0 +1,121 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"go.uber.org/zap"
+)
+
+// Store is the database access the scheduler needs
+type Store interface {
+	DueSchedules(ctx context.Context, now time.Time, limit int) ([]*models.Schedule, error)
+	ExecuteSchedule(ctx context.Context, schedule *models.Schedule, tx *models.Transaction, next *time.Time,
+		outbox func(run *models.ScheduleRun) ([]*models.OutboxMessage, error)) (*models.ScheduleRun, error)
+}
+
+// Scheduler materializes due schedule executions into transactions
+type Scheduler struct {
+	store  Store
+	region string
+	logger *zap.Logger
+}
+
+// NewScheduler creates a scheduler that posts transactions in region
+func NewScheduler(store Store, region string, logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		store:  store,
+		region: region,
+		logger: logger,
+	}
+}
+
+// RunDue executes up to limit schedules that are due at now and returns how
+// many executions it ran. Executions that were missed while no scheduler was
+// running are run once, and the schedule resumes from its next time after now.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time, limit int) (int, error) {
+	due, err := s.store.DueSchedules(ctx, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	executed := 0
+	for _, schedule := range due {
+		spec, err := Parse(schedule.Spec, schedule.StartAt)
+		if err != nil {
+			s.logger.Error("Skipping schedule with invalid spec",
+				zap.Error(err),
+				zap.String("schedule_id", schedule.ID.String()),
+			)
+			continue
+		}
+
+		after := now
+		if schedule.NextRunAt.After(after) {
+			after = *schedule.NextRunAt
+		}
+		var next *time.Time
+		if t := spec.Next(after); schedule.Allows(t, schedule.RunCount+1) {
+			next = &t
+		}
+
+		tx := schedule.NewExecution(s.region)
+		_, err = s.store.ExecuteSchedule(ctx, schedule, tx, next, func(run *models.ScheduleRun) ([]*models.OutboxMessage, error) {
+			return s.outbox(schedule, run, tx)
+		})
+		if err != nil {
+			if errors.Is(err, models.ErrScheduleRunClaimed) {
+				s.logger.Debug("Schedule run already claimed", zap.String("schedule_id", schedule.ID.String()))
+				continue
+			}
+			s.logger.Warn("Failed to execute schedule",
+				zap.Error(err),
+				zap.String("schedule_id", schedule.ID.String()),
+			)
+			continue
+		}
+		executed++
+	}
+
+	return executed, nil
+}
+
+// outbox builds the outbox messages that write the audit log for a scheduled
+// transaction to S3 and publish it, like transactions created through the
+// API. The event is correlated to the schedule and caused by the run.
+func (s *Scheduler) outbox(schedule *models.Schedule, run *models.ScheduleRun, tx *models.Transaction) ([]*models.OutboxMessage, error) {
+	auditLog := &models.AuditLog{
+		TransactionID: tx.ID,
+		Region:        s.region,
+		Action:        "transaction_created",
+		Timestamp:     time.Now().UTC(),
+		Details:       fmt.Sprintf("Transaction created by schedule %s", schedule.ID.String()),
+	}
+	auditJSON, err := auditLog.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit log: %w", err)
+	}
+
+	created := events.NewTransactionCreated(tx)
+	created.ScheduleID = &schedule.ID
+	event, err := events.NewEnvelope(s.region, schedule.ID.String(), created)
+	if err != nil {
+		return nil, err
+	}
+	event.CausationID = run.ID.String()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	key := fmt.Sprintf("transactions/%s/%s.json", s.region, tx.ID.String())
+	return []*models.OutboxMessage{
+		models.NewOutboxAuditLog(s.region, key, []byte(auditJSON)),
+		models.NewOutboxMessage(s.region, event.OrderingKey(), payload),
+	}, nil
+}
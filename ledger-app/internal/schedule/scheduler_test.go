@@ -0,0 +1,190 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/models"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	due     []*models.Schedule
+	execute func(schedule *models.Schedule, tx *models.Transaction, next *time.Time) (*models.ScheduleRun, error)
+
+	// outbox holds the outbox messages stored with succeeded runs
+	outbox []*models.OutboxMessage
+}
+
+func (f *fakeStore) DueSchedules(ctx context.Context, now time.Time, limit int) ([]*models.Schedule, error) {
+	return f.due, nil
+}
+
+func (f *fakeStore) ExecuteSchedule(ctx context.Context, schedule *models.Schedule, tx *models.Transaction, next *time.Time,
+	outbox func(run *models.ScheduleRun) ([]*models.OutboxMessage, error)) (*models.ScheduleRun, error) {
+	run, err := f.execute(schedule, tx, next)
+	if err != nil || run.Status != models.ScheduleRunSucceeded {
+		return run, err
+	}
+	messages, err := outbox(run)
+	if err != nil {
+		return nil, err
+	}
+	f.outbox = append(f.outbox, messages...)
+	return run, nil
+}
+
+func newTestSchedule(spec string, next time.Time) *models.Schedule {
+	return models.NewSchedule("acc1", "acc2", models.Money{Value: decimal.NewFromInt(10), Currency: "USD"},
+		spec, "us-east-1", next, next)
+}
+
+func TestRunDue_ExecutesAndPublishes(t *testing.T) {
+	now := mustTime(t, "2026-01-01T10:00:30Z")
+	schedule := newTestSchedule("0 * * * *", mustTime(t, "2026-01-01T10:00:00Z"))
+
+	var gotNext *time.Time
+	var gotTx *models.Transaction
+	runID := uuid.New()
+	store := &fakeStore{
+		due: []*models.Schedule{schedule},
+		execute: func(s *models.Schedule, tx *models.Transaction, next *time.Time) (*models.ScheduleRun, error) {
+			gotNext, gotTx = next, tx
+			if tx.FromAccount != "acc1" || tx.ToAccount != "acc2" || tx.Region != "us-west-2" {
+				t.Errorf("Unexpected transaction %+v", tx)
+			}
+			return &models.ScheduleRun{ID: runID, Status: models.ScheduleRunSucceeded, TransactionID: &tx.ID}, nil
+		},
+	}
+	scheduler := NewScheduler(store, "us-west-2", zap.NewNop())
+
+	executed, err := scheduler.RunDue(context.Background(), now, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if executed != 1 {
+		t.Errorf("Expected 1 execution, got %d", executed)
+	}
+	if gotNext == nil || !gotNext.Equal(mustTime(t, "2026-01-01T11:00:00Z")) {
+		t.Errorf("Expected next run at 11:00, got %v", gotNext)
+	}
+	// The audit log and event are stored with the run for the relay
+	if len(store.outbox) != 2 {
+		t.Fatalf("Expected an audit log and a message in the outbox, got %d", len(store.outbox))
+	}
+	audit, message := store.outbox[0], store.outbox[1]
+	expectedKey := fmt.Sprintf("transactions/us-west-2/%s.json", gotTx.ID)
+	if audit.Kind != models.OutboxKindAuditLog || audit.Key != expectedKey {
+		t.Errorf("Expected an audit log at %s, got %s at %s", expectedKey, audit.Kind, audit.Key)
+	}
+	var event events.Envelope
+	if message.Kind != models.OutboxKindMessage || json.Unmarshal(message.Payload, &event) != nil {
+		t.Fatalf("Expected an SQS message, got %s: %s", message.Kind, message.Payload)
+	}
+	if event.Type != events.TypeTransactionCreated {
+		t.Errorf("Expected a TransactionCreated event, got %s", event.Type)
+	}
+	if event.CorrelationID != schedule.ID.String() || event.CausationID != runID.String() {
+		t.Errorf("Expected the event correlated to the schedule and caused by the run, got %s and %s",
+			event.CorrelationID, event.CausationID)
+	}
+	var created events.TransactionCreated
+	if err := event.Decode(&created); err != nil || created.ScheduleID == nil || *created.ScheduleID != schedule.ID {
+		t.Errorf("Expected the schedule ID in the payload, got %+v (%v)", created, err)
+	}
+}
+
+func TestRunDue_MissedRunsCollapse(t *testing.T) {
+	// The scheduler was down for three hours; the missed executions run once
+	now := mustTime(t, "2026-01-01T13:10:00Z")
+	schedule := newTestSchedule("0 * * * *", mustTime(t, "2026-01-01T10:00:00Z"))
+
+	var gotNext *time.Time
+	store := &fakeStore{
+		due: []*models.Schedule{schedule},
+		execute: func(s *models.Schedule, tx *models.Transaction, next *time.Time) (*models.ScheduleRun, error) {
+			gotNext = next
+			return &models.ScheduleRun{Status: models.ScheduleRunSucceeded}, nil
+		},
+	}
+	scheduler := NewScheduler(store, "us-east-1", zap.NewNop())
+
+	if _, err := scheduler.RunDue(context.Background(), now, 10); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if gotNext == nil || !gotNext.Equal(mustTime(t, "2026-01-01T14:00:00Z")) {
+		t.Errorf("Expected next run at 14:00, got %v", gotNext)
+	}
+}
+
+func TestRunDue_LastRunCompletes(t *testing.T) {
+	now := mustTime(t, "2026-01-01T10:00:00Z")
+	schedule := newTestSchedule("0 * * * *", now)
+	maxRuns := 3
+	schedule.MaxRuns = &maxRuns
+	schedule.RunCount = 2
+
+	called := false
+	store := &fakeStore{
+		due: []*models.Schedule{schedule},
+		execute: func(s *models.Schedule, tx *models.Transaction, next *time.Time) (*models.ScheduleRun, error) {
+			called = true
+			if next != nil {
+				t.Errorf("Expected the last run to complete the schedule, got next run %s", next)
+			}
+			return &models.ScheduleRun{Status: models.ScheduleRunFailed}, nil
+		},
+	}
+	scheduler := NewScheduler(store, "us-east-1", zap.NewNop())
+
+	if _, err := scheduler.RunDue(context.Background(), now, 10); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !called {
+		t.Error("Expected the schedule to be executed")
+	}
+	if len(store.outbox) != 0 {
+		t.Errorf("Expected nothing in the outbox for a failed run, got %d messages", len(store.outbox))
+	}
+}
+
+func TestRunDue_SkipsClaimedRuns(t *testing.T) {
+	now := mustTime(t, "2026-01-01T10:00:00Z")
+	store := &fakeStore{
+		due: []*models.Schedule{newTestSchedule("0 * * * *", now), newTestSchedule("0 * * * *", now)},
+		execute: func(s *models.Schedule, tx *models.Transaction, next *time.Time) (*models.ScheduleRun, error) {
+			return nil, fmt.Errorf("%w: %s", models.ErrScheduleRunClaimed, s.ID)
+		},
+	}
+	scheduler := NewScheduler(store, "us-east-1", zap.NewNop())
+
+	executed, err := scheduler.RunDue(context.Background(), now, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if executed != 0 || len(store.outbox) != 0 {
+		t.Errorf("Expected claimed runs to be skipped, got %d executed and %d messages", executed, len(store.outbox))
+	}
+}
+
+func TestRunDue_StoreError(t *testing.T) {
+	store := &fakeStore{}
+	scheduler := NewScheduler(&erroringStore{store}, "us-east-1", zap.NewNop())
+
+	if _, err := scheduler.RunDue(context.Background(), time.Now(), 10); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+type erroringStore struct{ *fakeStore }
+
+func (e *erroringStore) DueSchedules(ctx context.Context, now time.Time, limit int) ([]*models.Schedule, error) {
+	return nil, errors.New("connection refused")
+}
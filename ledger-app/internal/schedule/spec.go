@@ -0,0 +1,448 @@
+// Package schedule parses the specs of scheduled transfers and materializes
+// due executions into transactions.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSpec is returned when a schedule spec can't be parsed
+var ErrInvalidSpec = errors.New("invalid schedule spec")
+
+// searchDays bounds how far ahead Next looks for a matching day. Five years
+// covers every valid spec, including February 29th.
+const searchDays = 5 * 366
+
+// RRULE frequencies
+const (
+	freqDaily   = "DAILY"
+	freqWeekly  = "WEEKLY"
+	freqMonthly = "MONTHLY"
+)
+
+// Spec is a parsed schedule. All times are in UTC and have minute precision.
+//
+// Three forms are accepted:
+//   - an empty spec runs once at the start time
+//   - a five-field cron expression ("minute hour day-of-month month
+//     day-of-week"), or one of @hourly, @daily, @weekly and @monthly
+//   - an RRULE-like rule such as "FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=9"
+type Spec struct {
+	// Count is the number of executions an RRULE allows, or zero
+	Count int
+	// Until is the last time an RRULE allows, or the zero time
+	Until time.Time
+
+	start    time.Time
+	once     bool
+	minutes  uint64
+	hours    uint32
+	days     uint32 // bit n set for day n of the month
+	lastDay  bool   // the last day of the month matches
+	months   uint16 // bit n set for month n
+	weekdays uint8  // bit n set for time.Weekday(n)
+	// anyDay is set when either day field is unrestricted, so a day matches
+	// when both fields do; otherwise matching either one is enough, as in cron
+	anyDay   bool
+	freq     string
+	interval int
+}
+
+// Parse parses spec. start is when the schedule begins: nothing runs before
+// it, and RRULE fields that are left out default to its time and date.
+func Parse(spec string, start time.Time) (*Spec, error) {
+	start = ceilMinute(start.UTC())
+	spec = strings.TrimSpace(spec)
+
+	switch {
+	case spec == "":
+		return &Spec{start: start, once: true}, nil
+	case strings.HasPrefix(strings.ToUpper(spec), "RRULE:"):
+		return parseRRule(spec[len("RRULE:"):], start)
+	case strings.HasPrefix(strings.ToUpper(spec), "FREQ="):
+		return parseRRule(spec, start)
+	default:
+		return parseCron(spec, start)
+	}
+}
+
+// IsOnce reports whether the spec runs a single time
+func (s *Spec) IsOnce() bool {
+	return s.once
+}
+
+// Next returns the first execution time strictly after after, or the zero
+// time if the schedule has no more executions
+func (s *Spec) Next(after time.Time) time.Time {
+	from := ceilMinute(after.UTC().Add(time.Nanosecond))
+	if from.Before(s.start) {
+		from = s.start
+	}
+
+	if s.once {
+		if after.Before(s.start) {
+			return s.start
+		}
+		return time.Time{}
+	}
+
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	for i := 0; i < searchDays; i++ {
+		if !s.Until.IsZero() && day.After(s.Until) {
+			return time.Time{}
+		}
+
+		if s.matchesDay(day) {
+			for hour := 0; hour < 24; hour++ {
+				if s.hours&(1<<hour) == 0 {
+					continue
+				}
+				for minute := 0; minute < 60; minute++ {
+					if s.minutes&(1<<minute) == 0 {
+						continue
+					}
+					t := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+					if t.Before(from) {
+						continue
+					}
+					if !s.Until.IsZero() && t.After(s.Until) {
+						return time.Time{}
+					}
+					return t
+				}
+			}
+		}
+
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return time.Time{}
+}
+
+// matchesDay reports whether executions may happen on day
+func (s *Spec) matchesDay(day time.Time) bool {
+	if s.months&(1<<int(day.Month())) == 0 {
+		return false
+	}
+
+	dom := s.days&(1<<day.Day()) != 0 || (s.lastDay && day.AddDate(0, 0, 1).Day() == 1)
+	dow := s.weekdays&(1<<int(day.Weekday())) != 0
+	if s.anyDay {
+		if !dom || !dow {
+			return false
+		}
+	} else if !dom && !dow {
+		return false
+	}
+
+	if s.interval <= 1 {
+		return true
+	}
+
+	// RRULE intervals count periods from the start date
+	startDay := time.Date(s.start.Year(), s.start.Month(), s.start.Day(), 0, 0, 0, 0, time.UTC)
+	switch s.freq {
+	case freqDaily:
+		return int(day.Sub(startDay).Hours()/24)%s.interval == 0
+	case freqWeekly:
+		return int(weekStart(day).Sub(weekStart(startDay)).Hours()/(24*7))%s.interval == 0
+	case freqMonthly:
+		months := (day.Year()-startDay.Year())*12 + int(day.Month()) - int(startDay.Month())
+		return months%s.interval == 0
+	}
+	return true
+}
+
+// cronDescriptors are the shorthand cron expressions that are accepted
+var cronDescriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var weekdayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+func parseCron(spec string, start time.Time) (*Spec, error) {
+	if expanded, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: cron expressions have 5 fields, got %d", ErrInvalidSpec, len(fields))
+	}
+
+	s := &Spec{start: start}
+	var err error
+	if s.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("%w: minute: %v", ErrInvalidSpec, err)
+	}
+
+	hours, err := parseCronField(fields[1], 0, 23, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: hour: %v", ErrInvalidSpec, err)
+	}
+	s.hours = uint32(hours)
+
+	// "L" in the day-of-month field is the last day of the month
+	domField := fields[2]
+	if strings.EqualFold(domField, "L") {
+		s.lastDay = true
+	} else {
+		days, err := parseCronField(domField, 1, 31, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: day of month: %v", ErrInvalidSpec, err)
+		}
+		s.days = uint32(days)
+	}
+
+	months, err := parseCronField(fields[3], 1, 12, monthNames)
+	if err != nil {
+		return nil, fmt.Errorf("%w: month: %v", ErrInvalidSpec, err)
+	}
+	s.months = uint16(months)
+
+	// Both 0 and 7 are Sunday
+	weekdays, err := parseCronField(fields[4], 0, 7, weekdayNames)
+	if err != nil {
+		return nil, fmt.Errorf("%w: day of week: %v", ErrInvalidSpec, err)
+	}
+	if weekdays&(1<<7) != 0 {
+		weekdays |= 1
+	}
+	s.weekdays = uint8(weekdays & 0x7f)
+
+	s.anyDay = strings.HasPrefix(domField, "*") || strings.HasPrefix(fields[4], "*")
+
+	return s, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges ("1-5"),
+// wildcards and steps ("*/15", "10-50/10") into a bit set
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step in %q", item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = cronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = cronValue(bounds[1], names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", item, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+
+	if set == 0 {
+		return 0, fmt.Errorf("%q matches nothing", field)
+	}
+	return set, nil
+}
+
+func cronValue(value string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToUpper(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", value)
+	}
+	return n, nil
+}
+
+// rruleWeekdays maps RRULE day codes to time.Weekday
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRRule parses the subset of RFC 5545 recurrence rules that transfers
+// need: FREQ (DAILY, WEEKLY or MONTHLY), INTERVAL, BYDAY, BYMONTHDAY
+// (-1 is the last day), BYHOUR, BYMINUTE, COUNT and UNTIL
+func parseRRule(rule string, start time.Time) (*Spec, error) {
+	s := &Spec{start: start, interval: 1, months: 0x1ffe, anyDay: true}
+
+	parts := map[string]string{}
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%w: bad rule part %q", ErrInvalidSpec, part)
+		}
+		parts[strings.ToUpper(kv[0])] = strings.ToUpper(kv[1])
+	}
+
+	for key, value := range parts {
+		var err error
+		switch key {
+		case "FREQ":
+			s.freq = value
+		case "INTERVAL":
+			s.interval, err = strconv.Atoi(value)
+			if err == nil && s.interval <= 0 {
+				err = errors.New("must be positive")
+			}
+		case "COUNT":
+			s.Count, err = strconv.Atoi(value)
+			if err == nil && s.Count <= 0 {
+				err = errors.New("must be positive")
+			}
+		case "UNTIL":
+			s.Until, err = parseUntil(value)
+		case "BYHOUR":
+			var hours uint64
+			hours, err = parseNumberList(value, 0, 23)
+			s.hours = uint32(hours)
+		case "BYMINUTE":
+			s.minutes, err = parseNumberList(value, 0, 59)
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := rruleWeekdays[code]
+				if !ok {
+					err = fmt.Errorf("bad day %q", code)
+					break
+				}
+				s.weekdays |= 1 << day
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(value, ",") {
+				if item == "-1" {
+					s.lastDay = true
+					continue
+				}
+				var days uint64
+				if days, err = parseNumberList(item, 1, 31); err != nil {
+					break
+				}
+				s.days |= uint32(days)
+			}
+		default:
+			err = errors.New("not supported")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSpec, key, err)
+		}
+	}
+
+	// Fields that are left out take their value from the start time
+	if s.minutes == 0 {
+		s.minutes = 1 << start.Minute()
+	}
+	if s.hours == 0 {
+		s.hours = 1 << start.Hour()
+	}
+
+	allDays := uint32(0xfffffffe)
+	allWeekdays := uint8(0x7f)
+	switch s.freq {
+	case freqDaily:
+		if s.days == 0 && !s.lastDay {
+			s.days = allDays
+		}
+		if s.weekdays == 0 {
+			s.weekdays = allWeekdays
+		}
+	case freqWeekly:
+		if s.days != 0 || s.lastDay {
+			return nil, fmt.Errorf("%w: BYMONTHDAY can't be used with FREQ=WEEKLY", ErrInvalidSpec)
+		}
+		s.days = allDays
+		if s.weekdays == 0 {
+			s.weekdays = 1 << start.Weekday()
+		}
+	case freqMonthly:
+		if s.days == 0 && !s.lastDay {
+			if s.weekdays != 0 {
+				s.days = allDays
+			} else {
+				s.days = 1 << start.Day()
+			}
+		}
+		if s.weekdays == 0 {
+			s.weekdays = allWeekdays
+		}
+	case "":
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidSpec)
+	default:
+		return nil, fmt.Errorf("%w: FREQ=%s is not supported", ErrInvalidSpec, s.freq)
+	}
+
+	return s, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("bad time %q", value)
+}
+
+func parseNumberList(value string, min, max int) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("%q is outside %d-%d", item, min, max)
+		}
+		set |= 1 << n
+	}
+	return set, nil
+}
+
+// ceilMinute rounds t up to a whole minute
+func ceilMinute(t time.Time) time.Time {
+	truncated := t.Truncate(time.Minute)
+	if truncated.Before(t) {
+		return truncated.Add(time.Minute)
+	}
+	return truncated
+}
+
+// weekStart returns the Monday starting the week of day
+func weekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
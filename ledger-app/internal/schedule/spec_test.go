@@ -0,0 +1,178 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("Bad test time %q: %v", value, err)
+	}
+	return parsed
+}
+
+func TestSpecNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		start    string
+		after    string
+		expected []string
+	}{
+		{
+			name:     "once",
+			spec:     "",
+			start:    "2026-03-01T09:30:00Z",
+			after:    "2026-02-01T00:00:00Z",
+			expected: []string{"2026-03-01T09:30:00Z"},
+		},
+		{
+			name:     "cron every 15 minutes",
+			spec:     "*/15 * * * *",
+			start:    "2026-01-01T00:00:00Z",
+			after:    "2026-01-01T10:07:00Z",
+			expected: []string{"2026-01-01T10:15:00Z", "2026-01-01T10:30:00Z", "2026-01-01T10:45:00Z"},
+		},
+		{
+			name:     "cron end of day on weekdays",
+			spec:     "55 23 * * MON-FRI",
+			start:    "2026-01-01T00:00:00Z",
+			after:    "2026-01-09T23:55:00Z", // a Friday
+			expected: []string{"2026-01-12T23:55:00Z", "2026-01-13T23:55:00Z"},
+		},
+		{
+			name:     "cron day of month or weekday",
+			spec:     "0 12 1 * 0",
+			start:    "2026-01-01T00:00:00Z",
+			after:    "2026-01-30T00:00:00Z",
+			expected: []string{"2026-02-01T12:00:00Z", "2026-02-08T12:00:00Z"},
+		},
+		{
+			name:     "cron last day of month",
+			spec:     "0 18 L * *",
+			start:    "2026-01-01T00:00:00Z",
+			after:    "2026-01-31T18:00:00Z",
+			expected: []string{"2026-02-28T18:00:00Z", "2026-03-31T18:00:00Z"},
+		},
+		{
+			name:     "cron descriptor",
+			spec:     "@monthly",
+			start:    "2026-01-01T00:00:00Z",
+			after:    "2026-01-15T00:00:00Z",
+			expected: []string{"2026-02-01T00:00:00Z"},
+		},
+		{
+			name:     "nothing before start",
+			spec:     "0 * * * *",
+			start:    "2026-05-01T10:20:00Z",
+			after:    "2026-01-01T00:00:00Z",
+			expected: []string{"2026-05-01T11:00:00Z"},
+		},
+		{
+			name:     "rrule monthly rent",
+			spec:     "FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=9;BYMINUTE=0",
+			start:    "2026-01-15T00:00:00Z",
+			after:    "2026-01-15T00:00:00Z",
+			expected: []string{"2026-02-01T09:00:00Z", "2026-03-01T09:00:00Z"},
+		},
+		{
+			name:     "rrule defaults to the start time",
+			spec:     "RRULE:FREQ=WEEKLY;INTERVAL=2",
+			start:    "2026-01-05T08:30:00Z", // a Monday
+			after:    "2026-01-01T00:00:00Z",
+			expected: []string{"2026-01-05T08:30:00Z", "2026-01-19T08:30:00Z", "2026-02-02T08:30:00Z"},
+		},
+		{
+			name:     "rrule last day of month",
+			spec:     "FREQ=MONTHLY;BYMONTHDAY=-1;BYHOUR=23;BYMINUTE=59",
+			start:    "2026-01-01T00:00:00Z",
+			after:    "2026-01-01T00:00:00Z",
+			expected: []string{"2026-01-31T23:59:00Z", "2026-02-28T23:59:00Z"},
+		},
+		{
+			name:     "rrule until",
+			spec:     "FREQ=DAILY;BYHOUR=6;BYMINUTE=0;UNTIL=20260103T060000Z",
+			start:    "2026-01-01T00:00:00Z",
+			after:    "2026-01-01T00:00:00Z",
+			expected: []string{"2026-01-01T06:00:00Z", "2026-01-02T06:00:00Z", "2026-01-03T06:00:00Z", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := Parse(tt.spec, mustTime(t, tt.start))
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.spec, err)
+			}
+
+			after := mustTime(t, tt.after)
+			for _, want := range tt.expected {
+				next := spec.Next(after)
+				if want == "" {
+					if !next.IsZero() {
+						t.Fatalf("Expected no more executions, got %s", next)
+					}
+					return
+				}
+				if !next.Equal(mustTime(t, want)) {
+					t.Fatalf("Expected %s after %s, got %s", want, after.Format(time.RFC3339), next.Format(time.RFC3339))
+				}
+				after = next
+			}
+		})
+	}
+}
+
+func TestSpecNext_OnceRunsOnlyOnce(t *testing.T) {
+	start := mustTime(t, "2026-03-01T09:30:00Z")
+	spec, err := Parse("", start)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !spec.IsOnce() {
+		t.Error("Expected an empty spec to run once")
+	}
+	if next := spec.Next(start); !next.IsZero() {
+		t.Errorf("Expected no execution after the start time, got %s", next)
+	}
+}
+
+func TestParse_RRuleCount(t *testing.T) {
+	spec, err := Parse("FREQ=DAILY;COUNT=3", time.Now())
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if spec.Count != 3 {
+		t.Errorf("Expected count 3, got %d", spec.Count)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	specs := []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;BYHOUR=25",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"BYHOUR=9",
+		"FREQ=DAILY;BYSECOND=1",
+	}
+
+	for _, spec := range specs {
+		if _, err := Parse(spec, time.Now()); !errors.Is(err, ErrInvalidSpec) {
+			t.Errorf("Parse(%q): expected ErrInvalidSpec, got %v", spec, err)
+		}
+	}
+}
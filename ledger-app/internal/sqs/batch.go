@@ -0,0 +1,288 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"go.uber.org/zap"
+)
+
+// maxBatchEntries is the most entries SQS takes in one batch call
+const maxBatchEntries = 10
+
+// maxBatchBytes is the most message bytes, bodies and attributes together,
+// SQS takes in one send batch
+const maxBatchBytes = 256 * 1024
+
+// maxBatchAttempts is how often a batch entry is tried before its failure is
+// reported
+const maxBatchAttempts = 3
+
+// batchRetryDelay is the wait before the failed entries of a batch are
+// retried; it doubles with every retry
+var batchRetryDelay = 100 * time.Millisecond
+
+// batchEntry is one entry of a batch call. Its ID is its index in the
+// caller's batch. Entries with a group are FIFO messages that must reach the
+// queue in the order of their index within their group.
+type batchEntry[T any] struct {
+	index int
+	size  int
+	group string
+	value T
+}
+
+// batchCall makes one batch call with entries and returns the entries SQS
+// reported as failed
+type batchCall[T any] func(ctx context.Context, entries []batchEntry[T]) ([]*sqs.BatchResultErrorEntry, error)
+
+// entryError is the failure SQS reported for one entry of a batch
+type entryError struct {
+	code    string
+	message string
+}
+
+func (e *entryError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.message)
+}
+
+// groupBlockedError is reported for an entry that wasn't sent because an
+// earlier entry of its message group failed
+func groupBlockedError(group string) error {
+	return fmt.Errorf("not sent after an earlier message of group %s failed", group)
+}
+
+// runBatch makes call with entries in chunks that fit one batch call. Entries
+// that fail on the side of SQS, or whose whole call fails, are retried up to
+// maxBatchAttempts times in all; entries SQS rejects as invalid are not. Once
+// an entry of a group fails, the later entries of its group are left out of
+// the calls that follow, so that they don't reach the queue ahead of it. It
+// returns the error of each entry that still failed or wasn't sent, by index.
+func runBatch[T any](ctx context.Context, entries []batchEntry[T], call batchCall[T]) map[int]error {
+	failed := make(map[int]error)
+	// blockedAfter holds the index of the first entry of each group that
+	// failed
+	blockedAfter := make(map[string]int)
+	block := func(entry batchEntry[T]) {
+		if first, ok := blockedAfter[entry.group]; entry.group != "" && (!ok || entry.index < first) {
+			blockedAfter[entry.group] = entry.index
+		}
+	}
+
+	delay := batchRetryDelay
+	for attempt := 1; len(entries) > 0; attempt++ {
+		var retry []batchEntry[T]
+		for _, chunk := range chunkBatch(entries) {
+			var send []batchEntry[T]
+			for _, entry := range chunk {
+				if first, ok := blockedAfter[entry.group]; entry.group != "" && ok && entry.index > first {
+					failed[entry.index] = groupBlockedError(entry.group)
+					continue
+				}
+				send = append(send, entry)
+			}
+			if len(send) == 0 {
+				continue
+			}
+			chunk = send
+
+			rejected, err := call(ctx, chunk)
+			if err != nil {
+				for _, entry := range chunk {
+					failed[entry.index] = err
+					block(entry)
+				}
+				retry = append(retry, chunk...)
+				continue
+			}
+
+			retryable := make(map[string]bool, len(rejected))
+			for _, result := range rejected {
+				index, err := strconv.Atoi(aws.StringValue(result.Id))
+				if err != nil {
+					continue
+				}
+				failed[index] = &entryError{code: aws.StringValue(result.Code), message: aws.StringValue(result.Message)}
+				retryable[aws.StringValue(result.Id)] = !aws.BoolValue(result.SenderFault)
+			}
+			// Keep the entries in order, for FIFO queues
+			for _, entry := range chunk {
+				if _, ok := failed[entry.index]; ok {
+					block(entry)
+				}
+				if retryable[strconv.Itoa(entry.index)] {
+					retry = append(retry, entry)
+				}
+			}
+		}
+
+		if len(retry) == 0 || attempt == maxBatchAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return failed
+		case <-time.After(delay):
+		}
+		delay *= 2
+		for _, entry := range retry {
+			delete(failed, entry.index)
+		}
+		entries = retry
+	}
+	return failed
+}
+
+// chunkBatch splits entries into chunks of at most maxBatchEntries entries
+// and maxBatchBytes bytes
+func chunkBatch[T any](entries []batchEntry[T]) [][]batchEntry[T] {
+	var chunks [][]batchEntry[T]
+	start, size := 0, 0
+	for i, entry := range entries {
+		if i > start && (i-start == maxBatchEntries || size+entry.size > maxBatchBytes) {
+			chunks = append(chunks, entries[start:i])
+			start, size = i, 0
+		}
+		size += entry.size
+	}
+	if start < len(entries) {
+		chunks = append(chunks, entries[start:])
+	}
+	return chunks
+}
+
+// messageSize returns the bytes input counts towards the size of a batch
+func messageSize(input *sqs.SendMessageInput) int {
+	size := len(aws.StringValue(input.MessageBody))
+	for name, value := range input.MessageAttributes {
+		size += len(name) + len(aws.StringValue(value.DataType)) + len(aws.StringValue(value.StringValue)) + len(value.BinaryValue)
+	}
+	return size
+}
+
+// SendMessages sends events to the queue in batches of up to ten. Events
+// that SQS fails to take are retried; those that still fail are reported by
+// their index in a *events.BatchError. On a FIFO queue the later events of
+// a message group that weren't sent by the time one of its events failed are
+// not sent at all and are reported as failed too, so the caller sends them
+// again in order.
+func (c *Client) SendMessages(ctx context.Context, batch []*events.Envelope) error {
+	failed := make(map[int]error)
+	blocked := make(map[string]bool)
+	entries := make([]batchEntry[*sqs.SendMessageBatchRequestEntry], 0, len(batch))
+	for i, event := range batch {
+		var group string
+		if c.fifo {
+			group = event.OrderingKey()
+			if blocked[group] {
+				failed[i] = groupBlockedError(group)
+				continue
+			}
+		}
+		input, err := c.sendInput(event)
+		if err != nil {
+			failed[i] = err
+			if c.fifo {
+				blocked[group] = true
+			}
+			continue
+		}
+		entries = append(entries, batchEntry[*sqs.SendMessageBatchRequestEntry]{
+			index: i,
+			size:  messageSize(input),
+			group: group,
+			value: &sqs.SendMessageBatchRequestEntry{
+				Id:                     aws.String(strconv.Itoa(i)),
+				MessageBody:            input.MessageBody,
+				MessageAttributes:      input.MessageAttributes,
+				MessageGroupId:         input.MessageGroupId,
+				MessageDeduplicationId: input.MessageDeduplicationId,
+			},
+		})
+	}
+
+	sendFailed := runBatch(ctx, entries, func(ctx context.Context, chunk []batchEntry[*sqs.SendMessageBatchRequestEntry]) ([]*sqs.BatchResultErrorEntry, error) {
+		ctx, cancel := withTimeout(ctx, c.timeout)
+		defer cancel()
+
+		input := &sqs.SendMessageBatchInput{QueueUrl: aws.String(c.queueURL)}
+		for _, entry := range chunk {
+			input.Entries = append(input.Entries, entry.value)
+		}
+		result, err := c.sqsClient.SendMessageBatchWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send message batch: %w", err)
+		}
+		return result.Failed, nil
+	})
+	for i, err := range sendFailed {
+		failed[i] = err
+	}
+
+	for i, event := range batch {
+		if err, ok := failed[i]; ok {
+			c.logger.Error("Failed to send message to SQS",
+				zap.Error(err),
+				zap.String("event_id", event.EventID.String()),
+			)
+			continue
+		}
+		c.logger.Info("Message sent",
+			zap.String("event_id", event.EventID.String()),
+			zap.String("event_type", event.Type),
+			zap.String("subject", event.Subject),
+		)
+	}
+
+	if len(failed) > 0 {
+		return &events.BatchError{Failed: failed, Total: len(batch)}
+	}
+	return nil
+}
+
+// DeleteMessages deletes messages from the queue in batches of up to ten.
+// Deletes that SQS fails are retried; those that still fail are reported by
+// their index in a *events.BatchError.
+func (c *Client) DeleteMessages(ctx context.Context, receiptHandles []string) error {
+	entries := make([]batchEntry[*sqs.DeleteMessageBatchRequestEntry], len(receiptHandles))
+	for i, receiptHandle := range receiptHandles {
+		entries[i] = batchEntry[*sqs.DeleteMessageBatchRequestEntry]{
+			index: i,
+			value: &sqs.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: aws.String(receiptHandle),
+			},
+		}
+	}
+
+	failed := runBatch(ctx, entries, func(ctx context.Context, chunk []batchEntry[*sqs.DeleteMessageBatchRequestEntry]) ([]*sqs.BatchResultErrorEntry, error) {
+		ctx, cancel := withTimeout(ctx, c.timeout)
+		defer cancel()
+
+		input := &sqs.DeleteMessageBatchInput{QueueUrl: aws.String(c.queueURL)}
+		for _, entry := range chunk {
+			input.Entries = append(input.Entries, entry.value)
+		}
+		result, err := c.sqsClient.DeleteMessageBatchWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete message batch: %w", err)
+		}
+		return result.Failed, nil
+	})
+
+	if len(failed) > 0 {
+		for i, err := range failed {
+			c.logger.Error("Failed to delete message from SQS",
+				zap.Error(err),
+				zap.String("receipt_handle", receiptHandles[i]),
+			)
+		}
+		return &events.BatchError{Failed: failed, Total: len(receiptHandles)}
+	}
+	return nil
+}
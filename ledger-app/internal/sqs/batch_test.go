@@ -0,0 +1,231 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// noBatchRetryDelay retries failed batch entries straight away for the
+// duration of a test
+func noBatchRetryDelay(t *testing.T) {
+	delay := batchRetryDelay
+	batchRetryDelay = 0
+	t.Cleanup(func() { batchRetryDelay = delay })
+}
+
+func newTestEvents(n int) []*events.Envelope {
+	batch := make([]*events.Envelope, n)
+	for i := range batch {
+		batch[i] = newTestEvent(fmt.Sprintf("tx-%d", i))
+	}
+	return batch
+}
+
+// batchIDs returns the IDs of the entries of a send batch
+func batchIDs(input *sqs.SendMessageBatchInput) []string {
+	ids := make([]string, len(input.Entries))
+	for i, entry := range input.Entries {
+		ids[i] = aws.StringValue(entry.Id)
+	}
+	return ids
+}
+
+func TestClient_SendMessages_Chunks(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newTestableClient(mockAPI, "https://sqs.test/queue", zap.NewNop())
+
+	var sizes []int
+	mockAPI.On("SendMessageBatchWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageBatchInput) bool {
+		return *input.QueueUrl == "https://sqs.test/queue"
+	})).Run(func(args mock.Arguments) {
+		sizes = append(sizes, len(args.Get(1).(*sqs.SendMessageBatchInput).Entries))
+	}).Return(&sqs.SendMessageBatchOutput{}, nil)
+
+	if err := client.SendMessages(context.Background(), newTestEvents(23)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(sizes) != 3 || sizes[0] != 10 || sizes[1] != 10 || sizes[2] != 3 {
+		t.Errorf("Expected batches of 10, 10 and 3, got %v", sizes)
+	}
+}
+
+func TestClient_SendMessages_RetriesFailedEntries(t *testing.T) {
+	noBatchRetryDelay(t)
+	mockAPI := new(mockSQSAPI)
+	client := newTestableClient(mockAPI, "https://sqs.test/queue", zap.NewNop())
+
+	mockAPI.On("SendMessageBatchWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageBatchInput) bool {
+		return len(input.Entries) == 3
+	})).Return(&sqs.SendMessageBatchOutput{
+		Failed: []*sqs.BatchResultErrorEntry{
+			{Id: aws.String("1"), Code: aws.String("InternalError"), Message: aws.String("try again"), SenderFault: aws.Bool(false)},
+			{Id: aws.String("2"), Code: aws.String("InvalidMessageContents"), Message: aws.String("bad body"), SenderFault: aws.Bool(true)},
+		},
+	}, nil).Once()
+	// Only the entry that failed on the side of SQS is sent again
+	mockAPI.On("SendMessageBatchWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageBatchInput) bool {
+		ids := batchIDs(input)
+		return len(ids) == 1 && ids[0] == "1"
+	})).Return(&sqs.SendMessageBatchOutput{}, nil).Once()
+
+	err := client.SendMessages(context.Background(), newTestEvents(3))
+
+	var batchErr *events.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a BatchError, got: %v", err)
+	}
+	if batchErr.Total != 3 || len(batchErr.Failed) != 1 || batchErr.Failed[2] == nil {
+		t.Errorf("Expected only entry 2 to fail, got %v", batchErr.Failed)
+	}
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_SendMessages_GivesUpAfterMaxAttempts(t *testing.T) {
+	noBatchRetryDelay(t)
+	mockAPI := new(mockSQSAPI)
+	client := newTestableClient(mockAPI, "https://sqs.test/queue", zap.NewNop())
+
+	mockAPI.On("SendMessageBatchWithContext", mock.Anything, mock.Anything).Return(nil, errors.New("connection reset"))
+
+	err := client.SendMessages(context.Background(), newTestEvents(2))
+
+	var batchErr *events.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 2 {
+		t.Fatalf("Expected both entries to fail, got: %v", err)
+	}
+	mockAPI.AssertNumberOfCalls(t, "SendMessageBatchWithContext", maxBatchAttempts)
+}
+
+func TestClient_SendMessages_FIFO(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newTestableClient(mockAPI, "https://sqs.test/queue.fifo", zap.NewNop())
+	client.fifo = true
+	batch := newTestEvents(2)
+	batch[0].SourceAccount = "acc1"
+
+	mockAPI.On("SendMessageBatchWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageBatchInput) bool {
+		return aws.StringValue(input.Entries[0].MessageGroupId) == "acc1" &&
+			aws.StringValue(input.Entries[0].MessageDeduplicationId) == batch[0].EventID.String() &&
+			aws.StringValue(input.Entries[1].MessageGroupId) == "tx-1"
+	})).Return(&sqs.SendMessageBatchOutput{}, nil)
+
+	if err := client.SendMessages(context.Background(), batch); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_DeleteMessages(t *testing.T) {
+	noBatchRetryDelay(t)
+	mockAPI := new(mockSQSAPI)
+	client := newTestableClient(mockAPI, "https://sqs.test/queue", zap.NewNop())
+
+	receipts := make([]string, 12)
+	for i := range receipts {
+		receipts[i] = fmt.Sprintf("receipt-%d", i)
+	}
+	mockAPI.On("DeleteMessageBatchWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.DeleteMessageBatchInput) bool {
+		return len(input.Entries) == 10 && *input.Entries[0].ReceiptHandle == "receipt-0"
+	})).Return(&sqs.DeleteMessageBatchOutput{}, nil).Once()
+	// The last receipt keeps failing
+	mockAPI.On("DeleteMessageBatchWithContext", mock.Anything, mock.Anything).Return(&sqs.DeleteMessageBatchOutput{
+		Failed: []*sqs.BatchResultErrorEntry{
+			{Id: aws.String("11"), Code: aws.String("InternalError"), Message: aws.String("try again"), SenderFault: aws.Bool(false)},
+		},
+	}, nil)
+
+	err := client.DeleteMessages(context.Background(), receipts)
+
+	var batchErr *events.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a BatchError, got: %v", err)
+	}
+	if len(batchErr.Failed) != 1 || batchErr.Failed[11] == nil {
+		t.Errorf("Expected only entry 11 to fail, got %v", batchErr.Failed)
+	}
+	// One call for the first ten, then the last two and two retries of the last
+	mockAPI.AssertNumberOfCalls(t, "DeleteMessageBatchWithContext", 1+maxBatchAttempts)
+}
+
+func TestClient_AckBatch(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+
+	mockAPI.On("DeleteMessageBatchWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.DeleteMessageBatchInput) bool {
+		return *input.QueueUrl == testQueueURL && len(input.Entries) == 2 &&
+			*input.Entries[0].ReceiptHandle == "receipt-1" && *input.Entries[1].ReceiptHandle == "receipt-2"
+	})).Return(&sqs.DeleteMessageBatchOutput{}, nil)
+
+	deliveries := []*events.Delivery{{Receipt: "receipt-1"}, {Receipt: "receipt-2"}}
+	if err := client.AckBatch(context.Background(), deliveries); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mockAPI.AssertExpectations(t)
+}
+
+func TestChunkBatch_Size(t *testing.T) {
+	entries := make([]batchEntry[int], 5)
+	for i := range entries {
+		entries[i] = batchEntry[int]{index: i, size: 100 * 1024}
+	}
+
+	chunks := chunkBatch(entries)
+	if len(chunks) != 3 || len(chunks[0]) != 2 || len(chunks[1]) != 2 || len(chunks[2]) != 1 {
+		t.Errorf("Expected chunks of 2, 2 and 1 entries, got %v", chunks)
+	}
+}
+
+func TestClient_SendMessages_FIFOHoldsBackGroupAfterFailure(t *testing.T) {
+	noBatchRetryDelay(t)
+	mockAPI := new(mockSQSAPI)
+	client := newTestableClient(mockAPI, "https://sqs.test/queue.fifo", zap.NewNop())
+	client.fifo = true
+	batch := newTestEvents(13)
+	for i, event := range batch {
+		event.SourceAccount = fmt.Sprintf("acc%d", i%2)
+	}
+
+	// Entry 2 of acc0 fails in the first chunk
+	mockAPI.On("SendMessageBatchWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageBatchInput) bool {
+		return len(input.Entries) == 10
+	})).Return(&sqs.SendMessageBatchOutput{
+		Failed: []*sqs.BatchResultErrorEntry{
+			{Id: aws.String("2"), Code: aws.String("InternalError"), Message: aws.String("try again"), SenderFault: aws.Bool(false)},
+		},
+	}, nil).Once()
+	// The later entries of acc0 are held back; those of acc1 are still sent
+	mockAPI.On("SendMessageBatchWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageBatchInput) bool {
+		ids := batchIDs(input)
+		return len(ids) == 1 && ids[0] == "11"
+	})).Return(&sqs.SendMessageBatchOutput{}, nil).Once()
+	// Entry 2 itself is retried
+	mockAPI.On("SendMessageBatchWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageBatchInput) bool {
+		ids := batchIDs(input)
+		return len(ids) == 1 && ids[0] == "2"
+	})).Return(&sqs.SendMessageBatchOutput{}, nil).Once()
+
+	err := client.SendMessages(context.Background(), batch)
+
+	var batchErr *events.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a BatchError, got: %v", err)
+	}
+	// Entries 4, 6 and 8 were sent with entry 2 in the first call
+	for _, i := range []int{10, 12} {
+		if batchErr.Failed[i] == nil {
+			t.Errorf("Expected entry %d to be reported as failed, got %v", i, batchErr.Failed)
+		}
+	}
+	if len(batchErr.Failed) != 2 {
+		t.Errorf("Expected only entries 10 and 12 to fail, got %v", batchErr.Failed)
+	}
+	mockAPI.AssertExpectations(t)
+}
@@ -0,0 +1,76 @@
+package sqs
+
+import (
+	"context"
+	"time"
+
+	"github.com/project-atlas/ledger-app/internal/events"
+)
+
+// maxReceiveBatch is the most messages SQS returns from one receive
+const maxReceiveBatch = 10
+
+// Publish sends an event to the queue
+func (c *Client) Publish(ctx context.Context, event *events.Envelope) error {
+	return c.SendMessage(ctx, event)
+}
+
+// PublishBatch sends events to the queue in batches
+func (c *Client) PublishBatch(ctx context.Context, batch []*events.Envelope) error {
+	return c.SendMessages(ctx, batch)
+}
+
+// Receive long-polls the queue for up to the configured wait time and returns
+// up to max messages, hidden from other receivers for visibility
+func (c *Client) Receive(ctx context.Context, max int, visibility time.Duration) ([]*events.Delivery, error) {
+	if max > maxReceiveBatch {
+		max = maxReceiveBatch
+	}
+
+	received, err := c.receiveMessages(ctx, int64(max), int64(c.waitTime/time.Second), int64(visibility/time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*events.Delivery, len(received))
+	for i, msg := range received {
+		deliveries[i] = &events.Delivery{
+			Envelope:      msg.Envelope,
+			Receipt:       msg.ReceiptHandle,
+			ReceiveCount:  msg.ReceiveCount,
+			FinalDelivery: msg.FinalDelivery,
+			OrderingKey:   msg.MessageGroupID,
+		}
+	}
+	return deliveries, nil
+}
+
+// Ack deletes a handled message from the queue
+func (c *Client) Ack(ctx context.Context, delivery *events.Delivery) error {
+	return c.DeleteMessage(ctx, delivery.Receipt)
+}
+
+// AckBatch deletes handled messages from the queue in batches
+func (c *Client) AckBatch(ctx context.Context, deliveries []*events.Delivery) error {
+	receipts := make([]string, len(deliveries))
+	for i, delivery := range deliveries {
+		receipts[i] = delivery.Receipt
+	}
+	return c.DeleteMessages(ctx, receipts)
+}
+
+// Extend keeps a message hidden for another visibility
+func (c *Client) Extend(ctx context.Context, delivery *events.Delivery, visibility time.Duration) error {
+	return c.changeVisibility(ctx, c.queueURL, delivery.Receipt, int64(visibility/time.Second))
+}
+
+// Release makes a message visible again after delay. SQS counts each
+// delivery, so a message released too often moves to the dead-letter queue.
+func (c *Client) Release(ctx context.Context, delivery *events.Delivery, delay time.Duration) error {
+	return c.changeVisibility(ctx, c.queueURL, delivery.Receipt, int64(delay/time.Second))
+}
+
+// Close does nothing; the client holds no connections of its own
+func (c *Client) Close() error {
+	return nil
+}
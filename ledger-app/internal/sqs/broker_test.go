@@ -0,0 +1,81 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClient_Receive(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+	client.waitTime = 20 * time.Second
+
+	body, _ := json.Marshal(newTestEvent("tx-1"))
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.ReceiveMessageInput) bool {
+		return *input.MaxNumberOfMessages == 10 &&
+			*input.WaitTimeSeconds == 20 &&
+			*input.VisibilityTimeout == 45
+	})).Return(&sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{{
+			MessageId:     aws.String("msg-1"),
+			Body:          aws.String(string(body)),
+			ReceiptHandle: aws.String("receipt-1"),
+			Attributes:    map[string]*string{"ApproximateReceiveCount": aws.String("3")},
+		}},
+	}, nil)
+
+	deliveries, err := client.Receive(context.Background(), 50, 45*time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d", len(deliveries))
+	}
+	delivery := deliveries[0]
+	if delivery.Envelope.Subject != "tx-1" || delivery.Receipt != "receipt-1" {
+		t.Errorf("Unexpected delivery %+v", delivery)
+	}
+	if delivery.ReceiveCount != 3 || !delivery.FinalDelivery {
+		t.Errorf("Expected the third and final delivery, got %+v", delivery)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_AckExtendRelease(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+	delivery := &events.Delivery{Receipt: "receipt-1"}
+
+	mockAPI.On("DeleteMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.DeleteMessageInput) bool {
+		return *input.QueueUrl == testQueueURL && *input.ReceiptHandle == "receipt-1"
+	})).Return(&sqs.DeleteMessageOutput{}, nil)
+	for _, seconds := range []int64{30, 0} {
+		seconds := seconds
+		mockAPI.On("ChangeMessageVisibilityWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.ChangeMessageVisibilityInput) bool {
+			return *input.QueueUrl == testQueueURL &&
+				*input.ReceiptHandle == "receipt-1" &&
+				*input.VisibilityTimeout == seconds
+		})).Return(&sqs.ChangeMessageVisibilityOutput{}, nil).Once()
+	}
+
+	ctx := context.Background()
+	if err := client.Extend(ctx, delivery, 30*time.Second); err != nil {
+		t.Errorf("Extend: %v", err)
+	}
+	if err := client.Release(ctx, delivery, 0); err != nil {
+		t.Errorf("Release: %v", err)
+	}
+	if err := client.Ack(ctx, delivery); err != nil {
+		t.Errorf("Ack: %v", err)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
@@ -1,32 +1,47 @@
 package sqs
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/project-atlas/ledger-app/internal/events"
 	"go.uber.org/zap"
 )
 
 // sqsAPI defines the SQS operations we need
 type sqsAPI interface {
-	GetQueueUrl(input *sqs.GetQueueUrlInput) (*sqs.GetQueueUrlOutput, error)
-	CreateQueue(input *sqs.CreateQueueInput) (*sqs.CreateQueueOutput, error)
-	SendMessage(input *sqs.SendMessageInput) (*sqs.SendMessageOutput, error)
-	ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error)
-	DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error)
-	GetQueueAttributes(input *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error)
+	GetQueueUrlWithContext(ctx aws.Context, input *sqs.GetQueueUrlInput, opts ...request.Option) (*sqs.GetQueueUrlOutput, error)
+	CreateQueueWithContext(ctx aws.Context, input *sqs.CreateQueueInput, opts ...request.Option) (*sqs.CreateQueueOutput, error)
+	SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error)
+	SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error)
+	ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error)
+	DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, opts ...request.Option) (*sqs.DeleteMessageBatchOutput, error)
+	GetQueueAttributesWithContext(ctx aws.Context, input *sqs.GetQueueAttributesInput, opts ...request.Option) (*sqs.GetQueueAttributesOutput, error)
+	SetQueueAttributesWithContext(ctx aws.Context, input *sqs.SetQueueAttributesInput, opts ...request.Option) (*sqs.SetQueueAttributesOutput, error)
+	ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error)
+	PurgeQueueWithContext(ctx aws.Context, input *sqs.PurgeQueueInput, opts ...request.Option) (*sqs.PurgeQueueOutput, error)
 }
 
 // Client wraps the SQS client for LocalStack
 type Client struct {
-	sqsClient sqsAPI
-	queueURL  string
-	logger    *zap.Logger
+	sqsClient       sqsAPI
+	queueURL        string
+	dlqURL          string
+	fifo            bool
+	maxReceiveCount int
+	waitTime        time.Duration
+	timeout         time.Duration
+	logger          *zap.Logger
 }
 
 // Config holds SQS configuration
@@ -34,21 +49,31 @@ type Config struct {
 	Endpoint string
 	Region   string
 	Queue    string
-}
-
-// Message represents an SQS message
-type Message struct {
-	TransactionID string    `json:"transaction_id"`
-	Region         string    `json:"region"`
-	Action         string    `json:"action"`
-	Timestamp      time.Time `json:"timestamp"`
-	Data           string    `json:"data"`
+	// Timeout bounds every SQS call; the context passed to a call can end it sooner
+	Timeout time.Duration
+	// WaitTime is how long Receive long-polls for messages; SQS allows up to 20s
+	WaitTime time.Duration
+	// MaxReceiveCount is how often a message is received without being deleted
+	// before SQS moves it to the dead-letter queue, named after Queue with a
+	// "-dlq" suffix. Zero disables the dead-letter queue.
+	MaxReceiveCount int
+	// FIFO uses FIFO queues, named with a ".fifo" suffix, which deliver the
+	// events of each source account in order and drop duplicate sends of an
+	// event. Standard and FIFO queues are separate queues.
+	FIFO bool
 }
 
 // ReceivedMessage represents a message received from SQS with its receipt handle
 type ReceivedMessage struct {
-	Message      *Message
+	Envelope      *events.Envelope
 	ReceiptHandle string
+	// ReceiveCount is how often the message has been received, including this time
+	ReceiveCount int
+	// FinalDelivery is set when the message moves to the dead-letter queue
+	// unless it is deleted now
+	FinalDelivery bool
+	// MessageGroupID is the message group of a message from a FIFO queue
+	MessageGroupID string
 }
 
 // New creates a new SQS client
@@ -66,7 +91,9 @@ func New(config Config, logger *zap.Logger) (*Client, error) {
 	sqsClient := sqs.New(sess)
 
 	// Get or create queue
-	queueURL, err := ensureQueue(sqsClient, config.Queue, config.Region)
+	ctx, cancel := withTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	queueURL, dlqURL, err := ensureQueue(ctx, sqsClient, config.Queue, config.Region, config.MaxReceiveCount, config.FIFO)
 	if err != nil {
 		return nil, fmt.Errorf("failed to ensure queue exists: %w", err)
 	}
@@ -76,90 +103,257 @@ func New(config Config, logger *zap.Logger) (*Client, error) {
 		zap.String("region", config.Region),
 		zap.String("queue", config.Queue),
 		zap.String("queue_url", queueURL),
+		zap.String("dead_letter_queue_url", dlqURL),
+		zap.Bool("fifo", config.FIFO),
 	)
 
 	return &Client{
-		sqsClient: sqsClient,
-		queueURL:  queueURL,
-		logger:    logger,
+		sqsClient:       sqsClient,
+		queueURL:        queueURL,
+		dlqURL:          dlqURL,
+		fifo:            config.FIFO,
+		maxReceiveCount: config.MaxReceiveCount,
+		waitTime:        config.WaitTime,
+		timeout:         config.Timeout,
+		logger:          logger,
 	}, nil
 }
 
-// ensureQueue gets the queue URL or creates the queue if it doesn't exist
-func ensureQueue(sqsClient sqsAPI, queueName, region string) (string, error) {
+// withTimeout derives the context of a single call from ctx, bounded by timeout
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// redrivePolicy is the RedrivePolicy attribute of a queue
+type redrivePolicy struct {
+	DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	MaxReceiveCount     string `json:"maxReceiveCount"`
+}
+
+// fifoSuffix ends the name of every FIFO queue
+const fifoSuffix = ".fifo"
+
+// queueNames returns the names of the queue and its dead-letter queue
+func queueNames(queueName string, fifo bool) (string, string) {
+	if !fifo {
+		return queueName, queueName + deadLetterSuffix
+	}
+	base := strings.TrimSuffix(queueName, fifoSuffix)
+	return base + fifoSuffix, base + deadLetterSuffix + fifoSuffix
+}
+
+// fifoAttributes returns the attributes that make a new queue a FIFO queue.
+// Deduplication is by the ID each message is sent with, not by its body.
+func fifoAttributes(attributes map[string]*string) map[string]*string {
+	attributes[sqs.QueueAttributeNameFifoQueue] = aws.String("true")
+	attributes[sqs.QueueAttributeNameContentBasedDeduplication] = aws.String("false")
+	return attributes
+}
+
+// ensureQueue gets the queue URL or creates the queue if it doesn't exist.
+// With a positive maxReceiveCount it also provisions the queue's dead-letter
+// queue and sets the queue's redrive policy, and returns the dead-letter
+// queue's URL too. With fifo both are FIFO queues.
+func ensureQueue(ctx context.Context, sqsClient sqsAPI, queueName, region string, maxReceiveCount int, fifo bool) (string, string, error) {
+	attributes := map[string]*string{
+		"VisibilityTimeoutSeconds":      aws.String("30"),
+		"MessageRetentionPeriod":        aws.String("1209600"), // 14 days
+		"ReceiveMessageWaitTimeSeconds": aws.String("0"),       // Short polling
+	}
+	queueName, dlqName := queueNames(queueName, fifo)
+
+	var dlqURL string
+	if maxReceiveCount > 0 {
+		var dlqARN string
+		var err error
+		dlqURL, dlqARN, err = ensureDeadLetterQueue(ctx, sqsClient, dlqName, fifo)
+		if err != nil {
+			return "", "", err
+		}
+		policy, err := json.Marshal(redrivePolicy{
+			DeadLetterTargetArn: dlqARN,
+			MaxReceiveCount:     strconv.Itoa(maxReceiveCount),
+		})
+		if err != nil {
+			return "", "", fmt.Errorf("failed to encode redrive policy: %w", err)
+		}
+		attributes["RedrivePolicy"] = aws.String(string(policy))
+	}
+
 	// Try to get queue URL
-	result, err := sqsClient.GetQueueUrl(&sqs.GetQueueUrlInput{
+	result, err := sqsClient.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{
 		QueueName: aws.String(queueName),
 	})
 	if err == nil {
-		return *result.QueueUrl, nil
+		// Queues created before the dead-letter queue existed, or with another
+		// maxReceiveCount, get the current redrive policy
+		if policy, ok := attributes["RedrivePolicy"]; ok {
+			if _, err := sqsClient.SetQueueAttributesWithContext(ctx, &sqs.SetQueueAttributesInput{
+				QueueUrl:   result.QueueUrl,
+				Attributes: map[string]*string{"RedrivePolicy": policy},
+			}); err != nil {
+				return "", "", fmt.Errorf("failed to set redrive policy: %w", err)
+			}
+		}
+		return *result.QueueUrl, dlqURL, nil
 	}
 
 	// Queue doesn't exist, create it
-	createResult, err := sqsClient.CreateQueue(&sqs.CreateQueueInput{
-		QueueName: aws.String(queueName),
-		Attributes: map[string]*string{
-			"VisibilityTimeoutSeconds":   aws.String("30"),
-			"MessageRetentionPeriod":    aws.String("1209600"), // 14 days
-			"ReceiveMessageWaitTimeSeconds": aws.String("0"), // Short polling
-		},
+	if fifo {
+		attributes = fifoAttributes(attributes)
+	}
+	createResult, err := sqsClient.CreateQueueWithContext(ctx, &sqs.CreateQueueInput{
+		QueueName:  aws.String(queueName),
+		Attributes: attributes,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create queue: %w", err)
+		return "", "", fmt.Errorf("failed to create queue: %w", err)
 	}
 
-	return *createResult.QueueUrl, nil
+	return *createResult.QueueUrl, dlqURL, nil
 }
 
-// SendMessage sends a message to the queue
-func (c *Client) SendMessage(msg *Message) error {
-	body, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+// ensureDeadLetterQueue gets or creates a dead-letter queue and returns its
+// URL and ARN. The dead-letter queue of a FIFO queue must be a FIFO queue.
+func ensureDeadLetterQueue(ctx context.Context, sqsClient sqsAPI, queueName string, fifo bool) (string, string, error) {
+	var queueURL *string
+	result, err := sqsClient.GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{
+		QueueName: aws.String(queueName),
+	})
+	if err == nil {
+		queueURL = result.QueueUrl
+	} else {
+		attributes := map[string]*string{
+			"MessageRetentionPeriod": aws.String("1209600"), // 14 days
+		}
+		if fifo {
+			attributes = fifoAttributes(attributes)
+		}
+		createResult, err := sqsClient.CreateQueueWithContext(ctx, &sqs.CreateQueueInput{
+			QueueName:  aws.String(queueName),
+			Attributes: attributes,
+		})
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create dead-letter queue: %w", err)
+		}
+		queueURL = createResult.QueueUrl
 	}
 
-	_, err = c.sqsClient.SendMessage(&sqs.SendMessageInput{
-		QueueUrl:    aws.String(c.queueURL),
-		MessageBody: aws.String(string(body)),
-		MessageAttributes: map[string]*sqs.MessageAttributeValue{
-			"Region": {
-				DataType:    aws.String("String"),
-				StringValue: aws.String(msg.Region),
-			},
-			"Action": {
-				DataType:    aws.String("String"),
-				StringValue: aws.String(msg.Action),
-			},
-		},
+	attrs, err := sqsClient.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       queueURL,
+		AttributeNames: []*string{aws.String(sqs.QueueAttributeNameQueueArn)},
 	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get dead-letter queue ARN: %w", err)
+	}
+	arn, ok := attrs.Attributes[sqs.QueueAttributeNameQueueArn]
+	if !ok || arn == nil {
+		return "", "", fmt.Errorf("dead-letter queue %s has no ARN", queueName)
+	}
+
+	return *queueURL, *arn, nil
+}
+
+// SendMessage sends an event to the queue
+func (c *Client) SendMessage(ctx context.Context, event *events.Envelope) error {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	input, err := c.sendInput(event)
+	if err != nil {
+		return err
+	}
+	_, err = c.sqsClient.SendMessageWithContext(ctx, input)
 
 	if err != nil {
 		c.logger.Error("Failed to send message to SQS",
 			zap.Error(err),
-			zap.String("transaction_id", msg.TransactionID),
+			zap.String("event_id", event.EventID.String()),
 		)
 		return fmt.Errorf("failed to send message: %w", err)
 	}
 
 	c.logger.Info("Message sent",
-		zap.String("transaction_id", msg.TransactionID),
-		zap.String("action", msg.Action),
+		zap.String("event_id", event.EventID.String()),
+		zap.String("event_type", event.Type),
+		zap.String("subject", event.Subject),
 	)
 
 	return nil
 }
 
+// sendInput returns the input that sends event to the queue
+func (c *Client) sendInput(event *events.Envelope) (*sqs.SendMessageInput, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	input := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(c.queueURL),
+		MessageBody: aws.String(string(body)),
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			"Region": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(event.ProducerRegion),
+			},
+			"EventType": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(event.Type),
+			},
+			"SchemaVersion": {
+				DataType:    aws.String("Number"),
+				StringValue: aws.String(strconv.Itoa(event.SchemaVersion)),
+			},
+		},
+	}
+	if c.fifo {
+		// The events of an account share a message group, so they are
+		// delivered in order. A resend of the event within the five minute
+		// deduplication interval, such as the relay retrying a send that
+		// timed out, is dropped by SQS.
+		input.MessageGroupId = aws.String(event.OrderingKey())
+		input.MessageDeduplicationId = aws.String(event.EventID.String())
+	}
+	return input, nil
+}
+
 // ReceiveMessages receives messages from the queue
-// Returns messages with their receipt handles for deletion after processing
-func (c *Client) ReceiveMessages(maxMessages int64, waitTimeSeconds int64) ([]*ReceivedMessage, error) {
-	result, err := c.sqsClient.ReceiveMessage(&sqs.ReceiveMessageInput{
+// Returns messages with their receipt handles for deletion after processing.
+// The call may wait up to waitTimeSeconds for messages on top of the timeout.
+func (c *Client) ReceiveMessages(ctx context.Context, maxMessages int64, waitTimeSeconds int64) ([]*ReceivedMessage, error) {
+	return c.receiveMessages(ctx, maxMessages, waitTimeSeconds, 0)
+}
+
+// receiveMessages receives messages and hides them for visibilitySeconds, or
+// for the queue's visibility timeout when it is zero
+func (c *Client) receiveMessages(ctx context.Context, maxMessages, waitTimeSeconds, visibilitySeconds int64) ([]*ReceivedMessage, error) {
+	timeout := c.timeout
+	if timeout > 0 {
+		timeout += time.Duration(waitTimeSeconds) * time.Second
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	input := &sqs.ReceiveMessageInput{
 		QueueUrl:            aws.String(c.queueURL),
 		MaxNumberOfMessages: aws.Int64(maxMessages),
 		WaitTimeSeconds:     aws.Int64(waitTimeSeconds),
+		AttributeNames: []*string{
+			aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
+			aws.String(sqs.MessageSystemAttributeNameMessageGroupId),
+		},
 		MessageAttributeNames: []*string{
 			aws.String("All"),
 		},
-	})
+	}
+	if visibilitySeconds > 0 {
+		input.VisibilityTimeout = aws.Int64(visibilitySeconds)
+	}
+	result, err := c.sqsClient.ReceiveMessageWithContext(ctx, input)
 
 	if err != nil {
 		c.logger.Error("Failed to receive messages from SQS", zap.Error(err))
@@ -168,26 +362,46 @@ func (c *Client) ReceiveMessages(maxMessages int64, waitTimeSeconds int64) ([]*R
 
 	var receivedMessages []*ReceivedMessage
 	for _, sqsMsg := range result.Messages {
-		var msg Message
-		if err := json.Unmarshal([]byte(*sqsMsg.Body), &msg); err != nil {
-			c.logger.Warn("Failed to unmarshal message",
+		event, err := events.Parse([]byte(*sqsMsg.Body))
+		if err != nil {
+			c.logger.Warn("Failed to parse message",
 				zap.Error(err),
 				zap.String("message_id", *sqsMsg.MessageId),
 			)
+			// No consumer can read it, so it goes straight to the dead-letter
+			// queue instead of being received again until it expires
+			c.deadLetter(ctx, sqsMsg, fmt.Sprintf("invalid message body: %v", err))
 			continue
 		}
+		receiveCount := receiveCountOf(sqsMsg)
 		receivedMessages = append(receivedMessages, &ReceivedMessage{
-			Message:      &msg,
-			ReceiptHandle: *sqsMsg.ReceiptHandle,
+			Envelope:       event,
+			ReceiptHandle:  *sqsMsg.ReceiptHandle,
+			ReceiveCount:   receiveCount,
+			FinalDelivery:  c.maxReceiveCount > 0 && receiveCount >= c.maxReceiveCount,
+			MessageGroupID: aws.StringValue(sqsMsg.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]),
 		})
 	}
 
 	return receivedMessages, nil
 }
 
+// receiveCountOf returns the ApproximateReceiveCount of a received message
+func receiveCountOf(sqsMsg *sqs.Message) int {
+	if value, ok := sqsMsg.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]; ok && value != nil {
+		if count, err := strconv.Atoi(*value); err == nil {
+			return count
+		}
+	}
+	return 0
+}
+
 // DeleteMessage deletes a message from the queue
-func (c *Client) DeleteMessage(receiptHandle string) error {
-	_, err := c.sqsClient.DeleteMessage(&sqs.DeleteMessageInput{
+func (c *Client) DeleteMessage(ctx context.Context, receiptHandle string) error {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.sqsClient.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
 		QueueUrl:      aws.String(c.queueURL),
 		ReceiptHandle: aws.String(receiptHandle),
 	})
@@ -204,8 +418,11 @@ func (c *Client) DeleteMessage(receiptHandle string) error {
 }
 
 // Health checks if SQS is accessible
-func (c *Client) Health() error {
-	_, err := c.sqsClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+func (c *Client) Health(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.sqsClient.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
 		QueueUrl: aws.String(c.queueURL),
 		AttributeNames: []*string{
 			aws.String("All"),
@@ -1,6 +1,7 @@
 package sqs
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"testing"
@@ -8,7 +9,10 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/google/uuid"
+	"github.com/project-atlas/ledger-app/internal/events"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 )
@@ -18,54 +22,94 @@ type mockSQSAPI struct {
 	mock.Mock
 }
 
-func (m *mockSQSAPI) GetQueueUrl(input *sqs.GetQueueUrlInput) (*sqs.GetQueueUrlOutput, error) {
-	args := m.Called(input)
+func (m *mockSQSAPI) GetQueueUrlWithContext(ctx aws.Context, input *sqs.GetQueueUrlInput, opts ...request.Option) (*sqs.GetQueueUrlOutput, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*sqs.GetQueueUrlOutput), args.Error(1)
 }
 
-func (m *mockSQSAPI) CreateQueue(input *sqs.CreateQueueInput) (*sqs.CreateQueueOutput, error) {
-	args := m.Called(input)
+func (m *mockSQSAPI) CreateQueueWithContext(ctx aws.Context, input *sqs.CreateQueueInput, opts ...request.Option) (*sqs.CreateQueueOutput, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*sqs.CreateQueueOutput), args.Error(1)
 }
 
-func (m *mockSQSAPI) SendMessage(input *sqs.SendMessageInput) (*sqs.SendMessageOutput, error) {
-	args := m.Called(input)
+func (m *mockSQSAPI) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*sqs.SendMessageOutput), args.Error(1)
 }
 
-func (m *mockSQSAPI) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
-	args := m.Called(input)
+func (m *mockSQSAPI) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.SendMessageBatchOutput), args.Error(1)
+}
+
+func (m *mockSQSAPI) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*sqs.ReceiveMessageOutput), args.Error(1)
 }
 
-func (m *mockSQSAPI) DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
-	args := m.Called(input)
+func (m *mockSQSAPI) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*sqs.DeleteMessageOutput), args.Error(1)
 }
 
-func (m *mockSQSAPI) GetQueueAttributes(input *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
-	args := m.Called(input)
+func (m *mockSQSAPI) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, opts ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.DeleteMessageBatchOutput), args.Error(1)
+}
+
+func (m *mockSQSAPI) GetQueueAttributesWithContext(ctx aws.Context, input *sqs.GetQueueAttributesInput, opts ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	args := m.Called(ctx, input)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*sqs.GetQueueAttributesOutput), args.Error(1)
 }
 
+func (m *mockSQSAPI) SetQueueAttributesWithContext(ctx aws.Context, input *sqs.SetQueueAttributesInput, opts ...request.Option) (*sqs.SetQueueAttributesOutput, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.SetQueueAttributesOutput), args.Error(1)
+}
+
+func (m *mockSQSAPI) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.ChangeMessageVisibilityOutput), args.Error(1)
+}
+
+func (m *mockSQSAPI) PurgeQueueWithContext(ctx aws.Context, input *sqs.PurgeQueueInput, opts ...request.Option) (*sqs.PurgeQueueOutput, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sqs.PurgeQueueOutput), args.Error(1)
+}
+
 // newTestableClient creates a client with injectable SQS API (for testing)
 func newTestableClient(sqsClient sqsAPI, queueURL string, logger *zap.Logger) *Client {
 	return &Client{
@@ -75,25 +119,34 @@ func newTestableClient(sqsClient sqsAPI, queueURL string, logger *zap.Logger) *C
 	}
 }
 
+// newTestEvent returns a TransactionCreated event about subject
+func newTestEvent(subject string) *events.Envelope {
+	return &events.Envelope{
+		EventID:        uuid.New(),
+		Type:           events.TypeTransactionCreated,
+		SchemaVersion:  1,
+		Subject:        subject,
+		ProducerRegion: "us-east-1",
+		OccurredAt:     time.Now(),
+		Payload:        json.RawMessage(`{}`),
+	}
+}
+
 func TestClient_SendMessage_Success(t *testing.T) {
 	mockAPI := new(mockSQSAPI)
 	logger := zap.NewNop()
 	client := newTestableClient(mockAPI, "https://sqs.test/queue", logger)
 
-	msg := &Message{
-		TransactionID: "test-tx-123",
-		Region:        "us-east-1",
-		Action:        "transaction_created",
-		Timestamp:     time.Now(),
-		Data:          `{"test": "data"}`,
-	}
+	msg := newTestEvent("test-tx-123")
 
-	mockAPI.On("SendMessage", mock.MatchedBy(func(input *sqs.SendMessageInput) bool {
+	mockAPI.On("SendMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageInput) bool {
 		return *input.QueueUrl == "https://sqs.test/queue" &&
-			*input.MessageBody != ""
+			*input.MessageBody != "" &&
+			*input.MessageAttributes["EventType"].StringValue == events.TypeTransactionCreated &&
+			*input.MessageAttributes["SchemaVersion"].StringValue == "1"
 	})).Return(&sqs.SendMessageOutput{}, nil)
 
-	err := client.SendMessage(msg)
+	err := client.SendMessage(context.Background(), msg)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -101,6 +154,35 @@ func TestClient_SendMessage_Success(t *testing.T) {
 	mockAPI.AssertExpectations(t)
 }
 
+func TestClient_SendMessage_FIFO(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newTestableClient(mockAPI, "https://sqs.test/queue.fifo", zap.NewNop())
+	client.fifo = true
+
+	withAccount := newTestEvent("tx-1")
+	withAccount.SourceAccount = "acc1"
+	withoutAccount := newTestEvent("batch-1")
+
+	for _, tc := range []struct {
+		event *events.Envelope
+		group string
+	}{
+		{withAccount, "acc1"},
+		{withoutAccount, "batch-1"},
+	} {
+		mockAPI.On("SendMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageInput) bool {
+			return aws.StringValue(input.MessageGroupId) == tc.group &&
+				aws.StringValue(input.MessageDeduplicationId) == tc.event.EventID.String()
+		})).Return(&sqs.SendMessageOutput{}, nil).Once()
+
+		if err := client.SendMessage(context.Background(), tc.event); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
 func TestClient_SendMessage_MarshalError(t *testing.T) {
 	mockAPI := new(mockSQSAPI)
 	logger := zap.NewNop()
@@ -108,17 +190,11 @@ func TestClient_SendMessage_MarshalError(t *testing.T) {
 
 	// Create a message that will fail to marshal (circular reference would do it, but simpler: invalid type)
 	// Actually, Message struct is simple, so marshal won't fail. Let's test SQS error instead
-	msg := &Message{
-		TransactionID: "test-tx-123",
-		Region:        "us-east-1",
-		Action:        "transaction_created",
-		Timestamp:     time.Now(),
-		Data:          `{"test": "data"}`,
-	}
+	msg := newTestEvent("test-tx-123")
 
-	mockAPI.On("SendMessage", mock.Anything).Return(nil, errors.New("SQS error"))
+	mockAPI.On("SendMessageWithContext", mock.Anything, mock.Anything).Return(nil, errors.New("SQS error"))
 
-	err := client.SendMessage(msg)
+	err := client.SendMessage(context.Background(), msg)
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -134,15 +210,9 @@ func TestClient_ReceiveMessages_Success(t *testing.T) {
 	logger := zap.NewNop()
 	client := newTestableClient(mockAPI, "https://sqs.test/queue", logger)
 
-	msgBody, _ := json.Marshal(&Message{
-		TransactionID: "test-tx-123",
-		Region:        "us-east-1",
-		Action:        "transaction_created",
-		Timestamp:     time.Now(),
-		Data:          `{"test": "data"}`,
-	})
+	msgBody, _ := json.Marshal(newTestEvent("test-tx-123"))
 
-	mockAPI.On("ReceiveMessage", mock.MatchedBy(func(input *sqs.ReceiveMessageInput) bool {
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.ReceiveMessageInput) bool {
 		return *input.QueueUrl == "https://sqs.test/queue" &&
 			*input.MaxNumberOfMessages == 10 &&
 			*input.WaitTimeSeconds == 0
@@ -156,7 +226,7 @@ func TestClient_ReceiveMessages_Success(t *testing.T) {
 		},
 	}, nil)
 
-	receivedMessages, err := client.ReceiveMessages(10, 0)
+	receivedMessages, err := client.ReceiveMessages(context.Background(), 10, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -165,8 +235,8 @@ func TestClient_ReceiveMessages_Success(t *testing.T) {
 		t.Errorf("Expected 1 message, got %d", len(receivedMessages))
 	}
 
-	if receivedMessages[0].Message.TransactionID != "test-tx-123" {
-		t.Errorf("Expected transaction ID 'test-tx-123', got '%s'", receivedMessages[0].Message.TransactionID)
+	if receivedMessages[0].Envelope.Subject != "test-tx-123" {
+		t.Errorf("Expected subject 'test-tx-123', got '%s'", receivedMessages[0].Envelope.Subject)
 	}
 
 	if receivedMessages[0].ReceiptHandle != "receipt-1" {
@@ -176,16 +246,69 @@ func TestClient_ReceiveMessages_Success(t *testing.T) {
 	mockAPI.AssertExpectations(t)
 }
 
+func TestClient_ReceiveMessages_UpcastsLegacyMessages(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newTestableClient(mockAPI, "https://sqs.test/queue", zap.NewNop())
+
+	// Sent by a release from before event envelopes
+	legacy := `{"transaction_id":"7d3f2c4e-9b1a-4f8e-a6d2-1c5b8e9f0a21","region":"us-east-1","action":"transaction_created","timestamp":"2024-03-01T12:00:00Z","data":"{}"}`
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{
+			{
+				MessageId:     aws.String("msg-1"),
+				Body:          aws.String(legacy),
+				ReceiptHandle: aws.String("receipt-1"),
+			},
+		},
+	}, nil)
+
+	receivedMessages, err := client.ReceiveMessages(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(receivedMessages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(receivedMessages))
+	}
+
+	event := receivedMessages[0].Envelope
+	if event.Type != events.TypeTransactionCreated || event.SchemaVersion != 1 {
+		t.Errorf("Expected a version 1 TransactionCreated event, got %s version %d", event.Type, event.SchemaVersion)
+	}
+	if event.Subject != "7d3f2c4e-9b1a-4f8e-a6d2-1c5b8e9f0a21" {
+		t.Errorf("Unexpected subject %s", event.Subject)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_ReceiveMessages_TimeoutCoversWait(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newTestableClient(mockAPI, "http://localhost:4566/queue/test", zap.NewNop())
+	client.timeout = time.Second
+
+	// A long poll must not be cut short by the per-call timeout
+	mockAPI.On("ReceiveMessageWithContext", mock.MatchedBy(func(ctx aws.Context) bool {
+		deadline, ok := ctx.Deadline()
+		return ok && time.Until(deadline) > 20*time.Second && time.Until(deadline) <= 21*time.Second
+	}), mock.Anything).Return(&sqs.ReceiveMessageOutput{}, nil)
+
+	if _, err := client.ReceiveMessages(context.Background(), 10, 20); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
 func TestClient_ReceiveMessages_Empty(t *testing.T) {
 	mockAPI := new(mockSQSAPI)
 	logger := zap.NewNop()
 	client := newTestableClient(mockAPI, "https://sqs.test/queue", logger)
 
-	mockAPI.On("ReceiveMessage", mock.Anything).Return(&sqs.ReceiveMessageOutput{
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.ReceiveMessageOutput{
 		Messages: []*sqs.Message{},
 	}, nil)
 
-	receivedMessages, err := client.ReceiveMessages(10, 0)
+	receivedMessages, err := client.ReceiveMessages(context.Background(), 10, 0)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -203,7 +326,7 @@ func TestClient_ReceiveMessages_UnmarshalError(t *testing.T) {
 	client := newTestableClient(mockAPI, "https://sqs.test/queue", logger)
 
 	// Return invalid JSON
-	mockAPI.On("ReceiveMessage", mock.Anything).Return(&sqs.ReceiveMessageOutput{
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.ReceiveMessageOutput{
 		Messages: []*sqs.Message{
 			{
 				MessageId:     aws.String("msg-1"),
@@ -213,7 +336,7 @@ func TestClient_ReceiveMessages_UnmarshalError(t *testing.T) {
 		},
 	}, nil)
 
-	receivedMessages, err := client.ReceiveMessages(10, 0)
+	receivedMessages, err := client.ReceiveMessages(context.Background(), 10, 0)
 	// Function should continue on unmarshal errors (logs warning, skips message)
 	if err != nil {
 		t.Errorf("Expected no error (unmarshal errors are logged but not returned), got: %v", err)
@@ -231,9 +354,9 @@ func TestClient_ReceiveMessages_SQSError(t *testing.T) {
 	logger := zap.NewNop()
 	client := newTestableClient(mockAPI, "https://sqs.test/queue", logger)
 
-	mockAPI.On("ReceiveMessage", mock.Anything).Return(nil, errors.New("SQS error"))
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(nil, errors.New("SQS error"))
 
-	receivedMessages, err := client.ReceiveMessages(10, 0)
+	receivedMessages, err := client.ReceiveMessages(context.Background(), 10, 0)
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -252,12 +375,12 @@ func TestClient_DeleteMessage_Success(t *testing.T) {
 
 	receiptHandle := "test-receipt-handle"
 
-	mockAPI.On("DeleteMessage", mock.MatchedBy(func(input *sqs.DeleteMessageInput) bool {
+	mockAPI.On("DeleteMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.DeleteMessageInput) bool {
 		return *input.QueueUrl == "https://sqs.test/queue" &&
 			*input.ReceiptHandle == receiptHandle
 	})).Return(&sqs.DeleteMessageOutput{}, nil)
 
-	err := client.DeleteMessage(receiptHandle)
+	err := client.DeleteMessage(context.Background(), receiptHandle)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -270,9 +393,9 @@ func TestClient_DeleteMessage_Error(t *testing.T) {
 	logger := zap.NewNop()
 	client := newTestableClient(mockAPI, "https://sqs.test/queue", logger)
 
-	mockAPI.On("DeleteMessage", mock.Anything).Return(nil, errors.New("SQS error"))
+	mockAPI.On("DeleteMessageWithContext", mock.Anything, mock.Anything).Return(nil, errors.New("SQS error"))
 
-	err := client.DeleteMessage("test-receipt")
+	err := client.DeleteMessage(context.Background(), "test-receipt")
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -288,11 +411,11 @@ func TestClient_Health_Success(t *testing.T) {
 	logger := zap.NewNop()
 	client := newTestableClient(mockAPI, "https://sqs.test/queue", logger)
 
-	mockAPI.On("GetQueueAttributes", mock.MatchedBy(func(input *sqs.GetQueueAttributesInput) bool {
+	mockAPI.On("GetQueueAttributesWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.GetQueueAttributesInput) bool {
 		return *input.QueueUrl == "https://sqs.test/queue"
 	})).Return(&sqs.GetQueueAttributesOutput{}, nil)
 
-	err := client.Health()
+	err := client.Health(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -305,9 +428,9 @@ func TestClient_Health_Error(t *testing.T) {
 	logger := zap.NewNop()
 	client := newTestableClient(mockAPI, "https://sqs.test/queue", logger)
 
-	mockAPI.On("GetQueueAttributes", mock.Anything).Return(nil, errors.New("queue not found"))
+	mockAPI.On("GetQueueAttributesWithContext", mock.Anything, mock.Anything).Return(nil, errors.New("queue not found"))
 
-	err := client.Health()
+	err := client.Health(context.Background())
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -321,13 +444,13 @@ func TestClient_Health_Error(t *testing.T) {
 func TestEnsureQueue_QueueExists(t *testing.T) {
 	mockAPI := new(mockSQSAPI)
 
-	mockAPI.On("GetQueueUrl", mock.MatchedBy(func(input *sqs.GetQueueUrlInput) bool {
+	mockAPI.On("GetQueueUrlWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.GetQueueUrlInput) bool {
 		return *input.QueueName == "existing-queue"
 	})).Return(&sqs.GetQueueUrlOutput{
 		QueueUrl: aws.String("https://sqs.test/existing-queue"),
 	}, nil)
 
-	queueURL, err := ensureQueue(mockAPI, "existing-queue", "us-east-1")
+	queueURL, _, err := ensureQueue(context.Background(), mockAPI, "existing-queue", "us-east-1", 0, false)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -344,18 +467,18 @@ func TestEnsureQueue_QueueDoesNotExist_CreateSuccess(t *testing.T) {
 	mockAPI := new(mockSQSAPI)
 
 	// GetQueueUrl fails (queue doesn't exist)
-	mockAPI.On("GetQueueUrl", mock.MatchedBy(func(input *sqs.GetQueueUrlInput) bool {
+	mockAPI.On("GetQueueUrlWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.GetQueueUrlInput) bool {
 		return *input.QueueName == "new-queue"
 	})).Return(nil, awserr.New("AWS.SimpleQueueService.NonExistentQueue", "queue not found", nil))
 
 	// CreateQueue succeeds
-	mockAPI.On("CreateQueue", mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
+	mockAPI.On("CreateQueueWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
 		return *input.QueueName == "new-queue"
 	})).Return(&sqs.CreateQueueOutput{
 		QueueUrl: aws.String("https://sqs.test/new-queue"),
 	}, nil)
 
-	queueURL, err := ensureQueue(mockAPI, "new-queue", "us-east-1")
+	queueURL, _, err := ensureQueue(context.Background(), mockAPI, "new-queue", "us-east-1", 0, false)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -371,16 +494,16 @@ func TestEnsureQueue_CreateFails(t *testing.T) {
 	mockAPI := new(mockSQSAPI)
 
 	// GetQueueUrl fails (queue doesn't exist)
-	mockAPI.On("GetQueueUrl", mock.MatchedBy(func(input *sqs.GetQueueUrlInput) bool {
+	mockAPI.On("GetQueueUrlWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.GetQueueUrlInput) bool {
 		return *input.QueueName == "new-queue"
 	})).Return(nil, awserr.New("AWS.SimpleQueueService.NonExistentQueue", "queue not found", nil))
 
 	// CreateQueue fails
-	mockAPI.On("CreateQueue", mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
+	mockAPI.On("CreateQueueWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
 		return *input.QueueName == "new-queue"
 	})).Return(nil, errors.New("create failed"))
 
-	queueURL, err := ensureQueue(mockAPI, "new-queue", "us-east-1")
+	queueURL, _, err := ensureQueue(context.Background(), mockAPI, "new-queue", "us-east-1", 0, false)
 	if err == nil {
 		t.Error("Expected error, got nil")
 	}
@@ -391,3 +514,23 @@ func TestEnsureQueue_CreateFails(t *testing.T) {
 
 	mockAPI.AssertExpectations(t)
 }
+
+func TestQueueNames(t *testing.T) {
+	cases := []struct {
+		queue     string
+		fifo      bool
+		wantQueue string
+		wantDLQ   string
+	}{
+		{"queue", false, "queue", "queue-dlq"},
+		{"queue", true, "queue.fifo", "queue-dlq.fifo"},
+		{"queue.fifo", true, "queue.fifo", "queue-dlq.fifo"},
+	}
+
+	for _, tc := range cases {
+		queue, dlq := queueNames(tc.queue, tc.fifo)
+		if queue != tc.wantQueue || dlq != tc.wantDLQ {
+			t.Errorf("queueNames(%q, %v) = %q, %q; expected %q, %q", tc.queue, tc.fifo, queue, dlq, tc.wantQueue, tc.wantDLQ)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"go.uber.org/zap"
+)
+
+// deadLetterSuffix is appended to a queue's name to name its dead-letter queue
+const deadLetterSuffix = "-dlq"
+
+// deadLetterReasonAttribute is the message attribute that records why the
+// client moved a message to the dead-letter queue itself
+const deadLetterReasonAttribute = "DeadLetterReason"
+
+// deadLetterVisibility is how long, in seconds, messages received from the
+// dead-letter queue by the admin operations stay hidden from other receivers
+const deadLetterVisibility = 30
+
+// ErrNoDeadLetterQueue is returned by the dead-letter operations of a client
+// configured without a dead-letter queue
+var ErrNoDeadLetterQueue = errors.New("no dead-letter queue configured")
+
+// DeadLetter is a message in the dead-letter queue
+type DeadLetter struct {
+	MessageID    string
+	Body         string
+	ReceiveCount int
+	SentAt       time.Time
+	// Reason is set when the client moved the message because it couldn't be
+	// read; messages moved by SQS after too many receives have none
+	Reason string
+}
+
+// deadLetter moves a message the consumer can't handle to the dead-letter
+// queue, recording reason. If that fails, the message stays in the queue and
+// SQS moves it once it has been received MaxReceiveCount times.
+func (c *Client) deadLetter(ctx context.Context, sqsMsg *sqs.Message, reason string) {
+	if c.dlqURL == "" {
+		return
+	}
+
+	attributes := map[string]*sqs.MessageAttributeValue{}
+	for name, value := range sqsMsg.MessageAttributes {
+		attributes[name] = value
+	}
+	attributes[deadLetterReasonAttribute] = &sqs.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(reason),
+	}
+
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(c.dlqURL),
+		MessageBody:       sqsMsg.Body,
+		MessageAttributes: attributes,
+	}
+	c.keepGroup(input, sqsMsg)
+	if _, err := c.sqsClient.SendMessageWithContext(ctx, input); err != nil {
+		c.logger.Error("Failed to move message to dead-letter queue",
+			zap.Error(err),
+			zap.String("message_id", aws.StringValue(sqsMsg.MessageId)),
+		)
+		return
+	}
+
+	if _, err := c.sqsClient.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(c.queueURL),
+		ReceiptHandle: sqsMsg.ReceiptHandle,
+	}); err != nil {
+		c.logger.Error("Failed to delete message moved to dead-letter queue",
+			zap.Error(err),
+			zap.String("message_id", aws.StringValue(sqsMsg.MessageId)),
+		)
+		return
+	}
+
+	c.logger.Warn("Message moved to dead-letter queue",
+		zap.String("message_id", aws.StringValue(sqsMsg.MessageId)),
+		zap.String("reason", reason),
+	)
+}
+
+// keepGroup sends input, a copy of sqsMsg, in the message group of sqsMsg
+// when the queues are FIFO queues. The copy is deduplicated by the ID of
+// sqsMsg rather than its event, which was sent moments ago.
+func (c *Client) keepGroup(input *sqs.SendMessageInput, sqsMsg *sqs.Message) {
+	if !c.fifo {
+		return
+	}
+	group := aws.StringValue(sqsMsg.Attributes[sqs.MessageSystemAttributeNameMessageGroupId])
+	if group == "" {
+		group = aws.StringValue(sqsMsg.MessageId)
+	}
+	input.MessageGroupId = aws.String(group)
+	input.MessageDeduplicationId = sqsMsg.MessageId
+}
+
+// receiveDeadLetters receives up to max messages from the dead-letter queue,
+// hiding them from other receivers for deadLetterVisibility seconds
+func (c *Client) receiveDeadLetters(ctx context.Context, max int) ([]*sqs.Message, error) {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	if max > 10 {
+		max = 10
+	}
+	result, err := c.sqsClient.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(c.dlqURL),
+		MaxNumberOfMessages: aws.Int64(int64(max)),
+		VisibilityTimeout:   aws.Int64(deadLetterVisibility),
+		AttributeNames: []*string{
+			aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
+			aws.String(sqs.MessageSystemAttributeNameSentTimestamp),
+			aws.String(sqs.MessageSystemAttributeNameMessageGroupId),
+		},
+		MessageAttributeNames: []*string{
+			aws.String("All"),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive dead letters: %w", err)
+	}
+	return result.Messages, nil
+}
+
+// InspectDeadLetters returns up to max messages from the dead-letter queue
+// without removing them
+func (c *Client) InspectDeadLetters(ctx context.Context, max int) ([]*DeadLetter, error) {
+	if c.dlqURL == "" {
+		return nil, ErrNoDeadLetterQueue
+	}
+
+	var received []*sqs.Message
+	for len(received) < max {
+		batch, err := c.receiveDeadLetters(ctx, max-len(received))
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		received = append(received, batch...)
+	}
+
+	letters := make([]*DeadLetter, 0, len(received))
+	for _, sqsMsg := range received {
+		letter := &DeadLetter{
+			MessageID:    aws.StringValue(sqsMsg.MessageId),
+			Body:         aws.StringValue(sqsMsg.Body),
+			ReceiveCount: receiveCountOf(sqsMsg),
+		}
+		if value, ok := sqsMsg.Attributes[sqs.MessageSystemAttributeNameSentTimestamp]; ok && value != nil {
+			if millis, err := strconv.ParseInt(*value, 10, 64); err == nil {
+				letter.SentAt = time.UnixMilli(millis).UTC()
+			}
+		}
+		if reason, ok := sqsMsg.MessageAttributes[deadLetterReasonAttribute]; ok {
+			letter.Reason = aws.StringValue(reason.StringValue)
+		}
+		letters = append(letters, letter)
+
+		// Make the message visible again right away
+		if err := c.changeVisibility(ctx, c.dlqURL, aws.StringValue(sqsMsg.ReceiptHandle), 0); err != nil {
+			c.logger.Warn("Failed to release inspected dead letter",
+				zap.Error(err),
+				zap.String("message_id", letter.MessageID),
+			)
+		}
+	}
+
+	return letters, nil
+}
+
+// RedriveDeadLetters moves up to max messages from the dead-letter queue back
+// to the queue, or all of them when max isn't positive, and returns how many
+// it moved
+func (c *Client) RedriveDeadLetters(ctx context.Context, max int) (int, error) {
+	if c.dlqURL == "" {
+		return 0, ErrNoDeadLetterQueue
+	}
+
+	moved := 0
+	for max <= 0 || moved < max {
+		want := 10
+		if max > 0 && max-moved < want {
+			want = max - moved
+		}
+		batch, err := c.receiveDeadLetters(ctx, want)
+		if err != nil {
+			return moved, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, sqsMsg := range batch {
+			if err := c.redrive(ctx, sqsMsg); err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+
+	c.logger.Info("Dead letters redriven", zap.Int("count", moved))
+	return moved, nil
+}
+
+// redrive sends a dead letter back to the queue and deletes it from the
+// dead-letter queue
+func (c *Client) redrive(ctx context.Context, sqsMsg *sqs.Message) error {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	attributes := map[string]*sqs.MessageAttributeValue{}
+	for name, value := range sqsMsg.MessageAttributes {
+		if name != deadLetterReasonAttribute {
+			attributes[name] = value
+		}
+	}
+	input := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(c.queueURL),
+		MessageBody: sqsMsg.Body,
+	}
+	if len(attributes) > 0 {
+		input.MessageAttributes = attributes
+	}
+	c.keepGroup(input, sqsMsg)
+	if _, err := c.sqsClient.SendMessageWithContext(ctx, input); err != nil {
+		return fmt.Errorf("failed to redrive message %s: %w", aws.StringValue(sqsMsg.MessageId), err)
+	}
+
+	// If this fails the message is redriven again later, like any duplicate
+	if _, err := c.sqsClient.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(c.dlqURL),
+		ReceiptHandle: sqsMsg.ReceiptHandle,
+	}); err != nil {
+		return fmt.Errorf("failed to delete redriven message %s: %w", aws.StringValue(sqsMsg.MessageId), err)
+	}
+	return nil
+}
+
+// PurgeDeadLetters deletes every message in the dead-letter queue
+func (c *Client) PurgeDeadLetters(ctx context.Context) error {
+	if c.dlqURL == "" {
+		return ErrNoDeadLetterQueue
+	}
+
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	if _, err := c.sqsClient.PurgeQueueWithContext(ctx, &sqs.PurgeQueueInput{
+		QueueUrl: aws.String(c.dlqURL),
+	}); err != nil {
+		return fmt.Errorf("failed to purge dead-letter queue: %w", err)
+	}
+
+	c.logger.Warn("Dead-letter queue purged", zap.String("queue_url", c.dlqURL))
+	return nil
+}
+
+// changeVisibility changes how long a received message stays hidden
+func (c *Client) changeVisibility(ctx context.Context, queueURL, receiptHandle string, seconds int64) error {
+	ctx, cancel := withTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.sqsClient.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queueURL),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: aws.Int64(seconds),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change message visibility: %w", err)
+	}
+	return nil
+}
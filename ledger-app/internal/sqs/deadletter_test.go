@@ -0,0 +1,359 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+const (
+	testQueueURL = "https://sqs.test/queue"
+	testDLQURL   = "https://sqs.test/queue-dlq"
+)
+
+func newDeadLetterClient(mockAPI *mockSQSAPI) *Client {
+	client := newTestableClient(mockAPI, testQueueURL, zap.NewNop())
+	client.dlqURL = testDLQURL
+	client.maxReceiveCount = 3
+	return client
+}
+
+func TestEnsureQueue_CreatesDeadLetterQueue(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	notFound := awserr.New("AWS.SimpleQueueService.NonExistentQueue", "queue not found", nil)
+
+	mockAPI.On("GetQueueUrlWithContext", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockAPI.On("CreateQueueWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
+		return *input.QueueName == "new-queue-dlq"
+	})).Return(&sqs.CreateQueueOutput{QueueUrl: aws.String("https://sqs.test/new-queue-dlq")}, nil)
+	mockAPI.On("GetQueueAttributesWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.GetQueueAttributesInput) bool {
+		return *input.QueueUrl == "https://sqs.test/new-queue-dlq"
+	})).Return(&sqs.GetQueueAttributesOutput{
+		Attributes: map[string]*string{"QueueArn": aws.String("arn:aws:sqs:us-east-1:000000000000:new-queue-dlq")},
+	}, nil)
+
+	var policy redrivePolicy
+	mockAPI.On("CreateQueueWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
+		if *input.QueueName != "new-queue" || input.Attributes["RedrivePolicy"] == nil {
+			return false
+		}
+		return json.Unmarshal([]byte(*input.Attributes["RedrivePolicy"]), &policy) == nil
+	})).Return(&sqs.CreateQueueOutput{QueueUrl: aws.String("https://sqs.test/new-queue")}, nil)
+
+	queueURL, dlqURL, err := ensureQueue(context.Background(), mockAPI, "new-queue", "us-east-1", 5, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if queueURL != "https://sqs.test/new-queue" || dlqURL != "https://sqs.test/new-queue-dlq" {
+		t.Errorf("Unexpected queue URLs %s and %s", queueURL, dlqURL)
+	}
+	if policy.DeadLetterTargetArn != "arn:aws:sqs:us-east-1:000000000000:new-queue-dlq" || policy.MaxReceiveCount != "5" {
+		t.Errorf("Unexpected redrive policy %+v", policy)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestEnsureQueue_SetsRedrivePolicyOnExistingQueue(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+
+	mockAPI.On("GetQueueUrlWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.GetQueueUrlInput) bool {
+		return *input.QueueName == "queue-dlq"
+	})).Return(&sqs.GetQueueUrlOutput{QueueUrl: aws.String(testDLQURL)}, nil)
+	mockAPI.On("GetQueueAttributesWithContext", mock.Anything, mock.Anything).Return(&sqs.GetQueueAttributesOutput{
+		Attributes: map[string]*string{"QueueArn": aws.String("arn:aws:sqs:us-east-1:000000000000:queue-dlq")},
+	}, nil)
+	mockAPI.On("GetQueueUrlWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.GetQueueUrlInput) bool {
+		return *input.QueueName == "queue"
+	})).Return(&sqs.GetQueueUrlOutput{QueueUrl: aws.String(testQueueURL)}, nil)
+	mockAPI.On("SetQueueAttributesWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SetQueueAttributesInput) bool {
+		return *input.QueueUrl == testQueueURL && input.Attributes["RedrivePolicy"] != nil
+	})).Return(&sqs.SetQueueAttributesOutput{}, nil)
+
+	if _, _, err := ensureQueue(context.Background(), mockAPI, "queue", "us-east-1", 5, false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	mockAPI.AssertExpectations(t)
+	mockAPI.AssertNotCalled(t, "CreateQueueWithContext", mock.Anything, mock.Anything)
+}
+
+func TestEnsureQueue_CreatesFIFOQueues(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	notFound := awserr.New("AWS.SimpleQueueService.NonExistentQueue", "queue not found", nil)
+	isFIFO := func(attributes map[string]*string) bool {
+		return aws.StringValue(attributes["FifoQueue"]) == "true" &&
+			aws.StringValue(attributes["ContentBasedDeduplication"]) == "false"
+	}
+
+	mockAPI.On("GetQueueUrlWithContext", mock.Anything, mock.Anything).Return(nil, notFound)
+	mockAPI.On("CreateQueueWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
+		return *input.QueueName == "new-queue-dlq.fifo" && isFIFO(input.Attributes)
+	})).Return(&sqs.CreateQueueOutput{QueueUrl: aws.String("https://sqs.test/new-queue-dlq.fifo")}, nil)
+	mockAPI.On("GetQueueAttributesWithContext", mock.Anything, mock.Anything).Return(&sqs.GetQueueAttributesOutput{
+		Attributes: map[string]*string{"QueueArn": aws.String("arn:aws:sqs:us-east-1:000000000000:new-queue-dlq.fifo")},
+	}, nil)
+	mockAPI.On("CreateQueueWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.CreateQueueInput) bool {
+		return *input.QueueName == "new-queue.fifo" && isFIFO(input.Attributes) && input.Attributes["RedrivePolicy"] != nil
+	})).Return(&sqs.CreateQueueOutput{QueueUrl: aws.String("https://sqs.test/new-queue.fifo")}, nil)
+
+	queueURL, dlqURL, err := ensureQueue(context.Background(), mockAPI, "new-queue", "us-east-1", 5, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if queueURL != "https://sqs.test/new-queue.fifo" || dlqURL != "https://sqs.test/new-queue-dlq.fifo" {
+		t.Errorf("Unexpected queue URLs %s and %s", queueURL, dlqURL)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_ReceiveMessages_ReceiveCount(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+
+	body, _ := json.Marshal(newTestEvent("tx-1"))
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{
+			{
+				MessageId:     aws.String("msg-1"),
+				Body:          aws.String(string(body)),
+				ReceiptHandle: aws.String("receipt-1"),
+				Attributes:    map[string]*string{"ApproximateReceiveCount": aws.String("1")},
+			},
+			{
+				MessageId:     aws.String("msg-2"),
+				Body:          aws.String(string(body)),
+				ReceiptHandle: aws.String("receipt-2"),
+				Attributes:    map[string]*string{"ApproximateReceiveCount": aws.String("3")},
+			},
+		},
+	}, nil)
+
+	received, err := client.ReceiveMessages(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(received))
+	}
+	if received[0].ReceiveCount != 1 || received[0].FinalDelivery {
+		t.Errorf("Expected a first delivery, got %+v", received[0])
+	}
+	if received[1].ReceiveCount != 3 || !received[1].FinalDelivery {
+		t.Errorf("Expected the final delivery, got %+v", received[1])
+	}
+}
+
+func TestClient_ReceiveMessages_MovesUnreadableToDeadLetterQueue(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{
+			{
+				MessageId:     aws.String("msg-1"),
+				Body:          aws.String("invalid json"),
+				ReceiptHandle: aws.String("receipt-1"),
+			},
+		},
+	}, nil)
+	mockAPI.On("SendMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageInput) bool {
+		reason := input.MessageAttributes[deadLetterReasonAttribute]
+		return *input.QueueUrl == testDLQURL && *input.MessageBody == "invalid json" && reason != nil && *reason.StringValue != ""
+	})).Return(&sqs.SendMessageOutput{}, nil)
+	mockAPI.On("DeleteMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.DeleteMessageInput) bool {
+		return *input.QueueUrl == testQueueURL && *input.ReceiptHandle == "receipt-1"
+	})).Return(&sqs.DeleteMessageOutput{}, nil)
+
+	received, err := client.ReceiveMessages(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("Expected the unreadable message to be skipped, got %d", len(received))
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_ReceiveMessages_FIFOKeepsMessageGroup(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+	client.fifo = true
+
+	body, _ := json.Marshal(newTestEvent("tx-1"))
+	group := map[string]*string{"MessageGroupId": aws.String("acc1")}
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{
+			{MessageId: aws.String("msg-1"), Body: aws.String(string(body)), ReceiptHandle: aws.String("receipt-1"), Attributes: group},
+			{MessageId: aws.String("msg-2"), Body: aws.String("invalid json"), ReceiptHandle: aws.String("receipt-2"), Attributes: group},
+		},
+	}, nil)
+	// The unreadable message goes to the dead-letter queue in its group,
+	// deduplicated by its message ID
+	mockAPI.On("SendMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageInput) bool {
+		return *input.QueueUrl == testDLQURL &&
+			aws.StringValue(input.MessageGroupId) == "acc1" &&
+			aws.StringValue(input.MessageDeduplicationId) == "msg-2"
+	})).Return(&sqs.SendMessageOutput{}, nil)
+	mockAPI.On("DeleteMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.DeleteMessageOutput{}, nil)
+
+	received, err := client.ReceiveMessages(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(received) != 1 || received[0].MessageGroupID != "acc1" {
+		t.Errorf("Expected one message in group acc1, got %+v", received)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_InspectDeadLetters(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+
+	sentAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.ReceiveMessageInput) bool {
+		return *input.QueueUrl == testDLQURL && *input.MaxNumberOfMessages == 5
+	})).Return(&sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{
+			{
+				MessageId:     aws.String("msg-1"),
+				Body:          aws.String("invalid json"),
+				ReceiptHandle: aws.String("receipt-1"),
+				Attributes: map[string]*string{
+					"ApproximateReceiveCount": aws.String("2"),
+					"SentTimestamp":           aws.String("1767323045000"),
+				},
+				MessageAttributes: map[string]*sqs.MessageAttributeValue{
+					deadLetterReasonAttribute: {DataType: aws.String("String"), StringValue: aws.String("invalid message body")},
+				},
+			},
+		},
+	}, nil).Once()
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.ReceiveMessageOutput{}, nil).Once()
+	mockAPI.On("ChangeMessageVisibilityWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.ChangeMessageVisibilityInput) bool {
+		return *input.QueueUrl == testDLQURL && *input.ReceiptHandle == "receipt-1" && *input.VisibilityTimeout == 0
+	})).Return(&sqs.ChangeMessageVisibilityOutput{}, nil)
+
+	letters, err := client.InspectDeadLetters(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", len(letters))
+	}
+	letter := letters[0]
+	if letter.MessageID != "msg-1" || letter.Body != "invalid json" || letter.ReceiveCount != 2 ||
+		!letter.SentAt.Equal(sentAt) || letter.Reason != "invalid message body" {
+		t.Errorf("Unexpected dead letter %+v", letter)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_RedriveDeadLetters(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.ReceiveMessageInput) bool {
+		return *input.QueueUrl == testDLQURL
+	})).Return(&sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{
+			{
+				MessageId:     aws.String("msg-1"),
+				Body:          aws.String(`{"action":"transaction_created"}`),
+				ReceiptHandle: aws.String("receipt-1"),
+				MessageAttributes: map[string]*sqs.MessageAttributeValue{
+					"Action":                  {DataType: aws.String("String"), StringValue: aws.String("transaction_created")},
+					deadLetterReasonAttribute: {DataType: aws.String("String"), StringValue: aws.String("invalid")},
+				},
+			},
+			{
+				MessageId:     aws.String("msg-2"),
+				Body:          aws.String(`{"action":"transaction_created"}`),
+				ReceiptHandle: aws.String("receipt-2"),
+			},
+		},
+	}, nil).Once()
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.ReceiveMessageOutput{}, nil).Once()
+	mockAPI.On("SendMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageInput) bool {
+		_, hasReason := input.MessageAttributes[deadLetterReasonAttribute]
+		return *input.QueueUrl == testQueueURL && !hasReason
+	})).Return(&sqs.SendMessageOutput{}, nil).Twice()
+	mockAPI.On("DeleteMessageWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.DeleteMessageInput) bool {
+		return *input.QueueUrl == testDLQURL
+	})).Return(&sqs.DeleteMessageOutput{}, nil).Twice()
+
+	moved, err := client.RedriveDeadLetters(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("Expected 2 messages redriven, got %d", moved)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_RedriveDeadLetters_SendFails(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+
+	mockAPI.On("ReceiveMessageWithContext", mock.Anything, mock.Anything).Return(&sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{
+			{MessageId: aws.String("msg-1"), Body: aws.String("{}"), ReceiptHandle: aws.String("receipt-1")},
+		},
+	}, nil)
+	mockAPI.On("SendMessageWithContext", mock.Anything, mock.Anything).Return(nil, errors.New("SQS error"))
+
+	moved, err := client.RedriveDeadLetters(context.Background(), 10)
+	if err == nil {
+		t.Error("Expected error, got nil")
+	}
+	if moved != 0 {
+		t.Errorf("Expected nothing redriven, got %d", moved)
+	}
+
+	// The message stays in the dead-letter queue
+	mockAPI.AssertNotCalled(t, "DeleteMessageWithContext", mock.Anything, mock.Anything)
+}
+
+func TestClient_PurgeDeadLetters(t *testing.T) {
+	mockAPI := new(mockSQSAPI)
+	client := newDeadLetterClient(mockAPI)
+
+	mockAPI.On("PurgeQueueWithContext", mock.Anything, mock.MatchedBy(func(input *sqs.PurgeQueueInput) bool {
+		return *input.QueueUrl == testDLQURL
+	})).Return(&sqs.PurgeQueueOutput{}, nil)
+
+	if err := client.PurgeDeadLetters(context.Background()); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestClient_DeadLetters_NotConfigured(t *testing.T) {
+	client := newTestableClient(new(mockSQSAPI), testQueueURL, zap.NewNop())
+
+	if _, err := client.InspectDeadLetters(context.Background(), 10); !errors.Is(err, ErrNoDeadLetterQueue) {
+		t.Errorf("Expected ErrNoDeadLetterQueue from inspect, got: %v", err)
+	}
+	if _, err := client.RedriveDeadLetters(context.Background(), 10); !errors.Is(err, ErrNoDeadLetterQueue) {
+		t.Errorf("Expected ErrNoDeadLetterQueue from redrive, got: %v", err)
+	}
+	if err := client.PurgeDeadLetters(context.Background()); !errors.Is(err, ErrNoDeadLetterQueue) {
+		t.Errorf("Expected ErrNoDeadLetterQueue from purge, got: %v", err)
+	}
+}
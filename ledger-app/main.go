@@ -2,22 +2,40 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/project-atlas/ledger-app/internal/api"
 	"github.com/project-atlas/ledger-app/internal/config"
 	"github.com/project-atlas/ledger-app/internal/database"
+	"github.com/project-atlas/ledger-app/internal/events"
+	"github.com/project-atlas/ledger-app/internal/fx"
+	"github.com/project-atlas/ledger-app/internal/kafka"
+	"github.com/project-atlas/ledger-app/internal/outbox"
 	"github.com/project-atlas/ledger-app/internal/s3"
+	"github.com/project-atlas/ledger-app/internal/schedule"
 	"github.com/project-atlas/ledger-app/internal/sqs"
 	"go.uber.org/zap"
 )
 
+const (
+	// holdSweepBatchSize is the number of expired holds released per database transaction
+	holdSweepBatchSize = 100
+	// scheduleBatchSize is the number of due schedules picked up per poll
+	scheduleBatchSize = 100
+	// outboxBatchSize is the number of outbox messages claimed at a time
+	outboxBatchSize = 100
+)
+
 func main() {
 	// Initialize logger
 	logger, err := zap.NewProduction()
@@ -32,15 +50,46 @@ func main() {
 	cfg := config.LoadConfig()
 	secrets := config.LoadSecrets()
 
+	dbConfig := database.Config{
+		Host:        cfg.Database.Host,
+		Port:        cfg.Database.Port,
+		Database:    cfg.Database.Database,
+		User:        secrets.DatabaseUser,
+		Password:    secrets.DatabasePassword,
+		Timeout:     cfg.Database.Timeout,
+		AutoMigrate: cfg.Database.AutoMigrate,
+	}
+
+	sqsConfig := sqs.Config{
+		Endpoint:        cfg.AWS.Endpoint,
+		Region:          cfg.AWS.Region,
+		Queue:           cfg.AWS.SQSQueue,
+		Timeout:         cfg.AWS.Timeout,
+		WaitTime:        cfg.AWS.SQSWaitTime,
+		MaxReceiveCount: cfg.AWS.MaxReceiveCount,
+		FIFO:            cfg.AWS.SQSFIFO,
+	}
+
+	// `ledger-app migrate up|down|status` manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		code := runMigrate(os.Args[2:], dbConfig, logger)
+		logger.Sync()
+		os.Exit(code)
+	}
+
+	// `ledger-app dlq inspect|redrive|purge` manages the dead-letter queue and exits
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		if cfg.Events.Backend != "sqs" {
+			fmt.Fprintf(os.Stderr, "dlq: the dead-letter queue belongs to SQS, but EVENTS_BACKEND is %q\n", cfg.Events.Backend)
+			os.Exit(2)
+		}
+		code := runDLQ(os.Args[2:], sqsConfig, logger)
+		logger.Sync()
+		os.Exit(code)
+	}
+
 	// Initialize database
-	db, err := database.New(database.Config{
-		Host:     cfg.Database.Host,
-		Port:     cfg.Database.Port,
-		Database: cfg.Database.Database,
-		User:     secrets.DatabaseUser,
-		Password: secrets.DatabasePassword,
-		Timeout:  10 * time.Second,
-	}, logger)
+	db, err := database.New(dbConfig, logger)
 	if err != nil {
 		logger.Fatal("Failed to initialize database", zap.Error(err))
 	}
@@ -51,38 +100,71 @@ func main() {
 		Endpoint: cfg.AWS.Endpoint,
 		Region:   cfg.AWS.Region,
 		Bucket:   cfg.AWS.S3Bucket,
+		Timeout:  cfg.AWS.Timeout,
 	}, logger)
 	if err != nil {
 		logger.Fatal("Failed to initialize S3 client", zap.Error(err))
 	}
 
-	// Initialize SQS client
-	sqsClient, err := sqs.New(sqs.Config{
-		Endpoint: cfg.AWS.Endpoint,
-		Region:   cfg.AWS.Region,
-		Queue:    cfg.AWS.SQSQueue,
-	}, logger)
+	// Initialize message broker
+	broker, err := openBroker(cfg, sqsConfig, logger)
 	if err != nil {
-		logger.Fatal("Failed to initialize SQS client", zap.Error(err))
+		logger.Fatal("Failed to initialize message broker", zap.Error(err))
 	}
+	defer broker.Close()
 
 	// Initialize HTTP handler
-	handler := api.NewHandler(db, s3Client, sqsClient, cfg.App.Region, logger)
+	handler := api.NewHandler(db, s3Client, broker, cfg.App.Region, logger)
+	handler.SetIdempotencyTTL(cfg.App.IdempotencyTTL)
+	handler.SetIdempotencyLease(cfg.App.IdempotencyLease)
+	handler.SetHoldTTL(cfg.Holds.DefaultTTL)
+	handler.SetMaxBatchSize(cfg.App.MaxBatchSize)
+
+	// FX quotes are only available when a rates file is configured
+	if cfg.FX.RatesFile != "" {
+		rates, err := fx.LoadFile(cfg.FX.RatesFile)
+		if err != nil {
+			logger.Fatal("Failed to load FX rates", zap.Error(err))
+		}
+		handler.SetRateProvider(rates, cfg.FX.QuoteTTL)
+	}
 
 	// Setup router
 	router := mux.NewRouter()
 	router.HandleFunc("/health", handler.Health).Methods("GET")
 	router.HandleFunc("/ready", handler.Readiness).Methods("GET")
 	router.HandleFunc("/live", handler.Liveness).Methods("GET")
-	router.HandleFunc("/transactions", handler.CreateTransaction).Methods("POST")
+	router.HandleFunc("/transactions", handler.Idempotent(handler.CreateTransaction)).Methods("POST")
 	router.HandleFunc("/transactions", handler.ListTransactions).Methods("GET")
+	router.HandleFunc("/transactions/batch", handler.Idempotent(handler.CreateTransactionBatch)).Methods("POST")
 	router.HandleFunc("/transactions/{id}", handler.GetTransaction).Methods("GET")
+	router.HandleFunc("/transactions/{id}/status", handler.UpdateTransactionStatus).Methods("PATCH")
+	router.HandleFunc("/transactions/{id}/reverse", handler.Idempotent(handler.ReverseTransaction)).Methods("POST")
+	router.HandleFunc("/fx/quotes", handler.CreateFXQuote).Methods("POST")
+	router.HandleFunc("/holds", handler.Idempotent(handler.CreateHold)).Methods("POST")
+	router.HandleFunc("/holds/{id}", handler.GetHold).Methods("GET")
+	router.HandleFunc("/holds/{id}/capture", handler.Idempotent(handler.CaptureHold)).Methods("POST")
+	router.HandleFunc("/holds/{id}/void", handler.Idempotent(handler.VoidHold)).Methods("POST")
+	router.HandleFunc("/schedules", handler.Idempotent(handler.CreateSchedule)).Methods("POST")
+	router.HandleFunc("/schedules/{id}", handler.GetSchedule).Methods("GET")
+	router.HandleFunc("/schedules/{id}/runs", handler.ListScheduleRuns).Methods("GET")
+	router.HandleFunc("/schedules/{id}/cancel", handler.CancelSchedule).Methods("POST")
+	router.HandleFunc("/accounts", handler.CreateAccount).Methods("POST")
+	router.HandleFunc("/accounts/{id}", handler.GetAccount).Methods("GET")
+	router.HandleFunc("/accounts/{id}/balance", handler.GetAccountBalance).Methods("GET")
+	router.HandleFunc("/accounts/{id}/transactions", handler.ListAccountTransactions).Methods("GET")
+	router.HandleFunc("/accounts/{id}/statement", handler.GetAccountStatement).Methods("GET")
 	router.HandleFunc("/stats", handler.GetStats).Methods("GET")
 
 	// Add middleware
 	router.Use(loggingMiddleware(logger))
 	router.Use(corsMiddleware())
 
+	// Requests and background workers run under ctx, which is cancelled
+	// when shutdown gives up waiting for them
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.App.Port),
@@ -90,6 +172,7 @@ func main() {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return ctx },
 	}
 
 	// Start server in a goroutine
@@ -103,8 +186,24 @@ func main() {
 		}
 	}()
 
-	// Start SQS message processor in background
-	go processSQSMessages(sqsClient, db, s3Client, cfg.App.Region, logger)
+	// Start message consumer in background
+	consumer := events.NewConsumer(broker, events.ConsumerConfig{
+		Workers:           cfg.Consumer.Workers,
+		VisibilityTimeout: cfg.Consumer.VisibilityTimeout,
+	}, logger)
+	registerEventHandlers(consumer, db, logger)
+	go consumer.Run(ctx)
+
+	// Start hold expiry sweeper in background
+	go expireHolds(ctx, db, cfg.Holds.SweepInterval, logger)
+
+	// Start scheduled transfer runner in background
+	scheduler := schedule.NewScheduler(db, cfg.App.Region, logger)
+	go runSchedules(ctx, scheduler, cfg.Schedules.PollInterval, logger)
+
+	// Start outbox relay in background
+	relay := outbox.NewRelay(db, s3Client, broker, cfg.App.Region, logger)
+	go relayOutbox(ctx, relay, cfg.Outbox.PollInterval, logger)
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -113,65 +212,291 @@ func main() {
 
 	logger.Info("Shutting down server...")
 
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// Graceful shutdown: in-flight requests and messages get 30 seconds
+	// to finish before their queries and AWS calls are cancelled
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer shutdownCancel()
+
+	consumerStopped := make(chan error, 1)
+	go func() { consumerStopped <- consumer.Shutdown(shutdownCtx) }()
 
-	if err := server.Shutdown(ctx); err != nil {
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		logger.Error("Server forced to shutdown", zap.Error(err))
 	}
+	if err := <-consumerStopped; err != nil {
+		logger.Error("Message consumer forced to stop", zap.Error(err))
+	}
+	cancel()
 
 	logger.Info("Server stopped")
 }
 
-// processSQSMessages processes messages from SQS queue
-func processSQSMessages(sqsClient *sqs.Client, db *database.DB, s3Client *s3.Client, region string, logger *zap.Logger) {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+// runMigrate runs the migrate subcommand and returns the process exit code.
+// `down` reverts one migration unless given a number of steps.
+func runMigrate(args []string, dbConfig database.Config, logger *zap.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: ledger-app migrate up|down [steps]|status")
+		return 2
+	}
 
-	for range ticker.C {
-		receivedMessages, err := sqsClient.ReceiveMessages(10, 0)
+	db, err := database.Open(dbConfig, logger)
+	if err != nil {
+		logger.Error("Failed to initialize database", zap.Error(err))
+		return 1
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp()
 		if err != nil {
-			logger.Warn("Failed to receive SQS messages", zap.Error(err))
-			continue
+			logger.Error("Migration failed", zap.Error(err))
+			return 1
 		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
 
-		for _, receivedMsg := range receivedMessages {
-			msg := receivedMsg.Message
-			logger.Info("Processing SQS message",
-				zap.String("transaction_id", msg.TransactionID),
-				zap.String("action", msg.Action),
-			)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, "migrate down: steps must be a positive number")
+				return 2
+			}
+		}
+		reverted, err := db.MigrateDown(steps)
+		if err != nil {
+			logger.Error("Migration failed", zap.Error(err))
+			return 1
+		}
+		fmt.Printf("Reverted %d migration(s)\n", reverted)
+
+	case "status":
+		statuses, err := db.MigrationStatus()
+		if err != nil {
+			logger.Error("Failed to read migration status", zap.Error(err))
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+		}
+		w.Flush()
+
+	default:
+		fmt.Fprintf(os.Stderr, "migrate: unknown command %q\n", args[0])
+		return 2
+	}
+
+	return 0
+}
+
+// runDLQ runs a dead-letter queue command and returns the process exit code
+func runDLQ(args []string, sqsConfig sqs.Config, logger *zap.Logger) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: ledger-app dlq inspect [count]|redrive [count]|purge")
+		return 2
+	}
+
+	// inspect shows 10 messages by default; redrive moves all of them
+	count := 0
+	if args[0] == "inspect" {
+		count = 10
+	}
+	if len(args) > 1 {
+		var err error
+		if count, err = strconv.Atoi(args[1]); err != nil || count < 1 {
+			fmt.Fprintf(os.Stderr, "dlq %s: count must be a positive number\n", args[0])
+			return 2
+		}
+	}
+
+	client, err := sqs.New(sqsConfig, logger)
+	if err != nil {
+		logger.Error("Failed to initialize SQS client", zap.Error(err))
+		return 1
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "inspect":
+		letters, err := client.InspectDeadLetters(ctx, count)
+		if err != nil {
+			logger.Error("Failed to inspect dead-letter queue", zap.Error(err))
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MESSAGE ID\tRECEIVES\tSENT\tREASON\tBODY")
+		for _, letter := range letters {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", letter.MessageID, letter.ReceiveCount,
+				letter.SentAt.Format(time.RFC3339), letter.Reason, abbreviate(letter.Body, 60))
+		}
+		w.Flush()
+
+	case "redrive":
+		moved, err := client.RedriveDeadLetters(ctx, count)
+		if err != nil {
+			logger.Error("Failed to redrive dead-letter queue", zap.Error(err), zap.Int("redriven", moved))
+			return 1
+		}
+		fmt.Printf("Redrove %d message(s)\n", moved)
+
+	case "purge":
+		if err := client.PurgeDeadLetters(ctx); err != nil {
+			logger.Error("Failed to purge dead-letter queue", zap.Error(err))
+			return 1
+		}
+		fmt.Println("Purged the dead-letter queue")
+
+	default:
+		fmt.Fprintf(os.Stderr, "dlq: unknown command %q\n", args[0])
+		return 2
+	}
+
+	return 0
+}
+
+// abbreviate shortens s to at most n runes for display
+func abbreviate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// openBroker connects to the message broker selected by EVENTS_BACKEND
+func openBroker(cfg config.Config, sqsConfig sqs.Config, logger *zap.Logger) (events.Broker, error) {
+	switch cfg.Events.Backend {
+	case "sqs":
+		client, err := sqs.New(sqsConfig, logger)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+
+	case "kafka":
+		client, err := kafka.New(kafka.Config{
+			Brokers: cfg.Events.KafkaBrokers,
+			Topic:   cfg.Events.KafkaTopic,
+			GroupID: cfg.Events.KafkaGroupID,
+			Timeout: cfg.AWS.Timeout,
+		}, logger)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+
+	case "memory":
+		logger.Warn("Using the in-memory message broker; messages are lost on restart and not shared with other instances")
+		return events.NewMemoryBroker(), nil
+	}
+
+	return nil, fmt.Errorf("unknown events backend %q", cfg.Events.Backend)
+}
 
-			// Process message based on action
-			processed := false
-			switch msg.Action {
-			case "transaction_created":
-				// Message already processed during API call, just log
-				logger.Info("Transaction created message processed",
-					zap.String("transaction_id", msg.TransactionID),
-				)
-				processed = true
-			default:
-				logger.Info("Unknown action", zap.String("action", msg.Action))
-				processed = true // Delete unknown messages to prevent infinite retries
+// registerEventHandlers registers a handler for the event types this release
+// acts on. Events of a known type without a handler are acked; events of a
+// type this release doesn't know are left to be delivered again, so a newer
+// release can handle them.
+// Handlers registered with HandleOnce make their writes in the transaction
+// that records the event in processed_events, so they take effect once.
+func registerEventHandlers(consumer *events.Consumer, db *database.DB, logger *zap.Logger) {
+	consumer.HandleOnce(events.TypeTransactionCreated, db, func(ctx context.Context, tx *sql.Tx, event *events.Envelope) error {
+		var created events.TransactionCreated
+		if err := event.Decode(&created); err != nil {
+			return err
+		}
+		// Message already processed during API call, just log
+		logger.Info("Transaction created message processed",
+			zap.String("transaction_id", created.TransactionID.String()),
+			zap.String("correlation_id", event.CorrelationID),
+		)
+		return nil
+	})
+}
+
+// expireHolds periodically releases holds that were neither captured nor voided
+// before they expired, until ctx is cancelled
+func expireHolds(ctx context.Context, db *database.DB, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Work through the backlog in batches so one sweep never holds
+		// locks on too many rows at once
+		for {
+			expired, err := db.ExpireHolds(ctx, time.Now().UTC(), holdSweepBatchSize)
+			if err != nil {
+				logger.Warn("Failed to expire holds", zap.Error(err))
+				break
+			}
+			if expired < holdSweepBatchSize {
+				break
 			}
+		}
+	}
+}
+
+// runSchedules periodically materializes due schedule executions into
+// transactions. The scheduler in the other region polls the same schedules;
+// each execution is still posted only once. It stops when ctx is cancelled.
+func runSchedules(ctx context.Context, scheduler *schedule.Scheduler, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-			// Delete message from queue after successful processing
-			if processed {
-				if err := sqsClient.DeleteMessage(receivedMsg.ReceiptHandle); err != nil {
-					logger.Error("Failed to delete SQS message after processing",
-						zap.Error(err),
-						zap.String("transaction_id", msg.TransactionID),
-						zap.String("receipt_handle", receivedMsg.ReceiptHandle),
-					)
-					// Message will become visible again after visibility timeout
-					// and will be retried
-				} else {
-					logger.Info("SQS message deleted after processing",
-						zap.String("transaction_id", msg.TransactionID),
-					)
-				}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			executed, err := scheduler.RunDue(ctx, time.Now().UTC(), scheduleBatchSize)
+			if err != nil {
+				logger.Warn("Failed to run due schedules", zap.Error(err))
+				break
+			}
+			if executed < scheduleBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// relayOutbox periodically delivers the side effects stored in the outbox to
+// S3 and SQS until ctx is cancelled. Relays of the same region claim disjoint
+// messages, so several replicas can run at once.
+func relayOutbox(ctx context.Context, relay *outbox.Relay, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			claimed, err := relay.RunPending(ctx, time.Now().UTC(), outboxBatchSize)
+			if err != nil {
+				logger.Warn("Failed to relay outbox messages", zap.Error(err))
+				break
+			}
+			if claimed < outboxBatchSize {
+				break
 			}
 		}
 	}
@@ -197,8 +522,8 @@ func corsMiddleware() mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)